@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/ErebusAJ/YatraBandhu/internals/db"
+	"github.com/ErebusAJ/YatraBandhu/internals/utils"
+	"github.com/google/uuid"
+)
+
+// createAdmin
+// creates a verified account with the admin access_level,
+// an existing account with the email is promoted instead
+// the password is read from stdin when --password is not given
+func createAdmin(args []string) error {
+	fs := newFlagSet("create-admin")
+	email := fs.String("email", "", "admin email")
+	name := fs.String("name", "Admin", "admin name")
+	phone := fs.String("phone", "", "admin phone number, must be unique")
+	age := fs.Int("age", 18, "admin age")
+	password := fs.String("password", "", "admin password, read from stdin if empty")
+
+	a, err := setup(fs, args)
+	if err != nil {
+		return err
+	}
+	defer a.close()
+
+	if *email == "" {
+		return fmt.Errorf("--email is required")
+	}
+
+	ctx := context.Background()
+	return a.withTx(ctx, func(q *db.Queries) error {
+		user, err := q.GetUserByEmail(ctx, *email)
+		if err == nil {
+			err = promote(ctx, q, user.ID)
+			if err == nil {
+				log.Printf("promoted existing user %s to admin \n", *email)
+			}
+			return err
+		} else if err != sql.ErrNoRows {
+			return err
+		}
+
+		if *phone == "" {
+			return fmt.Errorf("--phone is required for a new account")
+		}
+		if *password == "" {
+			*password, err = readPassword()
+			if err != nil {
+				return err
+			}
+		}
+
+		hash, err := utils.HashPassword(*password)
+		if err != nil {
+			return err
+		}
+		id, err := q.RegisterUser(ctx, db.RegisterUserParams{
+			Name:         *name,
+			Age:          int32(*age),
+			PhoneNumber:  *phone,
+			Email:        *email,
+			PasswordHash: hash,
+		})
+		if err != nil {
+			return err
+		}
+
+		err = promote(ctx, q, id)
+		if err == nil {
+			log.Printf("created admin %s (%v) \n", *email, id)
+		}
+		return err
+	})
+}
+
+// promote
+// sets the admin access_level and marks the account verified
+func promote(ctx context.Context, q *db.Queries, userID uuid.UUID) error {
+	err := q.UpdateUserAccessLevel(ctx, db.UpdateUserAccessLevelParams{
+		AccessLevel: sql.NullString{String: utils.RoleAdmin, Valid: true},
+		ID:          userID,
+	})
+	if err != nil {
+		return err
+	}
+
+	return q.SetUserVerified(ctx, userID)
+}
+
+// readPassword
+// reads one line from stdin
+func readPassword() (string, error) {
+	fmt.Fprint(os.Stderr, "password: ")
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("reading password: %w", err)
+	}
+
+	password := strings.TrimRight(line, "\r\n")
+	if password == "" {
+		return "", fmt.Errorf("password must not be empty")
+	}
+	return password, nil
+}
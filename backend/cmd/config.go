@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/ErebusAJ/YatraBandhu/config"
+)
+
+// configCmd
+// prints the effective config as yaml, secrets masked with --redacted
+// an invalid config is still printed so it can be fixed
+func configCmd(args []string) error {
+	if len(args) == 0 || args[0] != "print" {
+		return fmt.Errorf("usage: config print [--redacted] [flags]")
+	}
+
+	fs := newFlagSet("config print")
+	redacted := fs.Bool("redacted", false, "mask secrets")
+
+	cfg, err := config.LoadFlags(fs, args[1:])
+	if cfg == nil {
+		return err
+	}
+
+	out, printErr := cfg.Print(*redacted)
+	if printErr != nil {
+		return printErr
+	}
+	fmt.Print(out)
+
+	return err
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"time"
+
+	"github.com/ErebusAJ/YatraBandhu/internals/db"
+	"github.com/google/uuid"
+)
+
+// exportUser
+// writes everything stored about one user as json,
+// the password hash is left out
+func exportUser(args []string) error {
+	fs := newFlagSet("export-user")
+	email := fs.String("email", "", "email of the user")
+	id := fs.String("id", "", "id of the user")
+	out := fs.String("out", "", "output file, stdout if empty")
+
+	a, err := setup(fs, args)
+	if err != nil {
+		return err
+	}
+	defer a.close()
+
+	ctx := context.Background()
+	userID, err := lookupUser(ctx, a.q, *email, *id)
+	if err != nil {
+		return err
+	}
+
+	export, err := collectUserData(ctx, a.q, userID)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.OpenFile(*out, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(export)
+}
+
+// lookupUser
+// resolves the user given by email or id
+func lookupUser(ctx context.Context, q *db.Queries, email, id string) (uuid.UUID, error) {
+	switch {
+	case id != "":
+		return uuid.Parse(id)
+	case email != "":
+		user, err := q.GetUserByEmail(ctx, email)
+		if err == sql.ErrNoRows {
+			return uuid.Nil, fmt.Errorf("no user with email %s", email)
+		}
+		return user.ID, err
+	default:
+		return uuid.Nil, fmt.Errorf("--email or --id is required")
+	}
+}
+
+func collectUserData(ctx context.Context, q *db.Queries, userID uuid.UUID) (map[string]any, error) {
+	user, err := q.GetUserByID(ctx, userID)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("no user with id %v", userID)
+	} else if err != nil {
+		return nil, err
+	}
+
+	travelDetails, err := q.GetUserPlansDetails(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	groups, err := q.GetUserGroups(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	requests, err := q.GetRequestsSentByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	aiPlans, err := q.GetUserAIPlans(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	aiPlanVersions, err := q.GetUserAIPlanVersions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	auditEvents, err := q.ListAuditEvents(ctx, db.ListAuditEventsParams{
+		UserID:    uuid.NullUUID{UUID: userID, Valid: true},
+		PageLimit: math.MaxInt32,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]any{
+		"exported_at": time.Now().UTC(),
+		"user": map[string]any{
+			"id":              user.ID,
+			"name":            user.Name,
+			"age":             user.Age,
+			"phone_number":    user.PhoneNumber,
+			"email":           user.Email,
+			"access_level":    user.AccessLevel.String,
+			"verified_status": user.VerifiedStatus.Bool,
+			"created_at":      nullTime(user.CreatedAt),
+			"updated_at":      nullTime(user.UpdatedAt),
+			"last_logged_in":  nullTime(user.LastLoggedIn),
+			"suspended_at":    nullTime(user.SuspendedAt),
+		},
+		"travel_details":   travelDetails,
+		"groups":           groups,
+		"group_requests":   requests,
+		"ai_plans":         aiPlans,
+		"ai_plan_versions": aiPlanVersions,
+		"audit_events":     auditEvents,
+	}, nil
+}
+
+func nullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
@@ -1,43 +1,111 @@
 package main
 
 import (
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
 	"os"
+	"strings"
 
-	"github.com/ErebusAJ/YatraBandhu/internals/handlers"
+	"github.com/ErebusAJ/YatraBandhu/config"
+	"github.com/ErebusAJ/YatraBandhu/internals/db"
 	"github.com/ErebusAJ/YatraBandhu/internals/utils"
-	"github.com/gin-gonic/gin"
-	"github.com/joho/godotenv"
 )
 
+// command
+// A subcommand of the yatrabandhu binary
+type command struct {
+	name  string
+	usage string
+	run   func(args []string) error
+}
+
+var commands = []command{
+	{"serve", "start the API server [--migrate-on-start]", serve},
+	{"migrate", "apply or inspect schema migrations up|down|status|redo", migrateCmd},
+	{"seed", "load demo users, guides, plans and groups [--file fixtures.yaml]", seed},
+	{"create-admin", "create an admin account --email --name --phone [--password]", createAdmin},
+	{"purge", "delete expired tokens, stale pending requests, orphaned AI plans, finished planner jobs and sent mail [--stale-after 720h]", purge},
+	{"export-user", "dump one user's data as json --email|--id [--out file]", exportUser},
+	{"config", "print the effective config [--redacted]", configCmd},
+}
+
 func main() {
-	log.Printf("\t\t Starting server... \n")
+	args := os.Args[1:]
 
-	// Load godotenv
-	err := godotenv.Load()
-	if err != nil {
-		log.Fatalf("error loading .env \n")
+	// serve is the default command, also when only flags are given
+	name := "serve"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+
+	for _, cmd := range commands {
+		if cmd.name != name {
+			continue
+		}
+
+		err := cmd.run(args)
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+		if err != nil {
+			log.Fatalf("%s: %v \n", name, err)
+		}
+		return
+	}
+
+	if name != "help" {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
 	}
+	usage()
+	if name != "help" {
+		os.Exit(2)
+	}
+}
+
+// usage
+// prints the available commands
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: yatrabandhu <command> [flags]\n\ncommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-14s %s\n", cmd.name, cmd.usage)
+	}
+	fmt.Fprintf(os.Stderr, "\nevery command accepts --config and the config flags, see `config print`\n")
+}
 
-	// Get port
-	port := os.Getenv("PORT_NO")
-	if port == "" {
-		log.Fatalf("unable to start server couldn't get port \n")
+// app
+// Config and DB shared by every command
+type app struct {
+	cfg *config.Config
+	DB  *sql.DB
+	q   *db.Queries
+}
+
+// setup
+// loads the config with the command's flags registered on fs
+// and opens the shared DB pool, callers must call close
+func setup(fs *flag.FlagSet, args []string) (*app, error) {
+	cfg, err := config.LoadFlags(fs, args)
+	if err != nil {
+		return nil, err
 	}
 
-	// Open DB connection
-	log.Printf("connecting to database... \n")
-	DB, err := utils.ConnectDB()
+	DB, err := utils.ConnectDB(cfg.DBURL, cfg.DB)
 	if err != nil {
-		log.Fatalf("%v \n", err)
+		return nil, fmt.Errorf("error connecting to database: %w", err)
 	}
-	log.Printf("connected to database!! \n")
-	defer DB.Close()
 
-	// Initilize router
-	router := gin.Default()
+	return &app{cfg: cfg, DB: DB, q: db.New(DB)}, nil
+}
 
-	handlers.RegisterRoutes(router)
+func (a *app) close() {
+	a.DB.Close()
+}
 
-	router.Run(":" + port)
+// newFlagSet
+// returns the flag set of a command
+func newFlagSet(name string) *flag.FlagSet {
+	return flag.NewFlagSet("yatrabandhu "+name, flag.ContinueOnError)
 }
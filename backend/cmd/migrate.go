@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/ErebusAJ/YatraBandhu/internals/migrate"
+)
+
+// migrateCmd
+// applies or inspects the embedded schema migrations
+func migrateCmd(args []string) error {
+	if len(args) == 0 || len(args[0]) == 0 || args[0][0] == '-' {
+		return fmt.Errorf("usage: migrate up|down|status|redo [flags]")
+	}
+	action := args[0]
+
+	a, err := setup(newFlagSet("migrate "+action), args[1:])
+	if err != nil {
+		return err
+	}
+	defer a.close()
+
+	migrator, err := migrate.New(a.DB)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	switch action {
+	case "up":
+		return migrator.Up(ctx)
+	case "down":
+		return migrator.Down(ctx)
+	case "redo":
+		return migrator.Redo(ctx)
+	case "status":
+		return migrator.Status(ctx, os.Stdout)
+	default:
+		return fmt.Errorf("unknown migrate action %q, expected up, down, status or redo", action)
+	}
+}
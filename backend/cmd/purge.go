@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ErebusAJ/YatraBandhu/internals/limiter"
+)
+
+// purge
+// deletes expired password reset tokens, pending group join and
+// guide booking requests older than --stale-after, orphaned AI
+// plans (left without any version by a restore or an edit outside
+// the API, the history endpoints cannot serve them) untouched for
+// --stale-after, rate limit buckets idle for a day, quota counts
+// of past months and AI planner jobs finished and sent or dead
+// outbox mail queued more than --stale-after ago
+func purge(args []string) error {
+	fs := newFlagSet("purge")
+	staleAfter := fs.Duration("stale-after", 30*24*time.Hour, "age after which pending requests are stale")
+
+	a, err := setup(fs, args)
+	if err != nil {
+		return err
+	}
+	defer a.close()
+
+	if *staleAfter <= 0 {
+		return fmt.Errorf("--stale-after must be positive")
+	}
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+	before := sql.NullTime{Time: time.Now().Add(-*staleAfter), Valid: true}
+
+	steps := []struct {
+		what string
+		run  func() (int64, error)
+	}{
+		{"expired password reset tokens", func() (int64, error) { return a.q.DeleteExpiredTokens(ctx) }},
+		{"stale pending group requests", func() (int64, error) { return a.q.DeleteStalePendingRequests(ctx, before) }},
+		{"stale pending guide requests", func() (int64, error) { return a.q.DeleteStaleGuideRequests(ctx, before) }},
+		{"orphaned AI plans", func() (int64, error) { return a.q.DeleteOrphanedPlans(ctx, before.Time) }},
+		{"idle rate limit buckets", func() (int64, error) { return a.q.DeleteStaleRateBuckets(ctx, now.Add(-24*time.Hour)) }},
+		{"past quota counts", func() (int64, error) { return a.q.DeleteExpiredQuotaUsage(ctx, limiter.Month.Start(now)) }},
+		{"finished AI planner jobs", func() (int64, error) { return a.q.DeleteFinishedPlannerJobs(ctx, before) }},
+		{"sent and dead outbox mail", func() (int64, error) { return a.q.DeleteFinishedEmails(ctx, before.Time) }},
+	}
+
+	for _, step := range steps {
+		n, err := step.run()
+		if err != nil {
+			return fmt.Errorf("purging %s: %w", step.what, err)
+		}
+		log.Printf("purged %d %s \n", n, step.what)
+	}
+
+	return nil
+}
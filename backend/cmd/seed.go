@@ -0,0 +1,260 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/ErebusAJ/YatraBandhu/internals/db"
+	"github.com/ErebusAJ/YatraBandhu/internals/utils"
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v3"
+)
+
+// fixtures
+// Demo data loaded by seed, plans are referenced by key
+// and users by email
+type fixtures struct {
+	Users []struct {
+		Name     string `yaml:"name"`
+		Age      int32  `yaml:"age"`
+		Phone    string `yaml:"phone_number"`
+		Email    string `yaml:"email"`
+		Password string `yaml:"password"`
+		Role     string `yaml:"role"`
+		Verified bool   `yaml:"verified"`
+	} `yaml:"users"`
+
+	Guides []struct {
+		Name       string `yaml:"name"`
+		Bio        string `yaml:"bio"`
+		Location   string `yaml:"location"`
+		Expertise  string `yaml:"expertise"`
+		Rating     int32  `yaml:"rating"`
+		HourlyRate string `yaml:"hourly_rate"`
+		Verified   bool   `yaml:"verified"`
+		Available  *bool  `yaml:"available"`
+	} `yaml:"guides"`
+
+	Plans []struct {
+		Key       string   `yaml:"key"`
+		Creator   string   `yaml:"creator"`
+		Place     string   `yaml:"place"`
+		StartDate string   `yaml:"start_date"`
+		EndDate   string   `yaml:"end_date"`
+		TripType  string   `yaml:"trip_type"`
+		Pets      bool     `yaml:"pets"`
+		Interests []string `yaml:"interests"`
+	} `yaml:"plans"`
+
+	Groups []struct {
+		Name        string   `yaml:"name"`
+		Description string   `yaml:"description"`
+		Creator     string   `yaml:"creator"`
+		Plan        string   `yaml:"plan"`
+		Members     []string `yaml:"members"`
+	} `yaml:"groups"`
+}
+
+// seed
+// loads a fixtures file in a single transaction, refuses to
+// run when one of its users already exists
+func seed(args []string) error {
+	fs := newFlagSet("seed")
+	file := fs.String("file", "model/fixtures/demo.yaml", "fixtures file")
+
+	a, err := setup(fs, args)
+	if err != nil {
+		return err
+	}
+	defer a.close()
+
+	data, err := os.ReadFile(*file)
+	if err != nil {
+		return err
+	}
+	// unknown keys are rejected so typos don't silently drop data
+	var fx fixtures
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	err = dec.Decode(&fx)
+	if err != nil {
+		return fmt.Errorf("parsing %s: %w", *file, err)
+	}
+
+	ctx := context.Background()
+	err = a.withTx(ctx, func(q *db.Queries) error {
+		return loadFixtures(ctx, q, &fx)
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("seeded %d users, %d guides, %d plans and %d groups from %s \n",
+		len(fx.Users), len(fx.Guides), len(fx.Plans), len(fx.Groups), *file)
+	return nil
+}
+
+func loadFixtures(ctx context.Context, q *db.Queries, fx *fixtures) error {
+	users := map[string]uuid.UUID{}
+	for _, u := range fx.Users {
+		_, err := q.GetUserByEmail(ctx, u.Email)
+		if err == nil {
+			return fmt.Errorf("user %s already exists, database looks seeded", u.Email)
+		} else if err != sql.ErrNoRows {
+			return err
+		}
+
+		hash, err := utils.HashPassword(u.Password)
+		if err != nil {
+			return err
+		}
+		id, err := q.RegisterUser(ctx, db.RegisterUserParams{
+			Name:         u.Name,
+			Age:          u.Age,
+			PhoneNumber:  u.Phone,
+			Email:        u.Email,
+			PasswordHash: hash,
+		})
+		if err != nil {
+			return fmt.Errorf("user %s: %w", u.Email, err)
+		}
+		users[u.Email] = id
+
+		if u.Role != "" && u.Role != utils.RoleUser {
+			err = q.UpdateUserAccessLevel(ctx, db.UpdateUserAccessLevelParams{
+				AccessLevel: sql.NullString{String: u.Role, Valid: true},
+				ID:          id,
+			})
+			if err != nil {
+				return fmt.Errorf("user %s: %w", u.Email, err)
+			}
+		}
+		if u.Verified {
+			err = q.SetUserVerified(ctx, id)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	user := func(email string) (uuid.UUID, error) {
+		id, ok := users[email]
+		if !ok {
+			return uuid.Nil, fmt.Errorf("unknown fixture user %q", email)
+		}
+		return id, nil
+	}
+
+	for _, g := range fx.Guides {
+		id, err := q.AddGuide(ctx, db.AddGuideParams{
+			Name:       g.Name,
+			Bio:        g.Bio,
+			Location:   g.Location,
+			Expertise:  g.Expertise,
+			Rating:     g.Rating,
+			HourlyRate: g.HourlyRate,
+		})
+		if err != nil {
+			return fmt.Errorf("guide %s: %w", g.Name, err)
+		}
+		if g.Verified {
+			_, err = q.SetGuideVerified(ctx, db.SetGuideVerifiedParams{Verified: true, ID: id})
+			if err != nil {
+				return err
+			}
+		}
+		if g.Available != nil && !*g.Available {
+			_, err = q.UpdateGuideAvail(ctx, db.UpdateGuideAvailParams{Available: false, ID: id})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	plans := map[string]uuid.UUID{}
+	for _, p := range fx.Plans {
+		creatorID, err := user(p.Creator)
+		if err != nil {
+			return fmt.Errorf("plan %s: %w", p.Key, err)
+		}
+		id, err := q.AddTravelDetails(ctx, db.AddTravelDetailsParams{
+			CreatorID: creatorID,
+			Place:     p.Place,
+			StartDate: p.StartDate,
+			EndDate:   p.EndDate,
+			TripType:  p.TripType,
+			Pets:      p.Pets,
+			Interests: p.Interests,
+		})
+		if err != nil {
+			return fmt.Errorf("plan %s: %w", p.Key, err)
+		}
+		plans[p.Key] = id
+	}
+
+	for _, g := range fx.Groups {
+		creatorID, err := user(g.Creator)
+		if err != nil {
+			return fmt.Errorf("group %s: %w", g.Name, err)
+		}
+		planID, ok := plans[g.Plan]
+		if !ok {
+			return fmt.Errorf("group %s: unknown fixture plan %q", g.Name, g.Plan)
+		}
+
+		groupID := uuid.New()
+		err = q.CreateGroup(ctx, db.CreateGroupParams{
+			ID:          groupID,
+			CreatorID:   creatorID,
+			Name:        g.Name,
+			Description: g.Description,
+			PlanID:      planID,
+		})
+		if err != nil {
+			return fmt.Errorf("group %s: %w", g.Name, err)
+		}
+
+		// creator is always a member, like the createGroup handler
+		members := append([]string{g.Creator}, g.Members...)
+		seen := map[uuid.UUID]bool{}
+		for _, email := range members {
+			memberID, err := user(email)
+			if err != nil {
+				return fmt.Errorf("group %s: %w", g.Name, err)
+			}
+			if seen[memberID] {
+				continue
+			}
+			seen[memberID] = true
+
+			err = q.AddUserToGroup(ctx, db.AddUserToGroupParams{GroupID: groupID, UserID: memberID})
+			if err != nil {
+				return fmt.Errorf("group %s: %w", g.Name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// withTx
+// runs fn with queries bound to a transaction
+// commits if fn succeeds, rolls back otherwise
+func (a *app) withTx(ctx context.Context, fn func(q *db.Queries) error) error {
+	tx, err := a.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = fn(a.q.WithTx(tx))
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
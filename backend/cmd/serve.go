@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/ErebusAJ/YatraBandhu/config"
+	"github.com/ErebusAJ/YatraBandhu/internals/db"
+	"github.com/ErebusAJ/YatraBandhu/internals/handlers"
+	"github.com/ErebusAJ/YatraBandhu/internals/jobs"
+	"github.com/ErebusAJ/YatraBandhu/internals/logging"
+	"github.com/ErebusAJ/YatraBandhu/internals/mailer"
+	"github.com/ErebusAJ/YatraBandhu/internals/migrate"
+	"github.com/ErebusAJ/YatraBandhu/internals/outbox"
+	"github.com/ErebusAJ/YatraBandhu/internals/planner"
+	"github.com/gin-gonic/gin"
+)
+
+// serve
+// runs the API until SIGINT or SIGTERM, then drains
+// in-flight requests and background workers
+func serve(args []string) error {
+	fs := newFlagSet("serve")
+	migrateOnStart := fs.Bool("migrate-on-start", false, "apply pending migrations before serving")
+
+	a, err := setup(fs, args)
+	if err != nil {
+		return err
+	}
+	defer a.close()
+
+	// Structured logs from here on, the log package included
+	err = logging.Setup(os.Stderr, a.cfg.Log.Format, a.cfg.Log.Level)
+	if err != nil {
+		return err
+	}
+	slog.Info("starting server", "port", a.cfg.Port)
+
+	// Refuse to serve on an outdated schema
+	migrator, err := migrate.New(a.DB)
+	if err != nil {
+		return err
+	}
+	if *migrateOnStart {
+		err = migrator.Up(context.Background())
+		if err != nil {
+			return fmt.Errorf("error migrating database: %w", err)
+		}
+	}
+	err = migrator.Check(context.Background())
+	if err != nil {
+		return err
+	}
+
+	// Initilize router, requests are logged by the handlers' middleware
+	router := gin.New()
+	err = router.SetTrustedProxies(a.cfg.Server.TrustedProxies)
+	if err != nil {
+		return err
+	}
+
+	plannerJobs := handlers.RegisterRoutes(router, a.cfg, a.DB)
+
+	// Background workers, stopped after requests have drained
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	workers, err := startWorkers(workerCtx, a.cfg, a.DB, plannerJobs)
+	if err != nil {
+		return err
+	}
+
+	srv := &http.Server{
+		Addr:         ":" + a.cfg.Port,
+		Handler:      router,
+		ReadTimeout:  a.cfg.Server.ReadTimeout,
+		WriteTimeout: a.cfg.Server.WriteTimeout,
+		IdleTimeout:  a.cfg.Server.IdleTimeout,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		slog.Info("listening", "addr", srv.Addr)
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err = <-serveErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("server error", "error", err)
+		}
+	case <-ctx.Done():
+		slog.Info("shutting down, draining requests")
+	}
+
+	// Stop accepting connections and wait for in-flight requests
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), a.cfg.Server.ShutdownTimeout)
+	defer cancel()
+	err = srv.Shutdown(shutdownCtx)
+	if err != nil {
+		slog.Error("error draining requests", "error", err)
+	}
+
+	// Then the workers, finishing their current unit of work
+	stopWorkers()
+	drained := make(chan struct{})
+	go func() {
+		workers.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+	case <-shutdownCtx.Done():
+		slog.Warn("background workers did not stop in time")
+	}
+
+	slog.Info("server stopped")
+	return nil
+}
+
+// startWorkers
+// starts the background workers sharing the DB pool, plannerJobs
+// runs the AI planner jobs queued by the routes
+// the returned WaitGroup is done once every worker has returned
+func startWorkers(ctx context.Context, cfg *config.Config, DB *sql.DB, plannerJobs *planner.Worker) (*sync.WaitGroup, error) {
+	mail, err := mailer.New(mailer.Config{
+		Backend: cfg.Mail.Backend,
+		SMTP: mailer.SMTPConfig{
+			Host:     cfg.Mail.SMTPHost,
+			Port:     cfg.Mail.SMTPPort,
+			Username: cfg.Mail.Username,
+			Password: cfg.Mail.Password,
+			TLS:      cfg.Mail.SMTPTLS,
+		},
+		Dir:  cfg.Mail.Dir,
+		From: cfg.Mail.From,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error configuring mailer: %w", err)
+	}
+
+	q := db.New(DB)
+	var wg sync.WaitGroup
+	run := func(fn func()) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fn()
+		}()
+	}
+
+	// Deliver queued mail
+	run(func() { outbox.NewWorker(q, mail).Run(ctx) })
+
+	// Generate queued AI plans, running jobs are released on shutdown
+	run(func() { plannerJobs.Run(ctx) })
+
+	// Purge expired password reset tokens hourly
+	run(func() {
+		jobs.Every(ctx, time.Hour, "purge expired reset tokens", jobs.PurgeExpiredResetTokens(q))
+	})
+
+	return &wg, nil
+}
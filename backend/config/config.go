@@ -1 +1,499 @@
-package config
\ No newline at end of file
+package config
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// Config
+// Every setting of the server, loaded once at startup
+// Leaf fields are tagged with their yaml key, env var and
+// whether they are secret (redacted when printed)
+type Config struct {
+	Port    string `yaml:"port" env:"PORT_NO"`
+	DBURL   string `yaml:"db_url" env:"DB_URL" secret:"true"`
+	BaseURL string `yaml:"base_url" env:"APP_BASE_URL"`
+
+	DB      DBConfig      `yaml:"db"`
+	Server  ServerConfig  `yaml:"server"`
+	Auth    AuthConfig    `yaml:"auth"`
+	Mail    MailConfig    `yaml:"mail"`
+	Planner PlannerConfig `yaml:"planner"`
+	Log     LogConfig     `yaml:"log"`
+	Metrics MetricsConfig `yaml:"metrics"`
+	Limits  LimitsConfig  `yaml:"limits"`
+}
+
+// DBConfig
+// Connection pool settings of the shared database handle
+type DBConfig struct {
+	MaxOpenConns    int           `yaml:"max_open_conns" env:"DB_MAX_OPEN_CONNS"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" env:"DB_MAX_IDLE_CONNS"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" env:"DB_CONN_MAX_LIFETIME"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time" env:"DB_CONN_MAX_IDLE_TIME"`
+}
+
+// ServerConfig
+// HTTP server timeouts, WriteTimeout must outlast the slowest
+// handler (AI plan generation can take up to 300s)
+type ServerConfig struct {
+	ReadTimeout     time.Duration `yaml:"read_timeout" env:"SERVER_READ_TIMEOUT"`
+	WriteTimeout    time.Duration `yaml:"write_timeout" env:"SERVER_WRITE_TIMEOUT"`
+	IdleTimeout     time.Duration `yaml:"idle_timeout" env:"SERVER_IDLE_TIMEOUT"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env:"SERVER_SHUTDOWN_TIMEOUT"`
+	// TrustedProxies are the ips and CIDRs whose X-Forwarded-For is
+	// believed, when empty the client ip is the connection's address
+	TrustedProxies []string `yaml:"trusted_proxies" env:"SERVER_TRUSTED_PROXIES"`
+}
+
+// AuthConfig
+// Token signing and account protection settings
+type AuthConfig struct {
+	SignedKey            string `yaml:"signed_key" env:"SIGNED_KEY" secret:"true"`
+	RequireVerifiedEmail bool   `yaml:"require_verified_email" env:"REQUIRE_VERIFIED_EMAIL"`
+	// LoginLimiter is memory or postgres
+	LoginLimiter string `yaml:"login_limiter" env:"LOGIN_LIMITER"`
+	// Reset links, "{token}" is replaced by the token
+	PasswordResetURL    string `yaml:"password_reset_url" env:"PASSWORD_RESET_URL"`
+	PasswordResetAppURL string `yaml:"password_reset_app_url" env:"PASSWORD_RESET_APP_URL"`
+}
+
+// MailConfig
+// Outgoing mail backend settings
+type MailConfig struct {
+	// Backend is smtp, file or log
+	Backend  string `yaml:"backend" env:"MAIL_BACKEND"`
+	SMTPHost string `yaml:"smtp_host" env:"SMTP_HOST"`
+	SMTPPort int    `yaml:"smtp_port" env:"SMTP_PORT"`
+	SMTPTLS  string `yaml:"smtp_tls" env:"SMTP_TLS"`
+	Username string `yaml:"username" env:"EMAIL"`
+	Password string `yaml:"password" env:"PASS" secret:"true"`
+	From     string `yaml:"from" env:"MAIL_FROM"`
+	Dir      string `yaml:"dir" env:"MAIL_DIR"`
+}
+
+// PlannerConfig
+// Models and credentials of the AI planner's providers
+type PlannerConfig struct {
+	// DraftModels write the itinerary text and StructureModels turn it
+	// into json, both are provider:model lists tried in order
+	DraftModels     []string `yaml:"draft_models" env:"PLANNER_DRAFT_MODELS"`
+	StructureModels []string `yaml:"structure_models" env:"PLANNER_STRUCTURE_MODELS"`
+	// RepairAttempts is how often an itinerary that fails validation
+	// is sent back to the structuring model with the problems found
+	RepairAttempts int `yaml:"repair_attempts" env:"PLANNER_REPAIR_ATTEMPTS"`
+	// Workers is how many generations a replica runs at once and
+	// JobAttempts how often a job is tried, resuming at the failed stage
+	Workers     int `yaml:"workers" env:"PLANNER_WORKERS"`
+	JobAttempts int `yaml:"job_attempts" env:"PLANNER_JOB_ATTEMPTS"`
+
+	CloudflareAPIKey    string `yaml:"cloudflare_api_key" env:"CLOUDFLARE_API_KEY" secret:"true"`
+	CloudflareAccountID string `yaml:"cloudflare_account_id" env:"CLOUDFLARE_ACC_ID"`
+	GeminiAPIKey        string `yaml:"gemini_api_key" env:"GEMINI_API_KEY" secret:"true"`
+	OpenAIBaseURL       string `yaml:"openai_base_url" env:"OPENAI_BASE_URL"`
+	OpenAIAPIKey        string `yaml:"openai_api_key" env:"OPENAI_API_KEY" secret:"true"`
+	OllamaURL           string `yaml:"ollama_url" env:"OLLAMA_URL"`
+	MapboxToken         string `yaml:"mapbox_token" env:"MAPBOX_TOKEN" secret:"true"`
+	TomTomAPIKey        string `yaml:"tomtom_api_key" env:"TOMTOM_API_KEY" secret:"true"`
+}
+
+// LogConfig
+// Structured logging of the server
+type LogConfig struct {
+	// Level is debug, info, warn or error
+	Level string `yaml:"level" env:"LOG_LEVEL"`
+	// Format is json or text
+	Format string `yaml:"format" env:"LOG_FORMAT"`
+}
+
+// MetricsConfig
+// Access to the prometheus /metrics endpoint
+type MetricsConfig struct {
+	// Token, if set, must be sent as a bearer token to scrape
+	Token string `yaml:"token" env:"METRICS_TOKEN" secret:"true"`
+}
+
+// LimitsConfig
+// Request rate limits and AI planner quotas, a rate of 0 and a
+// quota of 0 are unlimited
+type LimitsConfig struct {
+	// Backend is memory or postgres, for both rates and quotas
+	Backend string `yaml:"backend" env:"RATE_LIMIT_BACKEND"`
+	// Requests per minute per client ip on every API route
+	IPPerMinute int `yaml:"ip_per_minute" env:"RATE_LIMIT_IP_PER_MINUTE"`
+	IPBurst     int `yaml:"ip_burst" env:"RATE_LIMIT_IP_BURST"`
+	// Requests per minute per user on authenticated routes
+	UserPerMinute int `yaml:"user_per_minute" env:"RATE_LIMIT_USER_PER_MINUTE"`
+	UserBurst     int `yaml:"user_burst" env:"RATE_LIMIT_USER_BURST"`
+	// AI planner calls per hour per user
+	PlannerPerHour int `yaml:"planner_per_hour" env:"RATE_LIMIT_PLANNER_PER_HOUR"`
+	PlannerBurst   int `yaml:"planner_burst" env:"RATE_LIMIT_PLANNER_BURST"`
+	// AI planner generations per role, days and months are UTC
+	UserDaily    int `yaml:"user_daily" env:"QUOTA_USER_DAILY"`
+	UserMonthly  int `yaml:"user_monthly" env:"QUOTA_USER_MONTHLY"`
+	GuideDaily   int `yaml:"guide_daily" env:"QUOTA_GUIDE_DAILY"`
+	GuideMonthly int `yaml:"guide_monthly" env:"QUOTA_GUIDE_MONTHLY"`
+	AdminDaily   int `yaml:"admin_daily" env:"QUOTA_ADMIN_DAILY"`
+	AdminMonthly int `yaml:"admin_monthly" env:"QUOTA_ADMIN_MONTHLY"`
+}
+
+// Default
+// returns the config used before any source is applied
+func Default() *Config {
+	return &Config{
+		Port:    "8080",
+		BaseURL: "http://localhost:8080",
+		DB: DBConfig{
+			MaxOpenConns:    25,
+			MaxIdleConns:    10,
+			ConnMaxLifetime: 30 * time.Minute,
+			ConnMaxIdleTime: 5 * time.Minute,
+		},
+		Server: ServerConfig{
+			ReadTimeout:     15 * time.Second,
+			WriteTimeout:    330 * time.Second,
+			IdleTimeout:     60 * time.Second,
+			ShutdownTimeout: 330 * time.Second,
+		},
+		Auth: AuthConfig{
+			LoginLimiter: "memory",
+		},
+		Mail: MailConfig{
+			Backend:  "smtp",
+			SMTPHost: "smtp.gmail.com",
+			SMTPPort: 587,
+			SMTPTLS:  "starttls",
+			Dir:      "mail",
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: "json",
+		},
+		Planner: PlannerConfig{
+			DraftModels:     []string{"cloudflare:@cf/mistral/mistral-7b-instruct-v0.1"},
+			StructureModels: []string{"gemini:gemini-2.0-flash"},
+			RepairAttempts:  2,
+			Workers:         2,
+			JobAttempts:     3,
+			OpenAIBaseURL:   "https://api.openai.com/v1",
+			OllamaURL:       "http://localhost:11434",
+		},
+		Limits: LimitsConfig{
+			Backend:        "memory",
+			IPPerMinute:    300,
+			IPBurst:        100,
+			UserPerMinute:  120,
+			UserBurst:      60,
+			PlannerPerHour: 10,
+			PlannerBurst:   3,
+			UserDaily:      5,
+			UserMonthly:    50,
+			GuideDaily:     10,
+			GuideMonthly:   100,
+		},
+	}
+}
+
+// Load
+// builds the config from defaults, then the optional yaml file given
+// by --config (or CONFIG_FILE), then env vars (.env is read if present),
+// then command line flags, and validates the result
+// args are the command line arguments without the program name
+func Load(args []string) (*Config, error) {
+	return LoadFlags(flag.NewFlagSet("yatrabandhu", flag.ContinueOnError), args)
+}
+
+// LoadFlags
+// same as Load but registers the config flags on fs,
+// so a command can parse its own flags alongside them
+func LoadFlags(fs *flag.FlagSet, args []string) (*Config, error) {
+	cfg := Default()
+	godotenv.Load()
+
+	configPath := fs.String("config", os.Getenv("CONFIG_FILE"), "path to a yaml config file")
+	overrides := map[string]*string{}
+	for _, f := range fields(cfg) {
+		overrides[f.key] = fs.String(f.key, "", fmt.Sprintf("overrides %s", f.env))
+	}
+
+	err := fs.Parse(args)
+	if err != nil {
+		return nil, err
+	}
+
+	if *configPath != "" {
+		data, err := os.ReadFile(*configPath)
+		if err != nil {
+			return nil, fmt.Errorf("reading config file: %w", err)
+		}
+		err = yaml.Unmarshal(data, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("parsing config file: %w", err)
+		}
+	}
+
+	for _, f := range fields(cfg) {
+		if val, ok := os.LookupEnv(f.env); ok && val != "" {
+			err = f.set(val)
+			if err != nil {
+				return nil, fmt.Errorf("env %s: %w", f.env, err)
+			}
+		}
+	}
+
+	visited := map[string]bool{}
+	fs.Visit(func(fl *flag.Flag) { visited[fl.Name] = true })
+	for _, f := range fields(cfg) {
+		if visited[f.key] {
+			err = f.set(*overrides[f.key])
+			if err != nil {
+				return nil, fmt.Errorf("flag --%s: %w", f.key, err)
+			}
+		}
+	}
+
+	return cfg, cfg.Validate()
+}
+
+// Validate
+// fails on missing secrets and invalid values
+func (cfg *Config) Validate() error {
+	var problems []string
+
+	if cfg.Port == "" {
+		problems = append(problems, "port (PORT_NO) is required")
+	}
+	if cfg.DBURL == "" {
+		problems = append(problems, "db_url (DB_URL) is required")
+	}
+	if cfg.DB.MaxOpenConns < 0 || cfg.DB.MaxIdleConns < 0 {
+		problems = append(problems, "db.max_open_conns and db.max_idle_conns must not be negative")
+	}
+	if cfg.Server.ShutdownTimeout <= 0 {
+		problems = append(problems, "server.shutdown_timeout must be positive")
+	}
+	for _, proxy := range cfg.Server.TrustedProxies {
+		if net.ParseIP(proxy) == nil {
+			if _, _, err := net.ParseCIDR(proxy); err != nil {
+				problems = append(problems, fmt.Sprintf("server.trusted_proxies: %q is not an ip or CIDR", proxy))
+			}
+		}
+	}
+	if cfg.Auth.PasswordResetAppURL != "" {
+		// mailed as a link, html/template only lets it through
+		// once checked here
+		u, err := url.Parse(cfg.Auth.PasswordResetAppURL)
+		if err != nil || u.Scheme == "" {
+			problems = append(problems, "auth.password_reset_app_url (PASSWORD_RESET_APP_URL) must be an absolute url")
+		} else {
+			switch strings.ToLower(u.Scheme) {
+			case "javascript", "data", "vbscript", "file":
+				problems = append(problems, fmt.Sprintf("auth.password_reset_app_url: scheme %q is not allowed", u.Scheme))
+			}
+		}
+	}
+	if cfg.Auth.SignedKey == "" {
+		problems = append(problems, "auth.signed_key (SIGNED_KEY) is required")
+	}
+
+	switch cfg.Auth.LoginLimiter {
+	case "memory", "postgres":
+	default:
+		problems = append(problems, fmt.Sprintf("auth.login_limiter must be memory or postgres, got %q", cfg.Auth.LoginLimiter))
+	}
+
+	switch cfg.Limits.Backend {
+	case "memory", "postgres":
+	default:
+		problems = append(problems, fmt.Sprintf("limits.backend must be memory or postgres, got %q", cfg.Limits.Backend))
+	}
+	for _, n := range []int{cfg.Limits.IPPerMinute, cfg.Limits.IPBurst, cfg.Limits.UserPerMinute, cfg.Limits.UserBurst, cfg.Limits.PlannerPerHour, cfg.Limits.PlannerBurst,
+		cfg.Limits.UserDaily, cfg.Limits.UserMonthly, cfg.Limits.GuideDaily, cfg.Limits.GuideMonthly, cfg.Limits.AdminDaily, cfg.Limits.AdminMonthly} {
+		if n < 0 {
+			problems = append(problems, "limits must not be negative")
+			break
+		}
+	}
+
+	used := map[string]bool{}
+	for _, models := range []struct {
+		key   string
+		specs []string
+	}{{"planner.draft_models", cfg.Planner.DraftModels}, {"planner.structure_models", cfg.Planner.StructureModels}} {
+		if len(models.specs) == 0 {
+			problems = append(problems, models.key+" must list at least one provider:model")
+		}
+		for _, spec := range models.specs {
+			provider, model, _ := strings.Cut(spec, ":")
+			switch provider {
+			case "cloudflare", "gemini", "openai", "ollama", "fake":
+			default:
+				problems = append(problems, fmt.Sprintf("%s: provider of %q must be cloudflare, gemini, openai, ollama or fake", models.key, spec))
+				continue
+			}
+			used[provider] = true
+			if model == "" {
+				problems = append(problems, fmt.Sprintf("%s: %q names no model", models.key, spec))
+			}
+		}
+	}
+
+	// credentials of the providers in use, fake needs none
+	if used["cloudflare"] && (cfg.Planner.CloudflareAPIKey == "" || cfg.Planner.CloudflareAccountID == "") {
+		problems = append(problems, "planner.cloudflare_api_key (CLOUDFLARE_API_KEY) and planner.cloudflare_account_id (CLOUDFLARE_ACC_ID) are required for cloudflare models")
+	}
+	if used["gemini"] && cfg.Planner.GeminiAPIKey == "" {
+		problems = append(problems, "planner.gemini_api_key (GEMINI_API_KEY) is required for gemini models")
+	}
+	if used["openai"] {
+		// the key is optional for local OpenAI compatible servers
+		u, err := url.Parse(cfg.Planner.OpenAIBaseURL)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			problems = append(problems, "planner.openai_base_url (OPENAI_BASE_URL) must be an absolute url for openai models")
+		} else if u.Hostname() == "api.openai.com" && cfg.Planner.OpenAIAPIKey == "" {
+			problems = append(problems, "planner.openai_api_key (OPENAI_API_KEY) is required for api.openai.com")
+		}
+	}
+	if used["ollama"] {
+		u, err := url.Parse(cfg.Planner.OllamaURL)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			problems = append(problems, "planner.ollama_url (OLLAMA_URL) must be an absolute url for ollama models")
+		}
+	}
+
+	if cfg.Planner.RepairAttempts < 0 {
+		problems = append(problems, "planner.repair_attempts must not be negative")
+	}
+	if cfg.Planner.Workers < 1 || cfg.Planner.JobAttempts < 1 {
+		problems = append(problems, "planner.workers and planner.job_attempts must be at least 1")
+	}
+
+	switch cfg.Mail.Backend {
+	case "smtp":
+		if cfg.Mail.Username == "" || cfg.Mail.Password == "" {
+			problems = append(problems, "mail.username (EMAIL) and mail.password (PASS) are required for the smtp backend")
+		}
+	case "file":
+		if cfg.Mail.Dir == "" {
+			problems = append(problems, "mail.dir (MAIL_DIR) is required for the file backend")
+		}
+	case "log":
+	default:
+		problems = append(problems, fmt.Sprintf("mail.backend must be smtp, file or log, got %q", cfg.Mail.Backend))
+	}
+
+	switch cfg.Log.Level {
+	case "debug", "info", "warn", "error":
+	default:
+		problems = append(problems, fmt.Sprintf("log.level must be debug, info, warn or error, got %q", cfg.Log.Level))
+	}
+	switch cfg.Log.Format {
+	case "json", "text":
+	default:
+		problems = append(problems, fmt.Sprintf("log.format must be json or text, got %q", cfg.Log.Format))
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid config:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// Print
+// returns the config as yaml, secrets are masked if redacted
+func (cfg *Config) Print(redacted bool) (string, error) {
+	out := *cfg
+	if redacted {
+		for _, f := range fields(&out) {
+			if f.secret && f.value.String() != "" {
+				f.value.SetString("[REDACTED]")
+			}
+		}
+	}
+
+	data, err := yaml.Marshal(&out)
+	return string(data), err
+}
+
+// field
+// A settable leaf of the config
+type field struct {
+	key    string
+	env    string
+	secret bool
+	value  reflect.Value
+}
+
+func (f field) set(val string) error {
+	switch f.value.Interface().(type) {
+	case string:
+		f.value.SetString(val)
+	case int:
+		n, err := strconv.Atoi(val)
+		if err != nil {
+			return err
+		}
+		f.value.SetInt(int64(n))
+	case bool:
+		b, err := strconv.ParseBool(val)
+		if err != nil {
+			return err
+		}
+		f.value.SetBool(b)
+	case time.Duration:
+		d, err := time.ParseDuration(val)
+		if err != nil {
+			return err
+		}
+		f.value.SetInt(int64(d))
+	case []string:
+		// comma separated
+		var list []string
+		for _, item := range strings.Split(val, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		f.value.Set(reflect.ValueOf(list))
+	default:
+		return fmt.Errorf("unsupported type %s", f.value.Type())
+	}
+	return nil
+}
+
+// fields
+// walks the config struct and returns its tagged leaves
+// keys are yaml keys joined with dots e.g. mail.smtp_host
+func fields(cfg *Config) []field {
+	var out []field
+	var walk func(v reflect.Value, prefix string)
+	walk = func(v reflect.Value, prefix string) {
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			key := prefix + strings.Split(sf.Tag.Get("yaml"), ",")[0]
+			if sf.Type.Kind() == reflect.Struct {
+				walk(v.Field(i), key+".")
+				continue
+			}
+			out = append(out, field{
+				key:    key,
+				env:    sf.Tag.Get("env"),
+				secret: sf.Tag.Get("secret") == "true",
+				value:  v.Field(i),
+			})
+		}
+	}
+	walk(reflect.ValueOf(cfg).Elem(), "")
+	return out
+}
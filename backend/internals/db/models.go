@@ -18,6 +18,44 @@ type AiPlan struct {
 	RawData   json.RawMessage
 	CreatedAt time.Time
 	UpdatedAt time.Time
+	Title     string
+	Version   int32
+}
+
+type AiPlanVersion struct {
+	PlanID    uuid.UUID
+	Version   int32
+	RawData   json.RawMessage
+	Source    string
+	CreatedAt time.Time
+}
+
+type AuditEvent struct {
+	ID         uuid.UUID
+	Action     string
+	ActorID    uuid.NullUUID
+	SubjectID  uuid.NullUUID
+	TargetType string
+	TargetID   uuid.NullUUID
+	Metadata   json.RawMessage
+	ClientIp   string
+	RequestID  string
+	CreatedAt  time.Time
+}
+
+type EmailOutbox struct {
+	ID            uuid.UUID
+	Recipient     string
+	Subject       string
+	TextBody      string
+	HtmlBody      string
+	Status        string
+	Attempts      int32
+	LastError     sql.NullString
+	NextAttemptAt time.Time
+	CreatedAt     time.Time
+	SentAt        sql.NullTime
+	Template      string
 }
 
 type Guide struct {
@@ -29,6 +67,7 @@ type Guide struct {
 	Rating     int32
 	HourlyRate string
 	Available  bool
+	Verified   bool
 }
 
 type GuideBooking struct {
@@ -50,15 +89,75 @@ type GuideBookingRequest struct {
 	UpdatedAt sql.NullTime
 }
 
+type LoginAttempt struct {
+	AttemptKey   string
+	Failures     int32
+	LastFailure  time.Time
+	BlockedUntil time.Time
+}
+
 type PasswordToken struct {
 	ID        uuid.UUID
 	UserID    uuid.UUID
-	Token     string
+	TokenHash string
 	CreatedAt sql.NullTime
 	ExpiresAt time.Time
 	UpdatedAt sql.NullTime
 }
 
+type PlannerJob struct {
+	ID            uuid.UUID
+	UserID        uuid.UUID
+	Location      string
+	Interests     string
+	Days          int32
+	StartDate     time.Time
+	Status        string
+	Stage         string
+	TravelData    sql.NullString
+	Draft         sql.NullString
+	PlanID        uuid.NullUUID
+	Attempts      int32
+	LastError     sql.NullString
+	NextAttemptAt time.Time
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	FinishedAt    sql.NullTime
+}
+
+type QuotaUsage struct {
+	QuotaKey    string
+	Period      string
+	PeriodStart time.Time
+	Used        int32
+}
+
+type RateBucket struct {
+	BucketKey string
+	Tokens    float64
+	UpdatedAt time.Time
+}
+
+type RefreshToken struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	TokenHash string
+	ExpiresAt time.Time
+	RevokedAt sql.NullTime
+	CreatedAt time.Time
+}
+
+type Report struct {
+	ID         uuid.UUID
+	ReporterID uuid.UUID
+	TargetType string
+	TargetID   uuid.UUID
+	Reason     string
+	Status     string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
 type TravelGroup struct {
 	ID          uuid.UUID
 	CreatorID   uuid.UUID
@@ -112,4 +211,14 @@ type User struct {
 	CreatedAt      sql.NullTime
 	UpdatedAt      sql.NullTime
 	LastLoggedIn   sql.NullTime
+	SuspendedAt    sql.NullTime
+}
+
+type VerificationToken struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	Token     string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UpdatedAt sql.NullTime
 }
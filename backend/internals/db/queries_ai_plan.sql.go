@@ -8,12 +8,358 @@ package db
 import (
 	"context"
 	"encoding/json"
+	"time"
 
 	"github.com/google/uuid"
 )
 
+const addAIPlanVersion = `-- name: AddAIPlanVersion :one
+WITH plan AS (
+    UPDATE ai_plan
+    SET raw_data=$1, version=version+1, updated_at=CURRENT_TIMESTAMP
+    WHERE id=$2 AND user_id=$3
+    RETURNING id, version, raw_data
+)
+INSERT INTO ai_plan_versions(plan_id, version, raw_data, source)
+SELECT id, version, raw_data, $4::text FROM plan
+RETURNING version
+`
+
+type AddAIPlanVersionParams struct {
+	RawData json.RawMessage
+	ID      uuid.UUID
+	UserID  uuid.UUID
+	Source  string
+}
+
+func (q *Queries) AddAIPlanVersion(ctx context.Context, arg AddAIPlanVersionParams) (int32, error) {
+	row := q.db.QueryRowContext(ctx, addAIPlanVersion,
+		arg.RawData,
+		arg.ID,
+		arg.UserID,
+		arg.Source,
+	)
+	var version int32
+	err := row.Scan(&version)
+	return version, err
+}
+
+const deleteAIPlan = `-- name: DeleteAIPlan :execrows
+DELETE FROM ai_plan
+WHERE id=$1 AND user_id=$2
+`
+
+type DeleteAIPlanParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) DeleteAIPlan(ctx context.Context, arg DeleteAIPlanParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteAIPlan, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteOrphanedPlans = `-- name: DeleteOrphanedPlans :execrows
+DELETE FROM ai_plan
+WHERE updated_at < $1
+    AND NOT EXISTS (SELECT 1 FROM ai_plan_versions WHERE ai_plan_versions.plan_id = ai_plan.id)
+`
+
+func (q *Queries) DeleteOrphanedPlans(ctx context.Context, updatedAt time.Time) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteOrphanedPlans, updatedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getAIPlan = `-- name: GetAIPlan :one
+SELECT id, user_id, raw_data, created_at, updated_at, title, version FROM ai_plan
+WHERE id=$1 AND user_id=$2
+`
+
+type GetAIPlanParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) GetAIPlan(ctx context.Context, arg GetAIPlanParams) (AiPlan, error) {
+	row := q.db.QueryRowContext(ctx, getAIPlan, arg.ID, arg.UserID)
+	var i AiPlan
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.RawData,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Version,
+	)
+	return i, err
+}
+
+const getAIPlanVersion = `-- name: GetAIPlanVersion :one
+SELECT plan_id, version, raw_data, source, created_at FROM ai_plan_versions
+WHERE plan_id=$1 AND version=$2
+`
+
+type GetAIPlanVersionParams struct {
+	PlanID  uuid.UUID
+	Version int32
+}
+
+func (q *Queries) GetAIPlanVersion(ctx context.Context, arg GetAIPlanVersionParams) (AiPlanVersion, error) {
+	row := q.db.QueryRowContext(ctx, getAIPlanVersion, arg.PlanID, arg.Version)
+	var i AiPlanVersion
+	err := row.Scan(
+		&i.PlanID,
+		&i.Version,
+		&i.RawData,
+		&i.Source,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getUserAIPlanVersions = `-- name: GetUserAIPlanVersions :many
+SELECT v.plan_id, v.version, v.raw_data, v.source, v.created_at FROM ai_plan_versions v
+JOIN ai_plan p ON p.id = v.plan_id
+WHERE p.user_id=$1
+ORDER BY v.plan_id, v.version
+`
+
+func (q *Queries) GetUserAIPlanVersions(ctx context.Context, userID uuid.UUID) ([]AiPlanVersion, error) {
+	rows, err := q.db.QueryContext(ctx, getUserAIPlanVersions, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []AiPlanVersion
+	for rows.Next() {
+		var i AiPlanVersion
+		if err := rows.Scan(
+			&i.PlanID,
+			&i.Version,
+			&i.RawData,
+			&i.Source,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUserAIPlans = `-- name: GetUserAIPlans :many
+SELECT id, user_id, raw_data, created_at, updated_at, title, version FROM ai_plan
+WHERE user_id=$1
+ORDER BY created_at DESC
+`
+
+func (q *Queries) GetUserAIPlans(ctx context.Context, userID uuid.UUID) ([]AiPlan, error) {
+	rows, err := q.db.QueryContext(ctx, getUserAIPlans, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []AiPlan
+	for rows.Next() {
+		var i AiPlan
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.RawData,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Version,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listAIPlanVersions = `-- name: ListAIPlanVersions :many
+SELECT version, source, created_at FROM ai_plan_versions
+WHERE plan_id = $1
+AND (NOT $2::boolean OR CASE $3::text
+    WHEN 'version' THEN version > $4::int
+    WHEN '-version' THEN version < $4::int
+END)
+ORDER BY
+    CASE WHEN $3::text = 'version' THEN version END,
+    CASE WHEN $3::text = '-version' THEN version END DESC
+LIMIT $5
+`
+
+type ListAIPlanVersionsParams struct {
+	PlanID    uuid.UUID
+	HasCursor bool
+	Sort      string
+	AfterNum  int32
+	PageLimit int32
+}
+
+type ListAIPlanVersionsRow struct {
+	Version   int32
+	Source    string
+	CreatedAt time.Time
+}
+
+func (q *Queries) ListAIPlanVersions(ctx context.Context, arg ListAIPlanVersionsParams) ([]ListAIPlanVersionsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listAIPlanVersions,
+		arg.PlanID,
+		arg.HasCursor,
+		arg.Sort,
+		arg.AfterNum,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListAIPlanVersionsRow
+	for rows.Next() {
+		var i ListAIPlanVersionsRow
+		if err := rows.Scan(
+			&i.Version,
+			&i.Source,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listAIPlans = `-- name: ListAIPlans :many
+SELECT id, title, version, created_at, updated_at FROM ai_plan
+WHERE user_id = $1
+AND ($2::text = '' OR title ILIKE '%' || $2::text || '%')
+AND (NOT $3::boolean OR CASE $4::text
+    WHEN 'title' THEN (title, id) > ($5::text, $6::uuid)
+    WHEN '-title' THEN (title, id) < ($5::text, $6::uuid)
+    WHEN 'created_at' THEN (created_at, id) > ($7::timestamp, $6::uuid)
+    WHEN '-created_at' THEN (created_at, id) < ($7::timestamp, $6::uuid)
+    WHEN 'updated_at' THEN (updated_at, id) > ($7::timestamp, $6::uuid)
+    WHEN '-updated_at' THEN (updated_at, id) < ($7::timestamp, $6::uuid)
+END)
+ORDER BY
+    CASE WHEN $4::text = 'title' THEN title END,
+    CASE WHEN $4::text = '-title' THEN title END DESC,
+    CASE WHEN $4::text = 'created_at' THEN created_at END,
+    CASE WHEN $4::text = '-created_at' THEN created_at END DESC,
+    CASE WHEN $4::text = 'updated_at' THEN updated_at END,
+    CASE WHEN $4::text = '-updated_at' THEN updated_at END DESC,
+    CASE WHEN $4::text LIKE '-%' THEN id END DESC,
+    id
+LIMIT $8
+`
+
+type ListAIPlansParams struct {
+	UserID    uuid.UUID
+	Title     string
+	HasCursor bool
+	Sort      string
+	AfterText string
+	AfterID   uuid.UUID
+	AfterTime time.Time
+	PageLimit int32
+}
+
+type ListAIPlansRow struct {
+	ID        uuid.UUID
+	Title     string
+	Version   int32
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (q *Queries) ListAIPlans(ctx context.Context, arg ListAIPlansParams) ([]ListAIPlansRow, error) {
+	rows, err := q.db.QueryContext(ctx, listAIPlans,
+		arg.UserID,
+		arg.Title,
+		arg.HasCursor,
+		arg.Sort,
+		arg.AfterText,
+		arg.AfterID,
+		arg.AfterTime,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListAIPlansRow
+	for rows.Next() {
+		var i ListAIPlansRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Version,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const renameAIPlan = `-- name: RenameAIPlan :execrows
+UPDATE ai_plan
+SET title=$1, updated_at=CURRENT_TIMESTAMP
+WHERE id=$2 AND user_id=$3
+`
+
+type RenameAIPlanParams struct {
+	Title  string
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) RenameAIPlan(ctx context.Context, arg RenameAIPlanParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, renameAIPlan, arg.Title, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const retreivePlan = `-- name: RetreivePlan :one
-SELECT id, user_id, raw_data, created_at, updated_at FROM ai_plan
+SELECT id, user_id, raw_data, created_at, updated_at, title, version FROM ai_plan
 WHERE user_id=$1
 `
 
@@ -26,21 +372,32 @@ func (q *Queries) RetreivePlan(ctx context.Context, userID uuid.UUID) (AiPlan, e
 		&i.RawData,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Title,
+		&i.Version,
 	)
 	return i, err
 }
 
-const savePlan = `-- name: SavePlan :exec
-INSERT INTO ai_plan(user_id, raw_data)
-VALUES($1, $2)
+const savePlan = `-- name: SavePlan :one
+WITH plan AS (
+    INSERT INTO ai_plan(user_id, title, raw_data)
+    VALUES($1, $2, $3)
+    RETURNING id, raw_data
+)
+INSERT INTO ai_plan_versions(plan_id, version, raw_data, source)
+SELECT id, 1, raw_data, 'generated' FROM plan
+RETURNING plan_id
 `
 
 type SavePlanParams struct {
 	UserID  uuid.UUID
+	Title   string
 	RawData json.RawMessage
 }
 
-func (q *Queries) SavePlan(ctx context.Context, arg SavePlanParams) error {
-	_, err := q.db.ExecContext(ctx, savePlan, arg.UserID, arg.RawData)
-	return err
+func (q *Queries) SavePlan(ctx context.Context, arg SavePlanParams) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, savePlan, arg.UserID, arg.Title, arg.RawData)
+	var plan_id uuid.UUID
+	err := row.Scan(&plan_id)
+	return plan_id, err
 }
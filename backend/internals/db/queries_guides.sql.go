@@ -12,9 +12,10 @@ import (
 	"github.com/google/uuid"
 )
 
-const addGuide = `-- name: AddGuide :exec
+const addGuide = `-- name: AddGuide :one
 INSERT INTO guides(name, bio, location, expertise, rating, hourly_rate)
 VALUES($1, $2, $3, $4, $5, $6)
+RETURNING id
 `
 
 type AddGuideParams struct {
@@ -26,8 +27,8 @@ type AddGuideParams struct {
 	HourlyRate string
 }
 
-func (q *Queries) AddGuide(ctx context.Context, arg AddGuideParams) error {
-	_, err := q.db.ExecContext(ctx, addGuide,
+func (q *Queries) AddGuide(ctx context.Context, arg AddGuideParams) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, addGuide,
 		arg.Name,
 		arg.Bio,
 		arg.Location,
@@ -35,7 +36,9 @@ func (q *Queries) AddGuide(ctx context.Context, arg AddGuideParams) error {
 		arg.Rating,
 		arg.HourlyRate,
 	)
-	return err
+	var id uuid.UUID
+	err := row.Scan(&id)
+	return id, err
 }
 
 const bookGuide = `-- name: BookGuide :exec
@@ -54,13 +57,94 @@ func (q *Queries) BookGuide(ctx context.Context, arg BookGuideParams) error {
 	return err
 }
 
-const getGuideDetails = `-- name: GetGuideDetails :many
-SELECT id, name, bio, location, expertise, rating, hourly_rate, available FROM guides
-WHERE location=$1 AND available=TRUE
+const deleteStaleGuideRequests = `-- name: DeleteStaleGuideRequests :execrows
+DELETE FROM guide_booking_requests
+WHERE status='pending' AND created_at < $1
 `
 
-func (q *Queries) GetGuideDetails(ctx context.Context, location string) ([]Guide, error) {
-	rows, err := q.db.QueryContext(ctx, getGuideDetails, location)
+func (q *Queries) DeleteStaleGuideRequests(ctx context.Context, createdAt sql.NullTime) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteStaleGuideRequests, createdAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getGuideByID = `-- name: GetGuideByID :one
+SELECT id, name, bio, location, expertise, rating, hourly_rate, available, verified FROM guides
+WHERE id=$1
+`
+
+func (q *Queries) GetGuideByID(ctx context.Context, id uuid.UUID) (Guide, error) {
+	row := q.db.QueryRowContext(ctx, getGuideByID, id)
+	var i Guide
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Bio,
+		&i.Location,
+		&i.Expertise,
+		&i.Rating,
+		&i.HourlyRate,
+		&i.Available,
+		&i.Verified,
+	)
+	return i, err
+}
+
+const listGuides = `-- name: ListGuides :many
+SELECT id, name, bio, location, expertise, rating, hourly_rate, available, verified FROM guides
+WHERE available = TRUE
+AND ($1::text = '' OR lower(location) = lower($1::text))
+AND ($2::text = '' OR expertise ILIKE '%' || $2::text || '%')
+AND rating >= $3::int
+AND ($4::numeric IS NULL OR hourly_rate <= $4::numeric)
+AND (NOT $5::boolean OR CASE $6::text
+    WHEN 'name' THEN (name, id) > ($7::text, $8::uuid)
+    WHEN '-name' THEN (name, id) < ($7::text, $8::uuid)
+    WHEN 'rating' THEN (rating, id) > ($9::numeric, $8::uuid)
+    WHEN '-rating' THEN (rating, id) < ($9::numeric, $8::uuid)
+    WHEN 'rate' THEN (hourly_rate, id) > ($9::numeric, $8::uuid)
+    WHEN '-rate' THEN (hourly_rate, id) < ($9::numeric, $8::uuid)
+END)
+ORDER BY
+    CASE WHEN $6::text = 'name' THEN name END,
+    CASE WHEN $6::text = '-name' THEN name END DESC,
+    CASE WHEN $6::text = 'rating' THEN rating END,
+    CASE WHEN $6::text = '-rating' THEN rating END DESC,
+    CASE WHEN $6::text = 'rate' THEN hourly_rate END,
+    CASE WHEN $6::text = '-rate' THEN hourly_rate END DESC,
+    CASE WHEN $6::text LIKE '-%' THEN id END DESC,
+    id
+LIMIT $10
+`
+
+type ListGuidesParams struct {
+	Location  string
+	Expertise string
+	MinRating int32
+	MaxRate   sql.NullString
+	HasCursor bool
+	Sort      string
+	AfterText string
+	AfterID   uuid.UUID
+	AfterNum  string
+	PageLimit int32
+}
+
+func (q *Queries) ListGuides(ctx context.Context, arg ListGuidesParams) ([]Guide, error) {
+	rows, err := q.db.QueryContext(ctx, listGuides,
+		arg.Location,
+		arg.Expertise,
+		arg.MinRating,
+		arg.MaxRate,
+		arg.HasCursor,
+		arg.Sort,
+		arg.AfterText,
+		arg.AfterID,
+		arg.AfterNum,
+		arg.PageLimit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -77,6 +161,7 @@ func (q *Queries) GetGuideDetails(ctx context.Context, location string) ([]Guide
 			&i.Rating,
 			&i.HourlyRate,
 			&i.Available,
+			&i.Verified,
 		); err != nil {
 			return nil, err
 		}
@@ -122,7 +207,7 @@ func (q *Queries) SendGuideRequest(ctx context.Context, arg SendGuideRequestPara
 	return err
 }
 
-const updateGuideAvail = `-- name: UpdateGuideAvail :exec
+const updateGuideAvail = `-- name: UpdateGuideAvail :execrows
 UPDATE guides 
 SET available=$1
 WHERE id=$2
@@ -133,9 +218,12 @@ type UpdateGuideAvailParams struct {
 	ID        uuid.UUID
 }
 
-func (q *Queries) UpdateGuideAvail(ctx context.Context, arg UpdateGuideAvailParams) error {
-	_, err := q.db.ExecContext(ctx, updateGuideAvail, arg.Available, arg.ID)
-	return err
+func (q *Queries) UpdateGuideAvail(ctx context.Context, arg UpdateGuideAvailParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, updateGuideAvail, arg.Available, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
 
 const updateGuideRequest = `-- name: UpdateGuideRequest :exec
@@ -12,54 +12,61 @@ import (
 	"github.com/google/uuid"
 )
 
-const deleteToken = `-- name: DeleteToken :exec
+const consumeUserToken = `-- name: ConsumeUserToken :one
 DELETE FROM password_tokens
-WHERE token=$1
+WHERE token_hash=$1 AND expires_at > CURRENT_TIMESTAMP
+RETURNING user_id
 `
 
-func (q *Queries) DeleteToken(ctx context.Context, token string) error {
-	_, err := q.db.ExecContext(ctx, deleteToken, token)
-	return err
+func (q *Queries) ConsumeUserToken(ctx context.Context, tokenHash string) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, consumeUserToken, tokenHash)
+	var user_id uuid.UUID
+	err := row.Scan(&user_id)
+	return user_id, err
+}
+
+const deleteExpiredTokens = `-- name: DeleteExpiredTokens :execrows
+DELETE FROM password_tokens
+WHERE expires_at < CURRENT_TIMESTAMP
+`
+
+func (q *Queries) DeleteExpiredTokens(ctx context.Context) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteExpiredTokens)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
 
-const getUserToken = `-- name: GetUserToken :one
-SELECT id, user_id, token, created_at, expires_at, updated_at FROM password_tokens
-WHERE token=$1
+const deleteUserTokens = `-- name: DeleteUserTokens :exec
+DELETE FROM password_tokens
+WHERE user_id=$1
 `
 
-func (q *Queries) GetUserToken(ctx context.Context, token string) (PasswordToken, error) {
-	row := q.db.QueryRowContext(ctx, getUserToken, token)
-	var i PasswordToken
-	err := row.Scan(
-		&i.ID,
-		&i.UserID,
-		&i.Token,
-		&i.CreatedAt,
-		&i.ExpiresAt,
-		&i.UpdatedAt,
-	)
-	return i, err
+func (q *Queries) DeleteUserTokens(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteUserTokens, userID)
+	return err
 }
 
 const insertToken = `-- name: InsertToken :exec
-INSERT INTO password_tokens(user_id, token, expires_at)
+INSERT INTO password_tokens(user_id, token_hash, expires_at)
 VALUES($1, $2, $3)
 `
 
 type InsertTokenParams struct {
 	UserID    uuid.UUID
-	Token     string
+	TokenHash string
 	ExpiresAt time.Time
 }
 
 func (q *Queries) InsertToken(ctx context.Context, arg InsertTokenParams) error {
-	_, err := q.db.ExecContext(ctx, insertToken, arg.UserID, arg.Token, arg.ExpiresAt)
+	_, err := q.db.ExecContext(ctx, insertToken, arg.UserID, arg.TokenHash, arg.ExpiresAt)
 	return err
 }
 
 const updateUserPassword = `-- name: UpdateUserPassword :exec
 UPDATE users
-SET password_hash=$1
+SET password_hash=$1, token_version=COALESCE(token_version, 0) + 1, updated_at=CURRENT_TIMESTAMP
 WHERE id=$2
 `
 
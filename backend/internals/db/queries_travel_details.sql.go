@@ -7,14 +7,17 @@ package db
 
 import (
 	"context"
+	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/lib/pq"
 )
 
-const addTravelDetails = `-- name: AddTravelDetails :exec
+const addTravelDetails = `-- name: AddTravelDetails :one
 INSERT INTO travel_plan_details(creator_id, place, start_date, end_date, trip_type, pets, interests)
 VALUES($1, $2, $3, $4, $5, $6, $7)
+RETURNING id
 `
 
 type AddTravelDetailsParams struct {
@@ -27,8 +30,8 @@ type AddTravelDetailsParams struct {
 	Interests []string
 }
 
-func (q *Queries) AddTravelDetails(ctx context.Context, arg AddTravelDetailsParams) error {
-	_, err := q.db.ExecContext(ctx, addTravelDetails,
+func (q *Queries) AddTravelDetails(ctx context.Context, arg AddTravelDetailsParams) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, addTravelDetails,
 		arg.CreatorID,
 		arg.Place,
 		arg.StartDate,
@@ -37,7 +40,9 @@ func (q *Queries) AddTravelDetails(ctx context.Context, arg AddTravelDetailsPara
 		arg.Pets,
 		pq.Array(arg.Interests),
 	)
-	return err
+	var id uuid.UUID
+	err := row.Scan(&id)
+	return id, err
 }
 
 const getUserPlansDetails = `-- name: GetUserPlansDetails :many
@@ -85,3 +90,96 @@ func (q *Queries) GetUserPlansDetails(ctx context.Context, id uuid.UUID) ([]GetU
 	}
 	return items, nil
 }
+
+const listUserPlans = `-- name: ListUserPlans :many
+SELECT t.id, t.place, t.start_date, t.end_date, t.trip_type, t.pets, t.interests, t.created_at
+FROM travel_plan_details as t
+WHERE t.creator_id = $1
+AND ($2::text = '' OR t.place ILIKE '%' || $2::text || '%')
+AND ($3::text = '' OR t.trip_type = $3::text)
+AND ($4::text = '' OR t.start_date >= $4::text)
+AND ($5::text = '' OR t.start_date <= $5::text)
+AND (NOT $6::boolean OR CASE $7::text
+    WHEN 'start_date' THEN (t.start_date, t.id) > ($8::text, $9::uuid)
+    WHEN '-start_date' THEN (t.start_date, t.id) < ($8::text, $9::uuid)
+    WHEN 'created_at' THEN (t.created_at, t.id) > ($10::timestamp, $9::uuid)
+    WHEN '-created_at' THEN (t.created_at, t.id) < ($10::timestamp, $9::uuid)
+END)
+ORDER BY
+    CASE WHEN $7::text = 'start_date' THEN t.start_date END,
+    CASE WHEN $7::text = '-start_date' THEN t.start_date END DESC,
+    CASE WHEN $7::text = 'created_at' THEN t.created_at END,
+    CASE WHEN $7::text = '-created_at' THEN t.created_at END DESC,
+    CASE WHEN $7::text LIKE '-%' THEN t.id END DESC,
+    t.id
+LIMIT $11
+`
+
+type ListUserPlansParams struct {
+	CreatorID uuid.UUID
+	Place     string
+	TripType  string
+	FromDate  string
+	ToDate    string
+	HasCursor bool
+	Sort      string
+	AfterText string
+	AfterID   uuid.UUID
+	AfterTime time.Time
+	PageLimit int32
+}
+
+type ListUserPlansRow struct {
+	ID        uuid.UUID
+	Place     string
+	StartDate string
+	EndDate   string
+	TripType  string
+	Pets      bool
+	Interests []string
+	CreatedAt sql.NullTime
+}
+
+func (q *Queries) ListUserPlans(ctx context.Context, arg ListUserPlansParams) ([]ListUserPlansRow, error) {
+	rows, err := q.db.QueryContext(ctx, listUserPlans,
+		arg.CreatorID,
+		arg.Place,
+		arg.TripType,
+		arg.FromDate,
+		arg.ToDate,
+		arg.HasCursor,
+		arg.Sort,
+		arg.AfterText,
+		arg.AfterID,
+		arg.AfterTime,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListUserPlansRow
+	for rows.Next() {
+		var i ListUserPlansRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Place,
+			&i.StartDate,
+			&i.EndDate,
+			&i.TripType,
+			&i.Pets,
+			pq.Array(&i.Interests),
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
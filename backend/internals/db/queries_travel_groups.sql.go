@@ -7,6 +7,8 @@ package db
 
 import (
 	"context"
+	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -182,6 +184,183 @@ func (q *Queries) GetUserGroups(ctx context.Context, userID uuid.UUID) ([]GetUse
 	return items, nil
 }
 
+const listGroupMembers = `-- name: ListGroupMembers :many
+SELECT u.id, u.name, u.age, u.phone_number, u.email, m.created_at AS joined_at
+FROM users u
+INNER JOIN travel_groups_members m ON m.user_id = u.id
+WHERE m.group_id = $1
+AND ($2::text = '' OR u.name ILIKE '%' || $2::text || '%')
+AND (NOT $3::boolean OR CASE $4::text
+    WHEN 'name' THEN (u.name, u.id) > ($5::text, $6::uuid)
+    WHEN '-name' THEN (u.name, u.id) < ($5::text, $6::uuid)
+    WHEN 'joined_at' THEN (m.created_at, u.id) > ($7::timestamp, $6::uuid)
+    WHEN '-joined_at' THEN (m.created_at, u.id) < ($7::timestamp, $6::uuid)
+END)
+ORDER BY
+    CASE WHEN $4::text = 'name' THEN u.name END,
+    CASE WHEN $4::text = '-name' THEN u.name END DESC,
+    CASE WHEN $4::text = 'joined_at' THEN m.created_at END,
+    CASE WHEN $4::text = '-joined_at' THEN m.created_at END DESC,
+    CASE WHEN $4::text LIKE '-%' THEN u.id END DESC,
+    u.id
+LIMIT $8
+`
+
+type ListGroupMembersParams struct {
+	GroupID   uuid.UUID
+	Name      string
+	HasCursor bool
+	Sort      string
+	AfterText string
+	AfterID   uuid.UUID
+	AfterTime time.Time
+	PageLimit int32
+}
+
+type ListGroupMembersRow struct {
+	ID          uuid.UUID
+	Name        string
+	Age         int32
+	PhoneNumber string
+	Email       string
+	JoinedAt    sql.NullTime
+}
+
+func (q *Queries) ListGroupMembers(ctx context.Context, arg ListGroupMembersParams) ([]ListGroupMembersRow, error) {
+	rows, err := q.db.QueryContext(ctx, listGroupMembers,
+		arg.GroupID,
+		arg.Name,
+		arg.HasCursor,
+		arg.Sort,
+		arg.AfterText,
+		arg.AfterID,
+		arg.AfterTime,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListGroupMembersRow
+	for rows.Next() {
+		var i ListGroupMembersRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Age,
+			&i.PhoneNumber,
+			&i.Email,
+			&i.JoinedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUserGroups = `-- name: ListUserGroups :many
+SELECT g.id, g.creator_id, g.name, g.description, g.plan_id, p.place, p.start_date, g.created_at
+FROM travel_groups g
+INNER JOIN travel_groups_members t ON t.group_id = g.id
+INNER JOIN travel_plan_details p ON p.id = g.plan_id
+WHERE t.user_id = $1
+AND ($2::text = '' OR p.place ILIKE '%' || $2::text || '%')
+AND ($3::text = '' OR p.start_date >= $3::text)
+AND ($4::text = '' OR p.start_date <= $4::text)
+AND (NOT $5::boolean OR CASE $6::text
+    WHEN 'name' THEN (g.name, g.id) > ($7::text, $8::uuid)
+    WHEN '-name' THEN (g.name, g.id) < ($7::text, $8::uuid)
+    WHEN 'start_date' THEN (p.start_date, g.id) > ($7::text, $8::uuid)
+    WHEN '-start_date' THEN (p.start_date, g.id) < ($7::text, $8::uuid)
+    WHEN 'created_at' THEN (g.created_at, g.id) > ($9::timestamp, $8::uuid)
+    WHEN '-created_at' THEN (g.created_at, g.id) < ($9::timestamp, $8::uuid)
+END)
+ORDER BY
+    CASE WHEN $6::text = 'name' THEN g.name END,
+    CASE WHEN $6::text = '-name' THEN g.name END DESC,
+    CASE WHEN $6::text = 'start_date' THEN p.start_date END,
+    CASE WHEN $6::text = '-start_date' THEN p.start_date END DESC,
+    CASE WHEN $6::text = 'created_at' THEN g.created_at END,
+    CASE WHEN $6::text = '-created_at' THEN g.created_at END DESC,
+    CASE WHEN $6::text LIKE '-%' THEN g.id END DESC,
+    g.id
+LIMIT $10
+`
+
+type ListUserGroupsParams struct {
+	UserID    uuid.UUID
+	Place     string
+	FromDate  string
+	ToDate    string
+	HasCursor bool
+	Sort      string
+	AfterText string
+	AfterID   uuid.UUID
+	AfterTime time.Time
+	PageLimit int32
+}
+
+type ListUserGroupsRow struct {
+	ID          uuid.UUID
+	CreatorID   uuid.UUID
+	Name        string
+	Description string
+	PlanID      uuid.UUID
+	Place       string
+	StartDate   string
+	CreatedAt   sql.NullTime
+}
+
+func (q *Queries) ListUserGroups(ctx context.Context, arg ListUserGroupsParams) ([]ListUserGroupsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listUserGroups,
+		arg.UserID,
+		arg.Place,
+		arg.FromDate,
+		arg.ToDate,
+		arg.HasCursor,
+		arg.Sort,
+		arg.AfterText,
+		arg.AfterID,
+		arg.AfterTime,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListUserGroupsRow
+	for rows.Next() {
+		var i ListUserGroupsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatorID,
+			&i.Name,
+			&i.Description,
+			&i.PlanID,
+			&i.Place,
+			&i.StartDate,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const updateGroupByID = `-- name: UpdateGroupByID :exec
 UPDATE travel_groups
 SET name=$1, description=$2, updated_at=CURRENT_TIMESTAMP
@@ -8,21 +8,105 @@ package db
 import (
 	"context"
 	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
 )
 
-const getUserGroupRequests = `-- name: GetUserGroupRequests :many
+const deleteStalePendingRequests = `-- name: DeleteStalePendingRequests :execrows
+DELETE FROM travel_groups_requests
+WHERE status='pending' AND created_at < $1
+`
+
+func (q *Queries) DeleteStalePendingRequests(ctx context.Context, createdAt sql.NullTime) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteStalePendingRequests, createdAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getRequestsSentByUser = `-- name: GetRequestsSentByUser :many
+SELECT r.id, r.group_id, g.name, r.status, r.created_at
+FROM travel_groups_requests as r
+JOIN travel_groups g ON r.group_id = g.id
+WHERE r.user_id=$1
+ORDER BY r.created_at DESC
+`
+
+type GetRequestsSentByUserRow struct {
+	ID        uuid.UUID
+	GroupID   uuid.UUID
+	Name      string
+	Status    string
+	CreatedAt sql.NullTime
+}
+
+func (q *Queries) GetRequestsSentByUser(ctx context.Context, userID uuid.UUID) ([]GetRequestsSentByUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getRequestsSentByUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetRequestsSentByUserRow
+	for rows.Next() {
+		var i GetRequestsSentByUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.GroupID,
+			&i.Name,
+			&i.Status,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listGroupRequests = `-- name: ListGroupRequests :many
 SELECT r.id AS request_id, r.group_id, g.name, u.id AS sender_id, u.name AS sender_name, r.status, r.created_at
 FROM travel_groups_requests as r
 JOIN travel_groups g ON r.group_id = g.id
 JOIN users u ON r.user_id = u.id
-WHERE g.creator_id =$1
+WHERE g.creator_id = $1
 AND r.status = 'pending'
-ORDER BY r.created_at DESC
+AND ($2::uuid IS NULL OR r.group_id = $2::uuid)
+AND (NOT $3::boolean OR CASE $4::text
+    WHEN 'created_at' THEN (r.created_at, r.id) > ($5::timestamp, $6::uuid)
+    WHEN '-created_at' THEN (r.created_at, r.id) < ($5::timestamp, $6::uuid)
+    WHEN 'sender_name' THEN (u.name, r.id) > ($7::text, $6::uuid)
+    WHEN '-sender_name' THEN (u.name, r.id) < ($7::text, $6::uuid)
+END)
+ORDER BY
+    CASE WHEN $4::text = 'created_at' THEN r.created_at END,
+    CASE WHEN $4::text = '-created_at' THEN r.created_at END DESC,
+    CASE WHEN $4::text = 'sender_name' THEN u.name END,
+    CASE WHEN $4::text = '-sender_name' THEN u.name END DESC,
+    CASE WHEN $4::text LIKE '-%' THEN r.id END DESC,
+    r.id
+LIMIT $8
 `
 
-type GetUserGroupRequestsRow struct {
+type ListGroupRequestsParams struct {
+	CreatorID uuid.UUID
+	GroupID   uuid.NullUUID
+	HasCursor bool
+	Sort      string
+	AfterTime time.Time
+	AfterID   uuid.UUID
+	AfterText string
+	PageLimit int32
+}
+
+type ListGroupRequestsRow struct {
 	RequestID  uuid.UUID
 	GroupID    uuid.UUID
 	Name       string
@@ -32,15 +116,24 @@ type GetUserGroupRequestsRow struct {
 	CreatedAt  sql.NullTime
 }
 
-func (q *Queries) GetUserGroupRequests(ctx context.Context, creatorID uuid.UUID) ([]GetUserGroupRequestsRow, error) {
-	rows, err := q.db.QueryContext(ctx, getUserGroupRequests, creatorID)
+func (q *Queries) ListGroupRequests(ctx context.Context, arg ListGroupRequestsParams) ([]ListGroupRequestsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listGroupRequests,
+		arg.CreatorID,
+		arg.GroupID,
+		arg.HasCursor,
+		arg.Sort,
+		arg.AfterTime,
+		arg.AfterID,
+		arg.AfterText,
+		arg.PageLimit,
+	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []GetUserGroupRequestsRow
+	var items []ListGroupRequestsRow
 	for rows.Next() {
-		var i GetUserGroupRequestsRow
+		var i ListGroupRequestsRow
 		if err := rows.Scan(
 			&i.RequestID,
 			&i.GroupID,
@@ -93,10 +186,10 @@ func (q *Queries) SendRequest(ctx context.Context, arg SendRequestParams) error
 	return err
 }
 
-const updateRequest = `-- name: UpdateRequest :exec
+const updateRequest = `-- name: UpdateRequest :execrows
 UPDATE travel_groups_requests
-SET status=$1
-WHERE group_id=$2 AND user_id=$3
+SET status=$1, updated_at=CURRENT_TIMESTAMP
+WHERE group_id=$2 AND user_id=$3 AND status='pending'
 `
 
 type UpdateRequestParams struct {
@@ -105,7 +198,10 @@ type UpdateRequestParams struct {
 	UserID  uuid.UUID
 }
 
-func (q *Queries) UpdateRequest(ctx context.Context, arg UpdateRequestParams) error {
-	_, err := q.db.ExecContext(ctx, updateRequest, arg.Status, arg.GroupID, arg.UserID)
-	return err
+func (q *Queries) UpdateRequest(ctx context.Context, arg UpdateRequestParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, updateRequest, arg.Status, arg.GroupID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
@@ -22,16 +22,36 @@ func (q *Queries) DeleteUser(ctx context.Context, id uuid.UUID) error {
 	return err
 }
 
+const getUserAuthState = `-- name: GetUserAuthState :one
+SELECT token_version, suspended_at FROM users
+WHERE id=$1
+`
+
+type GetUserAuthStateRow struct {
+	TokenVersion sql.NullInt32
+	SuspendedAt  sql.NullTime
+}
+
+func (q *Queries) GetUserAuthState(ctx context.Context, id uuid.UUID) (GetUserAuthStateRow, error) {
+	row := q.db.QueryRowContext(ctx, getUserAuthState, id)
+	var i GetUserAuthStateRow
+	err := row.Scan(&i.TokenVersion, &i.SuspendedAt)
+	return i, err
+}
+
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, email, password_hash, access_level FROM users
+SELECT id, email, password_hash, access_level, token_version, verified_status, suspended_at FROM users
 WHERE email=$1
 `
 
 type GetUserByEmailRow struct {
-	ID           uuid.UUID
-	Email        string
-	PasswordHash string
-	AccessLevel  sql.NullString
+	ID             uuid.UUID
+	Email          string
+	PasswordHash   string
+	AccessLevel    sql.NullString
+	TokenVersion   sql.NullInt32
+	VerifiedStatus sql.NullBool
+	SuspendedAt    sql.NullTime
 }
 
 func (q *Queries) GetUserByEmail(ctx context.Context, email string) (GetUserByEmailRow, error) {
@@ -42,12 +62,15 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (GetUserByEm
 		&i.Email,
 		&i.PasswordHash,
 		&i.AccessLevel,
+		&i.TokenVersion,
+		&i.VerifiedStatus,
+		&i.SuspendedAt,
 	)
 	return i, err
 }
 
 const getUserByID = `-- name: GetUserByID :one
-SELECT id, name, age, phone_number, email, password_hash, token_version, access_level, verified_status, created_at, updated_at, last_logged_in FROM users
+SELECT id, name, age, phone_number, email, password_hash, token_version, access_level, verified_status, created_at, updated_at, last_logged_in, suspended_at FROM users
 WHERE id=$1
 `
 
@@ -67,12 +90,25 @@ func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.LastLoggedIn,
+		&i.SuspendedAt,
 	)
 	return i, err
 }
 
+const getUserVerifiedStatus = `-- name: GetUserVerifiedStatus :one
+SELECT verified_status FROM users
+WHERE id=$1
+`
+
+func (q *Queries) GetUserVerifiedStatus(ctx context.Context, id uuid.UUID) (sql.NullBool, error) {
+	row := q.db.QueryRowContext(ctx, getUserVerifiedStatus, id)
+	var verified_status sql.NullBool
+	err := row.Scan(&verified_status)
+	return verified_status, err
+}
+
 const getUsers = `-- name: GetUsers :many
-SELECT id, name, age, phone_number, email, password_hash, token_version, access_level, verified_status, created_at, updated_at, last_logged_in FROM users
+SELECT id, name, age, phone_number, email, password_hash, token_version, access_level, verified_status, created_at, updated_at, last_logged_in, suspended_at FROM users
 `
 
 func (q *Queries) GetUsers(ctx context.Context) ([]User, error) {
@@ -97,6 +133,7 @@ func (q *Queries) GetUsers(ctx context.Context) ([]User, error) {
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.LastLoggedIn,
+			&i.SuspendedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -111,9 +148,21 @@ func (q *Queries) GetUsers(ctx context.Context) ([]User, error) {
 	return items, nil
 }
 
-const registerUser = `-- name: RegisterUser :exec
+const incrementTokenVersion = `-- name: IncrementTokenVersion :exec
+UPDATE users
+SET token_version=COALESCE(token_version, 0) + 1
+WHERE id=$1
+`
+
+func (q *Queries) IncrementTokenVersion(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, incrementTokenVersion, id)
+	return err
+}
+
+const registerUser = `-- name: RegisterUser :one
 INSERT INTO users(name, age, phone_number, email, password_hash)
 VALUES ($1, $2, $3, $4, $5)
+RETURNING id
 `
 
 type RegisterUserParams struct {
@@ -124,14 +173,27 @@ type RegisterUserParams struct {
 	PasswordHash string
 }
 
-func (q *Queries) RegisterUser(ctx context.Context, arg RegisterUserParams) error {
-	_, err := q.db.ExecContext(ctx, registerUser,
+func (q *Queries) RegisterUser(ctx context.Context, arg RegisterUserParams) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, registerUser,
 		arg.Name,
 		arg.Age,
 		arg.PhoneNumber,
 		arg.Email,
 		arg.PasswordHash,
 	)
+	var id uuid.UUID
+	err := row.Scan(&id)
+	return id, err
+}
+
+const updateLastLoggedIn = `-- name: UpdateLastLoggedIn :exec
+UPDATE users
+SET last_logged_in=CURRENT_TIMESTAMP
+WHERE id=$1
+`
+
+func (q *Queries) UpdateLastLoggedIn(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, updateLastLoggedIn, id)
 	return err
 }
 
@@ -157,3 +219,19 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) error {
 	)
 	return err
 }
+
+const updateUserAccessLevel = `-- name: UpdateUserAccessLevel :exec
+UPDATE users
+SET access_level=$1, token_version=COALESCE(token_version, 0) + 1, updated_at=CURRENT_TIMESTAMP
+WHERE id=$2
+`
+
+type UpdateUserAccessLevelParams struct {
+	AccessLevel sql.NullString
+	ID          uuid.UUID
+}
+
+func (q *Queries) UpdateUserAccessLevel(ctx context.Context, arg UpdateUserAccessLevelParams) error {
+	_, err := q.db.ExecContext(ctx, updateUserAccessLevel, arg.AccessLevel, arg.ID)
+	return err
+}
@@ -0,0 +1,446 @@
+package handlers
+
+import (
+	"database/sql"
+
+	"github.com/ErebusAJ/YatraBandhu/internals/db"
+	"github.com/ErebusAJ/YatraBandhu/internals/paging"
+	"github.com/ErebusAJ/YatraBandhu/internals/store"
+	"github.com/ErebusAJ/YatraBandhu/internals/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Sent for an unknown ?status= filter
+var invalidStatusError = &utils.APIError{
+	Status: 400,
+	Code: utils.CodeValidationFailed,
+	Message: "invalid status",
+	Details: []utils.FieldError{{Field: "status", Message: "unknown status"}},
+}
+
+// roleRequest
+// body of PUT /admin/users/:userID/role
+type roleRequest struct{
+	Role	string	`json:"role" binding:"required,oneof=user guide admin"`
+}
+
+// updateUserRole
+// promotes or demotes a user, existing tokens of the user are revoked
+// so the new role is picked up on next login
+func(cfg *apiConfig) updateUserRole(c *gin.Context){
+	var reqDetails roleRequest
+
+	err := c.ShouldBind(&reqDetails)
+	if err != nil{
+		utils.ErrorJSON(c, 400, utils.RequestBodyError, utils.JSONError, err)
+		return
+	}
+
+	userID, err := uuid.Parse(c.Param("userID"))
+	if err != nil{
+		utils.ErrorJSON(c, 400, utils.IDParseError, utils.EndpointError, err)
+		return
+	}
+
+	tempID, exists := c.Get("userID")
+	if !exists{
+		utils.ErrorJSON(c, 401, utils.MiddlewareError, utils.UnauthorizedError, nil)
+		return
+	}
+	if tempID.(uuid.UUID) == userID{
+		utils.ErrorJSON(c, 400, "admin changing own role", "cannot change your own role", nil)
+		return
+	}
+
+	_, err = cfg.DB.GetUserByID(c, userID)
+	if err != nil{
+		utils.DBErrorJSON(c, err, utils.ErrUserNotFound)
+		return
+	}
+
+	err = cfg.DB.UpdateUserAccessLevel(c, db.UpdateUserAccessLevelParams{
+		AccessLevel: sql.NullString{String: reqDetails.Role, Valid: true},
+		ID: userID,
+	})
+	if err != nil{
+		utils.DBErrorJSON(c, err, nil)
+		return
+	}
+
+	err = cfg.DB.RevokeUserRefreshTokens(c, userID)
+	if err != nil{
+		utils.DBErrorJSON(c, err, nil)
+		return
+	}
+
+	c.IndentedJSON(200, utils.MessageObj("role updated"))
+}
+
+
+// userList
+// sorts and filters of GET /admin/users
+var userList = paging.Spec{
+	Sorts: []paging.Field{
+		{Name: "created_at", Kind: paging.Time},
+		{Name: "name"},
+	},
+	Default: "-created_at",
+	Filters: []paging.Field{
+		{Name: "q", Description: "substring of the name or email, case insensitive"},
+	},
+}
+
+
+// listUsers
+// lists users matching the optional search query "q" on name or email,
+// a page at a time
+func(cfg *apiConfig) listUsers(c *gin.Context){
+	listPage(c, userList, func(r paging.Request) ([]db.SearchUsersRow, error){
+		after := r.AfterKey()
+		return cfg.DB.SearchUsers(c, db.SearchUsersParams{
+			Search: r.Filters["q"],
+			HasCursor: r.After != nil,
+			Sort: r.SortParam(),
+			AfterText: after.Text,
+			AfterID: after.ID,
+			AfterTime: after.Time,
+			PageLimit: r.Fetch(),
+		})
+	}, func(u db.SearchUsersRow, sort string) paging.Key {
+		if sort == "name"{
+			return paging.Key{Text: u.Name, ID: u.ID}
+		}
+		return paging.Key{Time: u.CreatedAt.Time, ID: u.ID}
+	})
+}
+
+
+// setUserSuspension
+// suspends or unsuspends a user account
+// suspending revokes every session of the user
+func(cfg *apiConfig) setUserSuspension(suspend bool) gin.HandlerFunc{
+	return func(c *gin.Context){
+		userID, err := uuid.Parse(c.Param("userID"))
+		if err != nil{
+			utils.ErrorJSON(c, 400, utils.IDParseError, utils.EndpointError, err)
+			return
+		}
+
+		_, err = cfg.DB.GetUserByID(c, userID)
+		if err != nil{
+			utils.DBErrorJSON(c, err, utils.ErrUserNotFound)
+			return
+		}
+
+		if !suspend{
+			err = cfg.DB.UnsuspendUser(c, userID)
+			if err != nil{
+				utils.DBErrorJSON(c, err, nil)
+				return
+			}
+
+			c.IndentedJSON(200, utils.MessageObj("user unsuspended"))
+			return
+		}
+
+		err = cfg.DB.SuspendUser(c, userID)
+		if err != nil{
+			utils.DBErrorJSON(c, err, nil)
+			return
+		}
+
+		err = cfg.DB.RevokeUserRefreshTokens(c, userID)
+		if err != nil{
+			utils.DBErrorJSON(c, err, nil)
+			return
+		}
+
+		c.IndentedJSON(200, utils.MessageObj("user suspended"))
+	}
+}
+
+
+// forceDeleteGroup
+// deletes any travel group regardless of its creator
+func(cfg *apiConfig) forceDeleteGroup(c *gin.Context){
+	groupID, err := uuid.Parse(c.Param("groupID"))
+	if err != nil{
+		utils.ErrorJSON(c, 400, utils.IDParseError, utils.EndpointError, err)
+		return
+	}
+
+	tempID, exists := c.Get("userID")
+	if !exists{
+		utils.ErrorJSON(c, 401, utils.MiddlewareError, utils.UnauthorizedError, nil)
+		return
+	}
+	adminID := tempID.(uuid.UUID)
+
+	err = cfg.DB.InTx(c, func(q store.Store) error{
+		group, err := q.GetGroupByID(c, groupID)
+		if err != nil{
+			return err
+		}
+
+		err = q.DeleteGroupByID(c, groupID)
+		if err != nil{
+			return err
+		}
+
+		return recordAudit(c, q, auditEvent{
+			Action: auditGroupDelete,
+			ActorID: adminID,
+			SubjectID: group.CreatorID,
+			TargetType: "group",
+			TargetID: groupID,
+			Metadata: gin.H{"name": group.Name, "forced": true},
+		})
+	})
+	if err != nil{
+		utils.DBErrorJSON(c, err, utils.ErrGroupNotFound)
+		return
+	}
+
+	c.IndentedJSON(204, utils.MessageObj("deletion success!!!"))
+}
+
+
+// moderateGuideRequest
+// body of PUT /admin/guides/:guideID, at least one field
+// is required
+type moderateGuideRequest struct{
+	Verified	*bool	`json:"verified"`
+	Available	*bool	`json:"available"`
+}
+
+// moderateGuide
+// verifies/unverifies a guide and lists/unlists it from search
+func(cfg *apiConfig) moderateGuide(c *gin.Context){
+	var reqDetails moderateGuideRequest
+
+	err := c.ShouldBind(&reqDetails)
+	if err != nil || (reqDetails.Verified == nil && reqDetails.Available == nil){
+		utils.ErrorJSON(c, 400, utils.RequestBodyError, utils.JSONError, err)
+		return
+	}
+
+	guideID, err := uuid.Parse(c.Param("guideID"))
+	if err != nil{
+		utils.ErrorJSON(c, 400, utils.IDParseError, utils.EndpointError, err)
+		return
+	}
+
+	if reqDetails.Verified != nil{
+		n, err := cfg.DB.SetGuideVerified(c, db.SetGuideVerifiedParams{
+			Verified: *reqDetails.Verified,
+			ID: guideID,
+		})
+		if err != nil{
+			utils.DBErrorJSON(c, err, nil)
+			return
+		}
+		if n == 0{
+			utils.SendError(c, utils.ErrGuideNotFound, "no guide with id", nil)
+			return
+		}
+	}
+
+	if reqDetails.Available != nil{
+		n, err := cfg.DB.UpdateGuideAvail(c, db.UpdateGuideAvailParams{
+			Available: *reqDetails.Available,
+			ID: guideID,
+		})
+		if err != nil{
+			utils.DBErrorJSON(c, err, nil)
+			return
+		}
+		if n == 0{
+			utils.SendError(c, utils.ErrGuideNotFound, "no guide with id", nil)
+			return
+		}
+	}
+
+	c.IndentedJSON(200, utils.MessageObj("guide updated"))
+}
+
+
+// reportList
+// sorts and filters of GET /admin/reports
+var reportList = paging.Spec{
+	Sorts: []paging.Field{
+		{Name: "created_at", Kind: paging.Time},
+	},
+	Default: "created_at",
+	Filters: []paging.Field{
+		{Name: "status", Description: "pending (default), resolved or dismissed"},
+	},
+}
+
+
+// getReports
+// lists reports by status, pending by default, oldest first
+func(cfg *apiConfig) getReports(c *gin.Context){
+	status := c.DefaultQuery("status", "pending")
+	if status != "pending" && status != "resolved" && status != "dismissed"{
+		utils.SendError(c, invalidStatusError, utils.ParsingError, nil)
+		return
+	}
+
+	listPage(c, reportList, func(r paging.Request) ([]db.Report, error){
+		after := r.AfterKey()
+		return cfg.DB.GetReportsByStatus(c, db.GetReportsByStatusParams{
+			Status: status,
+			HasCursor: r.After != nil,
+			Sort: r.SortParam(),
+			AfterTime: after.Time,
+			AfterID: after.ID,
+			PageLimit: r.Fetch(),
+		})
+	}, func(report db.Report, sort string) paging.Key {
+		return paging.Key{Time: report.CreatedAt, ID: report.ID}
+	})
+}
+
+
+// reportStatusRequest
+// body of PUT /admin/reports/:reportID
+type reportStatusRequest struct{
+	Status	string	`json:"status" binding:"required,oneof=resolved dismissed"`
+}
+
+// updateReport
+// resolves or dismisses a report
+func(cfg *apiConfig) updateReport(c *gin.Context){
+	var reqDetails reportStatusRequest
+
+	err := c.ShouldBind(&reqDetails)
+	if err != nil{
+		utils.ErrorJSON(c, 400, utils.RequestBodyError, utils.JSONError, err)
+		return
+	}
+
+	reportID, err := uuid.Parse(c.Param("reportID"))
+	if err != nil{
+		utils.ErrorJSON(c, 400, utils.IDParseError, utils.EndpointError, err)
+		return
+	}
+
+	n, err := cfg.DB.UpdateReportStatus(c, db.UpdateReportStatusParams{
+		Status: reqDetails.Status,
+		ID: reportID,
+	})
+	if err != nil{
+		utils.DBErrorJSON(c, err, nil)
+		return
+	}
+	if n == 0{
+		utils.SendError(c, utils.ErrReportNotFound, "no report with id", nil)
+		return
+	}
+
+	c.IndentedJSON(200, utils.MessageObj("report updated"))
+}
+
+
+// reportRequest
+// body of POST /auth/reports
+type reportRequest struct{
+	TargetType	string	`json:"target_type" binding:"required,oneof=user group guide"`
+	TargetID	string	`json:"target_id" binding:"required,uuid"`
+	Reason		string	`json:"reason" binding:"required,max=500"`
+}
+
+// createReport
+// lets a user report another user, a group or a guide for moderation
+func(cfg *apiConfig) createReport(c *gin.Context){
+	var reqDetails reportRequest
+
+	err := c.ShouldBind(&reqDetails)
+	if err != nil{
+		utils.ErrorJSON(c, 400, utils.RequestBodyError, utils.JSONError, err)
+		return
+	}
+
+	tempID, exists := c.Get("userID")
+	if !exists{
+		utils.ErrorJSON(c, 401, utils.MiddlewareError, utils.UnauthorizedError, nil)
+		return
+	}
+
+	err = cfg.DB.CreateReport(c, db.CreateReportParams{
+		ReporterID: tempID.(uuid.UUID),
+		TargetType: reqDetails.TargetType,
+		TargetID: uuid.MustParse(reqDetails.TargetID),
+		Reason: reqDetails.Reason,
+	})
+	if err != nil{
+		utils.DBErrorJSON(c, err, nil)
+		return
+	}
+
+	c.IndentedJSON(201, utils.MessageObj("report submitted"))
+}
+
+
+
+// outboxList
+// sorts and filters of GET /admin/outbox
+var outboxList = paging.Spec{
+	Sorts: []paging.Field{
+		{Name: "created_at", Kind: paging.Time},
+	},
+	Default: "-created_at",
+	Filters: []paging.Field{
+		{Name: "status", Description: "pending, sent or dead (default)"},
+	},
+}
+
+
+// getOutbox
+// lists queued mail by status, dead by default, newest first
+// bodies are left out, they can hold live reset links
+func(cfg *apiConfig) getOutbox(c *gin.Context){
+	status := c.DefaultQuery("status", "dead")
+	if status != "pending" && status != "sent" && status != "dead"{
+		utils.SendError(c, invalidStatusError, utils.ParsingError, nil)
+		return
+	}
+
+	listPage(c, outboxList, func(r paging.Request) ([]db.ListOutboxByStatusRow, error){
+		after := r.AfterKey()
+		return cfg.DB.ListOutboxByStatus(c, db.ListOutboxByStatusParams{
+			Status: status,
+			HasCursor: r.After != nil,
+			Sort: r.SortParam(),
+			AfterTime: after.Time,
+			AfterID: after.ID,
+			PageLimit: r.Fetch(),
+		})
+	}, func(e db.ListOutboxByStatusRow, sort string) paging.Key {
+		return paging.Key{Time: e.CreatedAt, ID: e.ID}
+	})
+}
+
+
+// requeueEmail
+// puts a dead mail back in the queue with a fresh attempt count
+func(cfg *apiConfig) requeueEmail(c *gin.Context){
+	emailID, err := uuid.Parse(c.Param("emailID"))
+	if err != nil{
+		utils.ErrorJSON(c, 400, utils.IDParseError, utils.EndpointError, err)
+		return
+	}
+
+	n, err := cfg.DB.RequeueEmail(c, emailID)
+	if err != nil{
+		utils.DBErrorJSON(c, err, nil)
+		return
+	}
+	if n == 0{
+		utils.SendError(c, utils.ErrEmailNotFound, "no dead email with id", nil)
+		return
+	}
+
+	c.IndentedJSON(200, utils.MessageObj("email requeued"))
+}
@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/ErebusAJ/YatraBandhu/internals/db"
+	"github.com/ErebusAJ/YatraBandhu/internals/paging"
+	"github.com/ErebusAJ/YatraBandhu/internals/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func TestAdminRequiresRole(t *testing.T) {
+	s := newTestServer(t)
+	user := s.signup("plain")
+
+	s.expect(401, "GET", "/admin/users", "", nil)
+	s.expectError(403, utils.CodeForbidden, "GET", "/admin/users", user.Token, nil)
+	s.expect(403, "GET", "/admin/users", s.withRole(user, utils.RoleGuide).Token, nil)
+}
+
+func TestListUsers(t *testing.T) {
+	s := newTestServer(t)
+	admin := s.withRole(s.signup("boss"), utils.RoleAdmin)
+	s.signup("priya")
+	s.signup("rahul")
+
+	w := s.expect(200, "GET", "/admin/users", admin.Token, nil)
+	all := decode[paging.Page[db.SearchUsersRow]](t, w)
+	if len(all.Items) != 3 || all.Limit != 20 || all.Next != "" {
+		t.Fatalf("unexpected listing %+v", all)
+	}
+
+	w = s.expect(200, "GET", "/admin/users?q=PRIYA&limit=500", admin.Token, nil)
+	found := decode[paging.Page[db.SearchUsersRow]](t, w)
+	if len(found.Items) != 1 || found.Items[0].Name != "priya" || found.Limit != 100 {
+		t.Fatalf("unexpected search result %+v", found)
+	}
+
+	// by name, two at a time
+	w = s.expect(200, "GET", "/admin/users?sort=name&limit=2", admin.Token, nil)
+	page := decode[paging.Page[db.SearchUsersRow]](t, w)
+	if len(page.Items) != 2 || page.Items[0].Name != "boss" || page.Next == "" {
+		t.Fatalf("unexpected first page %+v", page)
+	}
+	page = decode[paging.Page[db.SearchUsersRow]](t, s.expect(200, "GET", page.Next, admin.Token, nil))
+	if len(page.Items) != 1 || page.Items[0].Name != "rahul" || page.Next != "" {
+		t.Fatalf("unexpected last page %+v", page)
+	}
+	s.expectError(400, utils.CodeMalformedURL, "GET", "/admin/users?sort=email", admin.Token, nil)
+}
+
+func TestUpdateUserRole(t *testing.T) {
+	s := newTestServer(t)
+	admin := s.withRole(s.signup("root"), utils.RoleAdmin)
+	u := s.signup("asha")
+
+	rolePath := "/admin/users/" + u.ID.String() + "/role"
+	s.expect(400, "PUT", rolePath, admin.Token, gin.H{"role": "owner"})
+	s.expect(400, "PUT", "/admin/users/not-a-uuid/role", admin.Token, gin.H{"role": "guide"})
+	s.expect(400, "PUT", "/admin/users/"+admin.ID.String()+"/role", admin.Token, gin.H{"role": "user"})
+	s.expectError(404, utils.CodeUserNotFound, "PUT", "/admin/users/"+uuid.NewString()+"/role", admin.Token, gin.H{"role": "guide"})
+	s.expect(200, "PUT", rolePath, admin.Token, gin.H{"role": "guide"})
+
+	// the old token carried the old role
+	s.expect(401, "GET", "/auth/user", u.Token, nil)
+	s.login(u)
+	w := s.expect(200, "GET", "/auth/user", u.Token, nil)
+	if user := decode[db.User](t, w); user.AccessLevel.String != utils.RoleGuide {
+		t.Fatalf("role not updated: %+v", user.AccessLevel)
+	}
+}
+
+func TestUserSuspension(t *testing.T) {
+	s := newTestServer(t)
+	admin := s.withRole(s.signup("mod"), utils.RoleAdmin)
+	u := s.signup("troll")
+
+	suspendPath := "/admin/users/" + u.ID.String() + "/suspend"
+	unsuspendPath := "/admin/users/" + u.ID.String() + "/unsuspend"
+
+	s.expect(404, "POST", "/admin/users/"+uuid.NewString()+"/suspend", admin.Token, nil)
+	s.expect(400, "POST", "/admin/users/not-a-uuid/unsuspend", admin.Token, nil)
+	s.expect(200, "POST", suspendPath, admin.Token, nil)
+
+	s.expect(401, "GET", "/auth/user", u.Token, nil)
+	s.expectError(403, utils.CodeAccountSuspended, "POST", "/v1/login", "", gin.H{"email": u.Email, "password": u.Password})
+	s.expect(401, "POST", "/v1/token/refresh", "", gin.H{"refresh_token": u.Refresh})
+
+	s.expect(200, "POST", unsuspendPath, admin.Token, nil)
+	s.login(u)
+	s.expect(200, "GET", "/auth/user", u.Token, nil)
+}
+
+func TestForceDeleteGroup(t *testing.T) {
+	s := newTestServer(t)
+	admin := s.withRole(s.signup("chief"), utils.RoleAdmin)
+	u := s.signup("creator")
+	groupID := s.group(u, "Kutch")
+
+	s.expect(400, "DELETE", "/admin/groups/not-a-uuid", admin.Token, nil)
+	s.expectError(404, utils.CodeGroupNotFound, "DELETE", "/admin/groups/"+uuid.NewString(), admin.Token, nil)
+	s.expect(204, "DELETE", "/admin/groups/"+groupID.String(), admin.Token, nil)
+
+	if _, err := s.store.GetGroupByID(context.Background(), groupID); err == nil {
+		t.Fatalf("group still exists")
+	}
+}
+
+func TestModerateGuide(t *testing.T) {
+	s := newTestServer(t)
+	admin := s.withRole(s.signup("curator"), utils.RoleAdmin)
+	guideID := s.guide("Kabir", "Leh")
+
+	guidePath := "/admin/guides/" + guideID.String()
+	s.expect(400, "PUT", guidePath, admin.Token, gin.H{})
+	s.expect(400, "PUT", "/admin/guides/not-a-uuid", admin.Token, gin.H{"verified": true})
+	s.expectError(404, utils.CodeGuideNotFound, "PUT", "/admin/guides/"+uuid.NewString(), admin.Token, gin.H{"verified": true})
+	s.expectError(404, utils.CodeGuideNotFound, "PUT", "/admin/guides/"+uuid.NewString(), admin.Token, gin.H{"available": false})
+	s.expect(200, "PUT", guidePath, admin.Token, gin.H{"verified": true, "available": false})
+
+	guide, _ := s.store.GetGuideByID(context.Background(), guideID)
+	if !guide.Verified || guide.Available {
+		t.Fatalf("guide not moderated %+v", guide)
+	}
+}
+
+func TestReports(t *testing.T) {
+	s := newTestServer(t)
+	admin := s.withRole(s.signup("warden"), utils.RoleAdmin)
+	u := s.signup("reporter")
+
+	s.expect(400, "POST", "/auth/reports", u.Token, gin.H{"target_type": "planet", "target_id": uuid.NewString(), "reason": "x"})
+	s.expect(400, "POST", "/auth/reports", u.Token, gin.H{"target_type": "user", "target_id": "nope", "reason": "x"})
+	s.expect(201, "POST", "/auth/reports", u.Token, gin.H{"target_type": "user", "target_id": admin.ID.String(), "reason": "rude"})
+
+	s.expectError(400, utils.CodeValidationFailed, "GET", "/admin/reports?status=open", admin.Token, nil)
+	w := s.expect(200, "GET", "/admin/reports", admin.Token, nil)
+	pending := decode[paging.Page[db.Report]](t, w)
+	if len(pending.Items) != 1 || pending.Items[0].Reason != "rude" {
+		t.Fatalf("unexpected reports %+v", pending)
+	}
+
+	reportPath := "/admin/reports/" + pending.Items[0].ID.String()
+	s.expect(400, "PUT", reportPath, admin.Token, gin.H{"status": "pending"})
+	s.expect(400, "PUT", "/admin/reports/not-a-uuid", admin.Token, gin.H{"status": "resolved"})
+	s.expectError(404, utils.CodeReportNotFound, "PUT", "/admin/reports/"+uuid.NewString(), admin.Token, gin.H{"status": "resolved"})
+	s.expect(200, "PUT", reportPath, admin.Token, gin.H{"status": "resolved"})
+
+	w = s.expect(200, "GET", "/admin/reports?status=resolved", admin.Token, nil)
+	if resolved := decode[paging.Page[db.Report]](t, w); len(resolved.Items) != 1 {
+		t.Fatalf("unexpected resolved reports %+v", resolved)
+	}
+}
+
+func TestOutbox(t *testing.T) {
+	s := newTestServer(t)
+	admin := s.withRole(s.signup("postmaster"), utils.RoleAdmin)
+
+	s.expect(400, "GET", "/admin/outbox?status=lost", admin.Token, nil)
+	w := s.expect(200, "GET", "/admin/outbox?status=pending", admin.Token, nil)
+	if strings.Contains(w.Body.String(), "/v1/verify/") {
+		t.Fatalf("outbox list exposes mail bodies: %s", w.Body)
+	}
+	pending := decode[paging.Page[db.ListOutboxByStatusRow]](t, w)
+	if len(pending.Items) != 1 {
+		t.Fatalf("expected the verification mail, got %+v", pending)
+	}
+	emailID := pending.Items[0].ID
+
+	// only dead mail can be requeued
+	s.expectError(404, utils.CodeEmailNotFound, "POST", "/admin/outbox/"+emailID.String()+"/requeue", admin.Token, nil)
+	s.store.KillEmail(emailID, "smtp: connection refused")
+
+	w = s.expect(200, "GET", "/admin/outbox", admin.Token, nil)
+	if dead := decode[paging.Page[db.ListOutboxByStatusRow]](t, w); len(dead.Items) != 1 || dead.Items[0].ID != emailID {
+		t.Fatalf("unexpected dead mail %+v", dead)
+	}
+
+	s.expect(400, "POST", "/admin/outbox/not-a-uuid/requeue", admin.Token, nil)
+	s.expect(200, "POST", "/admin/outbox/"+emailID.String()+"/requeue", admin.Token, nil)
+
+	mail, _ := s.mailTo(admin.Email)
+	if mail.ID != emailID || mail.Attempts != 0 || mail.LastError.Valid {
+		t.Fatalf("mail not requeued %+v", mail)
+	}
+}
@@ -1,27 +1,103 @@
 package handlers
 
 import (
-	"encoding/json"
+	"context"
+	"log/slog"
+	"time"
 
 	"github.com/ErebusAJ/YatraBandhu/internals/db"
+	"github.com/ErebusAJ/YatraBandhu/internals/itinerary"
+	"github.com/ErebusAJ/YatraBandhu/internals/metrics"
+	"github.com/ErebusAJ/YatraBandhu/internals/planner"
 	"github.com/ErebusAJ/YatraBandhu/internals/utils"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
+// planRequest
+// body of POST /auth/ai-planner
+type planRequest struct{
+	Location	string	`json:"location" binding:"required"`
+	UserQuery	string	`json:"interests" binding:"required" doc:"free text describing the trip and interests"`
+	Days		int		`json:"days" binding:"required,min=1,max=30"`
+	PlanID		*uuid.UUID	`json:"plan_id" doc:"a saved AI plan to regenerate, the itinerary becomes its next version"`
+}
+
+// plannerJobResponse
+// an AI planner job as sent to clients
+type plannerJobResponse struct{
+	ID			uuid.UUID	`json:"id"`
+	Status		string		`json:"status" enum:"queued running succeeded failed cancelled"`
+	Stage		string		`json:"stage" enum:"queued collecting_data drafting structuring saved" doc:"stage in progress, or the last one reached"`
+	Location	string		`json:"location"`
+	Interests	string		`json:"interests"`
+	Days		int32		`json:"days"`
+	StartDate	string		`json:"start_date" doc:"YYYY-MM-DD"`
+	PlanID		*uuid.UUID	`json:"plan_id" doc:"the plan regenerated, or the saved plan once the job succeeded"`
+	Attempts	int32		`json:"attempts" doc:"failed attempts so far, a retry resumes at the failed stage"`
+	Error		string		`json:"error,omitempty" doc:"set once the job failed"`
+	CreatedAt	time.Time	`json:"created_at"`
+	UpdatedAt	time.Time	`json:"updated_at"`
+	FinishedAt	*time.Time	`json:"finished_at"`
+}
+
+// planStageEvent
+// data of the stage events of a streamed generation
+type planStageEvent struct{
+	Stage	string	`json:"stage"`
+	Step	string	`json:"step,omitempty"`
+}
+
+// planTokenEvent
+// data of the token events, the next piece of the draft
+type planTokenEvent struct{
+	Text	string	`json:"text"`
+}
+
+// planEvent
+// data of the closing plan event, the saved itinerary
+// and the plan it was saved to
+type planEvent struct{
+	PlanID		uuid.UUID				`json:"plan_id"`
+	Itinerary	*itinerary.Itinerary	`json:"itinerary"`
+}
+
+func plannerJobJSON(job db.PlannerJob) plannerJobResponse{
+	res := plannerJobResponse{
+		ID: job.ID,
+		Status: job.Status,
+		Stage: job.Stage,
+		Location: job.Location,
+		Interests: job.Interests,
+		Days: job.Days,
+		StartDate: job.StartDate.Format(itinerary.DateFormat),
+		Attempts: job.Attempts,
+		CreatedAt: job.CreatedAt,
+		UpdatedAt: job.UpdatedAt,
+	}
+	if job.PlanID.Valid{
+		res.PlanID = &job.PlanID.UUID
+	}
+	if job.FinishedAt.Valid{
+		res.FinishedAt = &job.FinishedAt.Time
+	}
+	// provider errors stay in the logs
+	if job.Status == planner.StatusFailed{
+		res.Error = utils.ErrPlannerFailed.Message
+	}
+	return res
+}
+
+
 // generatePlan
-// take params generate plan and give response
+// queue a plan generation, the job is polled for progress
 func(cfg *apiConfig) generatePlan(c *gin.Context){
-	var reqDetails struct{
-		Location	string	`json:"location" binding:"required"`
-		UserQuery	string	`json:"interests" binding:"required"`
-		Days		int		`json:"days" binding:"required"`
-	}
+	var reqDetails planRequest
 
 	tempID, exists := c.Get("userID")
 	if !exists {
-		utils.ErrorJSON(c, 400, utils.MiddlewareError, utils.UnauthorizedError, nil)
-		return 
+		utils.ErrorJSON(c, 401, utils.MiddlewareError, utils.UnauthorizedError, nil)
+		return
 	}
 	userID := tempID.(uuid.UUID)
 
@@ -31,26 +107,191 @@ func(cfg *apiConfig) generatePlan(c *gin.Context){
 		return
 	}
 
-	jsonData, err := utils.GenerateTravelItinerary(reqDetails.Location, reqDetails.UserQuery, reqDetails.Days)
+	planID, ok := cfg.regeneratedPlan(c, userID, reqDetails)
+	if !ok{
+		return
+	}
+
+	if !cfg.usePlannerQuota(c, userID){
+		return
+	}
+
+	// the trip starts today, fixed now so retries plan the same dates
+	job, err := cfg.DB.CreatePlannerJob(c, db.CreatePlannerJobParams{
+		UserID: userID,
+		Location: reqDetails.Location,
+		Interests: reqDetails.UserQuery,
+		Days: int32(reqDetails.Days),
+		StartDate: time.Now(),
+		PlanID: planID,
+	})
 	if err != nil{
-		utils.ErrorJSON(c, 500, "error generating plan", utils.InternalError, err)
-		return 
+		cfg.refundPlannerQuota(c, userID)
+		utils.DBErrorJSON(c, err, nil)
+		return
 	}
+	cfg.PlannerJobs.Notify()
 
-	jsonBytes, err := json.Marshal(jsonData)
+	c.Header("Location", "/auth/ai-planner/jobs/" + job.ID.String())
+	c.IndentedJSON(202, plannerJobJSON(job))
+}
+
+
+// streamPlan
+// generate a plan within the request, stages and the draft are sent
+// as server-sent events while they are made and the saved itinerary
+// closes the stream, a failure after the stream started is sent as
+// an error event
+func(cfg *apiConfig) streamPlan(c *gin.Context){
+	var reqDetails planRequest
+
+	tempID, exists := c.Get("userID")
+	if !exists {
+		utils.ErrorJSON(c, 401, utils.MiddlewareError, utils.UnauthorizedError, nil)
+		return
+	}
+	userID := tempID.(uuid.UUID)
+
+	err := c.BindJSON(&reqDetails)
 	if err != nil{
-		utils.ErrorJSON(c, 500, "ai plan marshaling error", utils.InternalError, err)
-		return 
+		utils.ErrorJSON(c, 400, utils.RequestBodyError, utils.JSONError, err)
+		return
 	}
 
-	err = cfg.DB.SavePlan(c, db.SavePlanParams{
-		UserID: userID,
-		RawData: jsonBytes,
+	planID, ok := cfg.regeneratedPlan(c, userID, reqDetails)
+	if !ok{
+		return
+	}
+
+	if !cfg.usePlannerQuota(c, userID){
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(200)
+	send := func(event string, data any){
+		c.SSEvent(event, data)
+		c.Writer.Flush()
+	}
+
+	// stops with the client
+	ctx := c.Request.Context()
+	started := time.Now()
+	it, err := planner.Run(ctx, cfg.PlannerJobs.Pipeline, reqDetails.Location, reqDetails.UserQuery, reqDetails.Days, started, func(e planner.Event){
+		if e.Text != ""{
+			send("token", planTokenEvent{Text: e.Text})
+			return
+		}
+		send("stage", planStageEvent{Stage: e.Stage, Step: e.Step})
 	})
 	if err != nil{
-		utils.ErrorJSON(c, 500, utils.DatabaseError, utils.InternalError, err)
+		cfg.refundPlannerQuota(context.WithoutCancel(ctx), userID)
+		if ctx.Err() != nil{
+			slog.InfoContext(c, "planner: client left the stream", "user_id", userID)
+			return
+		}
+		metrics.ObserveItinerary(utils.ItineraryOutcome(err), time.Since(started))
+		// provider errors stay in the logs
+		utils.SendErrorEvent(c, utils.ErrPlannerFailed, "error streaming plan", err)
 		return
 	}
+	metrics.ObserveItinerary(utils.ItineraryOutcome(nil), time.Since(started))
+
+	savedID, err := planner.SavePlan(c, cfg.DB, userID, planID, it)
+	if err != nil{
+		cfg.refundPlannerQuota(context.WithoutCancel(ctx), userID)
+		utils.SendErrorEvent(c, utils.FromDBError(err, utils.ErrAIPlanNotFound), utils.DatabaseError, err)
+		return
+	}
+
+	send("stage", planStageEvent{Stage: planner.StageSaved})
+	send("plan", planEvent{PlanID: savedID, Itinerary: it})
+}
+
+
+// regeneratedPlan
+// the saved plan a generation replaces, it must be one of the
+// user's, none for a new plan
+func(cfg *apiConfig) regeneratedPlan(c *gin.Context, userID uuid.UUID, req planRequest) (uuid.NullUUID, bool){
+	if req.PlanID == nil{
+		return uuid.NullUUID{}, true
+	}
+
+	_, err := cfg.DB.GetAIPlan(c, db.GetAIPlanParams{ID: *req.PlanID, UserID: userID})
+	if err != nil{
+		utils.DBErrorJSON(c, err, utils.ErrAIPlanNotFound)
+		return uuid.NullUUID{}, false
+	}
+	return uuid.NullUUID{UUID: *req.PlanID, Valid: true}, true
+}
+
+
+// getPlannerJob
+// progress of one of the user's planner jobs
+func(cfg *apiConfig) getPlannerJob(c *gin.Context){
+	userID, jobID, ok := plannerJobParams(c)
+	if !ok{
+		return
+	}
+
+	job, err := cfg.DB.GetPlannerJob(c, db.GetPlannerJobParams{ID: jobID, UserID: userID})
+	if err != nil{
+		utils.DBErrorJSON(c, err, utils.ErrPlannerJobNotFound)
+		return
+	}
+
+	c.IndentedJSON(200, plannerJobJSON(job))
+}
+
+
+// cancelPlannerJob
+// stops a queued or running job and gives back its quota
+func(cfg *apiConfig) cancelPlannerJob(c *gin.Context){
+	userID, jobID, ok := plannerJobParams(c)
+	if !ok{
+		return
+	}
+
+	n, err := cfg.DB.CancelPlannerJob(c, db.CancelPlannerJobParams{ID: jobID, UserID: userID})
+	if err != nil{
+		utils.DBErrorJSON(c, err, nil)
+		return
+	}
+	if n > 0{
+		cfg.PlannerJobs.Cancel(jobID)
+		cfg.refundPlannerQuota(c, userID)
+	}
+
+	job, err := cfg.DB.GetPlannerJob(c, db.GetPlannerJobParams{ID: jobID, UserID: userID})
+	if err != nil{
+		utils.DBErrorJSON(c, err, utils.ErrPlannerJobNotFound)
+		return
+	}
+	if n == 0{
+		utils.SendError(c, utils.ErrPlannerJobFinished, "planner job already finished", nil)
+		return
+	}
+
+	c.IndentedJSON(200, plannerJobJSON(job))
+}
+
+
+// plannerJobParams
+// the logged in user and the job id of the path
+func plannerJobParams(c *gin.Context) (uuid.UUID, uuid.UUID, bool){
+	tempID, exists := c.Get("userID")
+	if !exists {
+		utils.ErrorJSON(c, 401, utils.MiddlewareError, utils.UnauthorizedError, nil)
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	jobID, err := uuid.Parse(c.Param("jobID"))
+	if err != nil{
+		utils.ErrorJSON(c, 400, utils.IDParseError, utils.EndpointError, err)
+		return uuid.Nil, uuid.Nil, false
+	}
 
-	c.IndentedJSON(200, jsonData)
-}
\ No newline at end of file
+	return tempID.(uuid.UUID), jobID, true
+}
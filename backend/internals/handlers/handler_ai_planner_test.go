@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ErebusAJ/YatraBandhu/internals/itinerary"
+	"github.com/ErebusAJ/YatraBandhu/internals/planner"
+	"github.com/ErebusAJ/YatraBandhu/internals/utils"
+	"github.com/gin-gonic/gin"
+)
+
+func TestGeneratePlan(t *testing.T) {
+	s := newTestServer(t)
+	u := s.signup("planner")
+
+	s.expect(400, "POST", "/auth/ai-planner", u.Token, gin.H{"location": "Goa"})
+	s.expectError(400, utils.CodeValidationFailed, "POST", "/auth/ai-planner", u.Token, gin.H{"location": "Goa", "interests": "beaches", "days": 90})
+
+	w := s.expect(202, "POST", "/auth/ai-planner", u.Token, gin.H{"location": "Goa", "interests": "beaches", "days": 3})
+	job := decode[plannerJobResponse](t, w)
+	if job.Status != planner.StatusQueued || job.Stage != planner.StageQueued || job.PlanID != nil || w.Header().Get("Location") != "/auth/ai-planner/jobs/"+job.ID.String() {
+		t.Fatalf("unexpected job %+v, location %q", job, w.Header().Get("Location"))
+	}
+
+	s.work()
+
+	job = decode[plannerJobResponse](t, s.expect(200, "GET", "/auth/ai-planner/jobs/"+job.ID.String(), u.Token, nil))
+	if job.Status != planner.StatusSucceeded || job.Stage != planner.StageSaved || job.PlanID == nil || job.FinishedAt == nil || job.Error != "" {
+		t.Fatalf("unexpected finished job %+v", job)
+	}
+
+	saved, err := s.store.RetreivePlan(context.Background(), u.ID)
+	if err != nil || saved.ID != *job.PlanID {
+		t.Fatalf("plan not saved: %+v, %v", saved, err)
+	}
+	var plan itinerary.Itinerary
+	if err := json.Unmarshal(saved.RawData, &plan); err != nil || plan.Destination != "Goa" || len(plan.DailyItinerary) != 3 ||
+		plan.DailyItinerary[0].Activities[0].Name != "beaches" || plan.TravelDuration.StartDate != job.StartDate {
+		t.Fatalf("unexpected saved plan %s", saved.RawData)
+	}
+
+	// jobs are private, finished ones can't be cancelled
+	other := s.signup("snoop")
+	s.expectError(404, utils.CodePlannerJobNotFound, "GET", "/auth/ai-planner/jobs/"+job.ID.String(), other.Token, nil)
+	s.expectError(404, utils.CodePlannerJobNotFound, "DELETE", "/auth/ai-planner/jobs/"+job.ID.String(), other.Token, nil)
+	s.expectError(400, utils.CodeMalformedURL, "GET", "/auth/ai-planner/jobs/not-a-uuid", u.Token, nil)
+	s.expectError(409, utils.CodePlannerJobFinished, "DELETE", "/auth/ai-planner/jobs/"+job.ID.String(), u.Token, nil)
+}
+
+func TestGeneratePlanFailure(t *testing.T) {
+	s := newTestServer(t, func(cfg *apiConfig) {
+		cfg.PlannerJobs.Pipeline = &testPipeline{fail: errors.New("provider down")}
+	})
+	u := s.signup("unlucky")
+
+	job := decode[plannerJobResponse](t, s.expect(202, "POST", "/auth/ai-planner", u.Token, gin.H{"location": "Goa", "interests": "beaches", "days": 3}))
+	s.work()
+
+	// every attempt is used before the job fails, without the provider's error
+	job = decode[plannerJobResponse](t, s.expect(200, "GET", "/auth/ai-planner/jobs/"+job.ID.String(), u.Token, nil))
+	if job.Status != planner.StatusFailed || job.Stage != planner.StageCollecting || int(job.Attempts) != s.cfg.Config.Planner.JobAttempts || job.Error != utils.ErrPlannerFailed.Message {
+		t.Fatalf("unexpected failed job %+v", job)
+	}
+	if _, err := s.store.RetreivePlan(context.Background(), u.ID); err == nil {
+		t.Fatalf("failed plan was saved")
+	}
+}
+
+func TestCancelPlannerJob(t *testing.T) {
+	s := newTestServer(t)
+	u := s.signup("fickle")
+
+	job := decode[plannerJobResponse](t, s.expect(202, "POST", "/auth/ai-planner", u.Token, gin.H{"location": "Goa", "interests": "beaches", "days": 3}))
+	job = decode[plannerJobResponse](t, s.expect(200, "DELETE", "/auth/ai-planner/jobs/"+job.ID.String(), u.Token, nil))
+	if job.Status != planner.StatusCancelled || job.FinishedAt == nil {
+		t.Fatalf("unexpected cancelled job %+v", job)
+	}
+
+	s.work()
+	if _, err := s.store.RetreivePlan(context.Background(), u.ID); err == nil {
+		t.Fatalf("cancelled job saved a plan")
+	}
+	s.expectError(409, utils.CodePlannerJobFinished, "DELETE", "/auth/ai-planner/jobs/"+job.ID.String(), u.Token, nil)
+}
+
+// sseEvent
+// an event of a server-sent event stream
+type sseEvent struct {
+	name string
+	data string
+}
+
+func readEvents(body string) []sseEvent {
+	var events []sseEvent
+	for _, block := range strings.Split(strings.TrimSpace(body), "\n\n") {
+		var e sseEvent
+		for _, line := range strings.Split(block, "\n") {
+			if name, ok := strings.CutPrefix(line, "event:"); ok {
+				e.name = name
+			} else if data, ok := strings.CutPrefix(line, "data:"); ok {
+				e.data = data
+			}
+		}
+		events = append(events, e)
+	}
+	return events
+}
+
+func TestStreamPlan(t *testing.T) {
+	s := newTestServer(t)
+	u := s.signup("eager")
+
+	s.expectError(400, utils.CodeValidationFailed, "POST", "/auth/ai-planner/stream", u.Token, gin.H{"location": "Goa", "interests": "beaches", "days": 0})
+
+	w := s.expect(200, "POST", "/auth/ai-planner/stream", u.Token, gin.H{"location": "Goa", "interests": "beaches", "days": 2})
+	if ct := w.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+	events := readEvents(w.Body.String())
+	var got []string
+	for _, e := range events[:len(events)-1] {
+		got = append(got, e.name+" "+e.data)
+	}
+	want := []string{
+		`stage {"stage":"collecting_data"}`,
+		`stage {"stage":"collecting_data","step":"geocoding"}`,
+		`stage {"stage":"drafting"}`,
+		`token {"text":"Goa\n"}`,
+		`token {"text":"beaches"}`,
+		`stage {"stage":"structuring"}`,
+		`stage {"stage":"saved"}`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected events:\n%s", strings.Join(got, "\n"))
+	}
+
+	// the closing event has the saved plan
+	var plan planEvent
+	last := events[len(events)-1]
+	if err := json.Unmarshal([]byte(last.data), &plan); err != nil || last.name != "plan" || plan.Itinerary.Destination != "Goa" || len(plan.Itinerary.DailyItinerary) != 2 {
+		t.Fatalf("unexpected closing event %+v, %v", last, err)
+	}
+	saved, err := s.store.RetreivePlan(context.Background(), u.ID)
+	if err != nil || saved.ID != plan.PlanID {
+		t.Fatalf("plan not saved: %+v, %v", saved, err)
+	}
+	if usage := decode[usageResponse](t, s.expect(200, "GET", "/auth/usage", u.Token, nil)); usage.AIPlanner[0].Used != 1 {
+		t.Fatalf("generation not counted: %+v", usage)
+	}
+}
+
+func TestStreamPlanFailure(t *testing.T) {
+	s := newTestServer(t, func(cfg *apiConfig) {
+		cfg.PlannerJobs.Pipeline = &testPipeline{fail: errors.New("provider down")}
+	})
+	u := s.signup("stranded")
+
+	events := readEvents(s.expect(200, "POST", "/auth/ai-planner/stream", u.Token, gin.H{"location": "Goa", "interests": "beaches", "days": 2}).Body.String())
+	var apiErr utils.APIError
+	last := events[len(events)-1]
+	if err := json.Unmarshal([]byte(last.data), &apiErr); err != nil || len(events) != 3 || last.name != "error" ||
+		apiErr.Code != utils.CodePlannerFailed || strings.Contains(last.data, "provider down") || apiErr.RequestID == "" {
+		t.Fatalf("unexpected events %+v", events)
+	}
+
+	// failed generations are not counted or saved
+	if usage := decode[usageResponse](t, s.expect(200, "GET", "/auth/usage", u.Token, nil)); usage.AIPlanner[0].Used != 0 {
+		t.Fatalf("failed generation counted: %+v", usage)
+	}
+	if _, err := s.store.RetreivePlan(context.Background(), u.ID); err == nil {
+		t.Fatal("failed plan was saved")
+	}
+}
+
+func TestItinerarySchema(t *testing.T) {
+	s := newTestServer(t)
+
+	w := s.expect(200, "GET", "/schemas/itinerary.json", "", nil)
+	schema := decode[map[string]any](t, w)
+	if w.Header().Get("Content-Type") != "application/schema+json" || schema["title"] != "Itinerary" {
+		t.Fatalf("unexpected schema %v", schema)
+	}
+}
@@ -0,0 +1,479 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/ErebusAJ/YatraBandhu/internals/db"
+	"github.com/ErebusAJ/YatraBandhu/internals/itinerary"
+	"github.com/ErebusAJ/YatraBandhu/internals/jsondiff"
+	"github.com/ErebusAJ/YatraBandhu/internals/paging"
+	"github.com/ErebusAJ/YatraBandhu/internals/planner"
+	"github.com/ErebusAJ/YatraBandhu/internals/store"
+	"github.com/ErebusAJ/YatraBandhu/internals/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// aiPlanSummary
+// a saved AI plan as listed to clients
+type aiPlanSummary struct{
+	ID			uuid.UUID	`json:"id"`
+	Title		string		`json:"title"`
+	Version		int32		`json:"version" doc:"the current version"`
+	CreatedAt	time.Time	`json:"created_at"`
+	UpdatedAt	time.Time	`json:"updated_at"`
+}
+
+// aiPlanResponse
+// a saved AI plan with its current itinerary
+type aiPlanResponse struct{
+	aiPlanSummary
+	Itinerary	json.RawMessage	`json:"itinerary" doc:"see /schemas/itinerary.json"`
+}
+
+// aiPlanVersionSummary
+// a version of a saved AI plan as listed to clients
+type aiPlanVersionSummary struct{
+	Version		int32		`json:"version"`
+	Source		string		`json:"source" enum:"generated regenerated edited restored" doc:"what made the version"`
+	CreatedAt	time.Time	`json:"created_at"`
+}
+
+// aiPlanVersionResponse
+// a version of a saved AI plan with its itinerary
+type aiPlanVersionResponse struct{
+	aiPlanVersionSummary
+	Itinerary	json.RawMessage	`json:"itinerary" doc:"see /schemas/itinerary.json"`
+}
+
+// aiPlanDiffResponse
+// the changes between two versions of a saved AI plan
+type aiPlanDiffResponse struct{
+	From		int32				`json:"from"`
+	To			int32				`json:"to"`
+	Changes		[]jsondiff.Change	`json:"changes" doc:"a JSON Patch from the from version to the to version"`
+}
+
+// updateAIPlanRequest
+// body of PATCH /auth/ai-plans/:planID
+type updateAIPlanRequest struct{
+	Title		*string				`json:"title" binding:"omitempty,min=1,max=200"`
+	Itinerary	json.RawMessage		`json:"itinerary" doc:"the edited itinerary, see /schemas/itinerary.json, saved as a new version"`
+}
+
+// aiPlanList
+// sorts and filters of GET /auth/ai-plans
+var aiPlanList = paging.Spec{
+	Sorts: []paging.Field{
+		{Name: "created_at", Kind: paging.Time},
+		{Name: "updated_at", Kind: paging.Time},
+		{Name: "title"},
+	},
+	Default: "-created_at",
+	Filters: []paging.Field{
+		{Name: "title", Description: "substring, case insensitive"},
+	},
+}
+
+// aiPlanVersionList
+// sorts of GET /auth/ai-plans/:planID/versions
+var aiPlanVersionList = paging.Spec{
+	Sorts: []paging.Field{
+		{Name: "version", Kind: paging.Integer},
+	},
+	Default: "-version",
+}
+
+func aiPlanJSON(p db.AiPlan) aiPlanResponse{
+	return aiPlanResponse{
+		aiPlanSummary: aiPlanSummary{
+			ID: p.ID,
+			Title: p.Title,
+			Version: p.Version,
+			CreatedAt: p.CreatedAt,
+			UpdatedAt: p.UpdatedAt,
+		},
+		Itinerary: p.RawData,
+	}
+}
+
+
+// listAIPlans
+// the user's saved AI plans, a page at a time
+func(cfg *apiConfig) listAIPlans(c *gin.Context){
+	tempID, exists := c.Get("userID")
+	if !exists {
+		utils.ErrorJSON(c, 401, utils.MiddlewareError, utils.UnauthorizedError, nil)
+		return
+	}
+	userID := tempID.(uuid.UUID)
+
+	listPage(c, aiPlanList, func(r paging.Request) ([]aiPlanSummary, error){
+		after := r.AfterKey()
+		rows, err := cfg.DB.ListAIPlans(c, db.ListAIPlansParams{
+			UserID: userID,
+			Title: r.Filters["title"],
+			HasCursor: r.After != nil,
+			Sort: r.SortParam(),
+			AfterText: after.Text,
+			AfterID: after.ID,
+			AfterTime: after.Time,
+			PageLimit: r.Fetch(),
+		})
+		plans := make([]aiPlanSummary, len(rows))
+		for i, p := range rows{
+			plans[i] = aiPlanSummary{
+				ID: p.ID,
+				Title: p.Title,
+				Version: p.Version,
+				CreatedAt: p.CreatedAt,
+				UpdatedAt: p.UpdatedAt,
+			}
+		}
+		return plans, err
+	}, func(p aiPlanSummary, sort string) paging.Key {
+		switch sort{
+		case "title":
+			return paging.Key{Text: p.Title, ID: p.ID}
+		case "updated_at":
+			return paging.Key{Time: p.UpdatedAt, ID: p.ID}
+		}
+		return paging.Key{Time: p.CreatedAt, ID: p.ID}
+	})
+}
+
+
+// getAIPlan
+// one of the user's saved AI plans with its current itinerary
+func(cfg *apiConfig) getAIPlan(c *gin.Context){
+	plan, ok := cfg.aiPlanParam(c)
+	if !ok{
+		return
+	}
+
+	c.IndentedJSON(200, aiPlanJSON(plan))
+}
+
+
+// updateAIPlan
+// renames a saved AI plan and/or saves an edited itinerary as its
+// next version, an itinerary equal to the current one adds none
+func(cfg *apiConfig) updateAIPlan(c *gin.Context){
+	var reqDetails updateAIPlanRequest
+
+	userID, planID, ok := aiPlanParams(c)
+	if !ok{
+		return
+	}
+
+	err := c.BindJSON(&reqDetails)
+	if err != nil{
+		utils.ErrorJSON(c, 400, utils.RequestBodyError, utils.JSONError, err)
+		return
+	}
+	if reqDetails.Title == nil && reqDetails.Itinerary == nil{
+		apiErr := utils.NewAPIError(400, utils.JSONError)
+		apiErr.Details = []utils.FieldError{{Field: "itinerary", Message: "title or itinerary is required"}}
+		utils.SendError(c, apiErr, utils.RequestBodyError, nil)
+		return
+	}
+
+	var data json.RawMessage
+	if reqDetails.Itinerary != nil{
+		it, err := itinerary.ParseEdit(reqDetails.Itinerary)
+		if err != nil{
+			utils.SendError(c, itineraryError(err), utils.RequestBodyError, err)
+			return
+		}
+		data, err = json.Marshal(it)
+		if err != nil{
+			utils.ErrorJSON(c, 500, "error encoding plan", utils.InternalError, err)
+			return
+		}
+	}
+
+	var plan db.AiPlan
+	err = cfg.DB.InTx(c, func(q store.Store) error{
+		if reqDetails.Title != nil{
+			n, err := q.RenameAIPlan(c, db.RenameAIPlanParams{
+				Title: *reqDetails.Title,
+				ID: planID,
+				UserID: userID,
+			})
+			if err != nil{
+				return err
+			}
+			if n == 0{
+				return utils.ErrAIPlanNotFound
+			}
+		}
+
+		if data != nil{
+			err := addAIPlanVersion(c, q, userID, planID, data, planner.SourceEdited)
+			if err != nil{
+				return err
+			}
+		}
+
+		plan, err = q.GetAIPlan(c, db.GetAIPlanParams{ID: planID, UserID: userID})
+		return err
+	})
+	if err != nil{
+		utils.DBErrorJSON(c, err, utils.ErrAIPlanNotFound)
+		return
+	}
+
+	c.IndentedJSON(200, aiPlanJSON(plan))
+}
+
+
+// deleteAIPlan
+// deletes a saved AI plan with all its versions
+func(cfg *apiConfig) deleteAIPlan(c *gin.Context){
+	userID, planID, ok := aiPlanParams(c)
+	if !ok{
+		return
+	}
+
+	n, err := cfg.DB.DeleteAIPlan(c, db.DeleteAIPlanParams{ID: planID, UserID: userID})
+	if err != nil{
+		utils.DBErrorJSON(c, err, nil)
+		return
+	}
+	if n == 0{
+		utils.SendError(c, utils.ErrAIPlanNotFound, utils.DatabaseError, nil)
+		return
+	}
+
+	c.Status(204)
+}
+
+
+// listAIPlanVersions
+// the versions of a saved AI plan, newest first by default
+func(cfg *apiConfig) listAIPlanVersions(c *gin.Context){
+	plan, ok := cfg.aiPlanParam(c)
+	if !ok{
+		return
+	}
+
+	listPage(c, aiPlanVersionList, func(r paging.Request) ([]aiPlanVersionSummary, error){
+		after, _ := strconv.Atoi(r.AfterKey().Num)
+		rows, err := cfg.DB.ListAIPlanVersions(c, db.ListAIPlanVersionsParams{
+			PlanID: plan.ID,
+			HasCursor: r.After != nil,
+			Sort: r.SortParam(),
+			AfterNum: int32(after),
+			PageLimit: r.Fetch(),
+		})
+		versions := make([]aiPlanVersionSummary, len(rows))
+		for i, v := range rows{
+			versions[i] = aiPlanVersionSummary{
+				Version: v.Version,
+				Source: v.Source,
+				CreatedAt: v.CreatedAt,
+			}
+		}
+		return versions, err
+	}, func(v aiPlanVersionSummary, sort string) paging.Key {
+		return paging.Key{Num: strconv.Itoa(int(v.Version))}
+	})
+}
+
+
+// getAIPlanVersion
+// one version of a saved AI plan with its itinerary
+func(cfg *apiConfig) getAIPlanVersion(c *gin.Context){
+	plan, ok := cfg.aiPlanParam(c)
+	if !ok{
+		return
+	}
+
+	v, ok := cfg.aiPlanVersion(c, plan.ID, c.Param("version"))
+	if !ok{
+		return
+	}
+
+	c.IndentedJSON(200, aiPlanVersionResponse{
+		aiPlanVersionSummary: aiPlanVersionSummary{
+			Version: v.Version,
+			Source: v.Source,
+			CreatedAt: v.CreatedAt,
+		},
+		Itinerary: v.RawData,
+	})
+}
+
+
+// diffAIPlan
+// the changes between two versions of a saved AI plan, by default
+// from the previous version to the current one
+func(cfg *apiConfig) diffAIPlan(c *gin.Context){
+	plan, ok := cfg.aiPlanParam(c)
+	if !ok{
+		return
+	}
+
+	to := strconv.Itoa(int(plan.Version))
+	if c.Query("to") != ""{
+		to = c.Query("to")
+	}
+	toVersion, ok := cfg.aiPlanVersion(c, plan.ID, to)
+	if !ok{
+		return
+	}
+
+	from := strconv.Itoa(max(int(toVersion.Version) - 1, 1))
+	if c.Query("from") != ""{
+		from = c.Query("from")
+	}
+	fromVersion, ok := cfg.aiPlanVersion(c, plan.ID, from)
+	if !ok{
+		return
+	}
+
+	changes, err := jsondiff.Diff(fromVersion.RawData, toVersion.RawData)
+	if err != nil{
+		utils.ErrorJSON(c, 500, "error comparing plan versions", utils.InternalError, err)
+		return
+	}
+
+	c.IndentedJSON(200, aiPlanDiffResponse{
+		From: fromVersion.Version,
+		To: toVersion.Version,
+		Changes: changes,
+	})
+}
+
+
+// restoreAIPlanVersion
+// rolls a saved AI plan back to an earlier itinerary, saved as
+// its next version so the history is kept
+func(cfg *apiConfig) restoreAIPlanVersion(c *gin.Context){
+	plan, ok := cfg.aiPlanParam(c)
+	if !ok{
+		return
+	}
+
+	v, ok := cfg.aiPlanVersion(c, plan.ID, c.Param("version"))
+	if !ok{
+		return
+	}
+
+	err := cfg.DB.InTx(c, func(q store.Store) error{
+		err := addAIPlanVersion(c, q, plan.UserID, plan.ID, v.RawData, planner.SourceRestored)
+		if err != nil{
+			return err
+		}
+
+		plan, err = q.GetAIPlan(c, db.GetAIPlanParams{ID: plan.ID, UserID: plan.UserID})
+		return err
+	})
+	if err != nil{
+		utils.DBErrorJSON(c, err, utils.ErrAIPlanNotFound)
+		return
+	}
+
+	c.IndentedJSON(200, aiPlanJSON(plan))
+}
+
+
+// addAIPlanVersion
+// saves data as the next version of a plan unless it is
+// the current itinerary already
+func addAIPlanVersion(c *gin.Context, q store.Store, userID, planID uuid.UUID, data json.RawMessage, source string) error{
+	plan, err := q.GetAIPlan(c, db.GetAIPlanParams{ID: planID, UserID: userID})
+	if err != nil{
+		return err
+	}
+
+	changes, err := jsondiff.Diff(plan.RawData, data)
+	if err != nil{
+		return err
+	}
+	if len(changes) == 0{
+		return nil
+	}
+
+	_, err = q.AddAIPlanVersion(c, db.AddAIPlanVersionParams{
+		RawData: data,
+		ID: planID,
+		UserID: userID,
+		Source: source,
+	})
+	return err
+}
+
+
+// itineraryError
+// the client error for an itinerary that doesn't conform,
+// each problem is a detail of the itinerary field
+func itineraryError(err error) *utils.APIError{
+	apiErr := utils.NewAPIError(400, utils.JSONError)
+
+	var verr *itinerary.ValidationError
+	if errors.As(err, &verr){
+		for _, problem := range verr.Problems{
+			apiErr.Details = append(apiErr.Details, utils.FieldError{Field: "itinerary", Message: problem})
+		}
+	}
+	return apiErr
+}
+
+
+// aiPlanParam
+// the saved AI plan of the path, owned by the logged in user
+func(cfg *apiConfig) aiPlanParam(c *gin.Context) (db.AiPlan, bool){
+	userID, planID, ok := aiPlanParams(c)
+	if !ok{
+		return db.AiPlan{}, false
+	}
+
+	plan, err := cfg.DB.GetAIPlan(c, db.GetAIPlanParams{ID: planID, UserID: userID})
+	if err != nil{
+		utils.DBErrorJSON(c, err, utils.ErrAIPlanNotFound)
+		return db.AiPlan{}, false
+	}
+	return plan, true
+}
+
+
+// aiPlanVersion
+// version of the plan planID, a number that isn't
+// one is a malformed url
+func(cfg *apiConfig) aiPlanVersion(c *gin.Context, planID uuid.UUID, version string) (db.AiPlanVersion, bool){
+	n, err := strconv.ParseInt(version, 10, 32)
+	if err != nil{
+		utils.ErrorJSON(c, 400, utils.ParsingError, utils.EndpointError, err)
+		return db.AiPlanVersion{}, false
+	}
+
+	v, err := cfg.DB.GetAIPlanVersion(c, db.GetAIPlanVersionParams{PlanID: planID, Version: int32(n)})
+	if err != nil{
+		utils.DBErrorJSON(c, err, utils.ErrAIPlanVersionNotFound)
+		return db.AiPlanVersion{}, false
+	}
+	return v, true
+}
+
+
+// aiPlanParams
+// the logged in user and the AI plan id of the path
+func aiPlanParams(c *gin.Context) (uuid.UUID, uuid.UUID, bool){
+	tempID, exists := c.Get("userID")
+	if !exists {
+		utils.ErrorJSON(c, 401, utils.MiddlewareError, utils.UnauthorizedError, nil)
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	planID, err := uuid.Parse(c.Param("planID"))
+	if err != nil{
+		utils.ErrorJSON(c, 400, utils.IDParseError, utils.EndpointError, err)
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return tempID.(uuid.UUID), planID, true
+}
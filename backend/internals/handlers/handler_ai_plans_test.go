@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ErebusAJ/YatraBandhu/internals/itinerary"
+	"github.com/ErebusAJ/YatraBandhu/internals/jsondiff"
+	"github.com/ErebusAJ/YatraBandhu/internals/paging"
+	"github.com/ErebusAJ/YatraBandhu/internals/planner"
+	"github.com/ErebusAJ/YatraBandhu/internals/utils"
+	"github.com/gin-gonic/gin"
+)
+
+func TestAIPlans(t *testing.T) {
+	s := newTestServer(t)
+	u := s.signup("traveller")
+	other := s.signup("snoop")
+
+	events := readEvents(s.expect(200, "POST", "/auth/ai-planner/stream", u.Token, gin.H{"location": "Goa", "interests": "beaches", "days": 2}).Body.String())
+	var generated planEvent
+	if err := json.Unmarshal([]byte(events[len(events)-1].data), &generated); err != nil {
+		t.Fatal(err)
+	}
+	path := "/auth/ai-plans/" + generated.PlanID.String()
+
+	// a regeneration is the plan's next version, only of the user's own plans
+	s.expectError(404, utils.CodeAIPlanNotFound, "POST", "/auth/ai-planner", other.Token, gin.H{"location": "Goa", "interests": "forts", "days": 2, "plan_id": generated.PlanID})
+	job := decode[plannerJobResponse](t, s.expect(202, "POST", "/auth/ai-planner", u.Token, gin.H{"location": "Goa", "interests": "forts", "days": 2, "plan_id": generated.PlanID}))
+	if job.PlanID == nil || *job.PlanID != generated.PlanID {
+		t.Fatalf("unexpected job %+v", job)
+	}
+	s.work()
+
+	list := decode[paging.Page[aiPlanSummary]](t, s.expect(200, "GET", "/auth/ai-plans", u.Token, nil))
+	if len(list.Items) != 1 || list.Items[0].ID != generated.PlanID || list.Items[0].Title != "Goa" || list.Items[0].Version != 2 {
+		t.Fatalf("unexpected plans %+v", list)
+	}
+	if list := decode[paging.Page[aiPlanSummary]](t, s.expect(200, "GET", "/auth/ai-plans?title=paris", u.Token, nil)); len(list.Items) != 0 {
+		t.Fatalf("unexpected filtered plans %+v", list)
+	}
+
+	plan := decode[aiPlanResponse](t, s.expect(200, "GET", path, u.Token, nil))
+	var it itinerary.Itinerary
+	if err := json.Unmarshal(plan.Itinerary, &it); err != nil || it.DailyItinerary[0].Activities[0].Name != "forts" {
+		t.Fatalf("unexpected plan %+v, %v", plan, err)
+	}
+	s.expectError(404, utils.CodeAIPlanNotFound, "GET", path, other.Token, nil)
+	s.expectError(400, utils.CodeMalformedURL, "GET", "/auth/ai-plans/not-a-uuid", u.Token, nil)
+
+	// renaming keeps the version, an edit adds one unless nothing changed
+	s.expectError(400, utils.CodeValidationFailed, "PATCH", path, u.Token, gin.H{})
+	plan = decode[aiPlanResponse](t, s.expect(200, "PATCH", path, u.Token, gin.H{"title": "Monsoon in Goa"}))
+	if plan.Title != "Monsoon in Goa" || plan.Version != 2 {
+		t.Fatalf("unexpected renamed plan %+v", plan)
+	}
+	it.DailyItinerary[0].Activities[0].Name = "Fort Aguada"
+	plan = decode[aiPlanResponse](t, s.expect(200, "PATCH", path, u.Token, gin.H{"itinerary": it}))
+	if plan.Version != 3 {
+		t.Fatalf("edit not versioned %+v", plan)
+	}
+	if plan = decode[aiPlanResponse](t, s.expect(200, "PATCH", path, u.Token, gin.H{"itinerary": it})); plan.Version != 3 {
+		t.Fatalf("unchanged edit versioned %+v", plan)
+	}
+	it.TravelDuration.TotalDays = 3
+	apiErr := s.expectError(400, utils.CodeValidationFailed, "PATCH", path, u.Token, gin.H{"itinerary": it})
+	if len(apiErr.Details) == 0 || apiErr.Details[0].Field != "itinerary" {
+		t.Fatalf("unexpected error %+v", apiErr)
+	}
+	s.expectError(404, utils.CodeAIPlanNotFound, "PATCH", path, other.Token, gin.H{"title": "mine"})
+
+	// versions, newest first a page at a time
+	versions := decode[paging.Page[aiPlanVersionSummary]](t, s.expect(200, "GET", path+"/versions?limit=2", u.Token, nil))
+	if len(versions.Items) != 2 || versions.Items[0].Version != 3 || versions.Items[0].Source != planner.SourceEdited || versions.Next == "" {
+		t.Fatalf("unexpected versions %+v", versions)
+	}
+	versions = decode[paging.Page[aiPlanVersionSummary]](t, s.expect(200, "GET", path+"/versions?limit=2&cursor="+versions.NextCursor, u.Token, nil))
+	if len(versions.Items) != 1 || versions.Items[0].Version != 1 || versions.Items[0].Source != planner.SourceGenerated {
+		t.Fatalf("unexpected last page %+v", versions)
+	}
+	s.expectError(404, utils.CodeAIPlanNotFound, "GET", path+"/versions", other.Token, nil)
+
+	v := decode[aiPlanVersionResponse](t, s.expect(200, "GET", path+"/versions/2", u.Token, nil))
+	if v.Version != 2 || v.Source != planner.SourceRegenerated || len(v.Itinerary) == 0 {
+		t.Fatalf("unexpected version %+v", v)
+	}
+	s.expectError(404, utils.CodeAIPlanVersionNotFound, "GET", path+"/versions/9", u.Token, nil)
+	s.expectError(400, utils.CodeMalformedURL, "GET", path+"/versions/latest", u.Token, nil)
+
+	// the diff defaults to the last change
+	diff := decode[aiPlanDiffResponse](t, s.expect(200, "GET", path+"/diff", u.Token, nil))
+	want := jsondiff.Change{Op: jsondiff.OpReplace, Path: "/daily_itinerary/0/activities/0/name", Old: json.RawMessage(`"forts"`), Value: json.RawMessage(`"Fort Aguada"`)}
+	if diff.From != 2 || diff.To != 3 || len(diff.Changes) != 1 || diff.Changes[0].Path != want.Path || string(diff.Changes[0].Old) != string(want.Old) || string(diff.Changes[0].Value) != string(want.Value) {
+		t.Fatalf("unexpected diff %+v", diff)
+	}
+	if diff = decode[aiPlanDiffResponse](t, s.expect(200, "GET", path+"/diff?from=3&to=1", u.Token, nil)); diff.From != 3 || diff.To != 1 || len(diff.Changes) != 2 {
+		t.Fatalf("unexpected diff %+v", diff)
+	}
+	s.expectError(404, utils.CodeAIPlanVersionNotFound, "GET", path+"/diff?from=7", u.Token, nil)
+
+	// a rollback is saved as the next version
+	plan = decode[aiPlanResponse](t, s.expect(200, "POST", path+"/versions/1/restore", u.Token, nil))
+	v = decode[aiPlanVersionResponse](t, s.expect(200, "GET", path+"/versions/4", u.Token, nil))
+	first, _ := json.Marshal(generated.Itinerary)
+	if changes, err := jsondiff.Diff(plan.Itinerary, first); plan.Version != 4 || v.Source != planner.SourceRestored || err != nil || len(changes) != 0 {
+		t.Fatalf("unexpected restored plan %+v, %v", plan, changes)
+	}
+	s.expectError(404, utils.CodeAIPlanNotFound, "POST", path+"/versions/1/restore", other.Token, nil)
+
+	s.expectError(404, utils.CodeAIPlanNotFound, "DELETE", path, other.Token, nil)
+	s.expect(204, "DELETE", path, u.Token, nil)
+	s.expectError(404, utils.CodeAIPlanNotFound, "DELETE", path, u.Token, nil)
+	if list := decode[paging.Page[aiPlanSummary]](t, s.expect(200, "GET", "/auth/ai-plans", u.Token, nil)); len(list.Items) != 0 {
+		t.Fatalf("deleted plan listed %+v", list)
+	}
+}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+
+	"github.com/ErebusAJ/YatraBandhu/internals/db"
+	"github.com/ErebusAJ/YatraBandhu/internals/logging"
+	"github.com/ErebusAJ/YatraBandhu/internals/paging"
+	"github.com/ErebusAJ/YatraBandhu/internals/store"
+	"github.com/ErebusAJ/YatraBandhu/internals/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Audited actions
+const(
+	auditLogin			=	"user.login"
+	auditLoginFailed	=	"user.login_failed"
+	auditPasswordChange	=	"user.password_change"
+	auditPasswordReset	=	"user.password_reset"
+	auditGroupDelete	=	"group.delete"
+	auditMemberRemove	=	"group.member_remove"
+	auditGuideBook		=	"guide.book"
+)
+
+// auditEvent
+// one security relevant action, the actor performed it and the
+// subject is the account it concerns, zero ids are stored as null
+type auditEvent struct{
+	Action		string
+	ActorID		uuid.UUID
+	SubjectID	uuid.UUID
+	TargetType	string
+	TargetID	uuid.UUID
+	Metadata	gin.H
+}
+
+
+// recordAudit
+// appends e with the client ip and request id of c
+// pass the transaction's store so the event commits with the change
+func recordAudit(c *gin.Context, q store.Store, e auditEvent) error{
+	if e.Metadata == nil{
+		e.Metadata = gin.H{}
+	}
+	metadata, err := json.Marshal(e.Metadata)
+	if err != nil{
+		return err
+	}
+
+	return q.InsertAuditEvent(c, db.InsertAuditEventParams{
+		Action: e.Action,
+		ActorID: nullUUID(e.ActorID),
+		SubjectID: nullUUID(e.SubjectID),
+		TargetType: e.TargetType,
+		TargetID: nullUUID(e.TargetID),
+		Metadata: metadata,
+		ClientIp: c.ClientIP(),
+		RequestID: c.GetString(logging.RequestIDKey),
+	})
+}
+
+
+// recordAuditAfter
+// records an event for a change already committed,
+// failures are only logged
+func(cfg *apiConfig) recordAuditAfter(c *gin.Context, e auditEvent){
+	err := recordAudit(c, cfg.DB, e)
+	if err != nil{
+		slog.ErrorContext(c, "unable to record audit event", "action", e.Action, "error", err)
+	}
+}
+
+
+func nullUUID(id uuid.UUID) uuid.NullUUID{
+	return uuid.NullUUID{UUID: id, Valid: id != uuid.Nil}
+}
+
+
+// auditList
+// sorts and filters of GET /auth/audit
+var auditList = paging.Spec{
+	Sorts: []paging.Field{
+		{Name: "created_at", Kind: paging.Time},
+	},
+	Default: "-created_at",
+	Filters: []paging.Field{
+		{Name: "action", Description: "only events with this action"},
+	},
+}
+
+
+// adminAuditList
+// sorts and filters of GET /admin/audit
+var adminAuditList = paging.Spec{
+	Sorts: auditList.Sorts,
+	Default: auditList.Default,
+	Filters: append([]paging.Field{
+		{Name: "user_id", Kind: paging.UUID, Description: "only events performed by or concerning this user"},
+	}, auditList.Filters...),
+}
+
+
+// getUserAudit
+// lists the audit events performed by or concerning the logged in user
+// optionally filtered by "action"
+func(cfg *apiConfig) getUserAudit(c *gin.Context){
+	tempID, exists := c.Get("userID")
+	if !exists{
+		utils.ErrorJSON(c, 401, utils.MiddlewareError, utils.UnauthorizedError, nil)
+		return
+	}
+	userID := tempID.(uuid.UUID)
+
+	cfg.listAudit(c, auditList, userID)
+}
+
+
+// getAudit
+// lists every audit event, optionally filtered by "user_id"
+// (actor or subject) and "action"
+func(cfg *apiConfig) getAudit(c *gin.Context){
+	cfg.listAudit(c, adminAuditList, uuid.Nil)
+}
+
+
+// listAudit
+// responds with a page of events of userID, newest first by default,
+// uuid.Nil lists every user unless the "user_id" filter picks one
+func(cfg *apiConfig) listAudit(c *gin.Context, spec paging.Spec, userID uuid.UUID){
+	listPage(c, spec, func(r paging.Request) ([]db.AuditEvent, error){
+		if id, err := uuid.Parse(r.Filters["user_id"]); err == nil{
+			userID = id
+		}
+
+		after := r.AfterKey()
+		return cfg.DB.ListAuditEvents(c, db.ListAuditEventsParams{
+			UserID: nullUUID(userID),
+			Action: r.Filters["action"],
+			HasCursor: r.After != nil,
+			Sort: r.SortParam(),
+			AfterTime: after.Time,
+			AfterID: after.ID,
+			PageLimit: r.Fetch(),
+		})
+	}, func(e db.AuditEvent, sort string) paging.Key {
+		return paging.Key{Time: e.CreatedAt, ID: e.ID}
+	})
+}
@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ErebusAJ/YatraBandhu/internals/db"
+	"github.com/ErebusAJ/YatraBandhu/internals/paging"
+	"github.com/ErebusAJ/YatraBandhu/internals/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type auditPage = paging.Page[db.AuditEvent]
+
+// actions lists the actions of events, newest first
+func actions(events []db.AuditEvent) []string {
+	var out []string
+	for _, e := range events {
+		out = append(out, e.Action)
+	}
+	return out
+}
+
+func TestAuditTrail(t *testing.T) {
+	s := newTestServer(t)
+	owner := s.signup("owner")
+	member := s.signup("member")
+	groupID := s.group(owner, "Spiti")
+	guideID := s.guide("Kabir", "Kaza")
+
+	s.expect(401, "POST", "/v1/login", "", gin.H{"email": owner.Email, "password": "wrong"})
+	s.expect(200, "POST", "/auth/travel-group/"+groupID.String()+"/member/"+member.ID.String(), owner.Token, nil)
+	s.expect(204, "DELETE", "/auth/travel-group/"+groupID.String()+"/member/"+member.ID.String(), owner.Token, nil)
+	s.expect(200, "POST", "/auth/guide/book/"+groupID.String()+"/"+guideID.String(), owner.Token, nil)
+	s.expect(204, "DELETE", "/auth/travel-group/"+groupID.String(), owner.Token, nil)
+	s.expect(204, "PUT", "/auth/user", owner.Token, gin.H{"old_password": owner.Password, "new_password": "newpass123"})
+	owner.Password = "newpass123"
+	s.login(owner)
+
+	w := s.expect(200, "GET", "/auth/audit", owner.Token, nil)
+	got := actions(decode[auditPage](t, w).Items)
+	want := []string{
+		auditLogin, auditPasswordChange, auditGroupDelete, auditGuideBook,
+		auditMemberRemove, auditLoginFailed, auditLogin,
+	}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("got events %v, want %v", got, want)
+	}
+
+	// the removed member sees the removal, not the owner's events
+	w = s.expect(200, "GET", "/auth/audit", member.Token, nil)
+	if got := actions(decode[auditPage](t, w).Items); strings.Join(got, ",") != auditMemberRemove+","+auditLogin {
+		t.Fatalf("unexpected member events %v", got)
+	}
+
+	w = s.expect(200, "GET", "/auth/audit?action="+auditGuideBook, owner.Token, nil)
+	events := decode[auditPage](t, w).Items
+	if len(events) != 1 || events[0].TargetID.UUID != guideID || events[0].TargetType != "guide" {
+		t.Fatalf("unexpected booking events %+v", events)
+	}
+	var meta map[string]string
+	if err := json.Unmarshal(events[0].Metadata, &meta); err != nil || meta["group_id"] != groupID.String() {
+		t.Fatalf("unexpected booking metadata %s", events[0].Metadata)
+	}
+
+	// the cursor picks up after the newest event
+	w = s.expect(200, "GET", "/auth/audit?limit=1", owner.Token, nil)
+	first := decode[auditPage](t, w)
+	if len(first.Items) != 1 || first.NextCursor == "" {
+		t.Fatalf("unexpected first page %+v", first)
+	}
+	w = s.expect(200, "GET", "/auth/audit?limit=2&cursor="+first.NextCursor, owner.Token, nil)
+	if got := actions(decode[auditPage](t, w).Items); strings.Join(got, ",") != auditPasswordChange+","+auditGroupDelete {
+		t.Fatalf("unexpected page %v", got)
+	}
+	s.expectError(400, utils.CodeMalformedURL, "GET", "/auth/audit?action="+auditLogin+"&cursor="+first.NextCursor, owner.Token, nil)
+
+	s.expect(401, "GET", "/auth/audit", "", nil)
+}
+
+func TestAuditFailedLoginUnknownEmail(t *testing.T) {
+	s := newTestServer(t)
+	admin := s.withRole(s.signup("admin"), utils.RoleAdmin)
+
+	s.expect(401, "POST", "/v1/login", "", gin.H{"email": "ghost@example.com", "password": "password123"})
+
+	w := s.expect(200, "GET", "/admin/audit?action="+auditLoginFailed, admin.Token, nil)
+	events := decode[auditPage](t, w).Items
+	if len(events) != 1 || events[0].SubjectID.Valid || events[0].ActorID.Valid {
+		t.Fatalf("unexpected events %+v", events)
+	}
+	var meta map[string]string
+	if err := json.Unmarshal(events[0].Metadata, &meta); err != nil || meta["email"] != "ghost@example.com" {
+		t.Fatalf("unexpected metadata %s", events[0].Metadata)
+	}
+}
+
+func TestAdminAudit(t *testing.T) {
+	s := newTestServer(t)
+	owner := s.signup("owner")
+	other := s.signup("other")
+	admin := s.withRole(s.signup("admin"), utils.RoleAdmin)
+	groupID := s.group(owner, "Hampi")
+
+	s.expect(204, "DELETE", "/admin/groups/"+groupID.String(), admin.Token, nil)
+
+	// the forced deletion concerns the creator
+	w := s.expect(200, "GET", "/admin/audit?action="+auditGroupDelete+"&user_id="+owner.ID.String(), admin.Token, nil)
+	events := decode[auditPage](t, w).Items
+	if len(events) != 1 || events[0].ActorID.UUID != admin.ID || events[0].SubjectID.UUID != owner.ID {
+		t.Fatalf("unexpected events %+v", events)
+	}
+
+	w = s.expect(200, "GET", "/admin/audit", admin.Token, nil)
+	if n := len(decode[auditPage](t, w).Items); n != 5 {
+		t.Fatalf("got %d events, want 4 logins and the deletion", n)
+	}
+	w = s.expect(200, "GET", "/admin/audit?user_id="+other.ID.String(), admin.Token, nil)
+	if got := actions(decode[auditPage](t, w).Items); strings.Join(got, ",") != auditLogin {
+		t.Fatalf("unexpected events %v", got)
+	}
+
+	apiErr := s.expectError(400, utils.CodeMalformedURL, "GET", "/admin/audit?user_id=nope", admin.Token, nil)
+	if len(apiErr.Details) != 1 || apiErr.Details[0].Field != "user_id" {
+		t.Fatalf("unexpected details %+v", apiErr.Details)
+	}
+	s.expectError(403, utils.CodeForbidden, "GET", "/admin/audit", owner.Token, nil)
+}
+
+func TestRequestIDPropagation(t *testing.T) {
+	s := newTestServer(t)
+	u := s.signup("traced")
+	first := s.group(u, "Coorg")
+	second := s.group(u, "Wayanad")
+
+	serve := func(groupID uuid.UUID, id string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("DELETE", "/auth/travel-group/"+groupID.String(), nil)
+		req.Header.Set("Authorization", "Bearer "+u.Token)
+		req.Header.Set("X-Request-ID", id)
+		w := httptest.NewRecorder()
+		s.r.ServeHTTP(w, req)
+		return w
+	}
+
+	// ids that could break out of logs or sql comments are replaced
+	w := serve(first, "abc */ DROP TABLE users; --")
+	if id := w.Header().Get("X-Request-ID"); id == "" || strings.Contains(id, " ") {
+		t.Fatalf("unsafe request id echoed %q", id)
+	}
+	if w.Code != 204 {
+		t.Fatalf("got status %d", w.Code)
+	}
+
+	// a sane caller id is reused and recorded with the event
+	w = serve(second, "client-req.42")
+	if w.Header().Get("X-Request-ID") != "client-req.42" {
+		t.Fatalf("request id not reused, got %q", w.Header().Get("X-Request-ID"))
+	}
+	w = s.expect(200, "GET", "/auth/audit?action="+auditGroupDelete, u.Token, nil)
+	events := decode[auditPage](t, w).Items
+	if len(events) != 2 || events[0].RequestID != "client-req.42" || events[1].RequestID == "" {
+		t.Fatalf("unexpected events %+v", events)
+	}
+	if events[0].TargetID.UUID != second || events[0].ClientIp == "" {
+		t.Fatalf("unexpected event %+v", events[0])
+	}
+}
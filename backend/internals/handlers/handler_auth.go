@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/ErebusAJ/YatraBandhu/internals/db"
+	"github.com/ErebusAJ/YatraBandhu/internals/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// issueTokens
+// generates an access token and stores a new refresh token for a user
+func(cfg *apiConfig) issueTokens(c *gin.Context, userID uuid.UUID, userRole string, tokenVersion int32) (gin.H, error){
+	accessToken, err := utils.GenerateJWT(cfg.Config.Auth.SignedKey, userID, userRole, tokenVersion)
+	if err != nil{
+		return nil, err
+	}
+
+	refreshToken, tokenHash, err := utils.GenerateOpaqueToken()
+	if err != nil{
+		return nil, err
+	}
+
+	err = cfg.DB.InsertRefreshToken(c, db.InsertRefreshTokenParams{
+		UserID: userID,
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().Add(utils.RefreshTokenTTL),
+	})
+	if err != nil{
+		return nil, err
+	}
+
+	return gin.H{
+		"token": accessToken,
+		"refresh_token": refreshToken,
+		"expires_in": int(utils.AccessTokenTTL.Seconds()),
+	}, nil
+}
+
+
+// refreshTokenRequest
+// body of POST /v1/token/refresh and POST /auth/logout
+type refreshTokenRequest struct{
+	RefreshToken	string	`json:"refresh_token" binding:"required"`
+}
+
+// refreshToken
+// exchanges a valid refresh token for a new token pair
+// the used refresh token is revoked, reusing a revoked token revokes every session
+func(cfg *apiConfig) refreshToken(c *gin.Context){
+	var reqDetails refreshTokenRequest
+
+	err := c.ShouldBind(&reqDetails)
+	if err != nil{
+		utils.ErrorJSON(c, 400, utils.RequestBodyError, utils.JSONError, err)
+		return
+	}
+
+	token, err := cfg.DB.GetRefreshToken(c, utils.HashToken(reqDetails.RefreshToken))
+	if err == sql.ErrNoRows{
+		utils.ErrorJSON(c, 401, "unknown refresh token", utils.InvalidTokenError, err)
+		return
+	}else if err != nil{
+		utils.DBErrorJSON(c, err, nil)
+		return
+	}
+
+	if token.RevokedAt.Valid{
+		cfg.refreshTokenReused(c, token.UserID)
+		return
+	}
+
+	if token.ExpiresAt.Before(time.Now()){
+		utils.ErrorJSON(c, 401, "expired refresh token", utils.InvalidTokenError, nil)
+		return
+	}
+
+	user, err := cfg.DB.GetUserByID(c, token.UserID)
+	if err != nil{
+		utils.DBErrorJSON(c, err, utils.ErrUserNotFound)
+		return
+	}
+
+	if user.SuspendedAt.Valid{
+		utils.ErrorJSON(c, 403, utils.SuspendedError, utils.SuspendedError, nil)
+		return
+	}
+
+	// only one of concurrent refreshes with the token revokes it,
+	// the others are reuse
+	revoked, err := cfg.DB.RevokeRefreshToken(c, token.ID)
+	if err != nil{
+		utils.DBErrorJSON(c, err, nil)
+		return
+	}
+	if revoked == 0{
+		cfg.refreshTokenReused(c, token.UserID)
+		return
+	}
+
+	tokens, err := cfg.issueTokens(c, user.ID, user.AccessLevel.String, user.TokenVersion.Int32)
+	if err != nil{
+		utils.ErrorJSON(c, 500, "error generating token", utils.InternalError, err)
+		return
+	}
+
+	c.IndentedJSON(200, tokens)
+}
+
+
+// refreshTokenReused
+// a revoked refresh token was used, someone else may hold
+// a copy so every session of the user is ended
+func(cfg *apiConfig) refreshTokenReused(c *gin.Context, userID uuid.UUID){
+	_ = cfg.DB.RevokeUserRefreshTokens(c, userID)
+	_ = cfg.DB.IncrementTokenVersion(c, userID)
+	utils.ErrorJSON(c, 401, "revoked refresh token reused", utils.InvalidTokenError, nil)
+}
+
+
+// logoutUser
+// revokes the refresh token of the current session
+func(cfg *apiConfig) logoutUser(c *gin.Context){
+	var reqDetails refreshTokenRequest
+
+	err := c.ShouldBind(&reqDetails)
+	if err != nil{
+		utils.ErrorJSON(c, 400, utils.RequestBodyError, utils.JSONError, err)
+		return
+	}
+
+	tempID, exists := c.Get("userID")
+	if !exists{
+		utils.ErrorJSON(c, 401, utils.MiddlewareError, utils.UnauthorizedError, nil)
+		return
+	}
+	userID := tempID.(uuid.UUID)
+
+	token, err := cfg.DB.GetRefreshToken(c, utils.HashToken(reqDetails.RefreshToken))
+	if err == sql.ErrNoRows || (err == nil && token.UserID != userID){
+		utils.ErrorJSON(c, 400, "unknown refresh token", utils.InvalidTokenError, err)
+		return
+	}else if err != nil{
+		utils.DBErrorJSON(c, err, nil)
+		return
+	}
+
+	_, err = cfg.DB.RevokeRefreshToken(c, token.ID)
+	if err != nil{
+		utils.DBErrorJSON(c, err, nil)
+		return
+	}
+
+	c.IndentedJSON(200, utils.MessageObj("logged out"))
+}
+
+
+// logoutAllDevices
+// revokes every refresh token and invalidates all issued access tokens
+func(cfg *apiConfig) logoutAllDevices(c *gin.Context){
+	tempID, exists := c.Get("userID")
+	if !exists{
+		utils.ErrorJSON(c, 401, utils.MiddlewareError, utils.UnauthorizedError, nil)
+		return
+	}
+	userID := tempID.(uuid.UUID)
+
+	err := cfg.revokeSessions(c, userID)
+	if err != nil{
+		utils.DBErrorJSON(c, err, nil)
+		return
+	}
+
+	c.IndentedJSON(200, utils.MessageObj("logged out from all devices"))
+}
+
+
+// revokeSessions
+// bumps the user's token_version and revokes their refresh tokens
+func(cfg *apiConfig) revokeSessions(c *gin.Context, userID uuid.UUID) error{
+	err := cfg.DB.IncrementTokenVersion(c, userID)
+	if err != nil{
+		return err
+	}
+
+	return cfg.DB.RevokeUserRefreshTokens(c, userID)
+}
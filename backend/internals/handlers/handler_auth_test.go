@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRefreshToken(t *testing.T) {
+	s := newTestServer(t)
+	u := s.signup("arjun")
+
+	s.expect(400, "POST", "/v1/token/refresh", "", gin.H{})
+	s.expect(401, "POST", "/v1/token/refresh", "", gin.H{"refresh_token": "unknown"})
+
+	w := s.expect(200, "POST", "/v1/token/refresh", "", gin.H{"refresh_token": u.Refresh})
+	tokens := decode[struct {
+		Token   string `json:"token"`
+		Refresh string `json:"refresh_token"`
+	}](t, w)
+	if tokens.Token == "" || tokens.Refresh == "" || tokens.Refresh == u.Refresh {
+		t.Fatalf("refresh did not rotate the token pair: %+v", tokens)
+	}
+	s.expect(200, "GET", "/auth/user", tokens.Token, nil)
+
+	// reusing the rotated token ends every session
+	s.expect(401, "POST", "/v1/token/refresh", "", gin.H{"refresh_token": u.Refresh})
+	s.expect(401, "GET", "/auth/user", tokens.Token, nil)
+	s.expect(401, "POST", "/v1/token/refresh", "", gin.H{"refresh_token": tokens.Refresh})
+}
+
+func TestConcurrentRefreshToken(t *testing.T) {
+	s := newTestServer(t)
+	u := s.signup("meera")
+
+	// only one rotation of the same token wins, the rest are reuse
+	const n = 8
+	codes := make([]int, n)
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes[i] = s.do("POST", "/v1/token/refresh", "", gin.H{"refresh_token": u.Refresh}).Code
+		}()
+	}
+	wg.Wait()
+
+	ok := 0
+	for _, code := range codes {
+		switch code {
+		case 200:
+			ok++
+		case 401:
+		default:
+			t.Fatalf("unexpected status %d", code)
+		}
+	}
+	if ok > 1 {
+		t.Fatalf("%d refreshes with the same token succeeded", ok)
+	}
+}
+
+func TestLogout(t *testing.T) {
+	s := newTestServer(t)
+	u := s.signup("tara")
+	other := s.signup("veer")
+
+	s.expect(400, "POST", "/auth/logout", u.Token, gin.H{})
+	s.expect(400, "POST", "/auth/logout", u.Token, gin.H{"refresh_token": other.Refresh})
+	s.expect(200, "POST", "/auth/logout", u.Token, gin.H{"refresh_token": u.Refresh})
+	s.expect(401, "POST", "/v1/token/refresh", "", gin.H{"refresh_token": u.Refresh})
+
+	// the other user's session is untouched until they log out everywhere
+	s.expect(200, "GET", "/auth/user", other.Token, nil)
+	s.expect(200, "POST", "/auth/logout-all", other.Token, nil)
+	s.expect(401, "GET", "/auth/user", other.Token, nil)
+	s.expect(401, "POST", "/v1/token/refresh", "", gin.H{"refresh_token": other.Refresh})
+}
@@ -1,31 +1,46 @@
 package handlers
 
 import (
+	"strconv"
 
 	"github.com/ErebusAJ/YatraBandhu/internals/db"
+	"github.com/ErebusAJ/YatraBandhu/internals/mailer"
+	"github.com/ErebusAJ/YatraBandhu/internals/outbox"
+	"github.com/ErebusAJ/YatraBandhu/internals/paging"
+	"github.com/ErebusAJ/YatraBandhu/internals/store"
 	"github.com/ErebusAJ/YatraBandhu/internals/utils"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
+// registerGuideRequest
+// body of POST /guides/register, the location key is spelled
+// "loacation", kept for the clients already sending it
+type registerGuideRequest struct{
+	Name		string	`json:"name" binding:"required"`
+	Bio			string	`json:"bio" binding:"required"`
+	Location	string	`json:"loacation" binding:"required" doc:"city of the guide, note the key is spelled loacation"`
+	Expertise	string 	`json:"expertise" binding:"required"`
+	Rating		int		`json:"rating" binding:"min=0,max=5" doc:"only set when an admin registers the guide, 0 otherwise"`
+	HourRate	string	`json:"hourly_rate" binding:"required"`
+}
+
 // registerGuides
+// creates a guide profile, only admins may set the rating
 func(cfg *apiConfig) registerGuides(c *gin.Context){
-	var reqDetails struct{
-		Name		string	`json:"name" binding:"required"`
-		Bio			string	`json:"bio" binding:"required"`
-		Location	string	`json:"loacation" binding:"required"`
-		Expertise	string 	`json:"expertise" binding:"required"`
-		Rating		int		`json:"rating" binding:"required"`
-		HourRate	string	`json:"hourly_rate" binding:"required"`
-	}
+	var reqDetails registerGuideRequest
 
 	err := c.BindJSON(&reqDetails)
 	if err != nil{
-		utils.ErrorJSON(c, 400, utils.RequestBodyError, utils.InternalError, err)
+		utils.ErrorJSON(c, 400, utils.RequestBodyError, utils.JSONError, err)
 		return
 	}
 
-	err = cfg.DB.AddGuide(c, db.AddGuideParams{
+	if c.GetString("userRole") != utils.RoleAdmin{
+		reqDetails.Rating = 0
+	}
+
+	_, err = cfg.DB.AddGuide(c, db.AddGuideParams{
 		Name: reqDetails.Name,
 		Bio: reqDetails.Bio,
 		Location: reqDetails.Location,
@@ -34,7 +49,7 @@ func(cfg *apiConfig) registerGuides(c *gin.Context){
 		HourlyRate: reqDetails.HourRate,
 	})
 	if err != nil {
-		utils.ErrorJSON(c, 500, utils.DatabaseError, utils.InternalError, err)
+		utils.DBErrorJSON(c, err, nil)
 		return 
 	}
 
@@ -53,23 +68,43 @@ func(cfg *apiConfig) sendBookRequest(c *gin.Context){
 	tempGID := c.Param("groupID")
 	groupID, err := uuid.Parse(tempGID)
 	if err != nil {
-		utils.ErrorJSON(c, 400, utils.ParsingError, utils.JSONError, err)
+		utils.ErrorJSON(c, 400, utils.ParsingError, utils.EndpointError, err)
+		return
 	}
 
 	tempgID := c.Param("guideID")
 	guideID, err := uuid.Parse(tempgID)
 	if err != nil{
-		utils.ErrorJSON(c, 400, utils.ParsingError, utils.JSONError, err)
+		utils.ErrorJSON(c, 400, utils.ParsingError, utils.EndpointError, err)
 		return 
 	} 
 
-	err = cfg.DB.SendGuideRequest(c, db.SendGuideRequestParams{
-		GroupID: groupID,
-		GuideID: guideID,
-		UserID: userID,
+	err = cfg.DB.InTx(c, func(q store.Store) error{
+		err := q.SendGuideRequest(c, db.SendGuideRequestParams{
+			GroupID: groupID,
+			GuideID: guideID,
+			UserID: userID,
+		})
+		if err != nil{
+			return err
+		}
+
+		err = recordAudit(c, q, auditEvent{
+			Action: auditGuideBook,
+			ActorID: userID,
+			SubjectID: userID,
+			TargetType: "guide",
+			TargetID: guideID,
+			Metadata: gin.H{"group_id": groupID},
+		})
+		if err != nil{
+			return err
+		}
+
+		return notifyBookingRequested(c, q, userID, groupID, guideID)
 	})
 	if err != nil{
-		utils.ErrorJSON(c, 500, utils.DatabaseError, utils.InternalError, err)
+		utils.DBErrorJSON(c, err, nil)
 		return
 	}
 
@@ -77,17 +112,76 @@ func(cfg *apiConfig) sendBookRequest(c *gin.Context){
 }
 
 
+// guideList
+// sorts and filters of GET /auth/guide/
+var guideList = paging.Spec{
+	Sorts: []paging.Field{
+		{Name: "rating", Kind: paging.Number},
+		{Name: "rate", Kind: paging.Number, Description: "hourly rate"},
+		{Name: "name"},
+	},
+	Default: "-rating",
+	Filters: []paging.Field{
+		{Name: "location", Description: "exact match, case insensitive"},
+		{Name: "expertise", Description: "substring, case insensitive"},
+		{Name: "min_rating", Kind: paging.Integer},
+		{Name: "max_rate", Kind: paging.Number, Description: "highest hourly rate"},
+	},
+}
+
+
 // getGuideDetails
+// lists the available guides, a page at a time
 func(cfg *apiConfig) getGuideDetails(c *gin.Context){
-	var reqDetails struct{
-		Location 	string	`json:"location" binding:"required"`
+	listPage(c, guideList, func(r paging.Request) ([]db.Guide, error){
+		after := r.AfterKey()
+		minRating, _ := strconv.Atoi(r.Filters["min_rating"])
+		return cfg.DB.ListGuides(c, db.ListGuidesParams{
+			Location: r.Filters["location"],
+			Expertise: r.Filters["expertise"],
+			MinRating: int32(minRating),
+			MaxRate: nullFilter(r, "max_rate"),
+			HasCursor: r.After != nil,
+			Sort: r.SortParam(),
+			AfterText: after.Text,
+			AfterID: after.ID,
+			AfterNum: after.Num,
+			PageLimit: r.Fetch(),
+		})
+	}, func(g db.Guide, sort string) paging.Key {
+		switch sort{
+		case "name":
+			return paging.Key{Text: g.Name, ID: g.ID}
+		case "rating":
+			return paging.Key{Num: strconv.Itoa(int(g.Rating)), ID: g.ID}
+		}
+		return paging.Key{Num: g.HourlyRate, ID: g.ID}
+	})
+}
+
+
+// notifyBookingRequested
+// queues a mail confirming the booking request to the user
+// runs inside the booking transaction so the mail is only sent
+// if the request is saved
+func notifyBookingRequested(c *gin.Context, q store.Store, userID, groupID, guideID uuid.UUID) error{
+	user, err := q.GetUserByID(c, userID)
+	if err != nil{
+		return err
 	}
 
-	guides, err := cfg.DB.GetGuideDetails(c, reqDetails.Location)
+	group, err := q.GetGroupByID(c, groupID)
 	if err != nil{
-		utils.ErrorJSON(c, 500, utils.DatabaseError, utils.InternalError, err)
-		return
+		return err
 	}
 
-	c.IndentedJSON(200, guides)
-}
\ No newline at end of file
+	guide, err := q.GetGuideByID(c, guideID)
+	if err != nil{
+		return err
+	}
+
+	return outbox.Enqueue(c, q, user.Email, mailer.TemplateBookingConfirmed, gin.H{
+		"GuideName": guide.Name,
+		"GroupName": group.Name,
+	})
+}
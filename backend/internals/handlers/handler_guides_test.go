@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/ErebusAJ/YatraBandhu/internals/db"
+	"github.com/ErebusAJ/YatraBandhu/internals/paging"
+	"github.com/ErebusAJ/YatraBandhu/internals/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func TestRegisterGuides(t *testing.T) {
+	s := newTestServer(t)
+	user := s.signup("user")
+	guide := s.withRole(s.signup("guide"), utils.RoleGuide)
+	admin := s.withRole(s.signup("admin"), utils.RoleAdmin)
+
+	// the location key is spelled "loacation" in the request body
+	profile := gin.H{
+		"name":        "Kabir",
+		"bio":         "mountain guide",
+		"loacation":   "Leh",
+		"expertise":   "treks",
+		"rating":      5,
+		"hourly_rate": "800",
+	}
+
+	s.expect(401, "POST", "/guides/register", "", profile)
+	s.expect(403, "POST", "/guides/register", user.Token, profile)
+	s.expect(400, "POST", "/guides/register", guide.Token, gin.H{"name": "Kabir"})
+	s.expect(200, "POST", "/guides/register", guide.Token, profile)
+
+	profile["name"] = "Zoya"
+	s.expect(200, "POST", "/guides/register", admin.Token, profile)
+
+	// only admins may set a rating
+	guides, _ := s.store.ListGuides(context.Background(), db.ListGuidesParams{Location: "Leh", Sort: "name", PageLimit: 10})
+	if len(guides) != 2 {
+		t.Fatalf("unexpected guides %+v", guides)
+	}
+	for _, g := range guides {
+		want := int32(0)
+		if g.Name == "Zoya" {
+			want = 5
+		}
+		if g.Rating != want {
+			t.Fatalf("guide %s has rating %d, want %d", g.Name, g.Rating, want)
+		}
+	}
+}
+
+func TestGuideBooking(t *testing.T) {
+	s := newTestServer(t)
+	u := s.signup("traveller")
+	groupID := s.group(u, "Ladakh")
+	guideID := s.guide("Kabir", "Leh")
+
+	s.expectError(400, utils.CodeMalformedURL, "POST", "/auth/guide/book/"+groupID.String()+"/not-a-uuid", u.Token, nil)
+	s.expectError(400, utils.CodeMalformedURL, "POST", "/auth/guide/book/not-a-uuid/"+guideID.String(), u.Token, nil)
+	verification, _ := s.mailTo(u.Email)
+	s.expectError(404, utils.CodeGuideNotFound, "POST", "/auth/guide/book/"+groupID.String()+"/"+uuid.NewString(), u.Token, nil)
+	if mail, _ := s.mailTo(u.Email); mail.ID != verification.ID {
+		t.Fatalf("booking mail queued for a failed request: %+v", mail)
+	}
+	s.expect(200, "POST", "/auth/guide/book/"+groupID.String()+"/"+guideID.String(), u.Token, nil)
+
+	mail, ok := s.mailTo(u.Email)
+	if !ok || mail.ID == verification.ID || mail.Subject == "" {
+		t.Fatalf("no booking mail queued for %s", u.Email)
+	}
+}
+
+func TestGetGuideDetails(t *testing.T) {
+	s := newTestServer(t)
+	u := s.signup("seeker")
+
+	for _, g := range []db.AddGuideParams{
+		{Name: "Kabir", Location: "Leh", Expertise: "high altitude treks", Rating: 4, HourlyRate: "500"},
+		{Name: "Mira", Location: "Leh", Expertise: "monasteries", Rating: 5, HourlyRate: "900.50"},
+		{Name: "Dev", Location: "leh", Expertise: "river rafting", Rating: 3, HourlyRate: "300"},
+		{Name: "Asha", Location: "Goa", Expertise: "beaches", Rating: 5, HourlyRate: "400"},
+	} {
+		if _, err := s.store.AddGuide(context.Background(), g); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	names := func(path string) []string {
+		t.Helper()
+		w := s.expect(200, "GET", path, u.Token, nil)
+		var names []string
+		for _, g := range decode[paging.Page[db.Guide]](t, w).Items {
+			names = append(names, g.Name)
+		}
+		return names
+	}
+
+	for path, want := range map[string][]string{
+		// the location matches whatever its case
+		"/auth/guide/?location=LEH&sort=name":              {"Dev", "Kabir", "Mira"},
+		"/auth/guide/?location=Leh&sort=-rate":             {"Mira", "Kabir", "Dev"},
+		"/auth/guide/?location=leh&min_rating=4&sort=name": {"Kabir", "Mira"},
+		"/auth/guide/?max_rate=450&sort=rate":              {"Dev", "Asha"},
+		"/auth/guide/?expertise=TREK":                      {"Kabir"},
+		"/auth/guide/?location=Pune":                       nil,
+	} {
+		if got := names(path); !slices.Equal(got, want) {
+			t.Fatalf("%s: got %v, want %v", path, got, want)
+		}
+	}
+
+	// best rated first, a page at a time
+	w := s.expect(200, "GET", "/auth/guide/?limit=3", u.Token, nil)
+	page := decode[paging.Page[db.Guide]](t, w)
+	if len(page.Items) != 3 || page.Items[2].Rating != 4 || page.Next == "" {
+		t.Fatalf("unexpected first page %+v", page)
+	}
+	w = s.expect(200, "GET", page.Next, u.Token, nil)
+	page = decode[paging.Page[db.Guide]](t, w)
+	if len(page.Items) != 1 || page.Items[0].Name != "Dev" || page.Next != "" || page.NextCursor != "" {
+		t.Fatalf("unexpected last page %+v", page)
+	}
+
+	for path, field := range map[string]string{
+		"/auth/guide/?limit=0":            "limit",
+		"/auth/guide/?sort=price":         "sort",
+		"/auth/guide/?min_rating=4.5":     "min_rating",
+		"/auth/guide/?max_rate=cheap":     "max_rate",
+		"/auth/guide/?cursor=bm90LWpzb24": "cursor",
+		// a cursor is only valid for the sort it was made for
+		"/auth/guide/?sort=name&limit=1&cursor=" + firstCursor(t, s, u, "/auth/guide/?limit=1"): "cursor",
+	} {
+		apiErr := s.expectError(400, utils.CodeMalformedURL, "GET", path, u.Token, nil)
+		if len(apiErr.Details) != 1 || apiErr.Details[0].Field != field {
+			t.Fatalf("%s: unexpected details %+v", path, apiErr.Details)
+		}
+	}
+}
+
+// firstCursor
+// the next cursor of the first page at path
+func firstCursor(t *testing.T, s *testServer, u *testUser, path string) string {
+	t.Helper()
+	w := s.expect(200, "GET", path, u.Token, nil)
+	return decode[paging.Page[db.Guide]](t, w).NextCursor
+}
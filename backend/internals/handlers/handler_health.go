@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"time"
+
+	"github.com/ErebusAJ/YatraBandhu/internals/metrics"
+	"github.com/ErebusAJ/YatraBandhu/internals/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// Time allowed for the DB ping of a health check
+const healthCheckTimeout = 2 * time.Second
+
+// pingDB
+// pings the store, bounded by healthCheckTimeout
+func(cfg *apiConfig) pingDB(c *gin.Context) error{
+	ctx, cancel := context.WithTimeout(c, healthCheckTimeout)
+	defer cancel()
+
+	return cfg.DB.Ping(ctx)
+}
+
+
+// healthz
+// liveness check, the process is up
+// reports the DB state but does not fail on it
+func(cfg *apiConfig) healthz(c *gin.Context){
+	dbStatus := "ok"
+	err := cfg.pingDB(c)
+	if err != nil{
+		dbStatus = err.Error()
+	}
+
+	c.IndentedJSON(200, gin.H{
+		"status": "ok",
+		"db": dbStatus,
+	})
+}
+
+
+// readyz
+// readiness check, fails with 503 when the DB is unreachable
+func(cfg *apiConfig) readyz(c *gin.Context){
+	err := cfg.pingDB(c)
+	if err != nil{
+		c.IndentedJSON(503, gin.H{
+			"status": "unavailable",
+			"db": err.Error(),
+		})
+		return
+	}
+
+	res := gin.H{
+		"status": "ok",
+		"db": "ok",
+	}
+
+	// pool stats are only available from a postgres store
+	if pool, ok := cfg.DB.(interface{ Stats() sql.DBStats }); ok{
+		stats := pool.Stats()
+		res["open_connections"] = stats.OpenConnections
+		res["in_use"] = stats.InUse
+	}
+
+	c.IndentedJSON(200, res)
+}
+
+
+// getMetrics
+// prometheus metrics, open unless a metrics token is configured
+func(cfg *apiConfig) getMetrics(c *gin.Context){
+	token := cfg.Config.Metrics.Token
+	if token != ""{
+		got := c.GetHeader("Authorization")
+		if subtle.ConstantTimeCompare([]byte(got), []byte("Bearer " + token)) != 1{
+			utils.ErrorJSON(c, 401, "invalid metrics token", utils.UnauthorizedError, nil)
+			return
+		}
+	}
+
+	metrics.Handler().ServeHTTP(c.Writer, c.Request)
+}
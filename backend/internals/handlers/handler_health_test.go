@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ErebusAJ/YatraBandhu/internals/utils"
+)
+
+func TestHealth(t *testing.T) {
+	s := newTestServer(t)
+
+	for _, path := range []string{"/healthz", "/readyz"} {
+		w := s.expect(200, "GET", path, "", nil)
+		if body := decode[map[string]any](t, w); body["db"] != "ok" {
+			t.Fatalf("%s: unexpected body %+v", path, body)
+		}
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	s := newTestServer(t)
+	s.expect(200, "GET", "/healthz", "", nil)
+	s.expect(404, "GET", "/no/such/route", "", nil)
+
+	w := s.expect(200, "GET", "/metrics", "", nil)
+	body := w.Body.String()
+	for _, want := range []string{
+		`yatrabandhu_http_request_duration_seconds_count{method="GET",route="/healthz",status="200"}`,
+		`yatrabandhu_http_request_duration_seconds_count{method="GET",route="unmatched",status="404"}`,
+		`yatrabandhu_http_requests_in_flight`,
+		`go_goroutines`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("metrics missing %s", want)
+		}
+	}
+
+	// a configured token is required as a bearer token
+	s = newTestServer(t, func(cfg *apiConfig) { cfg.Config.Metrics.Token = "scrape-me" })
+	s.expectError(401, utils.CodeUnauthorized, "GET", "/metrics", "", nil)
+	s.expectError(401, utils.CodeUnauthorized, "GET", "/metrics", "wrong", nil)
+	s.expect(200, "GET", "/metrics", "scrape-me", nil)
+}
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"database/sql"
+
+	"github.com/ErebusAJ/YatraBandhu/internals/paging"
+	"github.com/ErebusAJ/YatraBandhu/internals/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// listPage
+// answers a list request with one page of rows, the limit, sort,
+// cursor and filters of spec are read from the query string, fetch
+// runs the query for them and key returns the cursor key of a row
+// for the field sorted on
+func listPage[T any](c *gin.Context, spec paging.Spec, fetch func(r paging.Request) ([]T, error), key func(row T, sort string) paging.Key){
+	req, err := spec.Parse(c.Request.URL.Query())
+	if err != nil{
+		perr := err.(*paging.Error)
+		apiErr := utils.NewAPIError(400, utils.EndpointError)
+		apiErr.Details = []utils.FieldError{{Field: perr.Field, Message: perr.Message}}
+		utils.SendError(c, apiErr, utils.ParsingError, err)
+		return
+	}
+
+	rows, err := fetch(req)
+	if err != nil{
+		utils.DBErrorJSON(c, err, nil)
+		return
+	}
+
+	c.IndentedJSON(200, paging.NewPage(req, rows, func(row T) paging.Key {
+		return key(row, req.Sort)
+	}, c.Request.URL))
+}
+
+
+// nullFilter
+// an optional filter as a nullable query arg
+func nullFilter(r paging.Request, name string) sql.NullString {
+	v, ok := r.Filters[name]
+	return sql.NullString{String: v, Valid: ok}
+}
@@ -0,0 +1,322 @@
+package handlers
+
+import (
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/ErebusAJ/YatraBandhu/internals/db"
+	"github.com/ErebusAJ/YatraBandhu/internals/itinerary"
+	"github.com/ErebusAJ/YatraBandhu/internals/openapi"
+	"github.com/ErebusAJ/YatraBandhu/internals/paging"
+	"github.com/ErebusAJ/YatraBandhu/internals/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// Response bodies documented in the spec
+// the handlers send them as gin.H
+type messageResponse struct{
+	Message		string	`json:"message"`
+}
+
+type tokenResponse struct{
+	Token			string	`json:"token" doc:"JWT access token, sent as Authorization: Bearer <token>"`
+	RefreshToken	string	`json:"refresh_token" doc:"single use, exchanged at /v1/token/refresh"`
+	ExpiresIn		int		`json:"expires_in" doc:"lifetime of the access token in seconds"`
+}
+
+type healthResponse struct{
+	Status			string	`json:"status"`
+	DB				string	`json:"db" doc:"ok or the ping error"`
+	OpenConnections	*int	`json:"open_connections,omitempty" doc:"readyz only"`
+	InUse			*int	`json:"in_use,omitempty" doc:"readyz only"`
+}
+
+
+// Query params shared by the list endpoints
+var(
+	limitParam = &openapi.Parameter{Name: "limit", In: "query", Description: "page size, default 20, at most 100", Schema: &openapi.Schema{Type: "integer"}}
+)
+
+// listParams
+// the query params of a paginated list, see listPage
+func listParams(spec paging.Spec) []*openapi.Parameter{
+	params := []*openapi.Parameter{
+		limitParam,
+		{Name: "cursor", In: "query", Description: "next_cursor of the previous page, only valid with the same sort and filters", Schema: &openapi.Schema{Type: "string"}},
+		{Name: "sort", In: "query", Description: "default " + spec.Default + ", \"-\" prefixed for descending", Schema: &openapi.Schema{Type: "string", Enum: spec.SortNames()}},
+	}
+	for _, f := range spec.Filters{
+		params = append(params, &openapi.Parameter{Name: f.Name, In: "query", Description: f.Description, Schema: kindSchema(f.Kind)})
+	}
+	return params
+}
+
+// kindSchema
+// the schema of a filter value
+func kindSchema(kind paging.Kind) *openapi.Schema{
+	switch kind{
+	case paging.Number:
+		return &openapi.Schema{Type: "number"}
+	case paging.Integer:
+		return &openapi.Schema{Type: "integer"}
+	case paging.Time:
+		return &openapi.Schema{Type: "string", Format: "date-time"}
+	case paging.Date:
+		return &openapi.Schema{Type: "string", Format: "date"}
+	case paging.UUID:
+		return &openapi.Schema{Type: "string", Format: "uuid"}
+	}
+	return &openapi.Schema{Type: "string"}
+}
+
+
+// Path params by name
+var pathParams = map[string]*openapi.Parameter{
+	"groupID":	{Description: "travel group id", Schema: &openapi.Schema{Type: "string", Format: "uuid"}},
+	"userID":	{Description: "user id", Schema: &openapi.Schema{Type: "string", Format: "uuid"}},
+	"senderID":	{Description: "id of the user who sent the join request", Schema: &openapi.Schema{Type: "string", Format: "uuid"}},
+	"guideID":	{Description: "guide id", Schema: &openapi.Schema{Type: "string", Format: "uuid"}},
+	"reportID":	{Description: "report id", Schema: &openapi.Schema{Type: "string", Format: "uuid"}},
+	"emailID":	{Description: "outbox email id", Schema: &openapi.Schema{Type: "string", Format: "uuid"}},
+	"token":	{Description: "token from the emailed link", Schema: &openapi.Schema{Type: "string"}},
+}
+
+
+// Shorthands for the route table
+const(
+	bearer		=	openapi.BearerAuth
+	notVerified	=	utils.CodeEmailNotVerified
+)
+
+var admins = []string{utils.RoleAdmin}
+
+// apiRoutes
+// documents every route registered by routes, keep the two in
+// sync, TestOpenAPICoversRoutes fails otherwise
+func apiRoutes() []openapi.Route{
+	return []openapi.Route{
+		// Health
+		{Method: "GET", Path: "/healthz", Tag: "Health", Summary: "Liveness check, 200 while the process runs", Response: healthResponse{}},
+		{Method: "GET", Path: "/readyz", Tag: "Health", Summary: "Readiness check, pings the database", Response: healthResponse{}, Responses: map[int]any{503: healthResponse{}}},
+		{Method: "GET", Path: "/metrics", Tag: "Health", Summary: "Prometheus metrics", Description: "Requires the metrics token as a bearer token when one is configured.", Auth: openapi.MetricsAuth, Response: "", ContentType: "text/plain",
+			Errors: map[int][]string{401: {utils.CodeUnauthorized}}},
+		{Method: "GET", Path: "/openapi.json", Tag: "Docs", Summary: "This document", Response: map[string]any{}},
+		{Method: "GET", Path: "/docs", Tag: "Docs", Summary: "Interactive API docs", Response: "", ContentType: "text/html"},
+		{Method: "GET", Path: "/schemas/itinerary.json", Tag: "Docs", Summary: "JSON Schema of AI plans", Description: "Given to the structuring models, generated itineraries are validated against it.", Response: map[string]any{}, ContentType: "application/schema+json"},
+
+		// Users
+		{Method: "POST", Path: "/v1/register", Tag: "Users", Summary: "Register an account and email a verification link", Body: registerUserRequest{}, Status: 201, Response: messageResponse{},
+			Errors: map[int][]string{409: {utils.CodeUserEmailTaken, utils.CodeUserPhoneTaken}}},
+		{Method: "POST", Path: "/v1/login", Tag: "Sessions", Summary: "Log in with email and password", Description: "Failed attempts are limited per account and per client ip.", Body: loginRequest{}, Response: tokenResponse{},
+			Errors: map[int][]string{401: {utils.CodeInvalidCredentials}, 403: {utils.CodeAccountSuspended}, 429: {utils.CodeTooManyRequests}}},
+		{Method: "POST", Path: "/v1/token/refresh", Tag: "Sessions", Summary: "Exchange a refresh token for a new token pair", Description: "Reusing a revoked refresh token ends every session of the account.", Body: refreshTokenRequest{}, Response: tokenResponse{},
+			Errors: map[int][]string{401: {utils.CodeInvalidToken}, 403: {utils.CodeAccountSuspended}, 404: {utils.CodeUserNotFound}}},
+		{Method: "GET", Path: "/v1/verify/:token", Tag: "Users", Summary: "Verify an email address", Response: messageResponse{},
+			Errors: map[int][]string{400: {utils.CodeInvalidToken}}},
+		{Method: "POST", Path: "/v1/verify/resend", Tag: "Users", Summary: "Send a new verification link", Description: "Responds the same whether or not the account exists, requests are limited per email.", Body: resendVerificationRequest{}, Response: messageResponse{},
+			Errors: map[int][]string{429: {utils.CodeTooManyRequests}}},
+		{Method: "POST", Path: "/v1/user/password-reset", Tag: "Users", Summary: "Email a password reset link", Description: "Responds the same whether or not the account exists, requests are limited per email.", Body: passwordResetRequest{}, Response: messageResponse{},
+			Errors: map[int][]string{429: {utils.CodeTooManyRequests}}},
+		{Method: "POST", Path: "/v1/user/password-reset/:token", Tag: "Users", Summary: "Set a new password with a reset link token", Description: "Ends every session of the account.", Body: passwordResetConfirmRequest{}, Response: messageResponse{},
+			Errors: map[int][]string{400: {utils.CodeInvalidToken}}},
+		{Method: "POST", Path: "/auth/logout", Tag: "Sessions", Summary: "Revoke the refresh token of this session", Auth: bearer, Body: refreshTokenRequest{}, Response: messageResponse{},
+			Errors: map[int][]string{400: {utils.CodeInvalidToken}}},
+		{Method: "POST", Path: "/auth/logout-all", Tag: "Sessions", Summary: "End every session of the account", Auth: bearer, Response: messageResponse{}},
+		{Method: "GET", Path: "/auth/user", Tag: "Users", Summary: "The logged in user", Auth: bearer, Response: db.User{},
+			Errors: map[int][]string{404: {utils.CodeUserNotFound}}},
+		{Method: "PUT", Path: "/auth/user", Tag: "Users", Summary: "Update the logged in user", Description: "Changing the password ends every session.", Auth: bearer, Body: updateUserRequest{}, Status: 204,
+			Errors: map[int][]string{404: {utils.CodeUserNotFound}, 409: {utils.CodeUserPhoneTaken}}},
+		{Method: "DELETE", Path: "/auth/user", Tag: "Users", Summary: "Delete the logged in user", Auth: bearer, Status: 204},
+		{Method: "GET", Path: "/auth/audit", Tag: "Users", Summary: "Audit events of the logged in user, newest first", Auth: bearer, Query: listParams(auditList), Response: paging.Page[db.AuditEvent]{}},
+
+		// Travel details
+		{Method: "POST", Path: "/auth/travel-details", Tag: "Travel Details", Summary: "Add a travel plan", Auth: bearer, Body: travelDetailsRequest{}, Response: messageResponse{}},
+		{Method: "GET", Path: "/auth/travel-details", Tag: "Travel Details", Summary: "Travel plans of the logged in user", Auth: bearer,
+			Query: listParams(planList), Response: paging.Page[db.ListUserPlansRow]{}},
+
+		// Travel groups
+		{Method: "POST", Path: "/auth/travel-group", Tag: "Travel Groups", Summary: "Create a group for a travel plan", Description: "The creator becomes the first member.", Auth: bearer, Body: createGroupRequest{}, Response: messageResponse{},
+			Errors: map[int][]string{403: {notVerified}, 404: {utils.CodePlanNotFound}, 409: {utils.CodeGroupExists}}},
+		{Method: "PUT", Path: "/auth/travel-group/:groupID", Tag: "Travel Groups", Summary: "Update a group, creator only", Auth: bearer, Body: updateGroupRequest{}, Status: 204,
+			Errors: map[int][]string{403: {utils.CodeNotGroupCreator}, 404: {utils.CodeGroupNotFound}}},
+		{Method: "DELETE", Path: "/auth/travel-group/:groupID", Tag: "Travel Groups", Summary: "Delete a group, creator only", Auth: bearer, Status: 204,
+			Errors: map[int][]string{403: {utils.CodeNotGroupCreator}, 404: {utils.CodeGroupNotFound}}},
+		{Method: "GET", Path: "/auth/travel-group/", Tag: "Travel Groups", Summary: "Groups the logged in user is a member of", Auth: bearer,
+			Query: listParams(groupList), Response: paging.Page[db.ListUserGroupsRow]{}},
+		{Method: "POST", Path: "/auth/travel-group/:groupID/member/:userID", Tag: "Travel Groups", Summary: "Add a member to a group, creator only", Auth: bearer, Response: messageResponse{},
+			Errors: map[int][]string{403: {utils.CodeNotGroupCreator}, 404: {utils.CodeGroupNotFound, utils.CodeUserNotFound}, 409: {utils.CodeMemberExists}}},
+		{Method: "GET", Path: "/auth/travel-group/:groupID/member", Tag: "Travel Groups", Summary: "Members of a group", Auth: bearer,
+			Query: listParams(memberList), Response: paging.Page[db.ListGroupMembersRow]{}},
+		{Method: "DELETE", Path: "/auth/travel-group/:groupID/member/:userID", Tag: "Travel Groups", Summary: "Remove a member, creator only", Auth: bearer, Status: 204,
+			Errors: map[int][]string{403: {utils.CodeNotGroupCreator}, 404: {utils.CodeGroupNotFound}}},
+
+		// Join requests
+		{Method: "POST", Path: "/auth/travel-group/:groupID/request", Tag: "Join Requests", Summary: "Ask to join a group", Auth: bearer, Response: messageResponse{},
+			Errors: map[int][]string{403: {notVerified}, 404: {utils.CodeGroupNotFound}, 409: {utils.CodeRequestPending}}},
+		{Method: "GET", Path: "/auth/travel-group/:groupID/request", Tag: "Join Requests", Summary: "Pending requests to every group created by the logged in user", Description: "The groupID in the path is not used, filter with group_id instead.", Auth: bearer,
+			Query: listParams(requestList), Response: paging.Page[db.ListGroupRequestsRow]{}},
+		{Method: "POST", Path: "/auth/travel-group/:groupID/request/:senderID", Tag: "Join Requests", Summary: "Accept or reject a join request, creator only", Description: "Accepting adds the sender to the group and emails them.", Auth: bearer, Body: joinRequestAction{}, Status: 201, Response: messageResponse{},
+			Errors: map[int][]string{403: {utils.CodeNotGroupCreator}, 404: {utils.CodeGroupNotFound, utils.CodeRequestNotFound}, 409: {utils.CodeMemberExists}}},
+
+		// Guides
+		{Method: "POST", Path: "/guides/register", Tag: "Guides", Summary: "Create a guide profile", Auth: bearer, Roles: []string{utils.RoleAdmin, utils.RoleGuide}, Body: registerGuideRequest{}, Response: messageResponse{}},
+		{Method: "POST", Path: "/auth/guide/book/:groupID/:guideID", Tag: "Guides", Summary: "Request a guide booking for a group", Auth: bearer, Response: messageResponse{},
+			Errors: map[int][]string{403: {notVerified}, 404: {utils.CodeGroupNotFound, utils.CodeGuideNotFound}}},
+		{Method: "GET", Path: "/auth/guide/", Tag: "Guides", Summary: "Available guides", Auth: bearer,
+			Query: listParams(guideList), Response: paging.Page[db.Guide]{}},
+
+		// Moderation and planner
+		{Method: "POST", Path: "/auth/reports", Tag: "Moderation", Summary: "Report a user, group or guide", Auth: bearer, Body: reportRequest{}, Status: 201, Response: messageResponse{}},
+		{Method: "POST", Path: "/auth/ai-planner", Tag: "AI Planner", Summary: "Queue an AI itinerary generation", Description: "Generations can take minutes and run in the background, poll the job named by the Location header until it succeeds and has a plan_id. With a plan_id the saved plan is regenerated, its new itinerary saved as the next version.", Auth: bearer, Body: planRequest{}, Status: 202, Response: plannerJobResponse{},
+			Errors: map[int][]string{404: {utils.CodeAIPlanNotFound}, 429: {utils.CodeQuotaExceeded}}},
+		{Method: "POST", Path: "/auth/ai-planner/stream", Tag: "AI Planner", Summary: "Generate an AI itinerary as server-sent events", Description: "The generation runs within the request. stage events ({stage, step}) report collecting_data with its steps, drafting and structuring, token events ({text}) relay the draft as the model writes it, in one piece for models that can't stream. A plan event ({plan_id, itinerary}) with the saved itinerary closes the stream, after it started failures are sent as an error event with the error body and are not counted against the quotas.", Auth: bearer, Body: planRequest{}, Response: "", ContentType: "text/event-stream",
+			Errors: map[int][]string{404: {utils.CodeAIPlanNotFound}, 429: {utils.CodeQuotaExceeded}}},
+		{Method: "GET", Path: "/auth/ai-planner/jobs/:jobID", Tag: "AI Planner", Summary: "Progress of a planner job", Description: "The stage goes from queued through collecting_data, drafting and structuring to saved. Failed stages are retried, a job that runs out of attempts fails and its generation is not counted.", Auth: bearer, Response: plannerJobResponse{},
+			Errors: map[int][]string{404: {utils.CodePlannerJobNotFound}}},
+		{Method: "DELETE", Path: "/auth/ai-planner/jobs/:jobID", Tag: "AI Planner", Summary: "Cancel a queued or running planner job", Description: "The generation is not counted against the quotas.", Auth: bearer, Response: plannerJobResponse{},
+			Errors: map[int][]string{404: {utils.CodePlannerJobNotFound}, 409: {utils.CodePlannerJobFinished}}},
+		{Method: "GET", Path: "/auth/usage", Tag: "AI Planner", Summary: "AI planner quotas of the logged in user", Description: "Quotas reset at the start of each UTC day and month, limit and remaining are null when unlimited.", Auth: bearer, Response: usageResponse{}},
+
+		// Saved AI plans
+		{Method: "GET", Path: "/auth/ai-plans", Tag: "AI Plans", Summary: "Saved AI plans of the logged in user", Auth: bearer,
+			Query: listParams(aiPlanList), Response: paging.Page[aiPlanSummary]{}},
+		{Method: "GET", Path: "/auth/ai-plans/:planID", Tag: "AI Plans", Summary: "A saved AI plan with its current itinerary", Auth: bearer, Response: aiPlanResponse{},
+			Errors: map[int][]string{404: {utils.CodeAIPlanNotFound}}},
+		{Method: "PATCH", Path: "/auth/ai-plans/:planID", Tag: "AI Plans", Summary: "Rename a saved AI plan or save an edited itinerary", Description: "At least one of title and itinerary is required. An edited itinerary must be consistent with its own travel_duration and is saved as the next version, unless it is the current one.", Auth: bearer, Body: updateAIPlanRequest{}, Response: aiPlanResponse{},
+			Errors: map[int][]string{404: {utils.CodeAIPlanNotFound}}},
+		{Method: "DELETE", Path: "/auth/ai-plans/:planID", Tag: "AI Plans", Summary: "Delete a saved AI plan and its versions", Auth: bearer, Status: 204,
+			Errors: map[int][]string{404: {utils.CodeAIPlanNotFound}}},
+		{Method: "GET", Path: "/auth/ai-plans/:planID/diff", Tag: "AI Plans", Summary: "Changes between two versions of a saved AI plan", Description: "Without from and to the previous version is compared with the current one.", Auth: bearer,
+			Query: []*openapi.Parameter{
+				{Name: "from", In: "query", Description: "default the version before to", Schema: &openapi.Schema{Type: "integer"}},
+				{Name: "to", In: "query", Description: "default the current version", Schema: &openapi.Schema{Type: "integer"}},
+			},
+			Response: aiPlanDiffResponse{}, Errors: map[int][]string{404: {utils.CodeAIPlanNotFound, utils.CodeAIPlanVersionNotFound}}},
+		{Method: "GET", Path: "/auth/ai-plans/:planID/versions", Tag: "AI Plans", Summary: "Versions of a saved AI plan", Auth: bearer,
+			Query: listParams(aiPlanVersionList), Response: paging.Page[aiPlanVersionSummary]{},
+			Errors: map[int][]string{404: {utils.CodeAIPlanNotFound}}},
+		{Method: "GET", Path: "/auth/ai-plans/:planID/versions/:version", Tag: "AI Plans", Summary: "A version of a saved AI plan with its itinerary", Auth: bearer, Response: aiPlanVersionResponse{},
+			Errors: map[int][]string{404: {utils.CodeAIPlanNotFound, utils.CodeAIPlanVersionNotFound}}},
+		{Method: "POST", Path: "/auth/ai-plans/:planID/versions/:version/restore", Tag: "AI Plans", Summary: "Roll a saved AI plan back to a version", Description: "The itinerary of the version is saved as the next version, so the history is kept.", Auth: bearer, Response: aiPlanResponse{},
+			Errors: map[int][]string{404: {utils.CodeAIPlanNotFound, utils.CodeAIPlanVersionNotFound}}},
+
+		// Admin
+		{Method: "GET", Path: "/admin/users", Tag: "Admin", Summary: "Search users by name or email", Auth: bearer, Roles: admins,
+			Query: listParams(userList), Response: paging.Page[db.SearchUsersRow]{}},
+		{Method: "PUT", Path: "/admin/users/:userID/role", Tag: "Admin", Summary: "Change a user's role", Description: "The user's refresh tokens are revoked so the role applies on next login.", Auth: bearer, Roles: admins, Body: roleRequest{}, Response: messageResponse{},
+			Errors: map[int][]string{404: {utils.CodeUserNotFound}}},
+		{Method: "POST", Path: "/admin/users/:userID/suspend", Tag: "Admin", Summary: "Suspend a user and end their sessions", Auth: bearer, Roles: admins, Response: messageResponse{},
+			Errors: map[int][]string{404: {utils.CodeUserNotFound}}},
+		{Method: "POST", Path: "/admin/users/:userID/unsuspend", Tag: "Admin", Summary: "Lift a suspension", Auth: bearer, Roles: admins, Response: messageResponse{},
+			Errors: map[int][]string{404: {utils.CodeUserNotFound}}},
+		{Method: "DELETE", Path: "/admin/groups/:groupID", Tag: "Admin", Summary: "Delete any group", Auth: bearer, Roles: admins, Status: 204,
+			Errors: map[int][]string{404: {utils.CodeGroupNotFound}}},
+		{Method: "PUT", Path: "/admin/guides/:guideID", Tag: "Admin", Summary: "Verify or list a guide", Auth: bearer, Roles: admins, Body: moderateGuideRequest{}, Response: messageResponse{},
+			Errors: map[int][]string{404: {utils.CodeGuideNotFound}}},
+		{Method: "GET", Path: "/admin/reports", Tag: "Admin", Summary: "Reports by status", Auth: bearer, Roles: admins,
+			Query: listParams(reportList), Response: paging.Page[db.Report]{},
+			Errors: map[int][]string{400: {utils.CodeValidationFailed}}},
+		{Method: "PUT", Path: "/admin/reports/:reportID", Tag: "Admin", Summary: "Resolve or dismiss a report", Auth: bearer, Roles: admins, Body: reportStatusRequest{}, Response: messageResponse{},
+			Errors: map[int][]string{404: {utils.CodeReportNotFound}}},
+		{Method: "GET", Path: "/admin/outbox", Tag: "Admin", Summary: "Queued mail by status", Auth: bearer, Roles: admins,
+			Query: listParams(outboxList), Response: paging.Page[db.ListOutboxByStatusRow]{},
+			Errors: map[int][]string{400: {utils.CodeValidationFailed}}},
+		{Method: "POST", Path: "/admin/outbox/:emailID/requeue", Tag: "Admin", Summary: "Retry a dead email", Auth: bearer, Roles: admins, Response: messageResponse{},
+			Errors: map[int][]string{404: {utils.CodeEmailNotFound}}},
+		{Method: "GET", Path: "/admin/audit", Tag: "Admin", Summary: "Every audit event, newest first", Auth: bearer, Roles: admins,
+			Query: listParams(adminAuditList), Response: paging.Page[db.AuditEvent]{}},
+	}
+}
+
+
+// impliedErrors
+// error codes every route can return by the way it is registered
+func impliedErrors(r openapi.Route) map[int][]string{
+	errs := map[int][]string{500: {utils.CodeInternal}}
+
+	if r.Tag != "Health" && r.Tag != "Docs"{
+		// rate limited per ip, and per user once logged in
+		errs[429] = []string{utils.CodeTooManyRequests}
+	}
+	if r.Auth == bearer{
+		errs[401] = []string{utils.CodeUnauthorized, utils.CodeInvalidToken}
+		errs[403] = []string{utils.CodeAccountSuspended}
+	}
+	if len(r.Roles) > 0{
+		errs[403] = append(errs[403], utils.CodeForbidden)
+	}
+	if r.Body != nil{
+		errs[400] = append(errs[400], utils.CodeValidationFailed)
+	}
+	if strings.Contains(r.Path, ":") && !strings.Contains(r.Path, ":token"){
+		errs[400] = append(errs[400], utils.CodeMalformedURL)
+	}
+	if slices.ContainsFunc(r.Query, func(p *openapi.Parameter) bool { return p.Name == "cursor" }){
+		// bad limit, sort, cursor or filter of a paginated list
+		errs[400] = append(errs[400], utils.CodeMalformedURL)
+	}
+
+	return errs
+}
+
+
+// apiSpec
+// the OpenAPI document, built once
+var apiSpec = sync.OnceValues(func() (*openapi.Document, error){
+	b := openapi.NewBuilder(openapi.Info{
+		Title: "YatraBandhu API",
+		Description: "Errors are sent as an APIError, clients should branch on its code. Every response carries an X-Request-ID header.",
+		Version: "1.0.0",
+	})
+	b.PathParams = pathParams
+	b.Implied = impliedErrors
+	b.ErrorSchema = b.Schema(utils.APIError{})
+	b.Security(openapi.BearerAuth, &openapi.SecurityScheme{
+		Type: "http",
+		Scheme: "bearer",
+		BearerFormat: "JWT",
+		Description: "access token from /v1/login or /v1/token/refresh",
+	})
+	b.Security(openapi.MetricsAuth, &openapi.SecurityScheme{
+		Type: "http",
+		Scheme: "bearer",
+		Description: "the metrics token, only when METRICS_TOKEN is set",
+	})
+
+	for _, r := range apiRoutes(){
+		err := b.Add(r)
+		if err != nil{
+			return nil, err
+		}
+	}
+
+	return b.Document(), nil
+})
+
+
+// getOpenAPI
+// serves the OpenAPI document
+func(cfg *apiConfig) getOpenAPI(c *gin.Context){
+	spec, err := apiSpec()
+	if err != nil{
+		utils.ErrorJSON(c, 500, "error building openapi document", utils.InternalError, err)
+		return
+	}
+
+	c.IndentedJSON(200, spec)
+}
+
+
+// getDocs
+// serves the docs page rendering /openapi.json
+func(cfg *apiConfig) getDocs(c *gin.Context){
+	c.Data(200, "text/html; charset=utf-8", openapi.DocsHTML)
+}
+
+
+// getItinerarySchema
+// serves the JSON Schema AI plans are validated against
+func(cfg *apiConfig) getItinerarySchema(c *gin.Context){
+	c.Data(200, "application/schema+json", itinerary.Schema)
+}
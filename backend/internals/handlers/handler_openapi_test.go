@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ErebusAJ/YatraBandhu/internals/openapi"
+)
+
+func TestOpenAPICoversRoutes(t *testing.T) {
+	s := newTestServer(t)
+
+	w := s.expect(200, "GET", "/openapi.json", "", nil)
+	spec := decode[openapi.Document](t, w)
+	if spec.OpenAPI != openapi.Version {
+		t.Fatalf("unexpected version %q", spec.OpenAPI)
+	}
+
+	registered := map[string]bool{}
+	for _, route := range s.r.Routes() {
+		registered[route.Method+" "+route.Path] = true
+		if !spec.Has(route.Method, route.Path) {
+			t.Errorf("%s %s is registered but missing from apiRoutes", route.Method, route.Path)
+		}
+	}
+
+	// documented routes must exist
+	for _, route := range apiRoutes() {
+		if !registered[route.Method+" "+route.Path] {
+			t.Errorf("%s %s is documented but not registered", route.Method, route.Path)
+		}
+	}
+
+	w = s.expect(200, "GET", "/docs", "", nil)
+	if !strings.Contains(w.Body.String(), "/openapi.json") {
+		t.Fatal("docs page doesn't load the spec")
+	}
+}
+
+func TestOpenAPISchemas(t *testing.T) {
+	spec, err := apiSpec()
+	if err != nil {
+		t.Fatal(err)
+	}
+	schemas := spec.Components.Schemas
+
+	// bodies are reflected from the handler structs, quirks included
+	guide := schemas["registerGuideRequest"]
+	if guide == nil || guide.Properties["loacation"] == nil || guide.Properties["location"] != nil {
+		t.Fatalf("unexpected guide body %+v", guide)
+	}
+	if !strings.Contains(strings.Join(guide.Required, ","), "loacation") || *guide.Properties["rating"].Maximum != 5 {
+		t.Fatalf("unexpected guide constraints %+v", guide)
+	}
+
+	action := schemas["joinRequestAction"].Properties["actions"]
+	if action == nil || strings.Join(action.Enum, ",") != "accept,reject" {
+		t.Fatalf("unexpected join request action %+v", action)
+	}
+
+	role := schemas["roleRequest"].Properties["role"]
+	if strings.Join(role.Enum, ",") != "user,guide,admin" {
+		t.Fatalf("unexpected role enum %v", role.Enum)
+	}
+
+	// path params, security and error codes
+	op := spec.Paths["/auth/travel-group/{groupID}/member/{userID}"]["delete"]
+	if op == nil || len(op.Parameters) != 2 || op.Parameters[0].Schema.Format != "uuid" || len(op.Security) != 1 {
+		t.Fatalf("unexpected operation %+v", op)
+	}
+	if codes := strings.Join(op.Responses["403"].ErrorCodes, ","); codes != "ACCOUNT_SUSPENDED,NOT_GROUP_CREATOR" {
+		t.Fatalf("unexpected 403 codes %s", codes)
+	}
+	if op.Responses["404"].Content["application/json"].Schema.Ref != "#/components/schemas/APIError" {
+		t.Fatalf("errors don't reference APIError")
+	}
+
+	login := spec.Paths["/v1/login"]["post"]
+	if login.Security != nil || login.Responses["429"].Headers["Retry-After"] == nil {
+		t.Fatalf("unexpected login operation %+v", login)
+	}
+	if admin := spec.Paths["/admin/audit"]["get"]; strings.Join(admin.Roles, ",") != "admin" {
+		t.Fatalf("unexpected admin roles %v", admin.Roles)
+	}
+}
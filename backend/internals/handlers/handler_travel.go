@@ -6,22 +6,27 @@ import (
 	"regexp"
 
 	"github.com/ErebusAJ/YatraBandhu/internals/db"
+	"github.com/ErebusAJ/YatraBandhu/internals/paging"
 	"github.com/ErebusAJ/YatraBandhu/internals/utils"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
+// travelDetailsRequest
+// body of POST /auth/travel-details
+type travelDetailsRequest struct{
+	Place		string		`json:"place" binding:"required"`
+	StartDate	string		`json:"start_date" binding:"required" doc:"YYYY-MM-DD"`
+	EndDate		string		`json:"end_date" binding:"required" doc:"YYYY-MM-DD"`
+	TripType	string		`json:"trip_type" binding:"required"`
+	Pets		bool		`json:"pets"`
+	Interestes 	[]string	`json:"interests" binding:"required"` 
+}
+
 // addTravelDetails
 // Adds travel plan parameters to database
 func(cfg *apiConfig) addTravelDetails(c *gin.Context){
-	var reqDetails struct{
-		Place		string		`json:"place" binding:"required"`
-		StartDate	string		`json:"start_date" binding:"required"`
-		EndDate		string		`json:"end_date" binding:"required"`
-		TripType	string		`json:"trip_type" binding:"required"`
-		Pets		bool		`json:"pets"`
-		Interestes 	[]string	`json:"interests" binding:"required"` 
-	}
+	var reqDetails travelDetailsRequest
 	err := c.BindJSON(&reqDetails)
 	if err != nil {
 		utils.ErrorJSON(c, 400, utils.RequestBodyError, utils.JSONError, err)
@@ -36,21 +41,30 @@ func(cfg *apiConfig) addTravelDetails(c *gin.Context){
 		return
 	}
 
-	if(!re.MatchString(reqDetails.StartDate) || !re.MatchString(reqDetails.EndDate)){
-		utils.ErrorJSON(c, 400, utils.RequestBodyError, utils.JSONError, nil)
+	var details []utils.FieldError
+	if !re.MatchString(reqDetails.StartDate){
+		details = append(details, utils.FieldError{Field: "start_date", Message: "must be YYYY-MM-DD"})
+	}
+	if !re.MatchString(reqDetails.EndDate){
+		details = append(details, utils.FieldError{Field: "end_date", Message: "must be YYYY-MM-DD"})
+	}
+	if details != nil{
+		apiErr := utils.NewAPIError(400, utils.JSONError)
+		apiErr.Details = details
+		utils.SendError(c, apiErr, utils.RequestBodyError, nil)
 		return
 	}
 
 	tempID, exists := c.Get("userID")
 	if !exists {
-		utils.ErrorJSON(c, 400, utils.MiddlewareError, utils.UnauthorizedError, nil)
+		utils.ErrorJSON(c, 401, utils.MiddlewareError, utils.UnauthorizedError, nil)
 		return
 	}
 	userID := tempID.(uuid.UUID)
 
 
 
-	err = cfg.DB.AddTravelDetails(c, db.AddTravelDetailsParams{
+	_, err = cfg.DB.AddTravelDetails(c, db.AddTravelDetailsParams{
 		CreatorID: userID,
 		Place: reqDetails.Place,
 		StartDate: reqDetails.StartDate,
@@ -60,7 +74,7 @@ func(cfg *apiConfig) addTravelDetails(c *gin.Context){
 		Interests: reqDetails.Interestes,
 	})
 	if err != nil{
-		utils.ErrorJSON(c, 500, utils.DatabaseError, utils.InternalError, err)
+		utils.DBErrorJSON(c, err, nil)
 		return
 	}
 
@@ -68,21 +82,52 @@ func(cfg *apiConfig) addTravelDetails(c *gin.Context){
 }
 
 
+// planList
+// sorts and filters of GET /auth/travel-details
+var planList = paging.Spec{
+	Sorts: []paging.Field{
+		{Name: "created_at", Kind: paging.Time},
+		{Name: "start_date", Kind: paging.Date},
+	},
+	Default: "created_at",
+	Filters: []paging.Field{
+		{Name: "place", Description: "substring, case insensitive"},
+		{Name: "trip_type"},
+		{Name: "from", Kind: paging.Date, Description: "earliest start date"},
+		{Name: "to", Kind: paging.Date, Description: "latest start date"},
+	},
+}
+
+
 // getUserPlansDetails
-// Retrieves users plans and its details
+// Retrieves users plans and its details, a page at a time
 func(cfg *apiConfig) getUserPlansDetails(c *gin.Context){
 	tempID, exists := c.Get("userID")
 	if !exists {
-		utils.ErrorJSON(c, 400, utils.MiddlewareError, utils.UnauthorizedError, nil)
+		utils.ErrorJSON(c, 401, utils.MiddlewareError, utils.UnauthorizedError, nil)
 		return
 	}
 	userID := tempID.(uuid.UUID)
 
-	plans, err := cfg.DB.GetUserPlansDetails(c, userID)
-	if err != nil{
-		utils.ErrorJSON(c, 500, utils.DatabaseError, utils.InternalError, err)
-		return
-	}
-
-	c.IndentedJSON(200, plans)
+	listPage(c, planList, func(r paging.Request) ([]db.ListUserPlansRow, error){
+		after := r.AfterKey()
+		return cfg.DB.ListUserPlans(c, db.ListUserPlansParams{
+			CreatorID: userID,
+			Place: r.Filters["place"],
+			TripType: r.Filters["trip_type"],
+			FromDate: r.Filters["from"],
+			ToDate: r.Filters["to"],
+			HasCursor: r.After != nil,
+			Sort: r.SortParam(),
+			AfterText: after.Text,
+			AfterID: after.ID,
+			AfterTime: after.Time,
+			PageLimit: r.Fetch(),
+		})
+	}, func(p db.ListUserPlansRow, sort string) paging.Key {
+		if sort == "start_date"{
+			return paging.Key{Text: p.StartDate, ID: p.ID}
+		}
+		return paging.Key{Time: p.CreatedAt.Time, ID: p.ID}
+	})
 }
@@ -1,7 +1,14 @@
 package handlers
 
 import (
+	"database/sql"
+	"errors"
+
 	"github.com/ErebusAJ/YatraBandhu/internals/db"
+	"github.com/ErebusAJ/YatraBandhu/internals/mailer"
+	"github.com/ErebusAJ/YatraBandhu/internals/outbox"
+	"github.com/ErebusAJ/YatraBandhu/internals/paging"
+	"github.com/ErebusAJ/YatraBandhu/internals/store"
 	"github.com/ErebusAJ/YatraBandhu/internals/utils"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -12,7 +19,7 @@ import (
 func(cfg *apiConfig) sendRequest(c *gin.Context){
 	tempID, exists := c.Get("userID")
 	if !exists {
-		utils.ErrorJSON(c, 500, utils.MiddlewareError, utils.UnauthorizedError, nil)
+		utils.ErrorJSON(c, 401, utils.MiddlewareError, utils.UnauthorizedError, nil)
 		return 
 	}
 	senderID := tempID.(uuid.UUID)
@@ -20,7 +27,7 @@ func(cfg *apiConfig) sendRequest(c *gin.Context){
 	tempGID := c.Param("groupID")
 	group_ID, err := uuid.Parse(tempGID)
 	if err != nil{
-		utils.ErrorJSON(c, 500, utils.ParsingError, utils.InternalError, err)
+		utils.ErrorJSON(c, 400, utils.ParsingError, utils.EndpointError, err)
 		return
 	}
 
@@ -29,19 +36,25 @@ func(cfg *apiConfig) sendRequest(c *gin.Context){
 		UserID: senderID,
 	})
 	if err != nil{
-		utils.ErrorJSON(c, 500, utils.DatabaseError, utils.InternalError, err)
+		utils.DBErrorJSON(c, err, nil)
 		return
 	}
 
 	c.IndentedJSON(200, utils.MessageObj("request sent!!"))
 }
 
+// joinRequestAction
+// body of POST /auth/travel-group/:groupID/request/:senderID,
+// the key is "actions" though it holds a single action
+type joinRequestAction struct{
+	Action	string `json:"actions" binding:"required" enum:"accept reject" doc:"accept or reject, note the key is actions"`
+}
+
 // updateRequest
-// defines action for a request "accept" or "reject"
+// defines action for a request "accept" or "reject",
+// only the group creator can answer requests
 func(cfg *apiConfig) updateRequest(c *gin.Context){
-	var reqDetails struct{
-		Action	string `json:"actions" binding:"required"`
-	}
+	var reqDetails joinRequestAction
 
 	err := c.BindJSON(&reqDetails)
 	if err != nil{
@@ -50,43 +63,84 @@ func(cfg *apiConfig) updateRequest(c *gin.Context){
 	}
 
 	if reqDetails.Action != "accept" && reqDetails.Action != "reject" {
-		utils.ErrorJSON(c, 400, utils.RequestBodyError, utils.JSONError, nil)
+		apiErr := utils.NewAPIError(400, utils.JSONError)
+		apiErr.Details = []utils.FieldError{{Field: "actions", Message: "must be accept or reject"}}
+		utils.SendError(c, apiErr, utils.RequestBodyError, nil)
 		return
 	}
 
 	tempGID := c.Param("groupID")
 	group_ID, err := uuid.Parse(tempGID)
 	if err != nil{
-		utils.ErrorJSON(c, 500, utils.ParsingError, utils.InternalError, err)
+		utils.ErrorJSON(c, 400, utils.ParsingError, utils.EndpointError, err)
 		return
 	}
 
 	tempiUID := c.Param("senderID")
 	sender_id, err := uuid.Parse(tempiUID)
 	if err != nil{
-		utils.ErrorJSON(c, 500, utils.ParsingError, utils.InternalError, err)
+		utils.ErrorJSON(c, 400, utils.ParsingError, utils.EndpointError, err)
+		return
+	}
+
+	tempID, exists := c.Get("userID")
+	if !exists {
+		utils.ErrorJSON(c, 401, utils.MiddlewareError, utils.UnauthorizedError, nil)
 		return
 	}
+	userID := tempID.(uuid.UUID)
 
 	if reqDetails.Action == "reject"{
-		err = cfg.DB.RejectRequest(c, db.RejectRequestParams{
-			GroupID: group_ID,
-			UserID: sender_id,
+		err = cfg.DB.InTx(c, func(q store.Store) error{
+			err := requireGroupCreator(c, q, group_ID, userID)
+			if err != nil{
+				return err
+			}
+
+			return q.RejectRequest(c, db.RejectRequestParams{
+				GroupID: group_ID,
+				UserID: sender_id,
+			})
 		})
 		if err != nil{
-			utils.ErrorJSON(c, 500, utils.DatabaseError, utils.InternalError, err)
+			utils.DBErrorJSON(c, err, nil)
 			return
 		}
 
 		c.IndentedJSON(201, utils.MessageObj("rejected !!"))
 		return
 	}else if reqDetails.Action == "accept"{
-		err = cfg.DB.AddUserToGroup(c, db.AddUserToGroupParams{
-			GroupID: group_ID,
-			UserID: sender_id,
+		// status, membership and the mail commit together
+		err = cfg.DB.InTx(c, func(q store.Store) error{
+			err := requireGroupCreator(c, q, group_ID, userID)
+			if err != nil{
+				return err
+			}
+
+			updated, err := q.UpdateRequest(c, db.UpdateRequestParams{
+				Status: "accepted",
+				GroupID: group_ID,
+				UserID: sender_id,
+			})
+			if err != nil{
+				return err
+			}
+			if updated == 0{
+				return utils.ErrRequestNotFound
+			}
+
+			err = q.AddUserToGroup(c, db.AddUserToGroupParams{
+				GroupID: group_ID,
+				UserID: sender_id,
+			})
+			if err != nil{
+				return err
+			}
+
+			return notifyRequestAccepted(c, q, group_ID, sender_id)
 		})
 		if err != nil{
-			utils.ErrorJSON(c, 500, utils.DatabaseError, utils.InternalError, err)
+			utils.DBErrorJSON(c, err, nil)
 			return
 		}
 	}
@@ -95,20 +149,88 @@ func(cfg *apiConfig) updateRequest(c *gin.Context){
 }
 
 
+// requestList
+// sorts and filters of GET /auth/travel-group/:groupID/request
+var requestList = paging.Spec{
+	Sorts: []paging.Field{
+		{Name: "created_at", Kind: paging.Time},
+		{Name: "sender_name"},
+	},
+	Default: "-created_at",
+	Filters: []paging.Field{
+		{Name: "group_id", Kind: paging.UUID, Description: "only requests to this group"},
+	},
+}
+
+
 // getUserGroupRequest
+// pending requests to the groups of the logged in user, a page at a time
 func(cfg *apiConfig) getUserGroupRequest(c *gin.Context){
 	tempID, exists := c.Get("userID")
 	if !exists {
-		utils.ErrorJSON(c, 500, utils.MiddlewareError, utils.UnauthorizedError, nil)
+		utils.ErrorJSON(c, 401, utils.MiddlewareError, utils.UnauthorizedError, nil)
 		return 
 	}
 	creatorID := tempID.(uuid.UUID)
 
-	requests, err := cfg.DB.GetUserGroupRequests(c, creatorID)
-	if err != nil {
-		utils.ErrorJSON(c, 500, utils.DatabaseError, utils.InternalError, err)
-		return 
+	listPage(c, requestList, func(r paging.Request) ([]db.ListGroupRequestsRow, error){
+		after := r.AfterKey()
+		groupID, err := uuid.Parse(r.Filters["group_id"])
+		return cfg.DB.ListGroupRequests(c, db.ListGroupRequestsParams{
+			CreatorID: creatorID,
+			GroupID: uuid.NullUUID{UUID: groupID, Valid: err == nil},
+			HasCursor: r.After != nil,
+			Sort: r.SortParam(),
+			AfterTime: after.Time,
+			AfterID: after.ID,
+			AfterText: after.Text,
+			PageLimit: r.Fetch(),
+		})
+	}, func(req db.ListGroupRequestsRow, sort string) paging.Key {
+		if sort == "sender_name"{
+			return paging.Key{Text: req.SenderName, ID: req.RequestID}
+		}
+		return paging.Key{Time: req.CreatedAt.Time, ID: req.RequestID}
+	})
+}
+
+
+// requireGroupCreator
+// the group must exist and be created by userID, run in
+// the transaction of the change it guards
+func requireGroupCreator(c *gin.Context, q store.Store, groupID, userID uuid.UUID) error{
+	group, err := q.GetGroupByID(c, groupID)
+	if errors.Is(err, sql.ErrNoRows){
+		return utils.ErrGroupNotFound
+	}
+	if err != nil{
+		return err
+	}
+
+	if group.CreatorID != userID{
+		return utils.ErrNotGroupCreator
+	}
+	return nil
+}
+
+
+// notifyRequestAccepted
+// queues a mail telling the sender that they joined the group
+// runs inside the accept transaction so the mail is only sent
+// if the membership is saved
+func notifyRequestAccepted(c *gin.Context, q store.Store, groupID, senderID uuid.UUID) error{
+	sender, err := q.GetUserByID(c, senderID)
+	if err != nil{
+		return err
+	}
+
+	group, err := q.GetGroupByID(c, groupID)
+	if err != nil{
+		return err
 	}
 
-	c.IndentedJSON(200, requests)
-}
\ No newline at end of file
+	return outbox.Enqueue(c, q, sender.Email, mailer.TemplateJoinRequestAccepted, gin.H{
+		"Name": sender.Name,
+		"GroupName": group.Name,
+	})
+}
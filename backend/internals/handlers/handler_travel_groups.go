@@ -2,19 +2,25 @@ package handlers
 
 import (
 	"github.com/ErebusAJ/YatraBandhu/internals/db"
+	"github.com/ErebusAJ/YatraBandhu/internals/paging"
+	"github.com/ErebusAJ/YatraBandhu/internals/store"
 	"github.com/ErebusAJ/YatraBandhu/internals/utils"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
+// createGroupRequest
+// body of POST /auth/travel-group
+type createGroupRequest struct{
+	Name		string	`json:"name" binding:"required"`
+	Description	string	`json:"description" binding:"required"`
+	PlanID 		string	`json:"plan_id" binding:"required" doc:"uuid of a travel plan"`
+}
+
 // createGroup
 // allows a logged in user creates a group
 func(cfg *apiConfig) createGroup(c *gin.Context){
-	var reqDetails struct{
-		Name		string	`json:"name" binding:"required"`
-		Description	string	`json:"description" binding:"required"`
-		PlanID 		string	`json:"plan_id" binding:"required"`	
-	}
+	var reqDetails createGroupRequest
 
 	err := c.BindJSON(&reqDetails)
 	if err != nil{
@@ -32,45 +38,53 @@ func(cfg *apiConfig) createGroup(c *gin.Context){
 	// parsin planID string --> uuid
 	planID, err := uuid.Parse(reqDetails.PlanID)
 	if err != nil{
-		utils.ErrorJSON(c, 500, utils.ParsingError, utils.InternalError, err)
+		apiErr := utils.NewAPIError(400, utils.JSONError)
+		apiErr.Details = []utils.FieldError{{Field: "plan_id", Message: "must be a uuid"}}
+		utils.SendError(c, apiErr, utils.ParsingError, err)
 		return
 	}
 
+	// group and creator membership are saved together
 	groupID := uuid.New()
-	err = cfg.DB.CreateGroup(c, db.CreateGroupParams{
-		ID: groupID,
-		CreatorID: userID,
-		Name: reqDetails.Name,
-		Description: reqDetails.Description,
-		PlanID: planID,
+	err = cfg.DB.InTx(c, func(q store.Store) error{
+		err := q.CreateGroup(c, db.CreateGroupParams{
+			ID: groupID,
+			CreatorID: userID,
+			Name: reqDetails.Name,
+			Description: reqDetails.Description,
+			PlanID: planID,
+		})
+		if err != nil{
+			return err
+		}
+
+		// Add creator to group member
+		return q.AddUserToGroup(c, db.AddUserToGroupParams{
+			GroupID: groupID,
+			UserID: userID,
+		})
 	})
 	if err != nil{
-		utils.ErrorJSON(c, 500, utils.DatabaseError, utils.InternalError, err)
+		utils.DBErrorJSON(c, err, nil)
 		return 
 	}
 
-	// Add creator to group member
-	err = cfg.DB.AddUserToGroup(c, db.AddUserToGroupParams{
-		GroupID: groupID,
-		UserID: userID,
-	})
-	if err != nil{
-		utils.ErrorJSON(c, 500, utils.DatabaseError, utils.InternalError, err)
-		return 
-	}
-	
-
 	c.IndentedJSON(200, utils.MessageObj("group created success!!"))
 }  
 
+// updateGroupRequest
+// body of PUT /auth/travel-group/:groupID, empty fields
+// are left unchanged
+type updateGroupRequest struct{
+	Name		string	`json:"name"`
+	Description	string	`json:"description"`
+	PlanID 		string	`json:"plan_id" binding:"required" doc:"required but ignored, the plan of a group can't be changed"`
+}
+
 // updateGroup
 // Update details of a group created by user with a certain PlanID
 func(cfg *apiConfig) updateGroup(c *gin.Context){
-	var reqDetails struct{
-		Name		string	`json:"name"`
-		Description	string	`json:"description"`
-		PlanID 		string	`json:"plan_id" binding:"required"`	
-	}
+	var reqDetails updateGroupRequest
 
 	err := c.BindJSON(&reqDetails)
 	if err != nil{
@@ -94,13 +108,13 @@ func(cfg *apiConfig) updateGroup(c *gin.Context){
 
 	group, err := cfg.DB.GetGroupByID(c, groupID)
 	if err != nil{
-		utils.ErrorJSON(c, 500, utils.DatabaseError, utils.InternalError, err)
+		utils.DBErrorJSON(c, err, utils.ErrGroupNotFound)
 		return
 	}
 	
 	// check if user sending request is the group creator or not 
 	if group.CreatorID != userID{
-		utils.ErrorJSON(c, 401, utils.InvalidAcces, utils.UnauthorizedError, err)
+		utils.SendError(c, utils.ErrNotGroupCreator, utils.InvalidAcces, nil)
 		return
 	}
 
@@ -118,7 +132,7 @@ func(cfg *apiConfig) updateGroup(c *gin.Context){
 		ID: group.ID,
 	})
 	if err != nil{
-		utils.ErrorJSON(c, 500, utils.DatabaseError, utils.InternalError, err)
+		utils.DBErrorJSON(c, err, nil)
 		return
 	}
 
@@ -144,21 +158,34 @@ func(cfg *apiConfig) deleteGroupByID(c *gin.Context){
 	}
 	userID := tempUID.(uuid.UUID)
 
-	//get group creatorID
-	group, err := cfg.DB.GetGroupByID(c, groupID)
-	if err != nil{
-		utils.ErrorJSON(c, 500, utils.DatabaseError, utils.InternalError, err)
-		return
-	}
-
-	if group.CreatorID != userID{
-		utils.ErrorJSON(c, 401, utils.InvalidAcces, utils.UnauthorizedError, nil)
-		return
-	}
-
-	err = cfg.DB.DeleteGroupByID(c, groupID)
+	// ownership check, deletion and audit run in one transaction,
+	// members and requests are removed by the cascades
+	err = cfg.DB.InTx(c, func(q store.Store) error{
+		group, err := q.GetGroupByID(c, groupID)
+		if err != nil{
+			return err
+		}
+
+		if group.CreatorID != userID{
+			return utils.ErrNotGroupCreator
+		}
+
+		err = q.DeleteGroupByID(c, groupID)
+		if err != nil{
+			return err
+		}
+
+		return recordAudit(c, q, auditEvent{
+			Action: auditGroupDelete,
+			ActorID: userID,
+			SubjectID: userID,
+			TargetType: "group",
+			TargetID: groupID,
+			Metadata: gin.H{"name": group.Name},
+		})
+	})
 	if err != nil{
-		utils.ErrorJSON(c, 500, utils.DatabaseError, utils.InternalError, err)
+		utils.DBErrorJSON(c, err, utils.ErrGroupNotFound)
 		return
 	}
 
@@ -169,8 +196,15 @@ func(cfg *apiConfig) deleteGroupByID(c *gin.Context){
 // Group Members Handlers
 
 // addGroupMember
-// adds a user to an existing group
+// adds a user to an existing group if requested by creator
 func(cfg *apiConfig) addGroupMember(c *gin.Context){
+	temp, exists := c.Get("userID")
+	if !exists {
+		utils.ErrorJSON(c, 401, utils.MiddlewareError, utils.UnauthorizedError, nil)
+		return
+	}
+	creatorID := temp.(uuid.UUID)
+
 	tempGID := c.Param("groupID")
 	groupID, err := uuid.Parse(tempGID)
 
@@ -186,10 +220,21 @@ func(cfg *apiConfig) addGroupMember(c *gin.Context){
 		return
 	}
 
-	cfg.DB.AddUserToGroup(c, db.AddUserToGroupParams{
-		GroupID: groupID,
-		UserID: userID,
+	err = cfg.DB.InTx(c, func(q store.Store) error{
+		err := requireGroupCreator(c, q, groupID, creatorID)
+		if err != nil{
+			return err
+		}
+
+		return q.AddUserToGroup(c, db.AddUserToGroupParams{
+			GroupID: groupID,
+			UserID: userID,
+		})
 	})
+	if err != nil{
+		utils.DBErrorJSON(c, err, nil)
+		return
+	}
 
 	c.IndentedJSON(200, utils.MessageObj("user added successfully!!!"))
 }
@@ -217,12 +262,12 @@ func(cfg *apiConfig) deleteGroupMember(c *gin.Context){
 	// Checks if request is sent by creator of group
 	group, err := cfg.DB.GetGroupByID(c, groupID)
 	if err != nil{
-		utils.ErrorJSON(c, 500, utils.DatabaseError, utils.InternalError, err)
+		utils.DBErrorJSON(c, err, utils.ErrGroupNotFound)
 		return
 	}
 
 	if group.CreatorID != creatorID {
-		utils.ErrorJSON(c, 401, utils.InvalidAcces, utils.UnauthorizedError, err)
+		utils.SendError(c, utils.ErrNotGroupCreator, utils.InvalidAcces, nil)
 		return
 	}
 
@@ -233,12 +278,25 @@ func(cfg *apiConfig) deleteGroupMember(c *gin.Context){
 		return
 	}
 
-	err = cfg.DB.DeleteUserFromGroup(c, db.DeleteUserFromGroupParams{
-		UserID: userID,
-		GroupID: groupID,
+	err = cfg.DB.InTx(c, func(q store.Store) error{
+		err := q.DeleteUserFromGroup(c, db.DeleteUserFromGroupParams{
+			UserID: userID,
+			GroupID: groupID,
+		})
+		if err != nil{
+			return err
+		}
+
+		return recordAudit(c, q, auditEvent{
+			Action: auditMemberRemove,
+			ActorID: creatorID,
+			SubjectID: userID,
+			TargetType: "group",
+			TargetID: groupID,
+		})
 	})
 	if err != nil{
-		utils.ErrorJSON(c, 500, utils.DatabaseError, utils.InternalError, err)
+		utils.DBErrorJSON(c, err, nil)
 		return
 	}
 
@@ -246,8 +304,22 @@ func(cfg *apiConfig) deleteGroupMember(c *gin.Context){
 }
 
 
+// memberList
+// sorts and filters of GET /auth/travel-group/:groupID/member
+var memberList = paging.Spec{
+	Sorts: []paging.Field{
+		{Name: "joined_at", Kind: paging.Time},
+		{Name: "name"},
+	},
+	Default: "joined_at",
+	Filters: []paging.Field{
+		{Name: "name", Description: "substring, case insensitive"},
+	},
+}
+
+
 // getGroupMembersDetails
-// returns details all the members of group 
+// returns details all the members of group, a page at a time
 func(cfg *apiConfig) getGroupMembersDetails(c *gin.Context){
 	tempGID := c.Param("groupID")
 	groupID, err := uuid.Parse(tempGID)
@@ -256,18 +328,46 @@ func(cfg *apiConfig) getGroupMembersDetails(c *gin.Context){
 		return
 	}
 
-	members, err := cfg.DB.GetGroupUsersDetails(c, groupID)
-	if err != nil{
-		utils.ErrorJSON(c, 500, utils.DatabaseError, utils.InternalError, err)
-		return
-	}
+	listPage(c, memberList, func(r paging.Request) ([]db.ListGroupMembersRow, error){
+		after := r.AfterKey()
+		return cfg.DB.ListGroupMembers(c, db.ListGroupMembersParams{
+			GroupID: groupID,
+			Name: r.Filters["name"],
+			HasCursor: r.After != nil,
+			Sort: r.SortParam(),
+			AfterText: after.Text,
+			AfterID: after.ID,
+			AfterTime: after.Time,
+			PageLimit: r.Fetch(),
+		})
+	}, func(m db.ListGroupMembersRow, sort string) paging.Key {
+		if sort == "name"{
+			return paging.Key{Text: m.Name, ID: m.ID}
+		}
+		return paging.Key{Time: m.JoinedAt.Time, ID: m.ID}
+	})
+}
 
-	c.IndentedJSON(200, members)
+
+// groupList
+// sorts and filters of GET /auth/travel-group/
+var groupList = paging.Spec{
+	Sorts: []paging.Field{
+		{Name: "created_at", Kind: paging.Time},
+		{Name: "name"},
+		{Name: "start_date", Kind: paging.Date, Description: "start date of the plan"},
+	},
+	Default: "created_at",
+	Filters: []paging.Field{
+		{Name: "place", Description: "place of the plan, substring, case insensitive"},
+		{Name: "from", Kind: paging.Date, Description: "earliest start date of the plan"},
+		{Name: "to", Kind: paging.Date, Description: "latest start date of the plan"},
+	},
 }
 
 
 // getUsersGroups
-// returns list of groups a user is part of
+// returns list of groups a user is part of, a page at a time
 func(cfg *apiConfig) getUsersGroups(c *gin.Context){
 	temp, exists := c.Get("userID")
 	if !exists {
@@ -276,11 +376,27 @@ func(cfg *apiConfig) getUsersGroups(c *gin.Context){
 	}
 	userID := temp.(uuid.UUID)
 
-	groups, err := cfg.DB.GetUserGroups(c, userID)
-	if err != nil{
-		utils.ErrorJSON(c, 500, utils.DatabaseError, utils.InternalError, err)
-		return
-	}
-
-	c.IndentedJSON(200, groups)
+	listPage(c, groupList, func(r paging.Request) ([]db.ListUserGroupsRow, error){
+		after := r.AfterKey()
+		return cfg.DB.ListUserGroups(c, db.ListUserGroupsParams{
+			UserID: userID,
+			Place: r.Filters["place"],
+			FromDate: r.Filters["from"],
+			ToDate: r.Filters["to"],
+			HasCursor: r.After != nil,
+			Sort: r.SortParam(),
+			AfterText: after.Text,
+			AfterID: after.ID,
+			AfterTime: after.Time,
+			PageLimit: r.Fetch(),
+		})
+	}, func(g db.ListUserGroupsRow, sort string) paging.Key {
+		switch sort{
+		case "name":
+			return paging.Key{Text: g.Name, ID: g.ID}
+		case "start_date":
+			return paging.Key{Text: g.StartDate, ID: g.ID}
+		}
+		return paging.Key{Time: g.CreatedAt.Time, ID: g.ID}
+	})
 }
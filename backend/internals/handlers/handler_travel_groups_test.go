@@ -0,0 +1,284 @@
+package handlers
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/ErebusAJ/YatraBandhu/internals/db"
+	"github.com/ErebusAJ/YatraBandhu/internals/paging"
+	"github.com/ErebusAJ/YatraBandhu/internals/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func TestTravelDetails(t *testing.T) {
+	s := newTestServer(t)
+	u := s.signup("sana")
+
+	details := gin.H{
+		"place":      "Manali",
+		"start_date": "2025-03-01",
+		"end_date":   "2025-03-07",
+		"trip_type":  "adventure",
+		"pets":       true,
+		"interests":  []string{"trekking", "food"},
+	}
+	s.expect(200, "POST", "/auth/travel-details", u.Token, details)
+
+	details["end_date"] = "07-03-2025"
+	apiErr := s.expectError(400, utils.CodeValidationFailed, "POST", "/auth/travel-details", u.Token, details)
+	if len(apiErr.Details) != 1 || apiErr.Details[0].Field != "end_date" {
+		t.Fatalf("unexpected details %+v", apiErr.Details)
+	}
+	s.expect(400, "POST", "/auth/travel-details", u.Token, gin.H{"place": "Manali"})
+
+	w := s.expect(200, "GET", "/auth/travel-details", u.Token, nil)
+	plans := decode[paging.Page[db.ListUserPlansRow]](t, w).Items
+	if len(plans) != 1 || plans[0].Place != "Manali" || !plans[0].Pets {
+		t.Fatalf("unexpected plans %+v", plans)
+	}
+}
+
+func TestListTravelDetails(t *testing.T) {
+	s := newTestServer(t)
+	u := s.signup("rhea")
+
+	for _, p := range []struct{ place, start, tripType string }{
+		{"Manali", "2025-03-01", "adventure"},
+		{"Goa", "2025-01-15", "leisure"},
+		{"Manali Old Town", "2025-06-10", "leisure"},
+	} {
+		s.expect(200, "POST", "/auth/travel-details", u.Token, gin.H{
+			"place": p.place, "start_date": p.start, "end_date": p.start, "trip_type": p.tripType, "interests": []string{"food"},
+		})
+	}
+
+	places := func(path string) []string {
+		t.Helper()
+		w := s.expect(200, "GET", path, u.Token, nil)
+		var places []string
+		for _, p := range decode[paging.Page[db.ListUserPlansRow]](t, w).Items {
+			places = append(places, p.Place)
+		}
+		return places
+	}
+
+	for path, want := range map[string][]string{
+		"/auth/travel-details":                                   {"Manali", "Goa", "Manali Old Town"},
+		"/auth/travel-details?sort=start_date":                   {"Goa", "Manali", "Manali Old Town"},
+		"/auth/travel-details?place=manali&sort=-start_date":     {"Manali Old Town", "Manali"},
+		"/auth/travel-details?trip_type=leisure&from=2025-02-01": {"Manali Old Town"},
+		"/auth/travel-details?from=2025-01-01&to=2025-03-01":     {"Manali", "Goa"},
+		"/auth/travel-details?sort=-created_at&to=2025-12-31":    {"Manali Old Town", "Goa", "Manali"},
+	} {
+		if got := places(path); !slices.Equal(got, want) {
+			t.Fatalf("%s: got %v, want %v", path, got, want)
+		}
+	}
+
+	// the next link keeps the sort and filters
+	w := s.expect(200, "GET", "/auth/travel-details?sort=start_date&limit=2", u.Token, nil)
+	page := decode[paging.Page[db.ListUserPlansRow]](t, w)
+	if len(page.Items) != 2 || page.Limit != 2 || page.Next == "" {
+		t.Fatalf("unexpected first page %+v", page)
+	}
+	if got := places(page.Next); !slices.Equal(got, []string{"Manali Old Town"}) {
+		t.Fatalf("unexpected second page %v", got)
+	}
+
+	apiErr := s.expectError(400, utils.CodeMalformedURL, "GET", "/auth/travel-details?from=01-03-2025", u.Token, nil)
+	if len(apiErr.Details) != 1 || apiErr.Details[0].Field != "from" {
+		t.Fatalf("unexpected details %+v", apiErr.Details)
+	}
+}
+
+func TestTravelGroups(t *testing.T) {
+	s := newTestServer(t)
+	owner := s.signup("owner")
+	member := s.signup("member")
+
+	groupID := s.group(owner, "Spiti")
+
+	// one group per creator and plan
+	group, _ := s.store.GetGroupByID(context.Background(), groupID)
+	s.expectError(409, utils.CodeGroupExists, "POST", "/auth/travel-group", owner.Token, gin.H{
+		"name": "again", "description": "dup", "plan_id": group.PlanID.String(),
+	})
+	s.expectError(400, utils.CodeValidationFailed, "POST", "/auth/travel-group", owner.Token, gin.H{
+		"name": "bad", "description": "bad", "plan_id": "not-a-uuid",
+	})
+	s.expectError(404, utils.CodePlanNotFound, "POST", "/auth/travel-group", owner.Token, gin.H{
+		"name": "bad", "description": "bad", "plan_id": uuid.NewString(),
+	})
+	s.expect(400, "POST", "/auth/travel-group", owner.Token, gin.H{"name": "missing"})
+
+	w := s.expect(200, "GET", "/auth/travel-group/", owner.Token, nil)
+	groups := decode[paging.Page[db.ListUserGroupsRow]](t, w).Items
+	if len(groups) != 1 || groups[0].ID != groupID {
+		t.Fatalf("unexpected groups %+v", groups)
+	}
+
+	// only the creator may edit
+	update := gin.H{"name": "Spiti Valley", "plan_id": group.PlanID.String()}
+	s.expectError(403, utils.CodeNotGroupCreator, "PUT", "/auth/travel-group/"+groupID.String(), member.Token, update)
+	s.expectError(400, utils.CodeMalformedURL, "PUT", "/auth/travel-group/not-a-uuid", owner.Token, update)
+	s.expectError(404, utils.CodeGroupNotFound, "PUT", "/auth/travel-group/"+uuid.NewString(), owner.Token, update)
+	s.expect(204, "PUT", "/auth/travel-group/"+groupID.String(), owner.Token, update)
+
+	group, _ = s.store.GetGroupByID(context.Background(), groupID)
+	if group.Name != "Spiti Valley" || group.Description != "a group" {
+		t.Fatalf("unexpected group after update %+v", group)
+	}
+
+	// members
+	membersPath := "/auth/travel-group/" + groupID.String() + "/member"
+	// only the creator adds members, joining otherwise goes through a request
+	s.expectError(403, utils.CodeNotGroupCreator, "POST", membersPath+"/"+member.ID.String(), member.Token, nil)
+	s.expectError(404, utils.CodeGroupNotFound, "POST", "/auth/travel-group/"+uuid.NewString()+"/member/"+member.ID.String(), owner.Token, nil)
+	s.expect(200, "POST", membersPath+"/"+member.ID.String(), owner.Token, nil)
+	s.expectError(409, utils.CodeMemberExists, "POST", membersPath+"/"+member.ID.String(), owner.Token, nil)
+	s.expect(400, "POST", membersPath+"/not-a-uuid", owner.Token, nil)
+	s.expectError(404, utils.CodeUserNotFound, "POST", membersPath+"/"+uuid.NewString(), owner.Token, nil)
+
+	w = s.expect(200, "GET", membersPath, member.Token, nil)
+	members := decode[paging.Page[db.ListGroupMembersRow]](t, w).Items
+	if len(members) != 2 || members[0].ID != owner.ID || members[1].ID != member.ID {
+		t.Fatalf("unexpected members %+v", members)
+	}
+	s.expect(400, "GET", "/auth/travel-group/not-a-uuid/member", owner.Token, nil)
+
+	w = s.expect(200, "GET", membersPath+"?name=MEM&sort=-name", member.Token, nil)
+	if members := decode[paging.Page[db.ListGroupMembersRow]](t, w).Items; len(members) != 1 || members[0].ID != member.ID {
+		t.Fatalf("unexpected filtered members %+v", members)
+	}
+
+	// groups of the member by the place of their plan
+	other := s.group(member, "Zanskar")
+	for path, want := range map[string][]uuid.UUID{
+		"/auth/travel-group/?sort=-name":      {other, groupID},
+		"/auth/travel-group/?place=spiti":     {groupID},
+		"/auth/travel-group/?from=2026-01-01": nil,
+	} {
+		w = s.expect(200, "GET", path, member.Token, nil)
+		var got []uuid.UUID
+		for _, g := range decode[paging.Page[db.ListUserGroupsRow]](t, w).Items {
+			got = append(got, g.ID)
+		}
+		if !slices.Equal(got, want) {
+			t.Fatalf("%s: got %v, want %v", path, got, want)
+		}
+	}
+	s.expectError(400, utils.CodeMalformedURL, "GET", "/auth/travel-group/?sort=place", member.Token, nil)
+
+	s.expectError(403, utils.CodeNotGroupCreator, "DELETE", membersPath+"/"+owner.ID.String(), member.Token, nil)
+	s.expect(204, "DELETE", membersPath+"/"+member.ID.String(), owner.Token, nil)
+
+	w = s.expect(200, "GET", "/auth/travel-group/", member.Token, nil)
+	if groups := decode[paging.Page[db.ListUserGroupsRow]](t, w).Items; len(groups) != 1 || groups[0].ID != other {
+		t.Fatalf("removed member still sees %+v", groups)
+	}
+
+	// deleting takes the memberships with it
+	s.expectError(403, utils.CodeNotGroupCreator, "DELETE", "/auth/travel-group/"+groupID.String(), member.Token, nil)
+	s.expect(204, "DELETE", "/auth/travel-group/"+groupID.String(), owner.Token, nil)
+	s.expectError(404, utils.CodeGroupNotFound, "DELETE", "/auth/travel-group/"+groupID.String(), owner.Token, nil)
+
+	if members, _ := s.store.GetGroupUsersDetails(context.Background(), groupID); len(members) != 0 {
+		t.Fatalf("members of deleted group remain %+v", members)
+	}
+}
+
+func TestGroupRequests(t *testing.T) {
+	s := newTestServer(t)
+	owner := s.signup("host")
+	sender := s.signup("guest")
+	groupID := s.group(owner, "Hampi")
+
+	requestPath := "/auth/travel-group/" + groupID.String() + "/request"
+
+	s.expectError(400, utils.CodeMalformedURL, "POST", "/auth/travel-group/not-a-uuid/request", sender.Token, nil)
+	s.expectError(404, utils.CodeGroupNotFound, "POST", "/auth/travel-group/"+uuid.NewString()+"/request", sender.Token, nil)
+	s.expect(200, "POST", requestPath, sender.Token, nil)
+	s.expectError(409, utils.CodeRequestPending, "POST", requestPath, sender.Token, nil)
+
+	w := s.expect(200, "GET", requestPath, owner.Token, nil)
+	requests := decode[paging.Page[db.ListGroupRequestsRow]](t, w).Items
+	if len(requests) != 1 || requests[0].SenderID != sender.ID || requests[0].Name != "Hampi" {
+		t.Fatalf("unexpected requests %+v", requests)
+	}
+
+	// filtered to one group, the path's group isn't used
+	other := s.group(owner, "Gokarna")
+	w = s.expect(200, "GET", requestPath+"?group_id="+other.String(), owner.Token, nil)
+	if requests := decode[paging.Page[db.ListGroupRequestsRow]](t, w).Items; len(requests) != 0 {
+		t.Fatalf("unexpected requests %+v", requests)
+	}
+	s.expectError(400, utils.CodeMalformedURL, "GET", requestPath+"?group_id=not-a-uuid", owner.Token, nil)
+
+	actionPath := requestPath + "/" + sender.ID.String()
+	apiErr := s.expectError(400, utils.CodeValidationFailed, "POST", actionPath, owner.Token, gin.H{"actions": "maybe"})
+	if len(apiErr.Details) != 1 || apiErr.Details[0].Field != "actions" {
+		t.Fatalf("unexpected details %+v", apiErr.Details)
+	}
+	s.expect(400, "POST", actionPath, owner.Token, gin.H{})
+	s.expectError(400, utils.CodeMalformedURL, "POST", requestPath+"/not-a-uuid", owner.Token, gin.H{"actions": "reject"})
+
+	// only the creator answers requests, not the sender or anyone else
+	s.expectError(403, utils.CodeNotGroupCreator, "POST", actionPath, sender.Token, gin.H{"actions": "accept"})
+	s.expectError(403, utils.CodeNotGroupCreator, "POST", actionPath, s.signup("stranger").Token, gin.H{"actions": "reject"})
+	s.expectError(404, utils.CodeGroupNotFound, "POST", "/auth/travel-group/"+uuid.NewString()+"/request/"+sender.ID.String(), owner.Token, gin.H{"actions": "reject"})
+	if members, _ := s.store.GetGroupUsersDetails(context.Background(), groupID); len(members) != 1 {
+		t.Fatalf("non-creator added a member %+v", members)
+	}
+
+	// rejecting removes the request
+	s.expect(201, "POST", actionPath, owner.Token, gin.H{"actions": "reject"})
+	w = s.expect(200, "GET", requestPath, owner.Token, nil)
+	if requests := decode[paging.Page[db.ListGroupRequestsRow]](t, w).Items; len(requests) != 0 {
+		t.Fatalf("rejected request still listed %+v", requests)
+	}
+
+	// accepting adds the sender, marks the request and mails them
+	s.expect(200, "POST", requestPath, sender.Token, nil)
+	s.expect(201, "POST", actionPath, owner.Token, gin.H{"actions": "accept"})
+
+	members, _ := s.store.GetGroupUsersDetails(context.Background(), groupID)
+	if len(members) != 2 {
+		t.Fatalf("accepted sender not added, members %+v", members)
+	}
+	mail, ok := s.mailTo(sender.Email)
+	if !ok || mail.Subject == "" {
+		t.Fatalf("no acceptance mail queued for %s", sender.Email)
+	}
+	w = s.expect(200, "GET", requestPath, owner.Token, nil)
+	if requests := decode[paging.Page[db.ListGroupRequestsRow]](t, w).Items; len(requests) != 0 {
+		t.Fatalf("accepted request still pending %+v", requests)
+	}
+
+	// nothing left to accept
+	s.expectError(404, utils.CodeRequestNotFound, "POST", actionPath, owner.Token, gin.H{"actions": "accept"})
+}
+
+func TestAcceptRequestRollback(t *testing.T) {
+	s := newTestServer(t)
+	owner := s.signup("lead")
+	sender := s.signup("joiner")
+	groupID := s.group(owner, "Coorg")
+
+	requestPath := "/auth/travel-group/" + groupID.String() + "/request"
+	s.expect(200, "POST", requestPath, sender.Token, nil)
+
+	// already added directly, so accepting conflicts and nothing is kept
+	s.expect(200, "POST", "/auth/travel-group/"+groupID.String()+"/member/"+sender.ID.String(), owner.Token, nil)
+	verification, _ := s.mailTo(sender.Email)
+	s.expectError(409, utils.CodeMemberExists, "POST", requestPath+"/"+sender.ID.String(), owner.Token, gin.H{"actions": "accept"})
+
+	w := s.expect(200, "GET", requestPath, owner.Token, nil)
+	if requests := decode[paging.Page[db.ListGroupRequestsRow]](t, w).Items; len(requests) != 1 {
+		t.Fatalf("request status not rolled back %+v", requests)
+	}
+	if mail, _ := s.mailTo(sender.Email); mail.ID != verification.ID {
+		t.Fatalf("acceptance mail queued despite rollback: %+v", mail)
+	}
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/ErebusAJ/YatraBandhu/internals/limiter"
+	"github.com/ErebusAJ/YatraBandhu/internals/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// quotaUsage
+// one period of a quota as sent to clients, limit and
+// remaining are null when the period is unlimited
+type quotaUsage struct{
+	Period		string		`json:"period" enum:"day month"`
+	Used		int			`json:"used"`
+	Limit		*int		`json:"limit"`
+	Remaining	*int		`json:"remaining"`
+	ResetsAt	time.Time	`json:"resets_at" doc:"start of the next period, UTC"`
+}
+
+// usageResponse
+// body of GET /auth/usage
+type usageResponse struct{
+	Role		string			`json:"role"`
+	AIPlanner	[]quotaUsage	`json:"ai_planner" doc:"generations in the current day and month"`
+}
+
+
+// plannerLimits
+// daily and monthly AI planner quotas of a role
+func(cfg *apiConfig) plannerLimits(role string) []limiter.Limit{
+	limits := cfg.Config.Limits
+	daily, monthly := limits.UserDaily, limits.UserMonthly
+	switch role{
+	case utils.RoleGuide:
+		daily, monthly = limits.GuideDaily, limits.GuideMonthly
+	case utils.RoleAdmin:
+		daily, monthly = limits.AdminDaily, limits.AdminMonthly
+	}
+
+	return []limiter.Limit{{Period: limiter.Day, Max: daily}, {Period: limiter.Month, Max: monthly}}
+}
+
+
+// usePlannerQuota
+// counts one AI planner generation for the logged in user,
+// answers 429 with Retry-After and returns false once a quota is used up
+func(cfg *apiConfig) usePlannerQuota(c *gin.Context, userID uuid.UUID) bool{
+	usage, ok, err := cfg.Quotas.Use(c, plannerQuotaKey(userID), cfg.plannerLimits(c.GetString("userRole")))
+	if err != nil{
+		utils.ErrorJSON(c, 500, "error counting planner quota", utils.InternalError, err)
+		return false
+	}
+	if !ok{
+		wait := limiter.RetryAfter(usage, time.Now())
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		utils.SendError(c, utils.ErrQuotaExceeded, "planner quota exceeded", nil)
+		return false
+	}
+	return true
+}
+
+
+// refundPlannerQuota
+// gives back a generation that failed on our side or was cancelled,
+// refunds only use the periods which are the same for every role
+func(cfg *apiConfig) refundPlannerQuota(ctx context.Context, userID uuid.UUID){
+	err := cfg.Quotas.Refund(ctx, plannerQuotaKey(userID), cfg.plannerLimits(utils.RoleUser))
+	if err != nil{
+		slog.ErrorContext(ctx, "error refunding planner quota", "user_id", userID, "error", err)
+	}
+}
+
+
+// getUsage
+// AI planner quotas of the logged in user and how much is used
+func(cfg *apiConfig) getUsage(c *gin.Context){
+	tempID, exists := c.Get("userID")
+	if !exists {
+		utils.ErrorJSON(c, 401, utils.MiddlewareError, utils.UnauthorizedError, nil)
+		return
+	}
+	userID := tempID.(uuid.UUID)
+
+	role := c.GetString("userRole")
+	if role == ""{
+		role = utils.RoleUser
+	}
+
+	usage, err := cfg.Quotas.Usage(c, plannerQuotaKey(userID), cfg.plannerLimits(role))
+	if err != nil{
+		utils.ErrorJSON(c, 500, "error reading planner quota", utils.InternalError, err)
+		return
+	}
+
+	res := usageResponse{Role: role, AIPlanner: []quotaUsage{}}
+	for _, u := range usage{
+		q := quotaUsage{Period: string(u.Period), Used: u.Used, ResetsAt: u.ResetsAt}
+		if u.Max > 0{
+			limit, remaining := u.Max, u.Remaining()
+			q.Limit, q.Remaining = &limit, &remaining
+		}
+		res.AIPlanner = append(res.AIPlanner, q)
+	}
+
+	c.IndentedJSON(200, res)
+}
+
+
+func plannerQuotaKey(userID uuid.UUID) string{
+	return "planner:" + userID.String()
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/ErebusAJ/YatraBandhu/internals/utils"
+	"github.com/gin-gonic/gin"
+)
+
+func TestPlannerQuota(t *testing.T) {
+	pipeline := &testPipeline{}
+	s := newTestServer(t, func(cfg *apiConfig) {
+		cfg.Config.Limits.UserDaily, cfg.Config.Limits.UserMonthly = 2, 10
+		cfg.Config.Limits.PlannerPerHour = 0
+		cfg.PlannerJobs.Pipeline = pipeline
+	})
+	u := s.signup("quota")
+	body := gin.H{"location": "Goa", "interests": "beaches", "days": 3}
+
+	s.expect(202, "POST", "/auth/ai-planner", u.Token, body)
+	s.work()
+
+	// failed and cancelled generations are given back
+	pipeline.fail = errors.New("provider down")
+	s.expect(202, "POST", "/auth/ai-planner", u.Token, body)
+	s.work()
+	pipeline.fail = nil
+	job := decode[plannerJobResponse](t, s.expect(202, "POST", "/auth/ai-planner", u.Token, body))
+	s.expect(200, "DELETE", "/auth/ai-planner/jobs/"+job.ID.String(), u.Token, nil)
+
+	w := s.expect(200, "GET", "/auth/usage", u.Token, nil)
+	usage := decode[usageResponse](t, w)
+	if usage.Role != utils.RoleUser || len(usage.AIPlanner) != 2 {
+		t.Fatalf("unexpected usage %+v", usage)
+	}
+	day := usage.AIPlanner[0]
+	if day.Period != "day" || day.Used != 1 || day.Limit == nil || *day.Limit != 2 || *day.Remaining != 1 || day.ResetsAt.IsZero() {
+		t.Fatalf("unexpected daily usage %+v", day)
+	}
+
+	s.expect(202, "POST", "/auth/ai-planner", u.Token, body)
+	w = s.expect(429, "POST", "/auth/ai-planner", u.Token, body)
+	if decode[utils.APIError](t, w).Code != utils.CodeQuotaExceeded {
+		t.Fatalf("expected %s, got %s", utils.CodeQuotaExceeded, w.Body)
+	}
+	if wait, err := strconv.Atoi(w.Header().Get("Retry-After")); err != nil || wait <= 0 || wait > 24*60*60 {
+		t.Fatalf("unexpected Retry-After %q", w.Header().Get("Retry-After"))
+	}
+
+	// refused generations are not counted either
+	usage = decode[usageResponse](t, s.expect(200, "GET", "/auth/usage", u.Token, nil))
+	if month := usage.AIPlanner[1]; month.Period != "month" || month.Used != 2 || *month.Remaining != 8 {
+		t.Fatalf("unexpected monthly usage %+v", month)
+	}
+
+	// admins are unlimited by default
+	admin := s.withRole(s.signup("quotaadmin"), utils.RoleAdmin)
+	for range 3 {
+		s.expect(202, "POST", "/auth/ai-planner", admin.Token, body)
+	}
+	usage = decode[usageResponse](t, s.expect(200, "GET", "/auth/usage", admin.Token, nil))
+	if day := usage.AIPlanner[0]; usage.Role != utils.RoleAdmin || day.Used != 3 || day.Limit != nil || day.Remaining != nil {
+		t.Fatalf("unexpected admin usage %+v", usage)
+	}
+}
+
+func TestRateLimits(t *testing.T) {
+	s := newTestServer(t, func(cfg *apiConfig) {
+		cfg.Config.Limits.PlannerPerHour, cfg.Config.Limits.PlannerBurst = 1, 1
+	})
+	u := s.signup("hasty")
+	body := gin.H{"location": "Goa", "interests": "beaches", "days": 3}
+
+	s.expect(202, "POST", "/auth/ai-planner", u.Token, body)
+	w := s.expect(429, "POST", "/auth/ai-planner", u.Token, body)
+	if decode[utils.APIError](t, w).Code != utils.CodeTooManyRequests || w.Header().Get("Retry-After") != "3600" {
+		t.Fatalf("throttled planner without Retry-After: %v %s", w.Header(), w.Body)
+	}
+
+	// the planner limit is per user
+	other := s.signup("patient")
+	s.expect(202, "POST", "/auth/ai-planner", other.Token, body)
+
+	s = newTestServer(t, func(cfg *apiConfig) {
+		cfg.Config.Limits.IPPerMinute, cfg.Config.Limits.IPBurst = 60, 2
+	})
+	s.expect(401, "GET", "/auth/usage", "", nil)
+	s.expect(401, "GET", "/auth/usage", "", nil)
+	w = s.expect(429, "GET", "/auth/usage", "", nil)
+	if w.Header().Get("Retry-After") != "1" || w.Header().Get("X-RateLimit-Remaining") != "0" {
+		t.Fatalf("unexpected rate limit headers %v", w.Header())
+	}
+
+	// a spoofed X-Forwarded-For is the same client
+	if w := s.forwarded("203.0.113.7", "GET", "/auth/usage", nil); w.Code != 429 {
+		t.Fatalf("spoofed ip not limited, got status %d", w.Code)
+	}
+
+	// health checks are not limited
+	s.expect(200, "GET", "/healthz", "", nil)
+}
@@ -1,29 +1,46 @@
 package handlers
 
 import (
-	"context"
 	"database/sql"
-	"fmt"
-	"log"
+	"html/template"
+	"log/slog"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/ErebusAJ/YatraBandhu/internals/db"
+	"github.com/ErebusAJ/YatraBandhu/internals/limiter"
+	"github.com/ErebusAJ/YatraBandhu/internals/mailer"
+	"github.com/ErebusAJ/YatraBandhu/internals/outbox"
+	"github.com/ErebusAJ/YatraBandhu/internals/store"
 	"github.com/ErebusAJ/YatraBandhu/internals/utils"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// Password reset link lifetime
+const passwordResetTTL = 15 * time.Minute
+
+// dummyPasswordHash
+// compared against on logins with an unknown email so they take as
+// long as a wrong password, bcrypt.DefaultCost like HashPassword
+var dummyPasswordHash = []byte("$2a$10$iJQz3QdZTCZ6YD4qkiy.QOBuktWecq8tAIh5OOIgmEuuPTaWLiOBa")
+
+// registerUserRequest
+// body of POST /v1/register
+type registerUserRequest struct{
+	Name 		string	`json:"name" binding:"required"`
+	Age			int		`json:"age" binding:"required"`
+	PhoneNum	string	`json:"phone_no" binding:"required"`
+	Email		string	`json:"email" binding:"required,email"`
+	Password	string	`json:"password" binding:"required"`
+}
+
 // registerUser
 // Registers user's details to server
 func(cfg *apiConfig) registerUser(c *gin.Context){
-	var reqDetails struct{
-		Name 		string	`json:"name" binding:"required"`
-		Age			int		`json:"age" binding:"required"`
-		PhoneNum	string	`json:"phone_no" binding:"required"`
-		Email		string	`json:"email" binding:"required,email"`
-		Password	string	`json:"password" binding:"required"`
-	}
+	var reqDetails registerUserRequest
 
 	err := c.ShouldBind(&reqDetails)
 	if err != nil{
@@ -37,29 +54,42 @@ func(cfg *apiConfig) registerUser(c *gin.Context){
 		return
 	}
 
-	err = cfg.DB.RegisterUser(c, db.RegisterUserParams{
-		Name: reqDetails.Name,
-		Age: int32(reqDetails.Age),
-		PhoneNumber: reqDetails.PhoneNum,
-		Email: reqDetails.Email,
-		PasswordHash: hashedPass,
+	// user and verification mail are saved together
+	err = cfg.DB.InTx(c, func(q store.Store) error{
+		userID, err := q.RegisterUser(c, db.RegisterUserParams{
+			Name: reqDetails.Name,
+			Age: int32(reqDetails.Age),
+			PhoneNumber: reqDetails.PhoneNum,
+			Email: reqDetails.Email,
+			PasswordHash: hashedPass,
+		})
+		if err != nil{
+			return err
+		}
+
+		return cfg.sendVerification(c, q, userID, reqDetails.Email)
 	})
 	if err != nil{
-		utils.ErrorJSON(c, 500, utils.DatabaseError, utils.InternalError, err)
+		utils.DBErrorJSON(c, err, nil)
 		return
 	}
 
-	c.IndentedJSON(201, utils.MessageObj("user successfully registered"))
+	c.IndentedJSON(201, utils.MessageObj("user successfully registered, verification link sent"))
 }
 
 
+// loginRequest
+// body of POST /v1/login
+type loginRequest struct{
+	Email		string	`json:"email" binding:"required"`
+	Password 	string	`json:"password" binding:"required"`
+}
+
 // loginUser
 // logs in a user and returns a JWT
+// failed attempts are limited per account and per client ip
 func(cfg *apiConfig) loginUser(c *gin.Context){
-	var reqDetails struct{
-		Email		string	`json:"email" binding:"required"`
-		Password 	string	`json:"password" binding:"required"`
-	}
+	var reqDetails loginRequest
 
 	err := c.ShouldBind(&reqDetails)
 	if err != nil{
@@ -67,25 +97,106 @@ func(cfg *apiConfig) loginUser(c *gin.Context){
 		return
 	}
 
+	accountKey := strings.ToLower(reqDetails.Email)
+	ipKey := c.ClientIP()
+
+	// reject early while either key is backing off or locked
+	for _, check := range []struct{
+		limiter	limiter.LoginLimiter
+		key		string
+	}{{cfg.AccountLimiter, accountKey}, {cfg.IPLimiter, ipKey}}{
+		wait, err := check.limiter.Check(c, check.key)
+		if err != nil{
+			utils.ErrorJSON(c, 500, "error checking login limiter", utils.InternalError, err)
+			return
+		}
+		if wait > 0{
+			c.Header("Retry-After", strconv.Itoa(int(wait.Seconds()) + 1))
+			utils.ErrorJSON(c, 429, "login attempt throttled", utils.TooManyAttemptsError, nil)
+			return
+		}
+	}
+
 	user, err := cfg.DB.GetUserByEmail(c, reqDetails.Email)
-	if err != nil || err == sql.ErrNoRows{
-		utils.ErrorJSON(c, 401, utils.UnauthorizedError, utils.UnauthorizedError, err)
+	if err != nil && err != sql.ErrNoRows{
+		utils.DBErrorJSON(c, err, nil)
 		return
 	}
-	log.Printf("Entered Password: %v", reqDetails.Password)
-	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(reqDetails.Password))
+
+	if err == nil{
+		err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(reqDetails.Password))
+	}else{
+		// same bcrypt work as for a known email, err stays ErrNoRows
+		_ = bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(reqDetails.Password))
+	}
 	if err != nil{
-		utils.ErrorJSON(c, 401, utils.UnauthorizedError, utils.UnauthorizedError, err)
+		cfg.recordLoginFailure(c, accountKey, ipKey, user.Email)
+		cfg.recordAuditAfter(c, auditEvent{
+			Action: auditLoginFailed,
+			SubjectID: user.ID,
+			Metadata: gin.H{"email": reqDetails.Email, "reason": "invalid credentials"},
+		})
+		utils.SendError(c, utils.ErrInvalidCredentials, utils.UnauthorizedError, err)
 		return
 	}
-	
-	token, err := utils.GenerateJWT(user.ID, user.AccessLevel.String)
+
+	if user.SuspendedAt.Valid{
+		cfg.recordAuditAfter(c, auditEvent{
+			Action: auditLoginFailed,
+			SubjectID: user.ID,
+			Metadata: gin.H{"email": reqDetails.Email, "reason": "suspended"},
+		})
+		utils.ErrorJSON(c, 403, utils.SuspendedError, utils.SuspendedError, nil)
+		return
+	}
+
+	err = cfg.AccountLimiter.Reset(c, accountKey)
+	if err != nil{
+		slog.ErrorContext(c, "error resetting login limiter", "error", err)
+	}
+
+	err = cfg.DB.UpdateLastLoggedIn(c, user.ID)
+	if err != nil{
+		slog.ErrorContext(c, utils.DatabaseError, "error", err)
+	}
+
+	tokens, err := cfg.issueTokens(c, user.ID, user.AccessLevel.String, user.TokenVersion.Int32)
 	if err != nil{
 		utils.ErrorJSON(c, 500, "error generating token", utils.InternalError, err)
 		return
 	}
 
-	c.IndentedJSON(200, gin.H{"token":token})
+	cfg.recordAuditAfter(c, auditEvent{Action: auditLogin, ActorID: user.ID, SubjectID: user.ID})
+
+	c.IndentedJSON(200, tokens)
+}
+
+
+// recordLoginFailure
+// counts a failed login against the account and ip
+// notifies the account owner when the account gets locked
+func(cfg *apiConfig) recordLoginFailure(c *gin.Context, accountKey, ipKey, email string){
+	_, err := cfg.IPLimiter.Fail(c, ipKey)
+	if err != nil{
+		slog.ErrorContext(c, "error recording login failure", "error", err)
+	}
+
+	status, err := cfg.AccountLimiter.Fail(c, accountKey)
+	if err != nil{
+		slog.ErrorContext(c, "error recording login failure", "error", err)
+		return
+	}
+
+	// email is empty when no such account exists
+	if status.Locked && email != ""{
+		err = outbox.Enqueue(c, cfg.DB, email, mailer.TemplateAccountLocked, gin.H{
+			"Until": status.BlockedUntil.Format(time.RFC1123),
+			"Failures": status.Failures,
+		})
+		if err != nil{
+			slog.ErrorContext(c, "unable to queue lockout mail", "error", err)
+		}
+	}
 }
 
 
@@ -102,7 +213,7 @@ func(cfg *apiConfig) getUserByID(c *gin.Context){
 
 	user, err := cfg.DB.GetUserByID(c, userID)
 	if err != nil{
-		utils.ErrorJSON(c, 500, utils.DatabaseError, utils.InternalError, err)
+		utils.DBErrorJSON(c, err, utils.ErrUserNotFound)
 		return 
 	}
 
@@ -110,16 +221,21 @@ func(cfg *apiConfig) getUserByID(c *gin.Context){
 }
 
 
+// updateUserRequest
+// body of PUT /auth/user, empty fields are left unchanged
+// new_password requires old_password
+type updateUserRequest struct{
+	Name		string 	`json:"name"`
+	OldPass		string	`json:"old_password"`
+	NewPass 	string	`json:"new_password"`
+	PhoneNum	string 	`json:"phone_no"`
+}
+
 // updateUser
 // Updates a existing user details
 func(cfg *apiConfig) updateUser(c *gin.Context){
 	// binding request json body
-	var reqDetails struct{
-		Name		string 	`json:"name"`
-		OldPass		string	`json:"old_password"`
-		NewPass 	string	`json:"new_password"`
-		PhoneNum	string 	`json:"phone_no"`
-	}
+	var reqDetails updateUserRequest
 	err := c.ShouldBind(&reqDetails)
 	if err != nil{
 		utils.ErrorJSON(c, 400, utils.RequestBodyError, utils.JSONError, err)
@@ -136,7 +252,7 @@ func(cfg *apiConfig) updateUser(c *gin.Context){
 	// retreive user to fill up empty values if any
 	user, err := cfg.DB.GetUserByID(c, userID)
 	if err != nil {
-		utils.ErrorJSON(c, 500, utils.DatabaseError, utils.InternalError, err)
+		utils.DBErrorJSON(c, err, utils.ErrUserNotFound)
 		return
 	}
 
@@ -144,7 +260,12 @@ func(cfg *apiConfig) updateUser(c *gin.Context){
 	if reqDetails.NewPass != ""{
 		err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(reqDetails.OldPass))
 		if err != nil{
-			utils.ErrorJSON(c, 400, "old password doesn't match", utils.UnauthorizedError, err)
+			utils.SendError(c, &utils.APIError{
+				Status: 400,
+				Code: utils.CodeValidationFailed,
+				Message: "old password doesn't match",
+				Details: []utils.FieldError{{Field: "old_password", Message: "doesn't match"}},
+			}, "old password doesn't match", err)
 			return
 		}
 		hashedPass, err = utils.HashPassword(reqDetails.NewPass)
@@ -172,10 +293,21 @@ func(cfg *apiConfig) updateUser(c *gin.Context){
 		ID: userID,
 	})
 	if err != nil{
-		utils.ErrorJSON(c, 500, utils.DatabaseError, utils.InternalError, err)
+		utils.DBErrorJSON(c, err, nil)
 		return
 	}
 
+	// password changed, sign out every existing session
+	if reqDetails.NewPass != ""{
+		err = cfg.revokeSessions(c, userID)
+		if err != nil{
+			utils.DBErrorJSON(c, err, nil)
+			return
+		}
+
+		cfg.recordAuditAfter(c, auditEvent{Action: auditPasswordChange, ActorID: userID, SubjectID: userID})
+	}
+
 	c.IndentedJSON(204, utils.MessageObj("updated success"))
 }
 
@@ -185,14 +317,14 @@ func(cfg *apiConfig) updateUser(c *gin.Context){
 func(cfg *apiConfig) deleteUser(c *gin.Context){
 	tempID, exists := c.Get("userID")
 	if !exists {
-		utils.ErrorJSON(c, 400, utils.MiddlewareError, utils.UnauthorizedError, nil)
+		utils.ErrorJSON(c, 401, utils.MiddlewareError, utils.UnauthorizedError, nil)
 		return
 	}
 	userID := tempID.(uuid.UUID)
 
 	err := cfg.DB.DeleteUser(c, userID)
 	if err != nil{
-		utils.ErrorJSON(c, 500, utils.DatabaseError, utils.InternalError, err)
+		utils.DBErrorJSON(c, err, nil)
 		return
 	}
 	
@@ -200,13 +332,18 @@ func(cfg *apiConfig) deleteUser(c *gin.Context){
 }
 
 
+// passwordResetRequest
+// body of POST /v1/user/password-reset
+type passwordResetRequest struct{
+	Email	string	`json:"email" binding:"required"`
+}
+
 // resetPasswordRequest
 // Sends a request to reset password
 // takes user's email sends a email if users exists
+// responds the same whether or not the account exists
 func(cfg *apiConfig) resetPasswordRequest(c *gin.Context){
-	var reqDetails struct{
-		Email	string	`json:"email" binding:"required"`
-	}
+	var reqDetails passwordResetRequest
 
 	err := c.BindJSON(&reqDetails)
 	if err != nil{
@@ -214,45 +351,89 @@ func(cfg *apiConfig) resetPasswordRequest(c *gin.Context){
 		return
 	}
 
-	user, err := cfg.DB.GetUserByEmail(c, reqDetails.Email)
+	// limit requests per email, checked before the lookup so
+	// throttling doesn't reveal whether the account exists
+	resetKey := strings.ToLower(reqDetails.Email)
+	wait, err := cfg.ResetLimiter.Check(c, resetKey)
 	if err != nil{
-		utils.ErrorJSON(c, 500, utils.DatabaseError, utils.InternalError, err)
+		utils.ErrorJSON(c, 500, "error checking reset limiter", utils.InternalError, err)
+		return
+	}
+	if wait > 0{
+		c.Header("Retry-After", strconv.Itoa(int(wait.Seconds()) + 1))
+		utils.ErrorJSON(c, 429, "password reset throttled", utils.TooManyAttemptsError, nil)
 		return
 	}
+	_, err = cfg.ResetLimiter.Fail(c, resetKey)
+	if err != nil{
+		slog.ErrorContext(c, "error recording reset request", "error", err)
+	}
 
-	// Insert Token
-	token := uuid.NewString()
-	expires := time.Now().Add(15 * time.Minute)
+	response := utils.MessageObj("if the account exists a password reset link has been sent")
 
-	err = cfg.DB.InsertToken(c, db.InsertTokenParams{
-		UserID: user.ID,
-		Token: token,
-		ExpiresAt: expires,
-	})
+	user, err := cfg.DB.GetUserByEmail(c, reqDetails.Email)
+	if err == sql.ErrNoRows{
+		c.IndentedJSON(200, response)
+		return
+	}else if err != nil{
+		utils.DBErrorJSON(c, err, nil)
+		return
+	}
+
+	token, tokenHash, err := utils.GenerateOpaqueToken()
 	if err != nil{
-		utils.ErrorJSON(c, 500, utils.DatabaseError, utils.InternalError, err)
-		return 
+		utils.ErrorJSON(c, 500, "error generating token", utils.InternalError, err)
+		return
 	}
 
-	// Send Email with Reset Link
-	url := fmt.Sprintf("http://localhost:8080/v1/user/password-reset/%v", token)
-	err = utils.SendMail(reqDetails.Email, fmt.Sprintf("Password reset link: %v", url))
+	// Replace older tokens and queue email with reset link together
+	err = cfg.DB.InTx(c, func(q store.Store) error{
+		err := q.DeleteUserTokens(c, user.ID)
+		if err != nil{
+			return err
+		}
+
+		err = q.InsertToken(c, db.InsertTokenParams{
+			UserID: user.ID,
+			TokenHash: tokenHash,
+			ExpiresAt: time.Now().Add(passwordResetTTL),
+		})
+		if err != nil{
+			return err
+		}
+
+		data := gin.H{
+			"URL": strings.ReplaceAll(cfg.ResetURL, "{token}", token),
+			"ExpiresIn": "15 minutes",
+		}
+		// the app's scheme is checked by config.Validate, html/template
+		// would replace a custom one with #ZgotmplZ otherwise
+		if cfg.ResetAppURL != ""{
+			data["AppURL"] = template.URL(strings.ReplaceAll(cfg.ResetAppURL, "{token}", token))
+		}
+
+		return outbox.Enqueue(c, q, user.Email, mailer.TemplateReset, data)
+	})
 	if err != nil{
-		utils.ErrorJSON(c, 500, "unable to send mail", "error sending password reset link", err)
+		utils.DBErrorJSON(c, err, nil)
 		return
 	}
 
-	c.IndentedJSON(200, utils.MessageObj("password reset link sent!!!"))
+	c.IndentedJSON(200, response)
 }
 
 
+// passwordResetConfirmRequest
+// body of POST /v1/user/password-reset/:token
+type passwordResetConfirmRequest struct{
+	NewPassword	string `json:"new_password" binding:"required"` 
+}
+
 // resetPasswordConfirm
 // confirms the token from the previous request
 // updates password and deletes token
 func(cfg *apiConfig) resetPasswordConfirm(c *gin.Context){
-	var reqDetails struct{
-		NewPassword	string `json:"new_password" binding:"required"` 
-	}
+	var reqDetails passwordResetConfirmRequest
 
 	err := c.BindJSON(&reqDetails)
 	if err != nil{
@@ -260,42 +441,52 @@ func(cfg *apiConfig) resetPasswordConfirm(c *gin.Context){
 		return
 	}
 
-	tempToken := c.Param("token")
-
-	token, err := cfg.DB.GetUserToken(context.Background(), tempToken)
-	if err != nil{
-		utils.ErrorJSON(c, 500, utils.DatabaseError, utils.InternalError, err)
-		return 
-	}else if err == sql.ErrNoRows{
-		c.IndentedJSON(400, gin.H{"msg":"invalid token"})
-	}
-
-	if token.ExpiresAt.Before(time.Now()){
-		_ = cfg.DB.DeleteToken(c, token.Token)
-		utils.ErrorJSON(c, 400, "expired reset token", "invalid/expired token", nil)
-		return
-	}
-
 	hashedPass, err := utils.HashPassword(reqDetails.NewPassword)
 	if err != nil{
 		utils.ErrorJSON(c, 500, utils.ParsingError, utils.InternalError, err)
 		return
 	}
 
-	err = cfg.DB.UpdateUserPassword(context.Background(), db.UpdateUserPasswordParams{
-		ID: token.UserID,
-		PasswordHash: hashedPass,
+	// the token is deleted by the statement that checks it so concurrent
+	// confirms can't both use it, token_version is bumped by the update,
+	// drop refresh tokens and the other reset tokens of the user too
+	err = cfg.DB.InTx(c, func(q store.Store) error{
+		userID, err := q.ConsumeUserToken(c, utils.HashToken(c.Param("token")))
+		if err != nil{
+			return err
+		}
+
+		err = q.UpdateUserPassword(c, db.UpdateUserPasswordParams{
+			ID: userID,
+			PasswordHash: hashedPass,
+		})
+		if err != nil{
+			return err
+		}
+
+		err = q.RevokeUserRefreshTokens(c, userID)
+		if err != nil{
+			return err
+		}
+
+		err = q.DeleteUserTokens(c, userID)
+		if err != nil{
+			return err
+		}
+
+		return recordAudit(c, q, auditEvent{
+			Action: auditPasswordReset,
+			ActorID: userID,
+			SubjectID: userID,
+		})
 	})
-	if err != nil{
-		utils.ErrorJSON(c, 500, utils.DatabaseError, utils.InternalError, err)
+	if err == sql.ErrNoRows{
+		utils.ErrorJSON(c, 400, "unknown or expired reset token", utils.InvalidTokenError, err)
 		return
-	}
-
-	err = cfg.DB.DeleteToken(c, token.Token)
-	if err != nil{
-		utils.ErrorJSON(c, 500, utils.DatabaseError, utils.InternalError, err)
+	}else if err != nil{
+		utils.DBErrorJSON(c, err, nil)
 		return
 	}
 
 	c.IndentedJSON(200, utils.MessageObj("password updation success !!!"))
-}
\ No newline at end of file
+}
@@ -0,0 +1,287 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ErebusAJ/YatraBandhu/internals/db"
+	"github.com/ErebusAJ/YatraBandhu/internals/limiter"
+	"github.com/ErebusAJ/YatraBandhu/internals/utils"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	verifyLink = regexp.MustCompile(`/v1/verify/(\S+)`)
+	resetLink  = regexp.MustCompile(`/v1/user/password-reset/(\S+)`)
+)
+
+func TestRegisterUser(t *testing.T) {
+	s := newTestServer(t)
+	u := s.signup("asha")
+
+	if _, ok := s.mailTo(u.Email); !ok {
+		t.Fatalf("no verification mail queued for %s", u.Email)
+	}
+
+	// invalid body, reported per field
+	apiErr := s.expectError(400, utils.CodeValidationFailed, "POST", "/v1/register", "", gin.H{"name": "x", "email": "not-an-email"})
+	fields := map[string]string{}
+	for _, d := range apiErr.Details {
+		fields[d.Field] = d.Message
+	}
+	if fields["email"] != "failed email check" || fields["age"] != "is required" || fields["password"] != "is required" {
+		t.Fatalf("unexpected details %+v", apiErr.Details)
+	}
+	apiErr = s.expectError(400, utils.CodeValidationFailed, "POST", "/v1/register", "", gin.H{"name": "x", "age": "old"})
+	if len(apiErr.Details) != 1 || apiErr.Details[0].Field != "age" {
+		t.Fatalf("unexpected details %+v", apiErr.Details)
+	}
+
+	// too young, rejected by the age check constraint
+	apiErr = s.expectError(400, utils.CodeValidationFailed, "POST", "/v1/register", "", gin.H{
+		"name": "kid", "age": 12, "phone_no": "8000000001", "email": "kid@example.com", "password": "secret",
+	})
+	if len(apiErr.Details) != 1 || apiErr.Details[0].Field != "age" {
+		t.Fatalf("unexpected details %+v", apiErr.Details)
+	}
+
+	// email and phone are unique
+	s.expectError(409, utils.CodeUserEmailTaken, "POST", "/v1/register", "", gin.H{
+		"name": "dup", "age": 30, "phone_no": "8000000000", "email": u.Email, "password": "secret",
+	})
+	s.expectError(409, utils.CodeUserPhoneTaken, "POST", "/v1/register", "", gin.H{
+		"name": "dup", "age": 30, "phone_no": u.Phone, "email": "other@example.com", "password": "secret",
+	})
+
+	// a failed registration leaves no user or mail behind
+	_, err := s.store.GetUserByEmail(context.Background(), "other@example.com")
+	if err == nil {
+		t.Fatalf("user from failed registration was stored")
+	}
+	if _, ok := s.mailTo("other@example.com"); ok {
+		t.Fatalf("mail from failed registration was queued")
+	}
+}
+
+func TestLoginUser(t *testing.T) {
+	s := newTestServer(t)
+	u := s.signup("ravi")
+
+	s.expect(400, "POST", "/v1/login", "", gin.H{"email": u.Email})
+	s.expectError(401, utils.CodeInvalidCredentials, "POST", "/v1/login", "", gin.H{"email": "nobody@example.com", "password": "password123"})
+
+	// unknown emails cost as much bcrypt work as real accounts
+	if cost, err := bcrypt.Cost(dummyPasswordHash); err != nil || cost != bcrypt.DefaultCost {
+		t.Fatalf("dummy hash has cost %d, %v", cost, err)
+	}
+
+	// three free failures, then backoff
+	for i := 0; i < 4; i++ {
+		s.expect(401, "POST", "/v1/login", "", gin.H{"email": u.Email, "password": "wrong"})
+	}
+	w := s.expect(429, "POST", "/v1/login", "", gin.H{"email": u.Email, "password": u.Password})
+	if decode[utils.APIError](t, w).Code != utils.CodeTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Fatalf("throttled login without Retry-After")
+	}
+}
+
+func TestLoginIPLimit(t *testing.T) {
+	// a spoofed X-Forwarded-For does not escape the per ip limit
+	s := newTestServer(t)
+	for i := range limiter.IPPolicy.FreeAttempts + 1 {
+		w := s.forwarded(fmt.Sprintf("203.0.113.%d", i), "POST", "/v1/login", gin.H{"email": fmt.Sprintf("nobody%d@example.com", i), "password": "password123"})
+		if w.Code != 401 {
+			t.Fatalf("attempt %d: got status %d", i, w.Code)
+		}
+	}
+	if w := s.forwarded("203.0.113.250", "POST", "/v1/login", gin.H{"email": "last@example.com", "password": "password123"}); w.Code != 429 {
+		t.Fatalf("spoofed ip not throttled, got status %d", w.Code)
+	}
+
+	// behind a trusted proxy each forwarded client has its own limit
+	s = newTestServer(t, func(cfg *apiConfig) {
+		cfg.Config.Server.TrustedProxies = []string{"192.0.2.0/24"}
+	})
+	for i := range limiter.IPPolicy.FreeAttempts + 2 {
+		w := s.forwarded(fmt.Sprintf("203.0.113.%d", i), "POST", "/v1/login", gin.H{"email": fmt.Sprintf("nobody%d@example.com", i), "password": "password123"})
+		if w.Code != 401 {
+			t.Fatalf("attempt %d: got status %d", i, w.Code)
+		}
+	}
+}
+
+func TestUserProfile(t *testing.T) {
+	s := newTestServer(t)
+	u := s.signup("meera")
+	other := s.signup("kiran")
+
+	w := s.expect(200, "GET", "/auth/user", u.Token, nil)
+	user := decode[db.User](t, w)
+	if user.Email != u.Email {
+		t.Fatalf("got user %s, want %s", user.Email, u.Email)
+	}
+
+	s.expectError(401, utils.CodeUnauthorized, "GET", "/auth/user", "", nil)
+	s.expectError(401, utils.CodeInvalidToken, "GET", "/auth/user", "not-a-jwt", nil)
+
+	s.expect(204, "PUT", "/auth/user", u.Token, gin.H{"name": "Meera K"})
+	user, _ = s.store.GetUserByID(context.Background(), u.ID)
+	if user.Name != "Meera K" || user.PhoneNumber != u.Phone {
+		t.Fatalf("update changed the wrong fields: %+v", user)
+	}
+
+	// taken phone number
+	s.expectError(409, utils.CodeUserPhoneTaken, "PUT", "/auth/user", u.Token, gin.H{"phone_no": other.Phone})
+
+	// changing the password needs the old one and ends every session
+	s.expectError(400, utils.CodeValidationFailed, "PUT", "/auth/user", u.Token, gin.H{"old_password": "wrong", "new_password": "newpass123"})
+	s.expect(204, "PUT", "/auth/user", u.Token, gin.H{"old_password": u.Password, "new_password": "newpass123"})
+	s.expect(401, "GET", "/auth/user", u.Token, nil)
+	u.Password = "newpass123"
+	s.login(u)
+
+	// deleting cascades to the user's plans and groups
+	groupID := s.group(u, "goa")
+	s.expect(204, "DELETE", "/auth/user", u.Token, nil)
+	s.expect(401, "GET", "/auth/user", u.Token, nil)
+	if _, err := s.store.GetGroupByID(context.Background(), groupID); err == nil {
+		t.Fatalf("group of deleted user still exists")
+	}
+}
+
+func TestPasswordReset(t *testing.T) {
+	s := newTestServer(t, func(cfg *apiConfig) {
+		cfg.ResetAppURL = "yatrabandhu://reset-password?token={token}"
+	})
+	u := s.signup("nisha")
+
+	// same answer for unknown accounts, nothing queued
+	s.expect(200, "POST", "/v1/user/password-reset", "", gin.H{"email": "ghost@example.com"})
+	if _, ok := s.mailTo("ghost@example.com"); ok {
+		t.Fatalf("reset mail queued for unknown account")
+	}
+
+	s.expect(200, "POST", "/v1/user/password-reset", "", gin.H{"email": u.Email})
+	token := s.linkToken(u.Email, resetLink)
+	if mail, _ := s.mailTo(u.Email); !strings.Contains(mail.HtmlBody, `href="yatrabandhu://reset-password?token=`+token+`"`) {
+		t.Fatalf("app link missing from the html body %q", mail.HtmlBody)
+	}
+
+	s.expect(400, "POST", "/v1/user/password-reset/unknown", "", gin.H{"new_password": "fresh123"})
+	s.expect(400, "POST", "/v1/user/password-reset/"+token, "", gin.H{})
+	s.expect(200, "POST", "/v1/user/password-reset/"+token, "", gin.H{"new_password": "fresh123"})
+
+	// single use, and old sessions are gone
+	s.expect(400, "POST", "/v1/user/password-reset/"+token, "", gin.H{"new_password": "again123"})
+	s.expect(401, "GET", "/auth/user", u.Token, nil)
+
+	u.Password = "fresh123"
+	s.login(u)
+
+	// expired links are rejected
+	s.expect(200, "POST", "/v1/user/password-reset", "", gin.H{"email": u.Email})
+	token = s.linkToken(u.Email, resetLink)
+	s.store.ExpireTokens(u.ID)
+	s.expect(400, "POST", "/v1/user/password-reset/"+token, "", gin.H{"new_password": "late1234"})
+
+	// every request counts against the per email limit
+	s.expect(200, "POST", "/v1/user/password-reset", "", gin.H{"email": u.Email})
+	s.expect(200, "POST", "/v1/user/password-reset", "", gin.H{"email": u.Email})
+	s.expect(429, "POST", "/v1/user/password-reset", "", gin.H{"email": u.Email})
+}
+
+func TestConcurrentPasswordReset(t *testing.T) {
+	s := newTestServer(t)
+	u := s.signup("arjun")
+	s.expect(200, "POST", "/v1/user/password-reset", "", gin.H{"email": u.Email})
+	token := s.linkToken(u.Email, resetLink)
+
+	// the link is used by exactly one of the confirms
+	const n = 8
+	codes := make([]int, n)
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes[i] = s.do("POST", "/v1/user/password-reset/"+token, "", gin.H{"new_password": fmt.Sprintf("racer%d123", i)}).Code
+		}()
+	}
+	wg.Wait()
+
+	ok := 0
+	for _, code := range codes {
+		switch code {
+		case 200:
+			ok++
+		case 400:
+		default:
+			t.Fatalf("unexpected status %d", code)
+		}
+	}
+	if ok != 1 {
+		t.Fatalf("%d confirms with the same link succeeded, want 1", ok)
+	}
+}
+
+func TestVerifyEmail(t *testing.T) {
+	s := newTestServer(t)
+	u := s.signup("dev")
+	token := s.linkToken(u.Email, verifyLink)
+
+	// throttled per email, the same for unknown accounts
+	for _, email := range []string{u.Email, "ghost@example.com"} {
+		s.expect(200, "POST", "/v1/verify/resend", "", gin.H{"email": email})
+		w := s.expect(429, "POST", "/v1/verify/resend", "", gin.H{"email": email})
+		if w.Header().Get("Retry-After") == "" {
+			t.Fatalf("throttled resend for %s without Retry-After", email)
+		}
+	}
+	if _, ok := s.mailTo("ghost@example.com"); ok {
+		t.Fatalf("verification mail queued for unknown account")
+	}
+	s.expect(400, "POST", "/v1/verify/resend", "", gin.H{"email": "bad"})
+
+	// the resend replaced the registration link
+	s.expect(400, "GET", "/v1/verify/"+token, "", nil)
+	token = s.linkToken(u.Email, verifyLink)
+
+	s.expect(400, "GET", "/v1/verify/unknown", "", nil)
+	s.expect(200, "GET", "/v1/verify/"+token, "", nil)
+
+	verified, _ := s.store.GetUserVerifiedStatus(context.Background(), u.ID)
+	if !verified.Bool {
+		t.Fatalf("user not verified")
+	}
+
+	// single use
+	s.expect(400, "GET", "/v1/verify/"+token, "", nil)
+
+	// already verified, nothing to resend
+	v := s.signup("eve")
+	s.expect(200, "GET", "/v1/verify/"+s.linkToken(v.Email, verifyLink), "", nil)
+	registered, _ := s.mailTo(v.Email)
+	s.expect(200, "POST", "/v1/verify/resend", "", gin.H{"email": v.Email})
+	if latest, _ := s.mailTo(v.Email); latest.ID != registered.ID {
+		t.Fatalf("verification mail queued for a verified account")
+	}
+}
+
+func TestVerifiedMiddleware(t *testing.T) {
+	s := newTestServer(t, func(cfg *apiConfig) {
+		cfg.RequireVerified = true
+	})
+
+	u := s.signup("ira")
+	planID := s.plan(u, "leh")
+	body := gin.H{"name": "leh", "description": "bikes", "plan_id": planID.String()}
+
+	s.expect(403, "POST", "/auth/travel-group", u.Token, body)
+	s.expect(200, "GET", "/v1/verify/"+s.linkToken(u.Email, verifyLink), "", nil)
+	s.expect(200, "POST", "/auth/travel-group", u.Token, body)
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ErebusAJ/YatraBandhu/internals/db"
+	"github.com/ErebusAJ/YatraBandhu/internals/mailer"
+	"github.com/ErebusAJ/YatraBandhu/internals/outbox"
+	"github.com/ErebusAJ/YatraBandhu/internals/store"
+	"github.com/ErebusAJ/YatraBandhu/internals/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// verificationTTL
+// lifetime of a verification link
+const verificationTTL = 24 * time.Hour
+
+// sendVerification
+// replaces any outstanding verification token of a user
+// and queues an email with a fresh expiring verification link
+func(cfg *apiConfig) sendVerification(c *gin.Context, q store.Store, userID uuid.UUID, email string) error{
+	token, tokenHash, err := utils.GenerateOpaqueToken()
+	if err != nil{
+		return err
+	}
+
+	err = q.DeleteUserVerificationTokens(c, userID)
+	if err != nil{
+		return err
+	}
+
+	err = q.InsertVerificationToken(c, db.InsertVerificationTokenParams{
+		UserID: userID,
+		Token: tokenHash,
+		ExpiresAt: time.Now().Add(verificationTTL),
+	})
+	if err != nil{
+		return err
+	}
+
+	return outbox.Enqueue(c, q, email, mailer.TemplateVerification, gin.H{
+		"URL": fmt.Sprintf("%v/v1/verify/%v", cfg.BaseURL, token),
+		"ExpiresIn": "24 hours",
+	})
+}
+
+
+// verifyEmail
+// verifies the token from the emailed link and marks user as verified
+func(cfg *apiConfig) verifyEmail(c *gin.Context){
+	token, err := cfg.DB.GetVerificationToken(c, utils.HashToken(c.Param("token")))
+	if err == sql.ErrNoRows{
+		utils.ErrorJSON(c, 400, "unknown verification token", utils.InvalidTokenError, err)
+		return
+	}else if err != nil{
+		utils.DBErrorJSON(c, err, nil)
+		return
+	}
+
+	if token.ExpiresAt.Before(time.Now()){
+		_ = cfg.DB.DeleteUserVerificationTokens(c, token.UserID)
+		utils.ErrorJSON(c, 400, "expired verification token", utils.InvalidTokenError, nil)
+		return
+	}
+
+	err = cfg.DB.SetUserVerified(c, token.UserID)
+	if err != nil{
+		utils.DBErrorJSON(c, err, nil)
+		return
+	}
+
+	err = cfg.DB.DeleteUserVerificationTokens(c, token.UserID)
+	if err != nil{
+		utils.DBErrorJSON(c, err, nil)
+		return
+	}
+
+	c.IndentedJSON(200, utils.MessageObj("email verified !!!"))
+}
+
+
+// resendVerificationRequest
+// body of POST /v1/verify/resend
+type resendVerificationRequest struct{
+	Email	string	`json:"email" binding:"required,email"`
+}
+
+// resendVerification
+// sends a new verification link, throttled per user
+// responds the same whether or not the email is registered
+func(cfg *apiConfig) resendVerification(c *gin.Context){
+	var reqDetails resendVerificationRequest
+
+	err := c.ShouldBind(&reqDetails)
+	if err != nil{
+		utils.ErrorJSON(c, 400, utils.RequestBodyError, utils.JSONError, err)
+		return
+	}
+
+	// limit resends per email, checked before the lookup so
+	// throttling doesn't reveal whether the account exists
+	verifyKey := strings.ToLower(reqDetails.Email)
+	wait, err := cfg.VerifyLimiter.Check(c, verifyKey)
+	if err != nil{
+		utils.ErrorJSON(c, 500, "error checking verification limiter", utils.InternalError, err)
+		return
+	}
+	if wait > 0{
+		c.Header("Retry-After", strconv.Itoa(int(wait.Seconds()) + 1))
+		utils.ErrorJSON(c, 429, "verification resend throttled", "please wait before requesting another link", nil)
+		return
+	}
+	_, err = cfg.VerifyLimiter.Fail(c, verifyKey)
+	if err != nil{
+		slog.ErrorContext(c, "error recording verification resend", "error", err)
+	}
+
+	response := utils.MessageObj("if the account exists and is unverified a link has been sent")
+
+	user, err := cfg.DB.GetUserByEmail(c, reqDetails.Email)
+	if err == sql.ErrNoRows || (err == nil && user.VerifiedStatus.Bool){
+		c.IndentedJSON(200, response)
+		return
+	}else if err != nil{
+		utils.DBErrorJSON(c, err, nil)
+		return
+	}
+
+	err = cfg.DB.InTx(c, func(q store.Store) error{
+		return cfg.sendVerification(c, q, user.ID, user.Email)
+	})
+	if err != nil{
+		utils.DBErrorJSON(c, err, nil)
+		return
+	}
+
+	c.IndentedJSON(200, response)
+}
@@ -0,0 +1,441 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ErebusAJ/YatraBandhu/config"
+	"github.com/ErebusAJ/YatraBandhu/internals/db"
+	"github.com/ErebusAJ/YatraBandhu/internals/itinerary"
+	"github.com/ErebusAJ/YatraBandhu/internals/store"
+	"github.com/ErebusAJ/YatraBandhu/internals/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const testSignedKey = "test-signed-key"
+
+// exercised records every "METHOD /path" hit by a test server
+// TestMain fails the run if a registered route was never exercised
+var exercised sync.Map
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	flag.Parse()
+	if !testing.Verbose() {
+		slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+		log.SetOutput(io.Discard)
+	}
+
+	code := m.Run()
+
+	// only a full run is expected to cover every route
+	if code == 0 && flag.Lookup("test.run").Value.String() == "" {
+		var missing []string
+		for _, route := range newTestServer(nil).r.Routes() {
+			key := route.Method + " " + route.Path
+			if _, ok := exercised.Load(key); !ok {
+				missing = append(missing, key)
+			}
+		}
+		if len(missing) > 0 {
+			sort.Strings(missing)
+			fmt.Fprintf(os.Stderr, "routes not exercised by any test:\n")
+			for _, key := range missing {
+				fmt.Fprintf(os.Stderr, "\t%s\n", key)
+			}
+			code = 1
+		}
+	}
+
+	os.Exit(code)
+}
+
+// testServer
+// every route of RegisterRoutes served from an in-memory store
+type testServer struct {
+	t     *testing.T
+	cfg   *apiConfig
+	store *store.Memory
+	r     *gin.Engine
+}
+
+// newTestServer
+// opts adjust the handler config before routes are registered
+func newTestServer(t *testing.T, opts ...func(cfg *apiConfig)) *testServer {
+	appCfg := config.Default()
+	appCfg.BaseURL = "http://yatra.test"
+	appCfg.Auth.SignedKey = testSignedKey
+
+	st := store.NewMemory()
+	cfg := newAPIConfig(appCfg, st)
+	cfg.PlannerJobs.Pipeline = &testPipeline{}
+	cfg.PlannerJobs.BaseDelay = 0
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	// as served, X-Forwarded-For is only believed from trusted proxies
+	r := gin.New()
+	if err := r.SetTrustedProxies(appCfg.Server.TrustedProxies); err != nil {
+		t.Fatal(err)
+	}
+	r.Use(func(c *gin.Context) {
+		if c.FullPath() != "" {
+			exercised.Store(c.Request.Method+" "+c.FullPath(), true)
+		}
+		c.Next()
+	})
+	cfg.routes(r)
+
+	return &testServer{t: t, cfg: cfg, store: st, r: r}
+}
+
+// testPipeline
+// plans a fakeItinerary without calling any provider, every
+// stage fails with fail when it is set
+type testPipeline struct {
+	fail error
+}
+
+func (p *testPipeline) Collect(ctx context.Context, location string, days int) (json.RawMessage, error) {
+	if p.fail != nil {
+		return nil, p.fail
+	}
+	return json.Marshal(location)
+}
+
+func (p *testPipeline) Draft(ctx context.Context, travelData json.RawMessage, interests string, days int) (string, error) {
+	var location string
+	if err := json.Unmarshal(travelData, &location); err != nil || p.fail != nil {
+		return "", errors.Join(err, p.fail)
+	}
+	return location + "\n" + interests, nil
+}
+
+// CollectSteps
+// reports one step, the streamed draft is sent a line at a time
+func (p *testPipeline) CollectSteps(ctx context.Context, location string, days int, onStep func(step string)) (json.RawMessage, error) {
+	onStep(utils.StepGeocoding)
+	return p.Collect(ctx, location, days)
+}
+
+func (p *testPipeline) DraftStream(ctx context.Context, travelData json.RawMessage, interests string, days int, onText func(text string)) (string, error) {
+	draft, err := p.Draft(ctx, travelData, interests, days)
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.SplitAfter(draft, "\n") {
+		onText(line)
+	}
+	return draft, nil
+}
+
+func (p *testPipeline) Structure(ctx context.Context, draft string, days int, start time.Time) (*itinerary.Itinerary, error) {
+	if p.fail != nil {
+		return nil, p.fail
+	}
+	location, interests, _ := strings.Cut(draft, "\n")
+	return fakeItinerary(location, interests, days, start), nil
+}
+
+// fakeItinerary
+// a valid itinerary of numDays days from start, each with one
+// activity named after userQuery
+func fakeItinerary(location, userQuery string, numDays int, start time.Time) *itinerary.Itinerary {
+	it := &itinerary.Itinerary{
+		Destination: location,
+		TravelDuration: itinerary.TravelDuration{
+			TotalDays: numDays,
+			StartDate: start.Format(itinerary.DateFormat),
+			EndDate:   start.AddDate(0, 0, numDays-1).Format(itinerary.DateFormat),
+		},
+	}
+	for i := range numDays {
+		it.DailyItinerary = append(it.DailyItinerary, itinerary.Day{
+			DayNumber:  i + 1,
+			Date:       start.AddDate(0, 0, i).Format(itinerary.DateFormat),
+			Activities: []itinerary.Activity{{TimeSlot: "Morning", Name: userQuery}},
+		})
+	}
+	return it
+}
+
+// work
+// runs the queued planner jobs to completion, retries included
+func (s *testServer) work() {
+	s.cfg.PlannerJobs.Work(context.Background())
+}
+
+// do
+// serves one request, body is encoded as json unless it is a string
+func (s *testServer) do(method, path, token string, body any) *httptest.ResponseRecorder {
+	s.t.Helper()
+
+	w := httptest.NewRecorder()
+	s.r.ServeHTTP(w, s.request(method, path, token, body))
+	return w
+}
+
+// forwarded
+// serves one request claiming to be forwarded for client ip, the
+// connection's address is httptest's 192.0.2.1
+func (s *testServer) forwarded(ip, method, path string, body any) *httptest.ResponseRecorder {
+	s.t.Helper()
+
+	req := s.request(method, path, "", body)
+	req.Header.Set("X-Forwarded-For", ip)
+	w := httptest.NewRecorder()
+	s.r.ServeHTTP(w, req)
+	return w
+}
+
+// request
+// builds a request for do
+func (s *testServer) request(method, path, token string, body any) *http.Request {
+	s.t.Helper()
+
+	var reader io.Reader
+	switch b := body.(type) {
+	case nil:
+	case string:
+		reader = bytes.NewBufferString(b)
+	default:
+		data, err := json.Marshal(b)
+		if err != nil {
+			s.t.Fatalf("encoding body: %v", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	if reader != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return req
+}
+
+// expect
+// serves a request and fails the test unless it answers with status
+func (s *testServer) expect(status int, method, path, token string, body any) *httptest.ResponseRecorder {
+	s.t.Helper()
+
+	w := s.do(method, path, token, body)
+	if w.Code != status {
+		s.t.Fatalf("%s %s: got status %d, want %d, body %s", method, path, w.Code, status, w.Body.String())
+	}
+	return w
+}
+
+// expectError checks the status and stable code of an error response
+func (s *testServer) expectError(status int, code, method, path, token string, body any) utils.APIError {
+	s.t.Helper()
+
+	w := s.expect(status, method, path, token, body)
+	apiErr := decode[utils.APIError](s.t, w)
+	if apiErr.Code != code {
+		s.t.Fatalf("%s %s: got code %q, want %q", method, path, apiErr.Code, code)
+	}
+	if apiErr.RequestID == "" || apiErr.RequestID != w.Header().Get("X-Request-ID") {
+		s.t.Fatalf("%s %s: request id %q not echoed in header %q", method, path, apiErr.RequestID, w.Header().Get("X-Request-ID"))
+	}
+	return apiErr
+}
+
+func decode[T any](t *testing.T, w *httptest.ResponseRecorder) T {
+	t.Helper()
+
+	var v T
+	err := json.Unmarshal(w.Body.Bytes(), &v)
+	if err != nil {
+		t.Fatalf("decoding %s: %v", w.Body.String(), err)
+	}
+	return v
+}
+
+// testUser
+// an account registered through the API with a live session
+type testUser struct {
+	ID       uuid.UUID
+	Name     string
+	Email    string
+	Phone    string
+	Password string
+	Token    string
+	Refresh  string
+}
+
+var userSeq atomic.Int64
+
+// signup
+// registers and logs in a new account
+func (s *testServer) signup(name string) *testUser {
+	s.t.Helper()
+
+	n := userSeq.Add(1)
+	u := &testUser{
+		Name:     name,
+		Email:    fmt.Sprintf("%s%d@example.com", name, n),
+		Phone:    fmt.Sprintf("9%09d", n),
+		Password: "password123",
+	}
+	s.expect(201, "POST", "/v1/register", "", gin.H{
+		"name":     u.Name,
+		"age":      25,
+		"phone_no": u.Phone,
+		"email":    u.Email,
+		"password": u.Password,
+	})
+
+	row, err := s.store.GetUserByEmail(context.Background(), u.Email)
+	if err != nil {
+		s.t.Fatalf("registered user not stored: %v", err)
+	}
+	u.ID = row.ID
+
+	s.login(u)
+	return u
+}
+
+// login
+// refreshes the session tokens of u
+func (s *testServer) login(u *testUser) {
+	s.t.Helper()
+
+	w := s.expect(200, "POST", "/v1/login", "", gin.H{"email": u.Email, "password": u.Password})
+	tokens := decode[struct {
+		Token   string `json:"token"`
+		Refresh string `json:"refresh_token"`
+	}](s.t, w)
+	u.Token, u.Refresh = tokens.Token, tokens.Refresh
+}
+
+// withRole
+// sets the role of u and logs in again, the old token is revoked
+func (s *testServer) withRole(u *testUser, role string) *testUser {
+	s.t.Helper()
+
+	err := s.store.UpdateUserAccessLevel(context.Background(), db.UpdateUserAccessLevelParams{
+		AccessLevel: sql.NullString{String: role, Valid: true},
+		ID:          u.ID,
+	})
+	if err != nil {
+		s.t.Fatalf("setting role: %v", err)
+	}
+	s.login(u)
+	return u
+}
+
+// mailTo
+// the latest pending mail queued for recipient
+func (s *testServer) mailTo(recipient string) (db.EmailOutbox, bool) {
+	s.t.Helper()
+
+	for _, e := range s.store.PendingEmails() {
+		if e.Recipient == recipient {
+			return e, true
+		}
+	}
+	return db.EmailOutbox{}, false
+}
+
+// linkToken
+// the token at the end of the link matching pattern in the latest
+// mail to recipient
+func (s *testServer) linkToken(recipient string, pattern *regexp.Regexp) string {
+	s.t.Helper()
+
+	e, ok := s.mailTo(recipient)
+	if !ok {
+		s.t.Fatalf("no mail queued for %s", recipient)
+	}
+	m := pattern.FindStringSubmatch(e.TextBody)
+	if m == nil {
+		s.t.Fatalf("no link matching %v in %q", pattern, e.TextBody)
+	}
+	return m[1]
+}
+
+// plan
+// stores a travel plan of u, the API doesn't return plan ids
+func (s *testServer) plan(u *testUser, place string) uuid.UUID {
+	s.t.Helper()
+
+	id, err := s.store.AddTravelDetails(context.Background(), db.AddTravelDetailsParams{
+		CreatorID: u.ID,
+		Place:     place,
+		StartDate: "2025-01-10",
+		EndDate:   "2025-01-15",
+		TripType:  "leisure",
+		Interests: []string{"food"},
+	})
+	if err != nil {
+		s.t.Fatalf("adding plan: %v", err)
+	}
+	return id
+}
+
+// group
+// creates a group of u on a fresh plan and returns its id
+func (s *testServer) group(u *testUser, name string) uuid.UUID {
+	s.t.Helper()
+
+	planID := s.plan(u, name+" trip")
+	s.expect(200, "POST", "/auth/travel-group", u.Token, gin.H{
+		"name":        name,
+		"description": "a group",
+		"plan_id":     planID.String(),
+	})
+
+	groups, err := s.store.GetUserGroups(context.Background(), u.ID)
+	if err != nil {
+		s.t.Fatalf("listing groups: %v", err)
+	}
+	for _, g := range groups {
+		if g.PlanID == planID {
+			return g.ID
+		}
+	}
+	s.t.Fatalf("group %s not stored", name)
+	return uuid.Nil
+}
+
+// guide
+// adds a guide directly to the store
+func (s *testServer) guide(name, location string) uuid.UUID {
+	s.t.Helper()
+
+	id, err := s.store.AddGuide(context.Background(), db.AddGuideParams{
+		Name:       name,
+		Bio:        "bio",
+		Location:   location,
+		Expertise:  "treks",
+		Rating:     4,
+		HourlyRate: "500",
+	})
+	if err != nil {
+		s.t.Fatalf("adding guide: %v", err)
+	}
+	return id
+}
@@ -1,48 +1,164 @@
 package handlers
 
 import (
-	"log"
-	"os"
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
 
+	"github.com/ErebusAJ/YatraBandhu/config"
 	"github.com/ErebusAJ/YatraBandhu/internals/db"
+	"github.com/ErebusAJ/YatraBandhu/internals/limiter"
+	"github.com/ErebusAJ/YatraBandhu/internals/metrics"
 	"github.com/ErebusAJ/YatraBandhu/internals/middleware"
+	"github.com/ErebusAJ/YatraBandhu/internals/planner"
+	"github.com/ErebusAJ/YatraBandhu/internals/store"
 	"github.com/ErebusAJ/YatraBandhu/internals/utils"
 	"github.com/gin-gonic/gin"
 	_ "github.com/lib/pq"
 )
 
 type apiConfig struct {
-	DB *db.Queries
+	DB 				store.Store
+	Config			*config.Config
+	BaseURL			string
+	RequireVerified	bool
+	AccountLimiter	limiter.LoginLimiter
+	IPLimiter		limiter.LoginLimiter
+	ResetLimiter	limiter.LoginLimiter
+	VerifyLimiter	limiter.LoginLimiter
+	// Request rate limits and AI planner quotas
+	RateLimiter		limiter.RateLimiter
+	Quotas			limiter.QuotaCounter
+	// Password reset links, "{token}" is replaced by the token
+	ResetURL		string
+	ResetAppURL		string
+	// Runs queued AI planner jobs, its pipeline is swapped out in tests
+	PlannerJobs		*planner.Worker
 }
 
-func RegisterRoutes(r *gin.Engine) {
+// RegisterRoutes
+// registers every route on r, DB is the process wide pool
+// opened and closed by main, the returned worker runs the
+// AI planner jobs the routes queue
+func RegisterRoutes(r *gin.Engine, appCfg *config.Config, DB *sql.DB) *planner.Worker {
+	apiCfg := newAPIConfig(appCfg, store.NewPostgres(DB))
+
+	// Pool statistics for /metrics
+	err := metrics.RegisterDB(DB)
+	if err != nil{
+		slog.Error("unable to register db metrics", "error", err)
+	}
+
+	// Login attempt limiters, postgres shares state across replicas
+	if appCfg.Auth.LoginLimiter == "postgres"{
+		apiCfg.AccountLimiter = limiter.NewPostgresLoginLimiter(DB, "account:", limiter.AccountPolicy)
+		apiCfg.IPLimiter = limiter.NewPostgresLoginLimiter(DB, "ip:", limiter.IPPolicy)
+		apiCfg.ResetLimiter = limiter.NewPostgresLoginLimiter(DB, "reset:", limiter.ResetPolicy)
+		apiCfg.VerifyLimiter = limiter.NewPostgresLoginLimiter(DB, "verify:", limiter.VerificationPolicy)
+	}
+	if appCfg.Limits.Backend == "postgres"{
+		apiCfg.RateLimiter = limiter.NewPostgresRateLimiter(DB)
+		apiCfg.Quotas = limiter.NewPostgresQuotaCounter(DB)
+	}
 
-	// Open up database connection
-	DB, err := utils.ConnectDB()
-	if err != nil {
-		log.Fatalf("%v", err)
+	apiCfg.routes(r)
+	return apiCfg.PlannerJobs
+}
+
+
+// newAPIConfig
+// builds the handler config on top of a store
+// limiters and quotas default to in memory
+func newAPIConfig(appCfg *config.Config, st store.Store) *apiConfig{
+	apiCfg := &apiConfig{
+		DB: st,
+		Config: appCfg,
+		BaseURL: appCfg.BaseURL,
+		RequireVerified: appCfg.Auth.RequireVerifiedEmail,
+		AccountLimiter: limiter.NewMemoryLoginLimiter(limiter.AccountPolicy),
+		IPLimiter: limiter.NewMemoryLoginLimiter(limiter.IPPolicy),
+		ResetLimiter: limiter.NewMemoryLoginLimiter(limiter.ResetPolicy),
+		VerifyLimiter: limiter.NewMemoryLoginLimiter(limiter.VerificationPolicy),
+		RateLimiter: limiter.NewMemoryRateLimiter(),
+		Quotas: limiter.NewMemoryQuotaCounter(),
 	}
 
-	newDB := db.New(DB)
-	apiCfg := apiConfig{
-		DB: newDB,
+	// Password reset links, web page and optional app deep link
+	apiCfg.ResetURL = appCfg.Auth.PasswordResetURL
+	if apiCfg.ResetURL == ""{
+		apiCfg.ResetURL = appCfg.BaseURL + "/v1/user/password-reset/{token}"
+	}
+	apiCfg.ResetAppURL = appCfg.Auth.PasswordResetAppURL
+
+	// AI planner jobs, a bad model list fails every job but
+	// config validation rejects it before the server starts
+	var pipeline planner.Pipeline
+	generator, err := utils.NewPlanner(appCfg.Planner)
+	if err != nil{
+		slog.Error("AI planner unavailable", "error", err)
+		pipeline = planner.Unavailable(err)
+	} else{
+		pipeline = generator
 	}
+	apiCfg.PlannerJobs = planner.NewWorker(st, pipeline, appCfg.Planner.Workers, appCfg.Planner.JobAttempts)
+	apiCfg.PlannerJobs.OnFailed = func(ctx context.Context, job db.PlannerJob){
+		apiCfg.refundPlannerQuota(ctx, job.UserID)
+	}
+
+	return apiCfg
+}
+
+
+// routes
+// registers the handlers of apiCfg on r
+func(apiCfg *apiConfig) routes(r *gin.Engine){
+	appCfg := apiCfg.Config
+
+	// Request ids for error bodies and logs, one log record per
+	// request and panics turned into 500s
+	r.Use(middleware.RequestID(), middleware.RequestLogger(), middleware.Metrics(), middleware.Recovery())
+
+	// Health checks and prometheus metrics
+	r.GET("/healthz", apiCfg.healthz)
+	r.GET("/readyz", apiCfg.readyz)
+	r.GET("/metrics", apiCfg.getMetrics)
+
+	// API description and docs page
+	r.GET("/openapi.json", apiCfg.getOpenAPI)
+	r.GET("/docs", apiCfg.getDocs)
+	r.GET("/schemas/itinerary.json", apiCfg.getItinerarySchema)
+
+	// Per client ip rate limit, gin applies it to the routes
+	// registered from here on so probes and scrapes above are exempt
+	limits := appCfg.Limits
+	r.Use(middleware.RateLimitIP(apiCfg.RateLimiter, "api", limiter.Rate{Limit: limits.IPPerMinute, Per: time.Minute, Burst: limits.IPBurst}))
+	userLimit := middleware.RateLimitUser(apiCfg.RateLimiter, "api", limiter.Rate{Limit: limits.UserPerMinute, Per: time.Minute, Burst: limits.UserBurst})
+	plannerLimit := middleware.RateLimitUser(apiCfg.RateLimiter, "planner", limiter.Rate{Limit: limits.PlannerPerHour, Per: time.Hour, Burst: limits.PlannerBurst})
 
 	r.POST("/v1/register", apiCfg.registerUser)
 	r.POST("/v1/login", apiCfg.loginUser)
-	r.POST("/guides/register", apiCfg.registerGuides)
-	
+	r.POST("/v1/token/refresh", apiCfg.refreshToken)
+	r.GET("/v1/verify/:token", apiCfg.verifyEmail)
+	r.POST("/v1/verify/resend", apiCfg.resendVerification)
 
-	// Load signed key for middleware
-	signedKey := os.Getenv("SIGNED_KEY")
-	if signedKey == "" {
-		log.Printf("error retrieving signed key \n")
-	}
+
+	auth := middleware.AuthMiddleware(appCfg.Auth.SignedKey, apiCfg.DB)
+
+	// Guide profiles can only be created by admins or guides
+	r.POST("/guides/register", auth, userLimit, middleware.RequireRole(utils.RoleAdmin, utils.RoleGuide), apiCfg.registerGuides)
 
 	// Authenticated Routes
 	protected := r.Group("/auth")
-	protected.Use(middleware.AuthMiddleware(signedKey))
+	protected.Use(auth, userLimit)
+
+	// Rejects unverified accounts if enabled
+	verified := middleware.VerifiedMiddleware(apiCfg.DB, apiCfg.RequireVerified)
 	{
+		// Session Routes
+		protected.POST("/logout", apiCfg.logoutUser)
+		protected.POST("/logout-all", apiCfg.logoutAllDevices)
+
 		// Users Routes
 		protected.GET("/user", apiCfg.getUserByID)
 		protected.PUT("/user", apiCfg.updateUser)
@@ -53,7 +169,7 @@ func RegisterRoutes(r *gin.Engine) {
 		protected.GET("/travel-details", apiCfg.getUserPlansDetails)
 
 		// Travel Groups and Members
-		protected.POST("/travel-group", apiCfg.createGroup)
+		protected.POST("/travel-group", verified, apiCfg.createGroup)
 		protected.PUT("/travel-group/:groupID", apiCfg.updateGroup)
 		protected.DELETE("/travel-group/:groupID", apiCfg.deleteGroupByID)
 		protected.GET("/travel-group/", apiCfg.getUsersGroups)
@@ -63,16 +179,60 @@ func RegisterRoutes(r *gin.Engine) {
 		protected.DELETE("/travel-group/:groupID/member/:userID", apiCfg.deleteGroupMember)
 
 		// requests
-		protected.POST("/travel-group/:groupID/request", apiCfg.sendRequest)
+		protected.POST("/travel-group/:groupID/request", verified, apiCfg.sendRequest)
 		protected.GET("/travel-group/:groupID/request", apiCfg.getUserGroupRequest)
 		protected.POST("/travel-group/:groupID/request/:senderID", apiCfg.updateRequest)
 
 		// booking request 
-		protected.POST("/guide/book/:groupID/:guideID", apiCfg.sendBookRequest)
+		protected.POST("/guide/book/:groupID/:guideID", verified, apiCfg.sendBookRequest)
 		protected.GET("/guide/", apiCfg.getGuideDetails)
 
-		// AI plan generaet
-		protected.POST("/ai-planner", apiCfg.generatePlan)
+		// moderation reports
+		protected.POST("/reports", apiCfg.createReport)
+
+		// audit trail of the user's account
+		protected.GET("/audit", apiCfg.getUserAudit)
+
+		// AI plan generaet, rate limited and counted against the
+		// quotas of the user's role, generations run as background
+		// jobs polled for their progress or streamed as they are made
+		protected.POST("/ai-planner", plannerLimit, apiCfg.generatePlan)
+		protected.POST("/ai-planner/stream", plannerLimit, apiCfg.streamPlan)
+		protected.GET("/ai-planner/jobs/:jobID", apiCfg.getPlannerJob)
+		protected.DELETE("/ai-planner/jobs/:jobID", apiCfg.cancelPlannerJob)
+		protected.GET("/usage", apiCfg.getUsage)
+
+		// saved AI plans, every regeneration, edit or restore
+		// adds a version
+		protected.GET("/ai-plans", apiCfg.listAIPlans)
+		protected.GET("/ai-plans/:planID", apiCfg.getAIPlan)
+		protected.PATCH("/ai-plans/:planID", apiCfg.updateAIPlan)
+		protected.DELETE("/ai-plans/:planID", apiCfg.deleteAIPlan)
+		protected.GET("/ai-plans/:planID/diff", apiCfg.diffAIPlan)
+		protected.GET("/ai-plans/:planID/versions", apiCfg.listAIPlanVersions)
+		protected.GET("/ai-plans/:planID/versions/:version", apiCfg.getAIPlanVersion)
+		protected.POST("/ai-plans/:planID/versions/:version/restore", apiCfg.restoreAIPlanVersion)
+	}
+
+	// Admin Routes
+	admin := r.Group("/admin")
+	admin.Use(auth, userLimit, middleware.RequireRole(utils.RoleAdmin))
+	{
+		admin.GET("/users", apiCfg.listUsers)
+		admin.PUT("/users/:userID/role", apiCfg.updateUserRole)
+		admin.POST("/users/:userID/suspend", apiCfg.setUserSuspension(true))
+		admin.POST("/users/:userID/unsuspend", apiCfg.setUserSuspension(false))
+
+		admin.DELETE("/groups/:groupID", apiCfg.forceDeleteGroup)
+		admin.PUT("/guides/:guideID", apiCfg.moderateGuide)
+
+		admin.GET("/reports", apiCfg.getReports)
+		admin.PUT("/reports/:reportID", apiCfg.updateReport)
+
+		admin.GET("/outbox", apiCfg.getOutbox)
+		admin.POST("/outbox/:emailID/requeue", apiCfg.requeueEmail)
+
+		admin.GET("/audit", apiCfg.getAudit)
 	}
 
 	// User Password Reset Routes
@@ -80,3 +240,4 @@ func RegisterRoutes(r *gin.Engine) {
 	r.POST("v1/user/password-reset/:token", apiCfg.resetPasswordConfirm)
 
 }
+
@@ -0,0 +1,88 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ErebusAJ/YatraBandhu/internals/logging"
+	"github.com/ErebusAJ/YatraBandhu/internals/metrics"
+)
+
+// RequestIDHeader carries the caller's request id to providers
+const RequestIDHeader = "X-Request-ID"
+
+// New
+// returns the client used for calls to an external provider,
+// requests made with a context carrying a request id forward it,
+// every call is logged with secrets in the url masked and counted
+// in the provider metrics
+func New(provider string, timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: &transport{provider: provider, next: http.DefaultTransport},
+	}
+}
+
+// transport
+// RoundTripper wrapping the default transport
+type transport struct {
+	provider string
+	next     http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	if id := logging.RequestID(ctx); id != "" && req.Header.Get(RequestIDHeader) == "" {
+		req = req.Clone(ctx)
+		req.Header.Set(RequestIDHeader, id)
+	}
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	elapsed := time.Since(start)
+	metrics.ObserveProvider(t.provider, outcome(resp, err), elapsed)
+
+	attrs := []any{
+		"provider", t.provider,
+		"method", req.Method,
+		"url", logging.RedactURL(req.URL),
+		"duration_ms", elapsed.Milliseconds(),
+	}
+	switch {
+	case err != nil:
+		slog.WarnContext(ctx, "outbound request failed", append(attrs, "error", err)...)
+	case resp.StatusCode >= 400:
+		slog.WarnContext(ctx, "outbound request", append(attrs, "status", resp.StatusCode)...)
+	default:
+		slog.DebugContext(ctx, "outbound request", append(attrs, "status", resp.StatusCode)...)
+	}
+
+	return resp, err
+}
+
+// outcome
+// the status class of resp, or timeout or error when the call failed
+func outcome(resp *http.Response, err error) string {
+	if err != nil {
+		if IsTimeout(err) {
+			return "timeout"
+		}
+		return "error"
+	}
+	return strconv.Itoa(resp.StatusCode/100) + "xx"
+}
+
+// IsTimeout
+// reports whether err is a deadline or network timeout
+func IsTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
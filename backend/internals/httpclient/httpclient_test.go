@@ -0,0 +1,70 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ErebusAJ/YatraBandhu/internals/logging"
+	"github.com/ErebusAJ/YatraBandhu/internals/metrics"
+)
+
+func scrape(t *testing.T) string {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	return w.Body.String()
+}
+
+func TestClient(t *testing.T) {
+	var gotID string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotID = r.Header.Get(RequestIDHeader)
+		if r.URL.Path == "/slow" {
+			time.Sleep(200 * time.Millisecond)
+		}
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(503)
+		}
+	}))
+	defer srv.Close()
+
+	client := New("test-provider", 50*time.Millisecond)
+	ctx := logging.WithRequestID(context.Background(), "req-9")
+
+	get := func(path string) error {
+		req, _ := http.NewRequestWithContext(ctx, "GET", srv.URL+path, nil)
+		resp, err := client.Do(req)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	if err := get("/ok"); err != nil || gotID != "req-9" {
+		t.Fatalf("got error %v and request id %q", err, gotID)
+	}
+	if err := get("/fail"); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	err := get("/slow")
+	if err == nil || !IsTimeout(err) {
+		t.Fatalf("expected a timeout, got %v", err)
+	}
+
+	body := scrape(t)
+	for _, want := range []string{
+		`yatrabandhu_provider_requests_total{outcome="2xx",provider="test-provider"} 1`,
+		`yatrabandhu_provider_requests_total{outcome="5xx",provider="test-provider"} 1`,
+		`yatrabandhu_provider_requests_total{outcome="timeout",provider="test-provider"} 1`,
+		`yatrabandhu_provider_request_duration_seconds_count{provider="test-provider"} 3`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("metrics missing %s", want)
+		}
+	}
+}
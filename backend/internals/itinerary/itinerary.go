@@ -0,0 +1,286 @@
+package itinerary
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// DateFormat of every date in an itinerary
+const DateFormat = time.DateOnly
+
+// Schema
+// the JSON Schema of an Itinerary, given to the structuring models
+// and served at /schemas/itinerary.json
+//
+//go:embed itinerary.schema.json
+var Schema json.RawMessage
+
+// Itinerary
+// A generated travel plan, the json is what the structuring model
+// returns and what is stored in ai_plan.raw_data
+type Itinerary struct {
+	Destination          string               `json:"destination" binding:"required" doc:"City, Country"`
+	TravelDuration       TravelDuration       `json:"travel_duration" binding:"required"`
+	DailyItinerary       []Day                `json:"daily_itinerary" binding:"required,min=1,dive"`
+	KeyHighlights        KeyHighlights        `json:"key_highlights"`
+	SafetyConsiderations SafetyConsiderations `json:"safety_considerations"`
+}
+
+// TravelDuration
+// Length and dates of the trip
+type TravelDuration struct {
+	TotalDays int    `json:"total_days" binding:"required,min=1"`
+	StartDate string `json:"start_date" binding:"required,datetime=2006-01-02" doc:"YYYY-MM-DD"`
+	EndDate   string `json:"end_date" binding:"required,datetime=2006-01-02" doc:"YYYY-MM-DD"`
+}
+
+// Day
+// The plan of one day
+type Day struct {
+	DayNumber      int              `json:"day_number" binding:"required,min=1"`
+	Date           string           `json:"date" binding:"required,datetime=2006-01-02" doc:"YYYY-MM-DD"`
+	Weather        *Weather         `json:"weather"`
+	Activities     []Activity       `json:"activities" binding:"required,min=1,dive"`
+	Dining         []Dining         `json:"dining" binding:"dive"`
+	Accommodation  *Accommodation   `json:"accommodation"`
+	Transportation []Transportation `json:"transportation" binding:"dive"`
+}
+
+// Weather
+// Expected weather of a day
+type Weather struct {
+	Temperature string `json:"temperature" doc:"e.g. 22°C"`
+	Conditions  string `json:"conditions"`
+}
+
+// Activity
+// Something to do in a time slot
+type Activity struct {
+	TimeSlot    string    `json:"time_slot" binding:"required" doc:"Morning, Afternoon or Evening"`
+	Name        string    `json:"name" binding:"required"`
+	Type        string    `json:"type" doc:"e.g. Cultural, Historical, Leisure"`
+	Duration    string    `json:"duration" doc:"e.g. 2 hours"`
+	Description string    `json:"description"`
+	Location    *Location `json:"location"`
+	Tips        []string  `json:"tips"`
+}
+
+// Location
+// A named place
+type Location struct {
+	Name        string    `json:"name"`
+	Coordinates []float64 `json:"coordinates" binding:"omitempty,len=2" doc:"[latitude, longitude]"`
+}
+
+// Dining
+// A meal suggestion
+type Dining struct {
+	MealType string `json:"meal_type" doc:"e.g. Lunch"`
+	Name     string `json:"name" binding:"required"`
+	Cuisine  string `json:"cuisine"`
+	Address  string `json:"address"`
+}
+
+// Accommodation
+// Where to stay the night
+type Accommodation struct {
+	Name                   string `json:"name"`
+	ProximityToAttractions string `json:"proximity_to_attractions"`
+}
+
+// Transportation
+// How to get around
+type Transportation struct {
+	Type    string `json:"type"`
+	Details string `json:"details"`
+}
+
+// KeyHighlights
+// The trip at a glance
+type KeyHighlights struct {
+	TopAttractions []string `json:"top_attractions"`
+	MustTryFoods   []string `json:"must_try_foods"`
+}
+
+// SafetyConsiderations
+// Advice and emergency numbers for the destination
+type SafetyConsiderations struct {
+	GeneralAdvice    []string `json:"general_advice"`
+	EmergencyNumbers []string `json:"emergency_numbers"`
+}
+
+// ValidationError
+// Ways a model's answer doesn't conform, phrased so they can be
+// sent back to the model
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "itinerary does not conform: " + strings.Join(e.Problems, "; ")
+}
+
+// Decode
+// strictly decodes data, unknown fields, trailing data and values
+// of the wrong type are errors, then validates the fields
+func Decode(data []byte) (*Itinerary, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+
+	var it Itinerary
+	if err := dec.Decode(&it); err != nil {
+		return nil, &ValidationError{Problems: []string{decodeProblem(err)}}
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, &ValidationError{Problems: []string{"the answer must be a single JSON object"}}
+	}
+
+	if err := validate.Struct(&it); err != nil {
+		var verrs validator.ValidationErrors
+		if !errors.As(err, &verrs) {
+			return nil, err
+		}
+		problems := make([]string, 0, len(verrs))
+		for _, fe := range verrs {
+			problems = append(problems, fieldProblem(fe))
+		}
+		return nil, &ValidationError{Problems: problems}
+	}
+	return &it, nil
+}
+
+// Check
+// validates the trip against what was asked for, days starting on start
+func (it *Itinerary) Check(days int, start time.Time) error {
+	var problems []string
+	add := func(format string, args ...any) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	end := start.AddDate(0, 0, days-1)
+	if it.TravelDuration.TotalDays != days {
+		add("travel_duration.total_days must be %d, got %d", days, it.TravelDuration.TotalDays)
+	}
+	if want := start.Format(DateFormat); it.TravelDuration.StartDate != want {
+		add("travel_duration.start_date must be %s, got %q", want, it.TravelDuration.StartDate)
+	}
+	if want := end.Format(DateFormat); it.TravelDuration.EndDate != want {
+		add("travel_duration.end_date must be %s, got %q", want, it.TravelDuration.EndDate)
+	}
+	if len(it.DailyItinerary) != days {
+		add("daily_itinerary must have exactly %d days, got %d", days, len(it.DailyItinerary))
+	}
+	for i, day := range it.DailyItinerary {
+		if day.DayNumber != i+1 {
+			add("daily_itinerary[%d].day_number must be %d, got %d", i, i+1, day.DayNumber)
+		}
+		if want := start.AddDate(0, 0, i).Format(DateFormat); day.Date != want {
+			add("daily_itinerary[%d].date must be %s, got %q", i, want, day.Date)
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// Parse
+// decodes a model's answer and checks it against the request
+func Parse(data []byte, days int, start time.Time) (*Itinerary, error) {
+	it, err := Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := it.Check(days, start); err != nil {
+		return nil, err
+	}
+	return it, nil
+}
+
+// ParseEdit
+// decodes an itinerary edited by a user, checked against its own
+// length and start date as there is no request to compare with
+func ParseEdit(data []byte) (*Itinerary, error) {
+	it, err := Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	// Decode checked the date format
+	start, _ := time.Parse(DateFormat, it.TravelDuration.StartDate)
+	if err := it.Check(it.TravelDuration.TotalDays, start); err != nil {
+		return nil, err
+	}
+	return it, nil
+}
+
+// validate
+// checks the binding tags, reporting json field names
+var validate = func() *validator.Validate {
+	v := validator.New()
+	v.SetTagName("binding")
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		return name
+	})
+	return v
+}()
+
+// fieldProblem
+// a validation failure with the field's json path
+func fieldProblem(fe validator.FieldError) string {
+	_, path, _ := strings.Cut(fe.Namespace(), ".")
+	switch fe.Tag() {
+	case "required":
+		return path + " is required"
+	case "min":
+		return fmt.Sprintf("%s must have at least %s", path, fe.Param())
+	case "len":
+		return fmt.Sprintf("%s must have exactly %s values", path, fe.Param())
+	case "datetime":
+		return path + " must be a YYYY-MM-DD date"
+	}
+	return fmt.Sprintf("%s failed the %s check", path, fe.Tag())
+}
+
+// decodeProblem
+// a json decoding error in terms the model can act on
+func decodeProblem(err error) string {
+	var typeErr *json.UnmarshalTypeError
+	var syntaxErr *json.SyntaxError
+	switch {
+	case errors.As(err, &typeErr):
+		return fmt.Sprintf("%s must be a JSON %s, got %s", typeErr.Field, jsonType(typeErr.Type), typeErr.Value)
+	case errors.As(err, &syntaxErr):
+		return fmt.Sprintf("invalid JSON at offset %d: %v", syntaxErr.Offset, err)
+	case errors.Is(err, io.EOF):
+		return "the answer is empty"
+	}
+	// unknown fields come back as plain errors
+	return strings.TrimPrefix(err.Error(), "json: ")
+}
+
+// jsonType
+// the JSON name of a Go type
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Int, reflect.Int64, reflect.Float64:
+		return "number"
+	case reflect.Slice:
+		return "array"
+	case reflect.Struct, reflect.Map, reflect.Pointer:
+		return "object"
+	}
+	return t.String()
+}
@@ -0,0 +1,134 @@
+package itinerary
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+var start = time.Date(2025, 3, 30, 10, 0, 0, 0, time.UTC)
+
+const valid = `{
+	"destination": "Goa, India",
+	"travel_duration": {"total_days": 2, "start_date": "2025-03-30", "end_date": "2025-03-31"},
+	"daily_itinerary": [
+		{"day_number": 1, "date": "2025-03-30", "weather": null,
+		 "activities": [{"time_slot": "Morning", "name": "Baga Beach", "location": {"name": "Baga", "coordinates": [15.55, 73.75]}, "tips": null}],
+		 "dining": [{"meal_type": "Lunch", "name": "Britto's", "cuisine": null, "address": null}],
+		 "accommodation": {"name": "Taj", "proximity_to_attractions": "1km"}, "transportation": []},
+		{"day_number": 2, "date": "2025-03-31", "activities": [{"time_slot": "Evening", "name": "Fort Aguada"}]}
+	],
+	"key_highlights": {"top_attractions": ["Baga Beach"], "must_try_foods": ["Bebinca"]},
+	"safety_considerations": {"general_advice": [], "emergency_numbers": ["112"]}
+}`
+
+func TestParse(t *testing.T) {
+	it, err := Parse([]byte(valid), 2, start)
+	if err != nil || it.DailyItinerary[0].Dining[0].Name != "Britto's" || it.DailyItinerary[1].Weather != nil {
+		t.Fatalf("unexpected itinerary %+v, %v", it, err)
+	}
+
+	for name, c := range map[string]struct {
+		data string
+		days int
+		want string
+	}{
+		"unknown field":  {strings.Replace(valid, `"destination"`, `"budget": 100, "destination"`, 1), 2, `unknown field "budget"`},
+		"wrong type":     {strings.Replace(valid, `"total_days": 2`, `"total_days": "2"`, 1), 2, "travel_duration.total_days must be a JSON number"},
+		"trailing data":  {valid + `{}`, 2, "single JSON object"},
+		"missing name":   {strings.Replace(valid, `"name": "Fort Aguada"`, `"name": ""`, 1), 2, "daily_itinerary[1].activities[0].name is required"},
+		"bad date":       {strings.Replace(valid, `"date": "2025-03-31"`, `"date": "31/03/2025"`, 1), 2, "daily_itinerary[1].date must be a YYYY-MM-DD date"},
+		"bad coords":     {strings.Replace(valid, `[15.55, 73.75]`, `[15.55]`, 1), 2, "coordinates must have exactly 2 values"},
+		"day count":      {valid, 3, "daily_itinerary must have exactly 3 days, got 2"},
+		"shifted dates":  {strings.ReplaceAll(valid, "2025-03-3", "2025-04-1"), 2, "travel_duration.start_date must be 2025-03-30"},
+		"not an object":  {`"a plan"`, 2, "must be a JSON object"},
+		"empty response": {``, 2, "the answer is empty"},
+	} {
+		_, err := Parse([]byte(c.data), c.days, start)
+		var verr *ValidationError
+		if !errors.As(err, &verr) || !strings.Contains(err.Error(), c.want) {
+			t.Fatalf("%s: expected %q, got %v", name, c.want, err)
+		}
+	}
+
+	// end_date and numbering are checked too, across months
+	it.TravelDuration.EndDate = "2025-04-01"
+	it.DailyItinerary[1].DayNumber = 3
+	err = it.Check(2, start)
+	var verr *ValidationError
+	if !errors.As(err, &verr) || len(verr.Problems) != 2 {
+		t.Fatalf("expected two problems, got %v", err)
+	}
+}
+
+func TestParseEdit(t *testing.T) {
+	it, err := ParseEdit([]byte(valid))
+	if err != nil || it.Destination != "Goa, India" {
+		t.Fatalf("unexpected itinerary %+v, %v", it, err)
+	}
+
+	// a day dropped without shortening the trip
+	_, err = ParseEdit([]byte(strings.Replace(valid, `"total_days": 2`, `"total_days": 3`, 1)))
+	var verr *ValidationError
+	if !errors.As(err, &verr) || !strings.Contains(err.Error(), "daily_itinerary must have exactly 3 days") {
+		t.Fatalf("expected a day count problem, got %v", err)
+	}
+
+	if _, err := ParseEdit([]byte(`{"destination": "Goa"}`)); !errors.As(err, &verr) {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+}
+
+// TestSchema
+// the published schema has the same fields as the Go types
+func TestSchema(t *testing.T) {
+	var schema map[string]any
+	if err := json.Unmarshal(Schema, &schema); err != nil {
+		t.Fatal(err)
+	}
+
+	var walk func(path string, s map[string]any, typ reflect.Type)
+	walk = func(path string, s map[string]any, typ reflect.Type) {
+		for typ.Kind() == reflect.Pointer || typ.Kind() == reflect.Slice {
+			if typ.Kind() == reflect.Slice {
+				s = s["items"].(map[string]any)
+			}
+			typ = typ.Elem()
+		}
+		if typ.Kind() != reflect.Struct {
+			return
+		}
+
+		props, _ := s["properties"].(map[string]any)
+		if s["additionalProperties"] != false {
+			t.Errorf("%s allows additional properties", path)
+		}
+		var fields []string
+		for i := 0; i < typ.NumField(); i++ {
+			f := typ.Field(i)
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			fields = append(fields, name)
+			prop, ok := props[name].(map[string]any)
+			if !ok {
+				t.Errorf("%s.%s is missing from the schema", path, name)
+				continue
+			}
+			if strings.HasPrefix(f.Tag.Get("binding"), "required") {
+				if required, _ := s["required"].([]any); !slices.Contains(required, any(name)) {
+					t.Errorf("%s.%s is required but not in the schema", path, name)
+				}
+			}
+			walk(path+"."+name, prop, f.Type)
+		}
+		for name := range props {
+			if !slices.Contains(fields, name) {
+				t.Errorf("%s.%s is not a field of %s", path, name, typ)
+			}
+		}
+	}
+	walk("itinerary", schema, reflect.TypeOf(Itinerary{}))
+}
@@ -0,0 +1,45 @@
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/ErebusAJ/YatraBandhu/internals/db"
+)
+
+// Every
+// runs fn immediately and then every interval until ctx is cancelled
+// errors are logged, the job keeps running
+func Every(ctx context.Context, interval time.Duration, name string, fn func(ctx context.Context) error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		err := fn(ctx)
+		if err != nil && ctx.Err() == nil {
+			slog.ErrorContext(ctx, "job failed", "job", name, "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// PurgeExpiredResetTokens
+// deletes password reset tokens past their expiry
+func PurgeExpiredResetTokens(q *db.Queries) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		n, err := q.DeleteExpiredTokens(ctx)
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			slog.InfoContext(ctx, "purged expired password reset tokens", "count", n)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,130 @@
+// Package jsondiff compares two JSON documents, the changes are
+// operations of a JSON Patch (RFC 6902) turning one into the other
+package jsondiff
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Operations of a Change
+const (
+	OpAdd     = "add"
+	OpRemove  = "remove"
+	OpReplace = "replace"
+)
+
+// Change
+// One difference between two documents, applied in order the
+// changes of Diff form a valid JSON Patch
+type Change struct {
+	Op   string `json:"op" enum:"add remove replace"`
+	Path string `json:"path" doc:"JSON Pointer to the changed value"`
+	// Old is the value removed or replaced, an addition to JSON
+	// Patch so a change can be shown or undone
+	Old   json.RawMessage `json:"old,omitempty" doc:"the value removed or replaced"`
+	Value json.RawMessage `json:"value,omitempty" doc:"the value added or replacing the old one"`
+}
+
+// Diff
+// the changes turning a into b, object keys are compared in sorted
+// order and arrays index by index, so an element inserted at the
+// front shows as every later element replaced
+func Diff(a, b []byte) ([]Change, error) {
+	var va, vb any
+	if err := unmarshal(a, &va); err != nil {
+		return nil, fmt.Errorf("jsondiff: first document: %w", err)
+	}
+	if err := unmarshal(b, &vb); err != nil {
+		return nil, fmt.Errorf("jsondiff: second document: %w", err)
+	}
+
+	changes := []Change{}
+	diff(&changes, "", va, vb)
+	return changes, nil
+}
+
+// unmarshal
+// decodes data keeping numbers as written
+func unmarshal(data []byte, v *any) error {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	return d.Decode(v)
+}
+
+func diff(changes *[]Change, path string, a, b any) {
+	switch a := a.(type) {
+	case map[string]any:
+		if b, ok := b.(map[string]any); ok {
+			diffObjects(changes, path, a, b)
+			return
+		}
+	case []any:
+		if b, ok := b.([]any); ok {
+			diffArrays(changes, path, a, b)
+			return
+		}
+	}
+
+	oldValue, newValue := encode(a), encode(b)
+	if !bytes.Equal(oldValue, newValue) {
+		*changes = append(*changes, Change{Op: OpReplace, Path: path, Old: oldValue, Value: newValue})
+	}
+}
+
+func diffObjects(changes *[]Change, path string, a, b map[string]any) {
+	keys := slices.Collect(maps.Keys(a))
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	slices.Sort(keys)
+
+	for _, k := range keys {
+		p := path + "/" + escape(k)
+		va, inA := a[k]
+		vb, inB := b[k]
+		switch {
+		case !inB:
+			*changes = append(*changes, Change{Op: OpRemove, Path: p, Old: encode(va)})
+		case !inA:
+			*changes = append(*changes, Change{Op: OpAdd, Path: p, Value: encode(vb)})
+		default:
+			diff(changes, p, va, vb)
+		}
+	}
+}
+
+func diffArrays(changes *[]Change, path string, a, b []any) {
+	n := min(len(a), len(b))
+	for i := range n {
+		diff(changes, path+"/"+strconv.Itoa(i), a[i], b[i])
+	}
+	for i := n; i < len(b); i++ {
+		*changes = append(*changes, Change{Op: OpAdd, Path: path + "/" + strconv.Itoa(i), Value: encode(b[i])})
+	}
+	// from the end so the indexes stay valid as the patch is applied
+	for i := len(a) - 1; i >= n; i-- {
+		*changes = append(*changes, Change{Op: OpRemove, Path: path + "/" + strconv.Itoa(i), Old: encode(a[i])})
+	}
+}
+
+// encode
+// a decoded value back as compact json, maps are written with
+// sorted keys so equal values encode the same
+func encode(v any) json.RawMessage {
+	data, _ := json.Marshal(v)
+	return data
+}
+
+// escape
+// a key as a JSON Pointer reference token
+func escape(key string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(key)
+}
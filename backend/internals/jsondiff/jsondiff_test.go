@@ -0,0 +1,45 @@
+package jsondiff
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	a := `{"destination": "Goa", "days": [{"n": 1}, {"n": 2}, {"n": 3}], "tips": ["sun"], "a/b": 1, "price": 1.50}`
+	b := `{"destination": "Goa, India", "days": [{"n": 1}, {"n": 5}], "tips": ["sun", "water"], "notes": null, "price": 1.50}`
+
+	changes, err := Diff([]byte(a), []byte(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := json.Marshal(changes)
+	want := `[{"op":"remove","path":"/a~1b","old":1},` +
+		`{"op":"replace","path":"/days/1/n","old":2,"value":5},` +
+		`{"op":"remove","path":"/days/2","old":{"n":3}},` +
+		`{"op":"replace","path":"/destination","old":"Goa","value":"Goa, India"},` +
+		`{"op":"add","path":"/notes","value":null},` +
+		`{"op":"add","path":"/tips/1","value":"water"}]`
+	if string(got) != want {
+		t.Fatalf("unexpected changes\n got %s\nwant %s", got, want)
+	}
+
+	// arrays shrinking by several elements remove from the end
+	changes, _ = Diff([]byte(`[1, 2, 3]`), []byte(`[1]`))
+	if len(changes) != 2 || changes[0].Path != "/2" || changes[1].Path != "/1" {
+		t.Fatalf("unexpected changes %+v", changes)
+	}
+
+	// a type change replaces the whole value
+	changes, _ = Diff([]byte(`{"x": {"y": 1}}`), []byte(`{"x": [1]}`))
+	if len(changes) != 1 || changes[0].Op != OpReplace || string(changes[0].Value) != "[1]" {
+		t.Fatalf("unexpected changes %+v", changes)
+	}
+
+	if changes, err := Diff([]byte(a), []byte(a)); err != nil || len(changes) != 0 {
+		t.Fatalf("expected no changes, got %+v, %v", changes, err)
+	}
+	if _, err := Diff([]byte(`{`), []byte(b)); err == nil {
+		t.Fatal("expected an error for invalid json")
+	}
+}
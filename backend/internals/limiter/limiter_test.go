@@ -0,0 +1,85 @@
+package limiter
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryRateLimiter(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	l := NewMemoryRateLimiter()
+	l.now = func() time.Time { return now }
+	rate := Rate{Limit: 6, Per: time.Minute, Burst: 2}
+
+	for i, want := range []int{1, 0} {
+		if d, _ := l.Allow(ctx, "a", rate); !d.Allowed || d.Remaining != want {
+			t.Fatalf("request %d: unexpected decision %+v", i, d)
+		}
+	}
+	if d, _ := l.Allow(ctx, "a", rate); d.Allowed || d.RetryAfter != 10*time.Second {
+		t.Fatalf("expected a 10s wait, got %+v", d)
+	}
+	if d, _ := l.Allow(ctx, "b", rate); !d.Allowed {
+		t.Fatal("buckets should be per key")
+	}
+
+	// one token refills every 10s, never past the burst
+	now = now.Add(5 * time.Second)
+	if d, _ := l.Allow(ctx, "a", rate); d.Allowed || d.RetryAfter != 5*time.Second {
+		t.Fatalf("expected a 5s wait, got %+v", d)
+	}
+	now = now.Add(time.Hour)
+	if d, _ := l.Allow(ctx, "a", rate); !d.Allowed || d.Remaining != 1 {
+		t.Fatalf("unexpected decision after refill %+v", d)
+	}
+}
+
+func TestMemoryQuotaCounter(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 1, 31, 23, 0, 0, 0, time.UTC)
+	q := NewMemoryQuotaCounter()
+	q.now = func() time.Time { return now }
+	limits := []Limit{{Period: Day, Max: 2}, {Period: Month, Max: 3}}
+
+	for range 2 {
+		if _, ok, err := q.Use(ctx, "u", limits); !ok || err != nil {
+			t.Fatalf("use refused early: %v", err)
+		}
+	}
+	usage, ok, _ := q.Use(ctx, "u", limits)
+	if ok || !usage[0].Exceeded() || usage[1].Remaining() != 1 || RetryAfter(usage, now) != time.Hour {
+		t.Fatalf("unexpected usage %+v", usage)
+	}
+
+	// a refund frees the daily quota again
+	if err := q.Refund(ctx, "u", limits); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := q.Use(ctx, "u", limits); !ok {
+		t.Fatal("refunded use refused")
+	}
+
+	// February starts with fresh counts
+	now = now.Add(2 * time.Hour)
+	usage, _ = q.Usage(ctx, "u", limits)
+	if usage[0].Used != 0 || usage[1].Used != 0 || !usage[1].ResetsAt.Equal(time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected usage in February %+v", usage)
+	}
+
+	// another day of January only resets the daily count
+	now = time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)
+	if _, ok, _ := q.Use(ctx, "u", limits); !ok {
+		t.Fatal("use refused on a new day")
+	}
+	usage, ok, _ = q.Use(ctx, "u", limits)
+	if ok || usage[0].Exceeded() || !usage[1].Exceeded() {
+		t.Fatalf("expected the monthly quota to be exceeded %+v", usage)
+	}
+
+	// zero is unlimited
+	if usage, ok, _ := q.Use(ctx, "u", []Limit{{Period: Day}}); !ok || usage[0].Remaining() != -1 {
+		t.Fatalf("unexpected unlimited usage %+v", usage)
+	}
+}
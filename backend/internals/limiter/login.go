@@ -0,0 +1,112 @@
+package limiter
+
+import (
+	"context"
+	"time"
+)
+
+// LoginLimiter
+// Tracks failed login attempts per key (account or client ip)
+// and tells callers how long a key has to wait before retrying
+type LoginLimiter interface {
+	// Check returns the remaining wait for a key, zero if an attempt is allowed
+	Check(ctx context.Context, key string) (time.Duration, error)
+	// Fail records a failed attempt for a key
+	Fail(ctx context.Context, key string) (Status, error)
+	// Reset clears the failures of a key after a successful login
+	Reset(ctx context.Context, key string) error
+}
+
+// Policy
+// Backoff and lockout settings for a limiter
+type Policy struct {
+	// FreeAttempts failures are allowed before any backoff
+	FreeAttempts int
+	// MaxFailures failures lock the key for LockoutDuration
+	MaxFailures int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	// LockoutDuration applies once MaxFailures is reached
+	LockoutDuration time.Duration
+	// Window after the last failure in which failures are remembered
+	Window time.Duration
+}
+
+// Status
+// State of a key after recording a failure
+type Status struct {
+	Failures     int
+	BlockedUntil time.Time
+	// Locked is true only for the failure that crossed MaxFailures
+	Locked bool
+}
+
+// AccountPolicy
+// Defaults for per account limiting
+var AccountPolicy = Policy{
+	FreeAttempts:    3,
+	MaxFailures:     10,
+	BaseDelay:       time.Second,
+	MaxDelay:        5 * time.Minute,
+	LockoutDuration: 30 * time.Minute,
+	Window:          time.Hour,
+}
+
+// IPPolicy
+// Defaults for per client ip limiting, looser since ips are often shared
+var IPPolicy = Policy{
+	FreeAttempts:    20,
+	MaxFailures:     100,
+	BaseDelay:       time.Second,
+	MaxDelay:        5 * time.Minute,
+	LockoutDuration: time.Hour,
+	Window:          time.Hour,
+}
+
+// ResetPolicy
+// Limits password reset requests per email, every request counts
+// so the 4th request within an hour waits a minute, doubling up to an hour
+var ResetPolicy = Policy{
+	FreeAttempts:    3,
+	MaxFailures:     10,
+	BaseDelay:       time.Minute,
+	MaxDelay:        time.Hour,
+	LockoutDuration: time.Hour,
+	Window:          time.Hour,
+}
+
+// VerificationPolicy
+// Limits verification resends per email, every request counts
+// so the 2nd request within two minutes waits, doubling up to an hour
+var VerificationPolicy = Policy{
+	FreeAttempts:    0,
+	MaxFailures:     10,
+	BaseDelay:       2 * time.Minute,
+	MaxDelay:        time.Hour,
+	LockoutDuration: time.Hour,
+	Window:          time.Hour,
+}
+
+// next
+// applies one failure at time now to the previous failure count
+func (p Policy) next(failures int, lastFailure time.Time, now time.Time) Status {
+	if now.Sub(lastFailure) > p.Window {
+		failures = 0
+	}
+	failures++
+
+	status := Status{Failures: failures, BlockedUntil: now}
+	switch {
+	case failures >= p.MaxFailures:
+		status.BlockedUntil = now.Add(p.LockoutDuration)
+		status.Locked = failures == p.MaxFailures
+	case failures > p.FreeAttempts:
+		delay := p.BaseDelay << (failures - p.FreeAttempts - 1)
+		if delay <= 0 || delay > p.MaxDelay {
+			delay = p.MaxDelay
+		}
+		status.BlockedUntil = now.Add(delay)
+	}
+
+	return status
+}
@@ -0,0 +1,234 @@
+package limiter
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+type memoryEntry struct {
+	failures     int
+	lastFailure  time.Time
+	blockedUntil time.Time
+}
+
+// MemoryLoginLimiter
+// In process LoginLimiter, state is lost on restart
+// and not shared between replicas
+type MemoryLoginLimiter struct {
+	policy  Policy
+	mu      sync.Mutex
+	entries map[string]*memoryEntry
+}
+
+// NewMemoryLoginLimiter
+// returns an in-memory limiter applying policy
+func NewMemoryLoginLimiter(policy Policy) *MemoryLoginLimiter {
+	return &MemoryLoginLimiter{
+		policy:  policy,
+		entries: make(map[string]*memoryEntry),
+	}
+}
+
+func (l *MemoryLoginLimiter) Check(ctx context.Context, key string) (time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry, ok := l.entries[key]
+	if !ok {
+		return 0, nil
+	}
+
+	return max(time.Until(entry.blockedUntil), 0), nil
+}
+
+func (l *MemoryLoginLimiter) Fail(ctx context.Context, key string) (Status, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry, ok := l.entries[key]
+	if !ok {
+		entry = &memoryEntry{}
+		l.entries[key] = entry
+	}
+
+	now := time.Now()
+	status := l.policy.next(entry.failures, entry.lastFailure, now)
+	entry.failures = status.Failures
+	entry.lastFailure = now
+	entry.blockedUntil = status.BlockedUntil
+
+	l.evict(now)
+	return status, nil
+}
+
+func (l *MemoryLoginLimiter) Reset(ctx context.Context, key string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.entries, key)
+	return nil
+}
+
+// evict
+// drops forgotten entries so the map doesn't grow unbounded
+func (l *MemoryLoginLimiter) evict(now time.Time) {
+	for key, entry := range l.entries {
+		if now.Sub(entry.lastFailure) > l.policy.Window && now.After(entry.blockedUntil) {
+			delete(l.entries, key)
+		}
+	}
+}
+
+type memoryBucket struct {
+	tokens float64
+	last   time.Time
+	// idle time after which the bucket is full again
+	full time.Duration
+}
+
+// MemoryRateLimiter
+// In process RateLimiter, buckets are lost on restart
+// and not shared between replicas
+type MemoryRateLimiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*memoryBucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewMemoryRateLimiter
+// returns an empty in-memory rate limiter
+func NewMemoryRateLimiter() *MemoryRateLimiter {
+	return &MemoryRateLimiter{
+		buckets: make(map[string]*memoryBucket),
+		now:     time.Now,
+	}
+}
+
+func (l *MemoryRateLimiter) Allow(ctx context.Context, key string, rate Rate) (Decision, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &memoryBucket{tokens: float64(rate.Size()), last: now}
+		l.buckets[key] = bucket
+	}
+
+	var decision Decision
+	bucket.tokens, decision = rate.take(bucket.tokens, bucket.last, now)
+	bucket.last = now
+	bucket.full = rate.full()
+
+	// full buckets behave like missing ones, drop them now and then
+	if now.Sub(l.lastSweep) > time.Minute {
+		l.lastSweep = now
+		for key, b := range l.buckets {
+			if now.Sub(b.last) > b.full {
+				delete(l.buckets, key)
+			}
+		}
+	}
+	return decision, nil
+}
+
+// MemoryQuotaCounter
+// In process QuotaCounter, counts are lost on restart
+// and not shared between replicas
+type MemoryQuotaCounter struct {
+	mu     sync.Mutex
+	counts map[string]int
+	swept  time.Time
+	now    func() time.Time
+}
+
+// NewMemoryQuotaCounter
+// returns an empty in-memory quota counter
+func NewMemoryQuotaCounter() *MemoryQuotaCounter {
+	return &MemoryQuotaCounter{
+		counts: make(map[string]int),
+		now:    time.Now,
+	}
+}
+
+func (q *MemoryQuotaCounter) Use(ctx context.Context, key string, limits []Limit) ([]Usage, bool, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := q.now()
+	usage := q.usage(key, limits, now)
+	for _, u := range usage {
+		if u.Exceeded() {
+			return usage, false, nil
+		}
+	}
+
+	for i := range usage {
+		usage[i].Used++
+		q.counts[countKey(key, usage[i].Period, now)] = usage[i].Used
+	}
+	q.evict(now)
+	return usage, true, nil
+}
+
+func (q *MemoryQuotaCounter) Refund(ctx context.Context, key string, limits []Limit) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := q.now()
+	for _, limit := range limits {
+		k := countKey(key, limit.Period, now)
+		if q.counts[k] > 0 {
+			q.counts[k]--
+		}
+	}
+	return nil
+}
+
+func (q *MemoryQuotaCounter) Usage(ctx context.Context, key string, limits []Limit) ([]Usage, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.usage(key, limits, q.now()), nil
+}
+
+func (q *MemoryQuotaCounter) usage(key string, limits []Limit, now time.Time) []Usage {
+	usage := make([]Usage, 0, len(limits))
+	for _, limit := range limits {
+		usage = append(usage, Usage{
+			Limit:    limit,
+			Used:     q.counts[countKey(key, limit.Period, now)],
+			ResetsAt: limit.Period.End(now),
+		})
+	}
+	return usage
+}
+
+// evict
+// drops the counts of past periods once a day
+func (q *MemoryQuotaCounter) evict(now time.Time) {
+	today := Day.Start(now)
+	if !today.After(q.swept) {
+		return
+	}
+	q.swept = today
+
+	current := map[string]bool{}
+	for _, p := range []Period{Day, Month} {
+		current[p.Start(now).Format(time.DateOnly)] = true
+	}
+	for k := range q.counts {
+		if !current[k[strings.LastIndex(k, "|")+1:]] {
+			delete(q.counts, k)
+		}
+	}
+}
+
+// countKey
+// the count of key in the period holding now
+func countKey(key string, period Period, now time.Time) string {
+	return key + "|" + string(period) + "|" + period.Start(now).Format(time.DateOnly)
+}
@@ -0,0 +1,230 @@
+package limiter
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/ErebusAJ/YatraBandhu/internals/db"
+)
+
+// PostgresLoginLimiter
+// LoginLimiter backed by the login_attempts table
+// so limits hold across replicas
+type PostgresLoginLimiter struct {
+	policy Policy
+	conn   *sql.DB
+	// prefix keeps keys of limiters with different policies apart
+	prefix string
+}
+
+// NewPostgresLoginLimiter
+// returns a limiter storing attempts in postgres under prefix
+func NewPostgresLoginLimiter(conn *sql.DB, prefix string, policy Policy) *PostgresLoginLimiter {
+	return &PostgresLoginLimiter{
+		policy: policy,
+		conn:   conn,
+		prefix: prefix,
+	}
+}
+
+func (l *PostgresLoginLimiter) Check(ctx context.Context, key string) (time.Duration, error) {
+	attempt, err := db.New(l.conn).GetLoginAttempt(ctx, l.prefix+key)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+
+	return max(time.Until(attempt.BlockedUntil), 0), nil
+}
+
+func (l *PostgresLoginLimiter) Fail(ctx context.Context, key string) (Status, error) {
+	tx, err := l.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return Status{}, err
+	}
+	defer tx.Rollback()
+
+	queries := db.New(tx)
+	err = queries.EnsureLoginAttempt(ctx, l.prefix+key)
+	if err != nil {
+		return Status{}, err
+	}
+
+	// row lock serialises concurrent failures for the same key
+	attempt, err := queries.GetLoginAttemptForUpdate(ctx, l.prefix+key)
+	if err != nil {
+		return Status{}, err
+	}
+
+	lastFailure := attempt.LastFailure
+	if attempt.Failures == 0 {
+		lastFailure = time.Time{}
+	}
+
+	now := time.Now()
+	status := l.policy.next(int(attempt.Failures), lastFailure, now)
+	err = queries.UpdateLoginAttempt(ctx, db.UpdateLoginAttemptParams{
+		Failures:     int32(status.Failures),
+		LastFailure:  now,
+		BlockedUntil: status.BlockedUntil,
+		AttemptKey:   l.prefix + key,
+	})
+	if err != nil {
+		return Status{}, err
+	}
+
+	return status, tx.Commit()
+}
+
+func (l *PostgresLoginLimiter) Reset(ctx context.Context, key string) error {
+	return db.New(l.conn).DeleteLoginAttempt(ctx, l.prefix+key)
+}
+
+// PostgresRateLimiter
+// RateLimiter backed by the rate_buckets table
+// so limits hold across replicas
+type PostgresRateLimiter struct {
+	conn *sql.DB
+}
+
+// NewPostgresRateLimiter
+// returns a rate limiter storing buckets in postgres
+func NewPostgresRateLimiter(conn *sql.DB) *PostgresRateLimiter {
+	return &PostgresRateLimiter{conn: conn}
+}
+
+func (l *PostgresRateLimiter) Allow(ctx context.Context, key string, rate Rate) (Decision, error) {
+	tx, err := l.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return Decision{}, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	queries := db.New(tx)
+	err = queries.EnsureRateBucket(ctx, db.EnsureRateBucketParams{
+		BucketKey: key,
+		Tokens:    float64(rate.Size()),
+		UpdatedAt: now,
+	})
+	if err != nil {
+		return Decision{}, err
+	}
+
+	// row lock serialises concurrent requests for the same key
+	bucket, err := queries.GetRateBucketForUpdate(ctx, key)
+	if err != nil {
+		return Decision{}, err
+	}
+
+	tokens, decision := rate.take(bucket.Tokens, bucket.UpdatedAt, now)
+	err = queries.UpdateRateBucket(ctx, db.UpdateRateBucketParams{
+		Tokens:    tokens,
+		UpdatedAt: now,
+		BucketKey: key,
+	})
+	if err != nil {
+		return Decision{}, err
+	}
+
+	return decision, tx.Commit()
+}
+
+// PostgresQuotaCounter
+// QuotaCounter backed by the quota_usage table
+type PostgresQuotaCounter struct {
+	conn *sql.DB
+}
+
+// NewPostgresQuotaCounter
+// returns a quota counter storing counts in postgres
+func NewPostgresQuotaCounter(conn *sql.DB) *PostgresQuotaCounter {
+	return &PostgresQuotaCounter{conn: conn}
+}
+
+func (q *PostgresQuotaCounter) Use(ctx context.Context, key string, limits []Limit) ([]Usage, bool, error) {
+	tx, err := q.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	queries := db.New(tx)
+	usage := make([]Usage, 0, len(limits))
+	exceeded := false
+	for _, limit := range limits {
+		row := db.GetQuotaUsedForUpdateParams{
+			QuotaKey:    key,
+			Period:      string(limit.Period),
+			PeriodStart: limit.Period.Start(now),
+		}
+		err = queries.EnsureQuotaUsage(ctx, db.EnsureQuotaUsageParams(row))
+		if err != nil {
+			return nil, false, err
+		}
+		// row lock serialises concurrent uses of the same key
+		used, err := queries.GetQuotaUsedForUpdate(ctx, row)
+		if err != nil {
+			return nil, false, err
+		}
+
+		u := Usage{Limit: limit, Used: int(used), ResetsAt: limit.Period.End(now)}
+		exceeded = exceeded || u.Exceeded()
+		usage = append(usage, u)
+	}
+	if exceeded {
+		return usage, false, nil
+	}
+
+	for i, u := range usage {
+		usage[i].Used++
+		err = queries.SetQuotaUsed(ctx, db.SetQuotaUsedParams{
+			Used:        int32(usage[i].Used),
+			QuotaKey:    key,
+			Period:      string(u.Period),
+			PeriodStart: u.Period.Start(now),
+		})
+		if err != nil {
+			return nil, false, err
+		}
+	}
+
+	return usage, true, tx.Commit()
+}
+
+func (q *PostgresQuotaCounter) Refund(ctx context.Context, key string, limits []Limit) error {
+	now := time.Now().UTC()
+	queries := db.New(q.conn)
+	for _, limit := range limits {
+		err := queries.RefundQuotaUsage(ctx, db.RefundQuotaUsageParams{
+			QuotaKey:    key,
+			Period:      string(limit.Period),
+			PeriodStart: limit.Period.Start(now),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (q *PostgresQuotaCounter) Usage(ctx context.Context, key string, limits []Limit) ([]Usage, error) {
+	now := time.Now().UTC()
+	queries := db.New(q.conn)
+	usage := make([]Usage, 0, len(limits))
+	for _, limit := range limits {
+		used, err := queries.GetQuotaUsed(ctx, db.GetQuotaUsedParams{
+			QuotaKey:    key,
+			Period:      string(limit.Period),
+			PeriodStart: limit.Period.Start(now),
+		})
+		if err != nil && err != sql.ErrNoRows {
+			return nil, err
+		}
+		usage = append(usage, Usage{Limit: limit, Used: int(used), ResetsAt: limit.Period.End(now)})
+	}
+	return usage, nil
+}
@@ -0,0 +1,89 @@
+package limiter
+
+import (
+	"context"
+	"time"
+)
+
+// QuotaCounter
+// Counts uses of a key per calendar period (UTC) and refuses
+// a use once any period is at its limit
+type QuotaCounter interface {
+	// Use counts one use of key in every period of limits, unless one
+	// is already at its limit, then nothing is counted and ok is false
+	Use(ctx context.Context, key string, limits []Limit) (usage []Usage, ok bool, err error)
+	// Refund takes back a use counted in the current periods
+	Refund(ctx context.Context, key string, limits []Limit) error
+	// Usage reports the current counts without using any
+	Usage(ctx context.Context, key string, limits []Limit) ([]Usage, error)
+}
+
+// Period
+// A calendar period quotas are counted in
+type Period string
+
+const (
+	Day   Period = "day"
+	Month Period = "month"
+)
+
+// Start
+// the start of the period holding t, in UTC
+func (p Period) Start(t time.Time) time.Time {
+	t = t.UTC()
+	if p == Month {
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// End
+// the start of the period after the one holding t
+func (p Period) End(t time.Time) time.Time {
+	if p == Month {
+		return p.Start(t).AddDate(0, 1, 0)
+	}
+	return p.Start(t).AddDate(0, 0, 1)
+}
+
+// Limit
+// Uses allowed per period, zero for no limit
+type Limit struct {
+	Period Period
+	Max    int
+}
+
+// Usage
+// Count of a key in the current period of a limit
+type Usage struct {
+	Limit
+	Used     int
+	ResetsAt time.Time
+}
+
+// Exceeded
+// reports whether another use would go over the limit
+func (u Usage) Exceeded() bool {
+	return u.Max > 0 && u.Used >= u.Max
+}
+
+// Remaining
+// uses left in the period, -1 when unlimited
+func (u Usage) Remaining() int {
+	if u.Max <= 0 {
+		return -1
+	}
+	return max(u.Max-u.Used, 0)
+}
+
+// RetryAfter
+// the wait until every exceeded limit of usage resets
+func RetryAfter(usage []Usage, now time.Time) time.Duration {
+	var wait time.Duration
+	for _, u := range usage {
+		if u.Exceeded() {
+			wait = max(wait, u.ResetsAt.Sub(now))
+		}
+	}
+	return wait
+}
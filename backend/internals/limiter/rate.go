@@ -0,0 +1,79 @@
+package limiter
+
+import (
+	"context"
+	"time"
+)
+
+// RateLimiter
+// Token buckets per key, every request takes one token and
+// buckets refill continuously up to their burst
+type RateLimiter interface {
+	// Allow takes a token from the bucket of key
+	Allow(ctx context.Context, key string, rate Rate) (Decision, error)
+}
+
+// Rate
+// Refill speed and size of a bucket
+type Rate struct {
+	// Limit requests per Per on average
+	Limit int
+	Per   time.Duration
+	// Burst is the bucket size, Limit when zero
+	Burst int
+}
+
+// Enabled
+// reports whether the rate limits anything, a zero rate does not
+func (r Rate) Enabled() bool {
+	return r.Limit > 0 && r.Per > 0
+}
+
+// Size
+// the bucket size
+func (r Rate) Size() int {
+	if r.Burst > 0 {
+		return r.Burst
+	}
+	return r.Limit
+}
+
+// Decision
+// Outcome of taking a token
+type Decision struct {
+	Allowed bool
+	// Remaining whole tokens after the request
+	Remaining int
+	// RetryAfter is the wait until a token is available, zero when allowed
+	RetryAfter time.Duration
+}
+
+// interval
+// time to refill one token
+func (r Rate) interval() time.Duration {
+	return r.Per / time.Duration(r.Limit)
+}
+
+// full
+// time for an empty bucket to refill, buckets untouched for longer
+// are the same as new ones
+func (r Rate) full() time.Duration {
+	return r.interval() * time.Duration(r.Size())
+}
+
+// take
+// applies one request at time now to a bucket holding tokens at last
+// and returns the tokens left
+func (r Rate) take(tokens float64, last time.Time, now time.Time) (float64, Decision) {
+	if elapsed := now.Sub(last); elapsed > 0 {
+		tokens += float64(elapsed) / float64(r.interval())
+	}
+	tokens = min(tokens, float64(r.Size()))
+
+	if tokens >= 1 {
+		tokens--
+		return tokens, Decision{Allowed: true, Remaining: int(tokens)}
+	}
+	wait := time.Duration((1 - tokens) * float64(r.interval()))
+	return tokens, Decision{RetryAfter: max(wait, time.Millisecond)}
+}
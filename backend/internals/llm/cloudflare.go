@@ -0,0 +1,143 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ErebusAJ/YatraBandhu/internals/httpclient"
+	"github.com/ErebusAJ/YatraBandhu/internals/metrics"
+)
+
+// Cloudflare
+// Workers AI models of an account
+type Cloudflare struct {
+	// BaseURL defaults to the public API
+	BaseURL   string
+	AccountID string
+	APIKey    string
+	client    *http.Client
+}
+
+// NewCloudflare
+// returns the Workers AI provider of an account
+func NewCloudflare(accountID, apiKey string) *Cloudflare {
+	return &Cloudflare{
+		BaseURL:   "https://api.cloudflare.com/client/v4",
+		AccountID: accountID,
+		APIKey:    apiKey,
+		client:    httpclient.New(ProviderCloudflare, 300*time.Second),
+	}
+}
+
+func (p *Cloudflare) Name() string {
+	return ProviderCloudflare
+}
+
+func (p *Cloudflare) Complete(ctx context.Context, model string, req Request) (Completion, error) {
+	return p.run(ctx, model, req, nil, nil)
+}
+
+// Stream
+// reads the server-sent events of Workers AI, the usage comes
+// with the last one on models reporting it
+func (p *Cloudflare) Stream(ctx context.Context, model string, req Request, onText func(text string)) (Completion, error) {
+	return p.run(ctx, model, req, nil, onText)
+}
+
+// Structured
+// uses JSON mode when a schema is given, models without it still
+// get the prompt and the JSON is cut out of their answer
+func (p *Cloudflare) Structured(ctx context.Context, model string, req Request, schema json.RawMessage) (Completion, error) {
+	var format any
+	if schema != nil {
+		format = map[string]any{"type": "json_schema", "json_schema": schema}
+	}
+	res, err := p.run(ctx, model, req, format, nil)
+	res.Text = ExtractJSON(res.Text)
+	return res, err
+}
+
+// run
+// streams the answer to onText when it is set
+func (p *Cloudflare) run(ctx context.Context, model string, req Request, format any, onText func(text string)) (Completion, error) {
+	if p.AccountID == "" || p.APIKey == "" {
+		return Completion{}, errors.New("cloudflare account id and api key are not configured")
+	}
+
+	payload := map[string]any{
+		"messages":    messages(req),
+		"temperature": req.Temperature,
+		"stream":      onText != nil,
+	}
+	if req.MaxTokens > 0 {
+		payload["max_tokens"] = req.MaxTokens
+	}
+	if format != nil {
+		payload["response_format"] = format
+	}
+
+	type usage struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	}
+
+	url := fmt.Sprintf("%s/accounts/%s/ai/run/%s", p.BaseURL, p.AccountID, model)
+	header := http.Header{"Authorization": {"Bearer " + p.APIKey}}
+	var res Completion
+	if onText == nil {
+		var result struct {
+			Result struct {
+				// a string, or an object in JSON mode
+				Response json.RawMessage `json:"response"`
+				Usage    usage           `json:"usage"`
+			} `json:"result"`
+			Success bool  `json:"success"`
+			Errors  []any `json:"errors"`
+		}
+		if err := postJSON(ctx, p.client, url, header, payload, &result); err != nil {
+			return Completion{}, err
+		}
+		if !result.Success || len(result.Errors) > 0 {
+			return Completion{}, fmt.Errorf("API errors: %v", result.Errors)
+		}
+
+		res = Completion{PromptTokens: result.Result.Usage.PromptTokens, CompletionTokens: result.Result.Usage.CompletionTokens}
+		if err := json.Unmarshal(result.Result.Response, &res.Text); err != nil {
+			res.Text = string(result.Result.Response)
+		}
+	} else {
+		var text strings.Builder
+		err := postStream(ctx, p.client, url, header, payload, func(data []byte) error {
+			var event struct {
+				Response string `json:"response"`
+				Usage    *usage `json:"usage"`
+			}
+			if err := json.Unmarshal(data, &event); err != nil {
+				return err
+			}
+			if event.Usage != nil {
+				res.PromptTokens, res.CompletionTokens = event.Usage.PromptTokens, event.Usage.CompletionTokens
+			}
+			if event.Response != "" {
+				text.WriteString(event.Response)
+				onText(event.Response)
+			}
+			return nil
+		})
+		if err != nil {
+			return Completion{}, err
+		}
+		res.Text = text.String()
+	}
+	metrics.AddTokens(ProviderCloudflare, model, res.PromptTokens, res.CompletionTokens)
+
+	if res.Text == "" || res.Text == "null" {
+		return res, ErrNoOutput
+	}
+	return res, nil
+}
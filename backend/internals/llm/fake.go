@@ -0,0 +1,85 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+)
+
+// Fake
+// A deterministic provider for tests, without answer funcs
+// completions echo the prompt and structured answers are {}
+type Fake struct {
+	// Text answers Complete and Stream calls
+	Text func(model string, req Request) (string, error)
+	// JSON answers Structured calls
+	JSON func(model string, req Request, schema json.RawMessage) (string, error)
+
+	mu    sync.Mutex
+	calls []string
+}
+
+func (p *Fake) Name() string {
+	return ProviderFake
+}
+
+func (p *Fake) Complete(ctx context.Context, model string, req Request) (Completion, error) {
+	p.record(model)
+	text := "[" + model + "] " + req.Prompt
+	if p.Text != nil {
+		var err error
+		if text, err = p.Text(model, req); err != nil {
+			return Completion{}, err
+		}
+	}
+	return p.completion(req, text)
+}
+
+// Stream
+// relays the answer of Complete a word at a time
+func (p *Fake) Stream(ctx context.Context, model string, req Request, onText func(text string)) (Completion, error) {
+	res, err := p.Complete(ctx, model, req)
+	if err != nil {
+		return res, err
+	}
+	for _, word := range strings.SplitAfter(res.Text, " ") {
+		onText(word)
+	}
+	return res, nil
+}
+
+func (p *Fake) Structured(ctx context.Context, model string, req Request, schema json.RawMessage) (Completion, error) {
+	p.record(model)
+	text := "{}"
+	if p.JSON != nil {
+		var err error
+		if text, err = p.JSON(model, req, schema); err != nil {
+			return Completion{}, err
+		}
+	}
+	return p.completion(req, ExtractJSON(text))
+}
+
+// Calls
+// the models called so far, in order
+func (p *Fake) Calls() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.calls...)
+}
+
+func (p *Fake) record(model string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls = append(p.calls, model)
+}
+
+// completion
+// counts a token per four bytes, roughly what real tokenizers do
+func (p *Fake) completion(req Request, text string) (Completion, error) {
+	if text == "" {
+		return Completion{}, ErrNoOutput
+	}
+	return Completion{Text: text, PromptTokens: len(req.System+req.Prompt) / 4, CompletionTokens: len(text) / 4}, nil
+}
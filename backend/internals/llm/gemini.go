@@ -0,0 +1,140 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ErebusAJ/YatraBandhu/internals/httpclient"
+	"github.com/ErebusAJ/YatraBandhu/internals/metrics"
+)
+
+// Gemini
+// Google's Gemini models, the key is sent in a header
+// so it stays out of urls and logs
+type Gemini struct {
+	// BaseURL defaults to the public v1beta API
+	BaseURL string
+	APIKey  string
+	client  *http.Client
+}
+
+// NewGemini
+// returns the Gemini provider of an API key
+func NewGemini(apiKey string) *Gemini {
+	return &Gemini{
+		BaseURL: "https://generativelanguage.googleapis.com/v1beta",
+		APIKey:  apiKey,
+		client:  httpclient.New(ProviderGemini, 60*time.Second),
+	}
+}
+
+func (p *Gemini) Name() string {
+	return ProviderGemini
+}
+
+func (p *Gemini) Complete(ctx context.Context, model string, req Request) (Completion, error) {
+	return p.generate(ctx, model, req, map[string]any{}, nil)
+}
+
+// Stream
+// uses streamGenerateContent as server-sent events, each holding
+// the next parts and the usage so far
+func (p *Gemini) Stream(ctx context.Context, model string, req Request, onText func(text string)) (Completion, error) {
+	return p.generate(ctx, model, req, map[string]any{}, onText)
+}
+
+// Structured
+// asks for an application/json answer, matching schema if set
+func (p *Gemini) Structured(ctx context.Context, model string, req Request, schema json.RawMessage) (Completion, error) {
+	config := map[string]any{"responseMimeType": "application/json"}
+	if schema != nil {
+		config["responseJsonSchema"] = schema
+	}
+	res, err := p.generate(ctx, model, req, config, nil)
+	res.Text = ExtractJSON(res.Text)
+	return res, err
+}
+
+// generate
+// streams the answer to onText when it is set
+func (p *Gemini) generate(ctx context.Context, model string, req Request, config map[string]any, onText func(text string)) (Completion, error) {
+	if p.APIKey == "" {
+		return Completion{}, errors.New("gemini api key is not configured")
+	}
+
+	config["temperature"] = req.Temperature
+	if req.MaxTokens > 0 {
+		config["maxOutputTokens"] = req.MaxTokens
+	}
+	payload := map[string]any{
+		"contents":         []any{map[string]any{"role": "user", "parts": []any{map[string]string{"text": req.Prompt}}}},
+		"generationConfig": config,
+	}
+	if req.System != "" {
+		payload["systemInstruction"] = map[string]any{"parts": []any{map[string]string{"text": req.System}}}
+	}
+
+	// an answer, or a chunk of one when streaming
+	type result struct {
+		Candidates []struct {
+			Content struct {
+				Parts []struct {
+					Text string `json:"text"`
+				} `json:"parts"`
+			} `json:"content"`
+		} `json:"candidates"`
+		UsageMetadata struct {
+			PromptTokenCount     int `json:"promptTokenCount"`
+			CandidatesTokenCount int `json:"candidatesTokenCount"`
+		} `json:"usageMetadata"`
+	}
+
+	var res Completion
+	var text strings.Builder
+	read := func(r result) {
+		if r.UsageMetadata.PromptTokenCount > 0 || r.UsageMetadata.CandidatesTokenCount > 0 {
+			res.PromptTokens, res.CompletionTokens = r.UsageMetadata.PromptTokenCount, r.UsageMetadata.CandidatesTokenCount
+		}
+		if len(r.Candidates) == 0 {
+			return
+		}
+		for _, part := range r.Candidates[0].Content.Parts {
+			text.WriteString(part.Text)
+			if onText != nil && part.Text != "" {
+				onText(part.Text)
+			}
+		}
+	}
+
+	url := p.BaseURL + "/models/" + model
+	header := http.Header{"X-Goog-Api-Key": {p.APIKey}}
+	var err error
+	if onText == nil {
+		var r result
+		err = postJSON(ctx, p.client, url+":generateContent", header, payload, &r)
+		read(r)
+	} else {
+		err = postStream(ctx, p.client, url+":streamGenerateContent?alt=sse", header, payload, func(data []byte) error {
+			var r result
+			if err := json.Unmarshal(data, &r); err != nil {
+				return err
+			}
+			read(r)
+			return nil
+		})
+	}
+	if err != nil {
+		return Completion{}, err
+	}
+	metrics.AddTokens(ProviderGemini, model, res.PromptTokens, res.CompletionTokens)
+
+	res.Text = text.String()
+	if res.Text == "" {
+		return res, ErrNoOutput
+	}
+	return res, nil
+}
@@ -0,0 +1,329 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// ErrNoOutput
+// the model answered without any usable text
+var ErrNoOutput = errors.New("model returned no output")
+
+// LLMProvider
+// A language model API, the model is named per call so one
+// provider serves every model of an account
+type LLMProvider interface {
+	// Name identifies the provider in config, logs and metrics
+	Name() string
+	// Complete returns the model's text answer to req
+	Complete(ctx context.Context, model string, req Request) (Completion, error)
+	// Structured returns an answer holding a single JSON document,
+	// constrained to schema when it is set and the API supports it
+	Structured(ctx context.Context, model string, req Request, schema json.RawMessage) (Completion, error)
+}
+
+// Streamer
+// A provider that can send its answer as it is written, Chain.Stream
+// answers in one piece with providers lacking it
+type Streamer interface {
+	// Stream returns the answer of Complete, passing each piece
+	// of text to onText as it arrives
+	Stream(ctx context.Context, model string, req Request, onText func(text string)) (Completion, error)
+}
+
+// Request
+// A single turn prompt
+type Request struct {
+	// System is an optional system prompt
+	System      string
+	Prompt      string
+	MaxTokens   int
+	Temperature float64
+}
+
+// Completion
+// The model's answer and the tokens it cost
+type Completion struct {
+	Text             string
+	PromptTokens     int
+	CompletionTokens int
+}
+
+// Providers accepted in model specs
+const (
+	ProviderCloudflare = "cloudflare"
+	ProviderGemini     = "gemini"
+	ProviderOpenAI     = "openai"
+	ProviderOllama     = "ollama"
+	ProviderFake       = "fake"
+)
+
+// Config
+// Credentials and endpoints of the providers
+type Config struct {
+	CloudflareAccountID string
+	CloudflareAPIKey    string
+	GeminiAPIKey        string
+	// OpenAIBaseURL is any OpenAI compatible API, e.g. https://api.openai.com/v1
+	OpenAIBaseURL string
+	OpenAIAPIKey  string
+	// OllamaURL is the root of an Ollama server
+	OllamaURL string
+}
+
+// Providers
+// The configured providers by name
+type Providers map[string]LLMProvider
+
+// NewProviders
+// returns every provider, ones without credentials fail when called
+func NewProviders(cfg Config) Providers {
+	return Providers{
+		ProviderCloudflare: NewCloudflare(cfg.CloudflareAccountID, cfg.CloudflareAPIKey),
+		ProviderGemini:     NewGemini(cfg.GeminiAPIKey),
+		ProviderOpenAI:     NewOpenAI(cfg.OpenAIBaseURL, cfg.OpenAIAPIKey),
+		ProviderOllama:     NewOllama(cfg.OllamaURL),
+		ProviderFake:       &Fake{},
+	}
+}
+
+// Chain
+// parses model specs of the form provider:model, e.g.
+// "gemini:gemini-2.0-flash", into a fallback chain
+func (p Providers) Chain(specs []string) (Chain, error) {
+	var chain Chain
+	for _, spec := range specs {
+		name, model, ok := strings.Cut(strings.TrimSpace(spec), ":")
+		if !ok || model == "" {
+			return nil, fmt.Errorf("model %q is not provider:model", spec)
+		}
+		provider, ok := p[name]
+		if !ok {
+			return nil, fmt.Errorf("model %q has unknown provider %q", spec, name)
+		}
+		chain = append(chain, Model{Provider: provider, Name: model})
+	}
+	if len(chain) == 0 {
+		return nil, errors.New("no models configured")
+	}
+	return chain, nil
+}
+
+// Model
+// A model of a provider
+type Model struct {
+	Provider LLMProvider
+	Name     string
+}
+
+func (m Model) String() string {
+	return m.Provider.Name() + ":" + m.Name
+}
+
+// Chain
+// Models tried in order until one answers
+type Chain []Model
+
+// Complete
+// asks each model in turn, returning the first answer and the model
+// that gave it
+func (c Chain) Complete(ctx context.Context, req Request) (Completion, Model, error) {
+	return c.try(ctx, func(m Model) (Completion, error) {
+		return m.Provider.Complete(ctx, m.Name, req)
+	})
+}
+
+// Structured
+// asks each model in turn for a JSON answer
+func (c Chain) Structured(ctx context.Context, req Request, schema json.RawMessage) (Completion, Model, error) {
+	return c.try(ctx, func(m Model) (Completion, error) {
+		return m.Provider.Structured(ctx, m.Name, req, schema)
+	})
+}
+
+// Stream
+// asks each model in turn like Complete, relaying the answer to onText
+// as it is written. Once text was relayed there is no falling back, the
+// client already has part of the answer, so a model failing midway
+// fails the stream
+func (c Chain) Stream(ctx context.Context, req Request, onText func(text string)) (Completion, Model, error) {
+	relayed := false
+	relay := func(text string) {
+		if text != "" {
+			relayed = true
+			onText(text)
+		}
+	}
+
+	var errs []error
+	for i, m := range c {
+		var res Completion
+		var err error
+		if s, ok := m.Provider.(Streamer); ok {
+			res, err = s.Stream(ctx, m.Name, req, relay)
+		} else if res, err = m.Provider.Complete(ctx, m.Name, req); err == nil {
+			relay(res.Text)
+		}
+		if err == nil {
+			return res, m, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", m, err))
+		if ctx.Err() != nil || relayed {
+			break
+		}
+		if i < len(c)-1 {
+			slog.WarnContext(ctx, "llm: model failed, falling back", "model", m.String(), "next", c[i+1].String(), "error", err)
+		}
+	}
+	if len(errs) == 0 {
+		return Completion{}, Model{}, errors.New("no models configured")
+	}
+	return Completion{}, Model{}, errors.Join(errs...)
+}
+
+// try
+// falls back to the next model on any error, unless ctx is done
+// the errors of every model are joined when all fail
+func (c Chain) try(ctx context.Context, call func(m Model) (Completion, error)) (Completion, Model, error) {
+	var errs []error
+	for i, m := range c {
+		res, err := call(m)
+		if err == nil {
+			return res, m, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", m, err))
+		if ctx.Err() != nil {
+			break
+		}
+		if i < len(c)-1 {
+			slog.WarnContext(ctx, "llm: model failed, falling back", "model", m.String(), "next", c[i+1].String(), "error", err)
+		}
+	}
+	if len(errs) == 0 {
+		return Completion{}, Model{}, errors.New("no models configured")
+	}
+	return Completion{}, Model{}, errors.Join(errs...)
+}
+
+// ExtractJSON
+// the JSON document in a model's answer, without the markdown
+// fences or prose models tend to wrap it in
+func ExtractJSON(text string) string {
+	text = strings.TrimSpace(text)
+	if _, after, ok := strings.Cut(text, "```json"); ok {
+		text, _, _ = strings.Cut(after, "```")
+	} else if _, after, ok := strings.Cut(text, "```"); ok {
+		text, _, _ = strings.Cut(after, "```")
+	}
+	text = strings.TrimSpace(text)
+
+	start := strings.IndexAny(text, "{[")
+	end := strings.LastIndexAny(text, "}]")
+	if start >= 0 && end > start {
+		text = text[start : end+1]
+	}
+	return text
+}
+
+// postJSON
+// sends payload as json and decodes a 200 answer into out
+func postJSON(ctx context.Context, client *http.Client, url string, header http.Header, payload, out any) error {
+	resp, err := post(ctx, client, url, header, payload)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("error decoding response: %w", err)
+	}
+	return nil
+}
+
+// postStream
+// sends payload as json and reads the streamed answer, server-sent
+// events or json lines, passing the data of each message to onData
+func postStream(ctx context.Context, client *http.Client, url string, header http.Header, payload any, onData func(data []byte) error) error {
+	resp, err := post(ctx, client, url, header, payload)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if data, ok := bytes.CutPrefix(line, []byte("data:")); ok {
+			line = bytes.TrimSpace(data)
+		} else if !bytes.HasPrefix(line, []byte("{")) {
+			// blank lines, comments and other event fields
+			continue
+		}
+		if len(line) == 0 || string(line) == "[DONE]" {
+			continue
+		}
+		if err := onData(line); err != nil {
+			return fmt.Errorf("error decoding stream: %w", err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading stream: %w", err)
+	}
+	return nil
+}
+
+// post
+// sends payload as json, the answer is returned only with status 200
+func post(ctx context.Context, client *http.Client, url string, header http.Header, payload any) (*http.Response, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("API request failed: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("API returned status %d: %s", resp.StatusCode, msg)
+	}
+	return resp, nil
+}
+
+// messages
+// the chat messages of req
+func messages(req Request) []message {
+	var msgs []message
+	if req.System != "" {
+		msgs = append(msgs, message{Role: "system", Content: req.System})
+	}
+	return append(msgs, message{Role: "user", Content: req.Prompt})
+}
+
+// message
+// A chat message as most APIs take it
+type message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
@@ -0,0 +1,291 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestChain(t *testing.T) {
+	fake := &Fake{
+		Text: func(model string, req Request) (string, error) {
+			if model == "down" {
+				return "", errors.New("provider down")
+			}
+			return "plan by " + model, nil
+		},
+	}
+	providers := Providers{ProviderFake: fake}
+
+	chain, err := providers.Chain([]string{"fake:down", " fake:up "})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, model, err := chain.Complete(context.Background(), Request{Prompt: "3 days in Goa"})
+	if err != nil || model.Name != "up" || res.Text != "plan by up" {
+		t.Fatalf("unexpected answer %+v from %s, %v", res, model, err)
+	}
+	if calls := fake.Calls(); strings.Join(calls, ",") != "down,up" {
+		t.Fatalf("unexpected calls %v", calls)
+	}
+
+	chain, _ = providers.Chain([]string{"fake:down"})
+	if _, _, err := chain.Complete(context.Background(), Request{}); err == nil || !strings.Contains(err.Error(), "fake:down: provider down") {
+		t.Fatalf("expected the models' errors, got %v", err)
+	}
+
+	// a cancelled request doesn't fall back
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	fake.Text = func(model string, req Request) (string, error) { return "", context.Canceled }
+	chain, _ = providers.Chain([]string{"fake:a", "fake:b"})
+	if _, _, err := chain.Complete(ctx, Request{}); !errors.Is(err, context.Canceled) || strings.Contains(err.Error(), "fake:b") {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	for _, specs := range [][]string{nil, {"fake"}, {"fake:"}, {"claude:x"}} {
+		if _, err := providers.Chain(specs); err == nil {
+			t.Fatalf("expected an error for %q", specs)
+		}
+	}
+}
+
+// textOnly
+// hides the Stream method of a provider
+type textOnly struct {
+	LLMProvider
+}
+
+func TestChainStream(t *testing.T) {
+	fake := &Fake{Text: func(model string, req Request) (string, error) {
+		if model == "down" {
+			return "", errors.New("provider down")
+		}
+		return "plan by " + model, nil
+	}}
+
+	// a streaming model relays the answer in pieces, a failing one
+	// that sent nothing falls back
+	var pieces []string
+	chain := Chain{{Provider: fake, Name: "down"}, {Provider: fake, Name: "up"}}
+	res, model, err := chain.Stream(context.Background(), Request{}, func(text string) { pieces = append(pieces, text) })
+	if err != nil || model.Name != "up" || res.Text != "plan by up" || strings.Join(pieces, "|") != "plan |by |up" {
+		t.Fatalf("unexpected stream %q of %+v from %s, %v", pieces, res, model, err)
+	}
+
+	// providers without streaming answer in one piece
+	pieces = nil
+	chain = Chain{{Provider: textOnly{fake}, Name: "up"}}
+	if res, _, err := chain.Stream(context.Background(), Request{}, func(text string) { pieces = append(pieces, text) }); err != nil || len(pieces) != 1 || pieces[0] != res.Text {
+		t.Fatalf("unexpected fallback stream %q, %v", pieces, err)
+	}
+
+	// once text was relayed a failure is final
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("data: {\"choices\":[{\"delta\":{\"content\":\"Day 1\"}}]}\n\ndata: {oops\n\n"))
+	}))
+	defer srv.Close()
+	chain = Chain{{Provider: NewOpenAI(srv.URL, ""), Name: "m"}, {Provider: fake, Name: "up"}}
+	if _, _, err := chain.Stream(context.Background(), Request{}, func(string) {}); err == nil || !strings.Contains(err.Error(), "error decoding stream") {
+		t.Fatalf("expected the stream error, got %v", err)
+	}
+	if calls := fake.Calls(); len(calls) != 3 {
+		t.Fatalf("fell back after a partial answer: %v", calls)
+	}
+}
+
+func TestExtractJSON(t *testing.T) {
+	for in, want := range map[string]string{
+		`{"a":1}`:                        `{"a":1}`,
+		"```json\n{\"a\":1}\n```":        `{"a":1}`,
+		"Here you go:\n```\n[1]\n```\n":  `[1]`,
+		"Sure! {\"a\":{\"b\":2}} Enjoy.": `{"a":{"b":2}}`,
+		"{\"a\":1}\nHope this helps!":    `{"a":1}`,
+	} {
+		if got := ExtractJSON(in); got != want {
+			t.Fatalf("ExtractJSON(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+// TestProviders
+// checks the request each provider sends and how it reads the answer
+func TestProviders(t *testing.T) {
+	schema := json.RawMessage(`{"type":"object"}`)
+
+	for _, c := range []struct {
+		name   string
+		model  string
+		path   string
+		header string
+		answer string
+		// the request's response format, as json
+		format   func(body map[string]any) any
+		provider func(url string) LLMProvider
+	}{
+		{
+			name:     ProviderCloudflare,
+			model:    "@cf/model",
+			path:     "/accounts/acc/ai/run/@cf/model",
+			header:   "Bearer cf-key",
+			answer:   `{"success":true,"result":{"response":{"plan":true},"usage":{"prompt_tokens":3,"completion_tokens":4}}}`,
+			format:   func(body map[string]any) any { return body["response_format"].(map[string]any)["json_schema"] },
+			provider: func(url string) LLMProvider { p := NewCloudflare("acc", "cf-key"); p.BaseURL = url; return p },
+		},
+		{
+			name:     ProviderGemini,
+			model:    "gemini-x",
+			path:     "/models/gemini-x:generateContent",
+			header:   "gm-key",
+			answer:   `{"candidates":[{"content":{"parts":[{"text":"{\"plan\":"},{"text":"true}"}]}}],"usageMetadata":{"promptTokenCount":3,"candidatesTokenCount":4}}`,
+			format:   func(body map[string]any) any { return body["generationConfig"].(map[string]any)["responseJsonSchema"] },
+			provider: func(url string) LLMProvider { p := NewGemini("gm-key"); p.BaseURL = url; return p },
+		},
+		{
+			name:   ProviderOpenAI,
+			model:  "gpt-x",
+			path:   "/v1/chat/completions",
+			header: "Bearer oa-key",
+			answer: `{"choices":[{"message":{"role":"assistant","content":"{\"plan\":true}"}}],"usage":{"prompt_tokens":3,"completion_tokens":4}}`,
+			format: func(body map[string]any) any {
+				return body["response_format"].(map[string]any)["json_schema"].(map[string]any)["schema"]
+			},
+			provider: func(url string) LLMProvider { return NewOpenAI(url+"/v1/", "oa-key") },
+		},
+		{
+			name:     ProviderOllama,
+			model:    "llama-x",
+			path:     "/api/chat",
+			answer:   `{"message":{"role":"assistant","content":"{\"plan\":true}"},"prompt_eval_count":3,"eval_count":4}`,
+			format:   func(body map[string]any) any { return body["format"] },
+			provider: func(url string) LLMProvider { return NewOllama(url) },
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			var body map[string]any
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				auth := r.Header.Get("Authorization") + r.Header.Get("X-Goog-Api-Key")
+				if r.URL.Path != c.path || auth != c.header || r.URL.RawQuery != "" {
+					t.Errorf("unexpected request %s %s, auth %q", r.Method, r.URL, auth)
+				}
+				json.NewDecoder(r.Body).Decode(&body)
+				w.Write([]byte(c.answer))
+			}))
+			defer srv.Close()
+
+			p := c.provider(srv.URL)
+			res, err := p.Structured(context.Background(), c.model, Request{System: "be brief", Prompt: "plan"}, schema)
+			if err != nil || res.Text != `{"plan":true}` || res.PromptTokens != 3 || res.CompletionTokens != 4 {
+				t.Fatalf("unexpected answer %+v, %v", res, err)
+			}
+			if got, _ := json.Marshal(c.format(body)); string(got) != string(schema) {
+				t.Fatalf("schema not sent, got %s in %v", got, body)
+			}
+			if !strings.Contains(mustJSON(body), "be brief") {
+				t.Fatalf("system prompt not sent in %v", body)
+			}
+		})
+	}
+}
+
+// TestProviderStreams
+// checks each provider reads its stream format
+func TestProviderStreams(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		path     string
+		answer   string
+		provider func(url string) LLMProvider
+	}{
+		{
+			name: ProviderCloudflare,
+			path: "/accounts/acc/ai/run/m",
+			answer: "data: {\"response\":\"Day 1: \"}\n\n" +
+				"data: {\"response\":\"Fort\"}\n\n" +
+				"data: {\"response\":\"\",\"usage\":{\"prompt_tokens\":3,\"completion_tokens\":4}}\n\n" +
+				"data: [DONE]\n\n",
+			provider: func(url string) LLMProvider { p := NewCloudflare("acc", "key"); p.BaseURL = url; return p },
+		},
+		{
+			name: ProviderGemini,
+			path: "/models/m:streamGenerateContent?alt=sse",
+			answer: "data: {\"candidates\":[{\"content\":{\"parts\":[{\"text\":\"Day 1: \"}]}}],\"usageMetadata\":{\"promptTokenCount\":3}}\r\n\r\n" +
+				"data: {\"candidates\":[{\"content\":{\"parts\":[{\"text\":\"Fort\"}]}}],\"usageMetadata\":{\"promptTokenCount\":3,\"candidatesTokenCount\":4}}\r\n\r\n",
+			provider: func(url string) LLMProvider { p := NewGemini("key"); p.BaseURL = url; return p },
+		},
+		{
+			name: ProviderOpenAI,
+			path: "/chat/completions",
+			answer: ": keep-alive\n\n" +
+				"data: {\"choices\":[{\"delta\":{\"role\":\"assistant\",\"content\":\"Day 1: \"}}]}\n\n" +
+				"data: {\"choices\":[{\"delta\":{\"content\":\"Fort\"}}],\"usage\":null}\n\n" +
+				"data: {\"choices\":[],\"usage\":{\"prompt_tokens\":3,\"completion_tokens\":4}}\n\n" +
+				"data: [DONE]\n\n",
+			provider: func(url string) LLMProvider { return NewOpenAI(url, "") },
+		},
+		{
+			name: ProviderOllama,
+			path: "/api/chat",
+			answer: "{\"message\":{\"role\":\"assistant\",\"content\":\"Day 1: \"},\"done\":false}\n" +
+				"{\"message\":{\"role\":\"assistant\",\"content\":\"Fort\"},\"done\":false}\n" +
+				"{\"message\":{\"role\":\"assistant\",\"content\":\"\"},\"done\":true,\"prompt_eval_count\":3,\"eval_count\":4}\n",
+			provider: func(url string) LLMProvider { return NewOllama(url) },
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			var body map[string]any
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.RequestURI() != c.path {
+					t.Errorf("unexpected request %s %s", r.Method, r.URL)
+				}
+				json.NewDecoder(r.Body).Decode(&body)
+				w.Write([]byte(c.answer))
+			}))
+			defer srv.Close()
+
+			var pieces []string
+			p := c.provider(srv.URL).(Streamer)
+			res, err := p.Stream(context.Background(), "m", Request{Prompt: "plan"}, func(text string) { pieces = append(pieces, text) })
+			if err != nil || res.Text != "Day 1: Fort" || res.PromptTokens != 3 || res.CompletionTokens != 4 {
+				t.Fatalf("unexpected answer %+v, %v", res, err)
+			}
+			if strings.Join(pieces, "|") != "Day 1: |Fort" {
+				t.Fatalf("unexpected pieces %q", pieces)
+			}
+			if c.name != ProviderGemini && body["stream"] != true {
+				t.Fatalf("stream not requested in %v", body)
+			}
+		})
+	}
+}
+
+func TestProviderErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/empty/chat/completions") {
+			w.Write([]byte(`{"choices":[]}`))
+			return
+		}
+		w.WriteHeader(429)
+		w.Write([]byte(`{"error":"slow down"}`))
+	}))
+	defer srv.Close()
+
+	if _, err := NewOpenAI(srv.URL, "").Complete(context.Background(), "m", Request{}); err == nil || !strings.Contains(err.Error(), "status 429") {
+		t.Fatalf("expected the status in the error, got %v", err)
+	}
+	if _, err := NewOpenAI(srv.URL+"/empty", "").Complete(context.Background(), "m", Request{}); !errors.Is(err, ErrNoOutput) {
+		t.Fatalf("expected ErrNoOutput, got %v", err)
+	}
+	if _, err := NewGemini("").Complete(context.Background(), "m", Request{}); err == nil {
+		t.Fatal("expected an error without an api key")
+	}
+}
+
+func mustJSON(v any) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
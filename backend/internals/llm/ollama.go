@@ -0,0 +1,122 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ErebusAJ/YatraBandhu/internals/httpclient"
+	"github.com/ErebusAJ/YatraBandhu/internals/metrics"
+)
+
+// Ollama
+// Models served by a local Ollama, or a server with its API
+type Ollama struct {
+	BaseURL string
+	client  *http.Client
+}
+
+// NewOllama
+// returns the provider of the server at baseURL
+func NewOllama(baseURL string) *Ollama {
+	return &Ollama{
+		BaseURL: strings.TrimSuffix(baseURL, "/"),
+		client:  httpclient.New(ProviderOllama, 300*time.Second),
+	}
+}
+
+func (p *Ollama) Name() string {
+	return ProviderOllama
+}
+
+func (p *Ollama) Complete(ctx context.Context, model string, req Request) (Completion, error) {
+	return p.chat(ctx, model, req, nil, nil)
+}
+
+// Stream
+// reads the json lines Ollama streams, the last one has the usage
+func (p *Ollama) Stream(ctx context.Context, model string, req Request, onText func(text string)) (Completion, error) {
+	return p.chat(ctx, model, req, nil, onText)
+}
+
+// Structured
+// sets format to schema, or "json" without one
+func (p *Ollama) Structured(ctx context.Context, model string, req Request, schema json.RawMessage) (Completion, error) {
+	var format any = "json"
+	if schema != nil {
+		format = schema
+	}
+	res, err := p.chat(ctx, model, req, format, nil)
+	res.Text = ExtractJSON(res.Text)
+	return res, err
+}
+
+// chat
+// streams the answer to onText when it is set
+func (p *Ollama) chat(ctx context.Context, model string, req Request, format any, onText func(text string)) (Completion, error) {
+	if p.BaseURL == "" {
+		return Completion{}, errors.New("ollama url is not configured")
+	}
+
+	options := map[string]any{"temperature": req.Temperature}
+	if req.MaxTokens > 0 {
+		options["num_predict"] = req.MaxTokens
+	}
+	payload := map[string]any{
+		"model":    model,
+		"messages": messages(req),
+		"stream":   onText != nil,
+		"options":  options,
+	}
+	if format != nil {
+		payload["format"] = format
+	}
+
+	// an answer, or a line of one when streaming
+	type result struct {
+		Message         message `json:"message"`
+		PromptEvalCount int     `json:"prompt_eval_count"`
+		EvalCount       int     `json:"eval_count"`
+	}
+
+	var res Completion
+	var text strings.Builder
+	read := func(r result) {
+		text.WriteString(r.Message.Content)
+		res.PromptTokens += r.PromptEvalCount
+		res.CompletionTokens += r.EvalCount
+	}
+
+	url := p.BaseURL + "/api/chat"
+	var err error
+	if onText == nil {
+		var r result
+		err = postJSON(ctx, p.client, url, nil, payload, &r)
+		read(r)
+	} else {
+		err = postStream(ctx, p.client, url, nil, payload, func(data []byte) error {
+			var r result
+			if err := json.Unmarshal(data, &r); err != nil {
+				return err
+			}
+			read(r)
+			if r.Message.Content != "" {
+				onText(r.Message.Content)
+			}
+			return nil
+		})
+	}
+	if err != nil {
+		return Completion{}, err
+	}
+	metrics.AddTokens(ProviderOllama, model, res.PromptTokens, res.CompletionTokens)
+
+	res.Text = text.String()
+	if res.Text == "" {
+		return res, ErrNoOutput
+	}
+	return res, nil
+}
@@ -0,0 +1,143 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ErebusAJ/YatraBandhu/internals/httpclient"
+	"github.com/ErebusAJ/YatraBandhu/internals/metrics"
+)
+
+// OpenAI
+// Any API speaking OpenAI's chat completions, such as OpenAI
+// itself, OpenRouter, Groq, vLLM or LM Studio
+type OpenAI struct {
+	BaseURL string
+	// APIKey is optional for local servers
+	APIKey string
+	client *http.Client
+}
+
+// NewOpenAI
+// returns the provider of an OpenAI compatible API
+func NewOpenAI(baseURL, apiKey string) *OpenAI {
+	return &OpenAI{
+		BaseURL: strings.TrimSuffix(baseURL, "/"),
+		APIKey:  apiKey,
+		client:  httpclient.New(ProviderOpenAI, 300*time.Second),
+	}
+}
+
+func (p *OpenAI) Name() string {
+	return ProviderOpenAI
+}
+
+func (p *OpenAI) Complete(ctx context.Context, model string, req Request) (Completion, error) {
+	return p.chat(ctx, model, req, nil, nil)
+}
+
+// Stream
+// asks for server-sent chunks, with the usage in the last one
+func (p *OpenAI) Stream(ctx context.Context, model string, req Request, onText func(text string)) (Completion, error) {
+	return p.chat(ctx, model, req, nil, onText)
+}
+
+// Structured
+// uses json_schema response format when schema is set, JSON mode otherwise
+func (p *OpenAI) Structured(ctx context.Context, model string, req Request, schema json.RawMessage) (Completion, error) {
+	format := map[string]any{"type": "json_object"}
+	if schema != nil {
+		format = map[string]any{
+			"type":        "json_schema",
+			"json_schema": map[string]any{"name": "response", "schema": schema},
+		}
+	}
+	res, err := p.chat(ctx, model, req, format, nil)
+	res.Text = ExtractJSON(res.Text)
+	return res, err
+}
+
+// chat
+// streams the answer to onText when it is set
+func (p *OpenAI) chat(ctx context.Context, model string, req Request, format any, onText func(text string)) (Completion, error) {
+	if p.BaseURL == "" {
+		return Completion{}, errors.New("openai base url is not configured")
+	}
+
+	payload := map[string]any{
+		"model":       model,
+		"messages":    messages(req),
+		"temperature": req.Temperature,
+	}
+	if req.MaxTokens > 0 {
+		payload["max_tokens"] = req.MaxTokens
+	}
+	if format != nil {
+		payload["response_format"] = format
+	}
+
+	// an answer, or a chunk of one when streaming
+	type result struct {
+		Choices []struct {
+			Message message `json:"message"`
+			Delta   message `json:"delta"`
+		} `json:"choices"`
+		Usage *struct {
+			PromptTokens     int `json:"prompt_tokens"`
+			CompletionTokens int `json:"completion_tokens"`
+		} `json:"usage"`
+	}
+
+	var res Completion
+	var text strings.Builder
+	read := func(r result) {
+		if r.Usage != nil {
+			res.PromptTokens, res.CompletionTokens = r.Usage.PromptTokens, r.Usage.CompletionTokens
+		}
+		if len(r.Choices) == 0 {
+			return
+		}
+		text.WriteString(r.Choices[0].Message.Content)
+		if delta := r.Choices[0].Delta.Content; delta != "" && onText != nil {
+			text.WriteString(delta)
+			onText(delta)
+		}
+	}
+
+	header := http.Header{}
+	if p.APIKey != "" {
+		header.Set("Authorization", "Bearer "+p.APIKey)
+	}
+	url := p.BaseURL + "/chat/completions"
+	var err error
+	if onText == nil {
+		var r result
+		err = postJSON(ctx, p.client, url, header, payload, &r)
+		read(r)
+	} else {
+		payload["stream"] = true
+		payload["stream_options"] = map[string]any{"include_usage": true}
+		err = postStream(ctx, p.client, url, header, payload, func(data []byte) error {
+			var r result
+			if err := json.Unmarshal(data, &r); err != nil {
+				return err
+			}
+			read(r)
+			return nil
+		})
+	}
+	if err != nil {
+		return Completion{}, err
+	}
+	metrics.AddTokens(ProviderOpenAI, model, res.PromptTokens, res.CompletionTokens)
+
+	res.Text = text.String()
+	if res.Text == "" {
+		return res, ErrNoOutput
+	}
+	return res, nil
+}
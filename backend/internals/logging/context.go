@@ -0,0 +1,49 @@
+package logging
+
+import "context"
+
+// RequestIDKey
+// gin context key the request id is stored under
+const RequestIDKey = "requestID"
+
+type requestIDKey struct{}
+
+// WithRequestID
+// returns a copy of ctx carrying the request id
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID
+// returns the request id carried by ctx, empty if there is none
+// gin contexts are looked up by RequestIDKey
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	if id, ok := ctx.Value(requestIDKey{}).(string); ok {
+		return id
+	}
+	if id, ok := ctx.Value(RequestIDKey).(string); ok {
+		return id
+	}
+	return ""
+}
+
+// ValidRequestID
+// reports whether id is safe to echo in headers, logs and sql comments:
+// 1 to 64 letters, digits, '-', '_', '.' or ':'
+func ValidRequestID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
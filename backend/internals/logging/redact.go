@@ -0,0 +1,78 @@
+package logging
+
+import (
+	"log/slog"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Redacted replaces secret values in logs
+const Redacted = "[REDACTED]"
+
+// secretKeys are matched against lower cased attribute and query
+// parameter names, a name containing any of them is secret
+var secretKeys = []string{"password", "secret", "token", "api_key", "apikey", "authorization", "cookie"}
+
+// secretParams matches secrets inside free text: query parameters
+// (access_token=, key=, ...) and bearer credentials
+var secretParams = regexp.MustCompile(`(?i)((?:[?&]|\b)(?:access_token|api_key|apikey|key|token|password|secret)=)[^&\s"']+|(bearer\s+)[^\s"']+`)
+
+// IsSecret
+// reports whether values named key must not be logged
+func IsSecret(key string) bool {
+	key = strings.ToLower(key)
+	if key == "key" || key == "pass" {
+		return true
+	}
+	for _, s := range secretKeys {
+		if strings.Contains(key, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactString
+// masks secret query parameters and bearer tokens inside s
+// e.g. urls quoted in errors returned by net/http
+func RedactString(s string) string {
+	return secretParams.ReplaceAllString(s, "$1$2"+Redacted)
+}
+
+// RedactURL
+// returns u as a string with secret query parameters masked
+func RedactURL(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+
+	out := *u
+	out.User = nil
+	q := out.Query()
+	for key := range q {
+		if IsSecret(key) {
+			q.Set(key, Redacted)
+		}
+	}
+	out.RawQuery = q.Encode()
+	return out.String()
+}
+
+// redactAttr
+// ReplaceAttr of the handlers built by New
+func redactAttr(groups []string, a slog.Attr) slog.Attr {
+	if IsSecret(a.Key) {
+		return slog.String(a.Key, Redacted)
+	}
+
+	switch a.Value.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, RedactString(a.Value.String()))
+	case slog.KindAny:
+		if err, ok := a.Value.Any().(error); ok {
+			return slog.String(a.Key, RedactString(err.Error()))
+		}
+	}
+	return a
+}
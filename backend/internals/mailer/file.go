@@ -0,0 +1,64 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+	"gopkg.in/gomail.v2"
+)
+
+// FileMailer
+// Writes each message as an .eml file into a maildir style
+// directory (tmp/ then renamed into new/) instead of sending it
+type FileMailer struct {
+	dir  string
+	from string
+}
+
+// NewFileMailer
+// returns a file mailer writing under dir, creating it if needed
+func NewFileMailer(dir, from string) (*FileMailer, error) {
+	for _, sub := range []string{"tmp", "new", "cur"} {
+		err := os.MkdirAll(filepath.Join(dir, sub), 0o755)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &FileMailer{dir: dir, from: from}, nil
+}
+
+func (m *FileMailer) Send(ctx context.Context, msg Message) error {
+	gm := gomail.NewMessage()
+	gm.SetHeader("From", m.from)
+	gm.SetHeader("To", msg.To)
+	gm.SetHeader("Subject", msg.Subject)
+	gm.SetDateHeader("Date", time.Now())
+	gm.SetBody("text/plain", msg.Text)
+	if msg.HTML != "" {
+		gm.AddAlternative("text/html", msg.HTML)
+	}
+
+	name := fmt.Sprintf("%d.%s.eml", time.Now().UnixNano(), uuid.NewString())
+	tmpPath := filepath.Join(m.dir, "tmp", name)
+
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+
+	_, err = gm.WriteTo(f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	return os.Rename(tmpPath, filepath.Join(m.dir, "new", name))
+}
@@ -0,0 +1,16 @@
+package mailer
+
+import (
+	"context"
+	"log"
+)
+
+// LogMailer
+// Only logs messages, for development and tests
+// bodies are left out, they can hold live reset links
+type LogMailer struct{}
+
+func (LogMailer) Send(ctx context.Context, msg Message) error {
+	log.Printf("mail to=%q template=%q", msg.To, msg.Template)
+	return nil
+}
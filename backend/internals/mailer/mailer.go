@@ -0,0 +1,120 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"text/template"
+)
+
+// Template names
+const (
+	TemplateReset               = "reset"
+	TemplateVerification        = "verification"
+	TemplateAccountLocked       = "account_locked"
+	TemplateJoinRequestAccepted = "join_request_accepted"
+	TemplateBookingConfirmed    = "booking_confirmed"
+)
+
+//go:embed templates/*.tmpl
+var templateFS embed.FS
+
+var (
+	textTemplates = template.Must(template.ParseFS(templateFS, "templates/*.tmpl"))
+	htmlTemplates = htmltemplate.Must(htmltemplate.ParseFS(templateFS, "templates/*.tmpl"))
+)
+
+// Message
+// A rendered email, HTML is optional
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+	// Template is the name the message was rendered from
+	Template string
+}
+
+// Mailer
+// Delivers rendered messages, implemented by SMTP, file and log backends
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Render
+// renders the named template for a recipient
+// each template file defines "<name>.subject", "<name>.text" and "<name>.html"
+func Render(name, to string, data any) (Message, error) {
+	msg := Message{To: to, Template: name}
+
+	var buf bytes.Buffer
+	err := textTemplates.ExecuteTemplate(&buf, name+".subject", data)
+	if err != nil {
+		return msg, fmt.Errorf("rendering %s subject: %w", name, err)
+	}
+	msg.Subject = buf.String()
+
+	buf.Reset()
+	err = textTemplates.ExecuteTemplate(&buf, name+".text", data)
+	if err != nil {
+		return msg, fmt.Errorf("rendering %s text: %w", name, err)
+	}
+	msg.Text = buf.String()
+
+	buf.Reset()
+	err = htmlTemplates.ExecuteTemplate(&buf, name+".html", data)
+	if err != nil {
+		return msg, fmt.Errorf("rendering %s html: %w", name, err)
+	}
+	msg.HTML = buf.String()
+
+	return msg, nil
+}
+
+// SendTemplate
+// renders the named template and sends it with m
+func SendTemplate(ctx context.Context, m Mailer, to, name string, data any) error {
+	msg, err := Render(name, to, data)
+	if err != nil {
+		return err
+	}
+
+	return m.Send(ctx, msg)
+}
+
+// Backends accepted by New
+const (
+	BackendSMTP = "smtp"
+	BackendFile = "file"
+	BackendLog  = "log"
+)
+
+// Config
+// Selects and configures a mailer backend
+type Config struct {
+	Backend string
+	SMTP    SMTPConfig
+	// Dir is the output directory of the file backend
+	Dir  string
+	From string
+}
+
+// New
+// returns the mailer for cfg.Backend
+func New(cfg Config) (Mailer, error) {
+	switch cfg.Backend {
+	case BackendSMTP, "":
+		if cfg.SMTP.From == "" {
+			cfg.SMTP.From = cfg.From
+		}
+		return NewSMTPMailer(cfg.SMTP)
+	case BackendFile:
+		return NewFileMailer(cfg.Dir, cfg.From)
+	case BackendLog:
+		return LogMailer{}, nil
+	default:
+		return nil, fmt.Errorf("unknown mail backend %q", cfg.Backend)
+	}
+}
@@ -0,0 +1,96 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	htmltemplate "html/template"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		data map[string]any
+		// in the subject, the text and the html body
+		subject, text, html string
+	}{
+		{
+			name:    TemplateReset,
+			data:    map[string]any{"URL": "https://yatra.test/reset/abc", "AppURL": htmltemplate.URL("yatrabandhu://reset?token=abc"), "ExpiresIn": "30 minutes"},
+			subject: "password reset",
+			text:    "Password reset link: https://yatra.test/reset/abc\nOpen in the app: yatrabandhu://reset?token=abc",
+			html:    `<a href="yatrabandhu://reset?token=abc">`,
+		},
+		{
+			name:    TemplateVerification,
+			data:    map[string]any{"URL": "https://yatra.test/v1/verify/xyz", "ExpiresIn": "24 hours"},
+			subject: "Verify",
+			text:    "Verify your email: https://yatra.test/v1/verify/xyz",
+			html:    `<a href="https://yatra.test/v1/verify/xyz">`,
+		},
+		{
+			name:    TemplateAccountLocked,
+			data:    map[string]any{"Until": "10:30 UTC", "Failures": 10},
+			subject: "locked",
+			text:    "locked until 10:30 UTC after 10 failed",
+			html:    "<b>10:30 UTC</b>",
+		},
+		{
+			// user provided names are escaped in the html body only
+			name:    TemplateJoinRequestAccepted,
+			data:    map[string]any{"Name": "Tara", "GroupName": "Goa <Gang>"},
+			subject: "You joined Goa <Gang>",
+			text:    `"Goa <Gang>" was accepted`,
+			html:    "<b>Goa &lt;Gang&gt;</b>",
+		},
+		{
+			name:    TemplateBookingConfirmed,
+			data:    map[string]any{"GuideName": "Kabir", "GroupName": "Ladakh"},
+			subject: "Ladakh",
+			text:    `book Kabir for the travel group "Ladakh"`,
+			html:    "<b>Kabir</b>",
+		},
+	} {
+		msg, err := Render(c.name, "tara@example.com", c.data)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if msg.To != "tara@example.com" || msg.Template != c.name {
+			t.Fatalf("%s: unexpected message %+v", c.name, msg)
+		}
+		if !strings.Contains(msg.Subject, c.subject) || !strings.Contains(msg.Text, c.text) || !strings.Contains(msg.HTML, c.html) {
+			t.Fatalf("%s: unexpected rendering\nsubject %q\ntext %q\nhtml %q", c.name, msg.Subject, msg.Text, msg.HTML)
+		}
+	}
+
+	// the app link is optional
+	msg, err := Render(TemplateReset, "tara@example.com", map[string]any{"URL": "https://yatra.test/reset/abc", "ExpiresIn": "30 minutes"})
+	if err != nil || strings.Contains(msg.Text, "Open in the app") {
+		t.Fatalf("unexpected reset mail %q, %v", msg.Text, err)
+	}
+
+	if _, err := Render("missing", "tara@example.com", nil); err == nil {
+		t.Fatal("expected an error for an unknown template")
+	}
+}
+
+func TestLogMailer(t *testing.T) {
+	var buf bytes.Buffer
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(&buf)
+
+	msg, err := Render(TemplateVerification, "tara@example.com", map[string]any{"URL": "https://yatra.test/v1/verify/xyz", "ExpiresIn": "24 hours"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := (LogMailer{}).Send(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+
+	out := buf.String()
+	if !strings.Contains(out, `to="tara@example.com"`) || !strings.Contains(out, `template="verification"`) || strings.Contains(out, "/v1/verify/") {
+		t.Fatalf("unexpected log %q", out)
+	}
+}
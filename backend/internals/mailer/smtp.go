@@ -0,0 +1,77 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+
+	"gopkg.in/gomail.v2"
+)
+
+// TLS modes for SMTPConfig
+// starttls upgrades the connection when the server offers it (port 587),
+// implicit connects over TLS from the start (port 465)
+const (
+	TLSStartTLS = "starttls"
+	TLSImplicit = "implicit"
+)
+
+// SMTPConfig
+// Connection settings of an SMTP server
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	// TLS is one of starttls (default) or implicit
+	TLS string
+}
+
+// SMTPMailer
+// Sends mail through an SMTP server
+type SMTPMailer struct {
+	cfg SMTPConfig
+}
+
+// NewSMTPMailer
+// returns an SMTP mailer, From defaults to Username
+func NewSMTPMailer(cfg SMTPConfig) (*SMTPMailer, error) {
+	if cfg.Host == "" || cfg.Port == 0 {
+		return nil, fmt.Errorf("smtp host and port are required")
+	}
+	if cfg.From == "" {
+		cfg.From = cfg.Username
+	}
+	if cfg.TLS == "" {
+		cfg.TLS = TLSStartTLS
+	}
+
+	switch cfg.TLS {
+	case TLSStartTLS, TLSImplicit:
+	default:
+		return nil, fmt.Errorf("unknown smtp tls mode %q", cfg.TLS)
+	}
+
+	return &SMTPMailer{cfg: cfg}, nil
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	gm := gomail.NewMessage()
+	gm.SetHeader("From", m.cfg.From)
+	gm.SetHeader("To", msg.To)
+	gm.SetHeader("Subject", msg.Subject)
+	gm.SetBody("text/plain", msg.Text)
+	if msg.HTML != "" {
+		gm.AddAlternative("text/html", msg.HTML)
+	}
+
+	d := gomail.NewDialer(m.cfg.Host, m.cfg.Port, m.cfg.Username, m.cfg.Password)
+	d.SSL = m.cfg.TLS == TLSImplicit
+
+	err := d.DialAndSend(gm)
+	if err != nil {
+		return fmt.Errorf("unable to send email: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,137 @@
+package metrics
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "yatrabandhu"
+
+// Registry
+// every metric of the server, served by Handler
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of HTTP requests by route and status.",
+		Buckets:   []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60, 120, 300},
+	}, []string{"method", "route", "status"})
+
+	httpInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "http_requests_in_flight",
+		Help:      "HTTP requests currently being served.",
+	})
+
+	providerRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "provider_requests_total",
+		Help:      "Calls to external providers by outcome: 2xx, 3xx, 4xx, 5xx, timeout or error.",
+	}, []string{"provider", "outcome"})
+
+	providerDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "provider_request_duration_seconds",
+		Help:      "Latency of calls to external providers.",
+		Buckets:   []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60, 120, 300},
+	}, []string{"provider"})
+
+	llmTokens = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "llm_tokens_total",
+		Help:      "Tokens used by LLM calls, kind is prompt or completion.",
+	}, []string{"provider", "model", "kind"})
+
+	itineraries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "itinerary_generations_total",
+		Help:      "AI itinerary generations by outcome.",
+	}, []string{"outcome"})
+
+	itineraryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "itinerary_generation_duration_seconds",
+		Help:      "Time taken to generate an AI itinerary by outcome.",
+		Buckets:   []float64{1, 2.5, 5, 10, 20, 30, 60, 120, 180, 300},
+	}, []string{"outcome"})
+)
+
+// Itinerary generation outcomes
+const (
+	OutcomeSuccess       = "success"
+	OutcomeParseFailure  = "parse_failure"
+	OutcomeTimeout       = "timeout"
+	OutcomeProviderError = "provider_error"
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpDuration, httpInFlight,
+		providerRequests, providerDuration,
+		llmTokens, itineraries, itineraryDuration,
+	)
+}
+
+var handler = promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+
+// Handler
+// serves the registry in the prometheus exposition format
+func Handler() http.Handler {
+	return handler
+}
+
+// RegisterDB
+// exports the pool statistics of DB (open, in use and idle
+// connections, waits), registering the same pool twice is a no-op
+func RegisterDB(DB *sql.DB) error {
+	err := Registry.Register(collectors.NewDBStatsCollector(DB, namespace))
+	var already prometheus.AlreadyRegisteredError
+	if errors.As(err, &already) {
+		return nil
+	}
+	return err
+}
+
+// HTTPStarted
+// counts a request in flight, the returned func records its
+// route and status once it is served
+func HTTPStarted() func(method, route string, status int) {
+	start := time.Now()
+	httpInFlight.Inc()
+	return func(method, route string, status int) {
+		httpInFlight.Dec()
+		httpDuration.WithLabelValues(method, route, strconv.Itoa(status)).Observe(time.Since(start).Seconds())
+	}
+}
+
+// ObserveProvider
+// records one call to provider
+func ObserveProvider(provider, outcome string, d time.Duration) {
+	providerRequests.WithLabelValues(provider, outcome).Inc()
+	providerDuration.WithLabelValues(provider).Observe(d.Seconds())
+}
+
+// AddTokens
+// records the tokens used by one LLM call
+func AddTokens(provider, model string, prompt, completion int) {
+	llmTokens.WithLabelValues(provider, model, "prompt").Add(float64(prompt))
+	llmTokens.WithLabelValues(provider, model, "completion").Add(float64(completion))
+}
+
+// ObserveItinerary
+// records the outcome and duration of one itinerary generation
+func ObserveItinerary(outcome string, d time.Duration) {
+	itineraries.WithLabelValues(outcome).Inc()
+	itineraryDuration.WithLabelValues(outcome).Observe(d.Seconds())
+}
@@ -3,6 +3,7 @@ package middleware
 import (
 	"strings"
 
+	"github.com/ErebusAJ/YatraBandhu/internals/store"
 	"github.com/ErebusAJ/YatraBandhu/internals/utils"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v4"
@@ -11,8 +12,10 @@ import (
 
 // AuthMiddleware
 // Used for authenticating requests basend on JWT
-// verifies using a unique secret key
-func AuthMiddleware(signedKey string) gin.HandlerFunc{
+// verifies using a unique secret key and rejects tokens
+// whose version is older than the user's current token_version
+// or belonging to a suspended account
+func AuthMiddleware(signedKey string, DB store.UserStore) gin.HandlerFunc{
 	return func(c *gin.Context){
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer"){
@@ -24,22 +27,46 @@ func AuthMiddleware(signedKey string) gin.HandlerFunc{
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error){
 			return []byte(signedKey), nil
-		})	
+		})
 		if err != nil {
-			utils.ErrorJSON(c, 400, "ivalid token", "invalid token", err)
+			utils.ErrorJSON(c, 401, "ivalid token", utils.InvalidTokenError, err)
 			c.Abort()
 			return
 		}
 
 		claims := token.Claims.(jwt.MapClaims)
-		tempID := claims["user_id"].(string)
+		tempID, _ := claims["user_id"].(string)
+		userID, err := uuid.Parse(tempID)
+		if err != nil{
+			utils.ErrorJSON(c, 401, utils.IDParseError, utils.UnauthorizedError, err)
+			c.Abort()
+			return
+		}
+
+		userRole, _ := claims["user_role"].(string)
+		tokenVersion, _ := claims["token_version"].(float64)
 
-		userID := uuid.MustParse(tempID)
-		userRole := claims["user_role"].(string)
+		// check token has not been revoked
+		state, err := DB.GetUserAuthState(c, userID)
+		if err != nil{
+			utils.ErrorJSON(c, 401, utils.DatabaseError, utils.UnauthorizedError, err)
+			c.Abort()
+			return
+		}
+		if int32(tokenVersion) < state.TokenVersion.Int32{
+			utils.ErrorJSON(c, 401, utils.RevokedTokenError, utils.UnauthorizedError, nil)
+			c.Abort()
+			return
+		}
+		if state.SuspendedAt.Valid{
+			utils.ErrorJSON(c, 403, utils.SuspendedError, utils.SuspendedError, nil)
+			c.Abort()
+			return
+		}
 
 		c.Set("userID", userID)
 		c.Set("userRole", userRole)
-		
+
 		c.Next()
 	}
 }
\ No newline at end of file
@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"runtime/debug"
+	"time"
+
+	"github.com/ErebusAJ/YatraBandhu/internals/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// RequestLogger
+// Logs one record per request once it is served
+// the matched route is logged rather than the path, paths
+// of the verification and reset routes carry tokens
+func RequestLogger() gin.HandlerFunc{
+	return func(c *gin.Context){
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		if status >= 500{
+			level = slog.LevelError
+		}else if status >= 400{
+			level = slog.LevelWarn
+		}
+
+		route := c.FullPath()
+		if route == ""{
+			route = "unmatched"
+		}
+
+		attrs := []any{
+			"method", c.Request.Method,
+			"route", route,
+			"status", status,
+			"duration_ms", time.Since(start).Milliseconds(),
+			"bytes", c.Writer.Size(),
+			"client_ip", c.ClientIP(),
+		}
+		if userID, exists := c.Get("userID"); exists{
+			attrs = append(attrs, "user_id", userID)
+		}
+
+		slog.Log(c, level, "request", attrs...)
+	}
+}
+
+
+// Recovery
+// Turns a panicking handler into a 500 error response and
+// logs the panic with its stack as a structured record
+func Recovery() gin.HandlerFunc{
+	return gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, err any){
+		slog.ErrorContext(c, "panic serving request", "panic", fmt.Sprint(err), "stack", string(debug.Stack()))
+		utils.ErrorJSON(c, 500, "panic serving request", utils.InternalError, nil)
+		c.Abort()
+	})
+}
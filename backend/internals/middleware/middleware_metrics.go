@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"github.com/ErebusAJ/YatraBandhu/internals/metrics"
+	"github.com/gin-gonic/gin"
+)
+
+// Metrics
+// Records latency and status of every request by route,
+// unmatched paths share one label to bound cardinality
+func Metrics() gin.HandlerFunc{
+	return func(c *gin.Context){
+		done := metrics.HTTPStarted()
+		c.Next()
+
+		route := c.FullPath()
+		if route == ""{
+			route = "unmatched"
+		}
+		done(c.Request.Method, route, c.Writer.Status())
+	}
+}
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"strconv"
+
+	"github.com/ErebusAJ/YatraBandhu/internals/limiter"
+	"github.com/ErebusAJ/YatraBandhu/internals/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimitIP
+// Token bucket per client ip, name keeps the buckets of
+// different limits apart
+// the ip comes from X-Forwarded-For only behind the router's
+// trusted proxies, otherwise it is the connection's address
+func RateLimitIP(l limiter.RateLimiter, name string, rate limiter.Rate) gin.HandlerFunc{
+	return rateLimit(l, rate, func(c *gin.Context) string{
+		return name + ":ip:" + c.ClientIP()
+	})
+}
+
+
+// RateLimitUser
+// Token bucket per user, must run after AuthMiddleware
+func RateLimitUser(l limiter.RateLimiter, name string, rate limiter.Rate) gin.HandlerFunc{
+	return rateLimit(l, rate, func(c *gin.Context) string{
+		userID, exists := c.Get("userID")
+		if !exists{
+			return ""
+		}
+		return fmt.Sprintf("%s:user:%s", name, userID)
+	})
+}
+
+
+// rateLimit
+// takes a token for the key of each request, answering 429 with
+// Retry-After once the bucket is empty
+// requests are let through if the limiter fails, a limiter outage
+// shouldn't take the API down with it
+func rateLimit(l limiter.RateLimiter, rate limiter.Rate, key func(c *gin.Context) string) gin.HandlerFunc{
+	if !rate.Enabled(){
+		return func(c *gin.Context){ c.Next() }
+	}
+
+	return func(c *gin.Context){
+		k := key(c)
+		if k == ""{
+			c.Next()
+			return
+		}
+
+		decision, err := l.Allow(c, k, rate)
+		if err != nil{
+			slog.ErrorContext(c, "rate limiter failed, request let through", "error", err)
+			c.Next()
+			return
+		}
+
+		c.Header("X-RateLimit-Limit", strconv.Itoa(rate.Size()))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(decision.Remaining))
+		if !decision.Allowed{
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(decision.RetryAfter.Seconds()))))
+			utils.SendError(c, utils.ErrRateLimited, "request rate limited", nil)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
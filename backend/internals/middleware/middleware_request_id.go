@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"github.com/ErebusAJ/YatraBandhu/internals/logging"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Header carrying the request id in both directions
+const RequestIDHeader = "X-Request-ID"
+
+// RequestID
+// Tags every request with an id, reusing the caller's X-Request-ID
+// when it looks sane, the id is returned in the response header,
+// included in error bodies and logs and passed on to the database
+// and outbound provider calls through the request context
+func RequestID() gin.HandlerFunc{
+	return func(c *gin.Context){
+		id := c.GetHeader(RequestIDHeader)
+		if !logging.ValidRequestID(id){
+			id = uuid.NewString()
+		}
+
+		c.Set(logging.RequestIDKey, id)
+		c.Request = c.Request.WithContext(logging.WithRequestID(c.Request.Context(), id))
+		c.Header(RequestIDHeader, id)
+		c.Next()
+	}
+}
@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"github.com/ErebusAJ/YatraBandhu/internals/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// RequireRole
+// Allows a request only if the user_role claim set by
+// AuthMiddleware is one of the given roles
+func RequireRole(roles ...string) gin.HandlerFunc{
+	return func(c *gin.Context){
+		userRole := c.GetString("userRole")
+		if userRole == ""{
+			userRole = utils.RoleUser
+		}
+
+		for _, role := range roles{
+			if role == userRole{
+				c.Next()
+				return
+			}
+		}
+
+		utils.ErrorJSON(c, 403, utils.InvalidAcces, utils.ForbiddenError, nil)
+		c.Abort()
+	}
+}
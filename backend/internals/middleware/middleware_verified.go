@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"github.com/ErebusAJ/YatraBandhu/internals/store"
+	"github.com/ErebusAJ/YatraBandhu/internals/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// VerifiedMiddleware
+// Rejects requests from users who haven't verified their email
+// does nothing when enabled is false, must run after AuthMiddleware
+func VerifiedMiddleware(DB store.UserStore, enabled bool) gin.HandlerFunc{
+	return func(c *gin.Context){
+		if !enabled{
+			c.Next()
+			return
+		}
+
+		tempID, exists := c.Get("userID")
+		if !exists{
+			utils.ErrorJSON(c, 401, utils.MiddlewareError, utils.UnauthorizedError, nil)
+			c.Abort()
+			return
+		}
+		userID := tempID.(uuid.UUID)
+
+		verified, err := DB.GetUserVerifiedStatus(c, userID)
+		if err != nil{
+			utils.DBErrorJSON(c, err, nil)
+			c.Abort()
+			return
+		}
+
+		if !verified.Bool{
+			utils.ErrorJSON(c, 403, utils.UnverifiedError, utils.UnverifiedError, nil)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,133 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ErebusAJ/YatraBandhu/model/schema"
+	"github.com/pressly/goose/v3"
+	"github.com/pressly/goose/v3/lock"
+)
+
+// ErrSchemaBehind
+// returned by Check when migrations are pending
+var ErrSchemaBehind = errors.New("database schema is behind")
+
+// Migrator
+// Applies the migrations embedded from model/schema, versions
+// are tracked in goose's goose_db_version table so databases
+// migrated by hand with the goose cli are picked up as is
+type Migrator struct {
+	provider *goose.Provider
+}
+
+// New
+// returns a migrator for DB, a postgres advisory lock keeps
+// replicas starting together from migrating concurrently
+func New(DB *sql.DB) (*Migrator, error) {
+	locker, err := lock.NewPostgresSessionLocker()
+	if err != nil {
+		return nil, err
+	}
+
+	provider, err := goose.NewProvider(goose.DialectPostgres, DB, schema.Migrations,
+		goose.WithSessionLocker(locker),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("loading migrations: %w", err)
+	}
+
+	return &Migrator{provider: provider}, nil
+}
+
+// Up
+// applies every pending migration
+func (m *Migrator) Up(ctx context.Context) error {
+	results, err := m.provider.Up(ctx)
+	logResults(results...)
+	return err
+}
+
+// Down
+// rolls back the latest applied migration
+func (m *Migrator) Down(ctx context.Context) error {
+	result, err := m.provider.Down(ctx)
+	if result != nil {
+		logResults(result)
+	}
+	return err
+}
+
+// Redo
+// rolls back and re-applies the latest migration
+func (m *Migrator) Redo(ctx context.Context) error {
+	err := m.Down(ctx)
+	if err != nil {
+		return err
+	}
+
+	result, err := m.provider.UpByOne(ctx)
+	if result != nil {
+		logResults(result)
+	}
+	return err
+}
+
+// Status
+// writes the state of every migration to w
+func (m *Migrator) Status(ctx context.Context, w io.Writer) error {
+	statuses, err := m.provider.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tSTATE\tAPPLIED AT\tFILE")
+	for _, s := range statuses {
+		applied := "-"
+		if !s.AppliedAt.IsZero() {
+			applied = s.AppliedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", s.Source.Version, s.State, applied, s.Source.Path)
+	}
+	return tw.Flush()
+}
+
+// Check
+// fails with ErrSchemaBehind if the database is older than the
+// embedded migrations, a newer database is only logged
+func (m *Migrator) Check(ctx context.Context) error {
+	current, target, err := m.provider.GetVersions(ctx)
+	if err != nil {
+		return err
+	}
+
+	pending, err := m.provider.HasPending(ctx)
+	if err != nil {
+		return err
+	}
+
+	if pending {
+		return fmt.Errorf("%w: at version %d, code expects %d, run `migrate up`", ErrSchemaBehind, current, target)
+	}
+	if current > target {
+		log.Printf("migrate: database at version %d is newer than code version %d", current, target)
+	}
+	return nil
+}
+
+func logResults(results ...*goose.MigrationResult) {
+	for _, r := range results {
+		if r.Error != nil {
+			log.Printf("migrate: %s %s failed: %v", r.Direction, r.Source.Path, r.Error)
+			continue
+		}
+		log.Printf("migrate: %s %s (%v)", r.Direction, r.Source.Path, r.Duration.Round(time.Millisecond))
+	}
+}
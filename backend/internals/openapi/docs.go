@@ -0,0 +1,10 @@
+package openapi
+
+import _ "embed"
+
+// DocsHTML
+// the docs page, Swagger UI rendering /openapi.json, its script
+// and stylesheet are loaded from unpkg
+//
+//go:embed docs.html
+var DocsHTML []byte
@@ -0,0 +1,313 @@
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Version of the OpenAPI specification the documents follow
+const Version = "3.0.3"
+
+// Document
+// an OpenAPI 3 document, only the parts used by the server
+type Document struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       Info                             `json:"info"`
+	Paths      map[string]map[string]*Operation `json:"paths"`
+	Components Components                       `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	Description  string `json:"description,omitempty"`
+}
+
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []*Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+	// Roles allowed to call the operation, empty for any
+	Roles []string `json:"x-roles,omitempty"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                  `json:"required"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string                `json:"description"`
+	Headers     map[string]*Header    `json:"headers,omitempty"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+	// Stable error codes the response may carry
+	ErrorCodes []string `json:"x-error-codes,omitempty"`
+}
+
+type Header struct {
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Security requirement names
+const (
+	// BearerAuth is the access token from /v1/login
+	BearerAuth = "bearerAuth"
+	// MetricsAuth is the optional metrics token
+	MetricsAuth = "metricsToken"
+)
+
+// Route
+// documentation of one registered route, Path uses the gin
+// syntax (:param), Body and Response are Go values whose types
+// are reflected into schemas
+type Route struct {
+	Method      string
+	Path        string
+	Tag         string
+	Summary     string
+	Description string
+	// Security scheme required, empty for public routes
+	Auth  string
+	Roles []string
+	Query []*Parameter
+	Body  any
+	// Status of the success response, Response nil for no body
+	Status      int
+	Response    any
+	ContentType string
+	// Error codes by status, on top of the ones implied by the route
+	Errors map[int][]string
+	// Other responses that aren't errors, by status
+	Responses map[int]any
+}
+
+// Builder
+// collects routes into a document
+type Builder struct {
+	doc *Document
+	// PathParams documents path params by name, others are strings
+	PathParams map[string]*Parameter
+	// Implied returns the error codes every route implies, such as
+	// 401 for authenticated routes, merged with Route.Errors
+	Implied func(r Route) map[int][]string
+	// ErrorSchema is the body of error responses
+	ErrorSchema *Schema
+}
+
+// NewBuilder
+// starts a document with info
+func NewBuilder(info Info) *Builder {
+	return &Builder{doc: &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Paths:   map[string]map[string]*Operation{},
+		Components: Components{
+			Schemas:         map[string]*Schema{},
+			SecuritySchemes: map[string]*SecurityScheme{},
+		},
+	}}
+}
+
+// Schema
+// the schema of v's type, named structs are added to the
+// components and referenced
+func (b *Builder) Schema(v any) *Schema {
+	return reflectSchema(b.doc.Components.Schemas, v)
+}
+
+// Security
+// registers a security scheme under name
+func (b *Builder) Security(name string, s *SecurityScheme) {
+	b.doc.Components.SecuritySchemes[name] = s
+}
+
+// Add
+// documents r, adding a route twice is an error
+func (b *Builder) Add(r Route) error {
+	path, params := b.path(r.Path)
+	method := strings.ToLower(r.Method)
+
+	item := b.doc.Paths[path]
+	if item == nil {
+		item = map[string]*Operation{}
+		b.doc.Paths[path] = item
+	}
+	if item[method] != nil {
+		return fmt.Errorf("openapi: %s %s documented twice", r.Method, r.Path)
+	}
+
+	op := &Operation{
+		OperationID: operationID(r.Method, path),
+		Summary:     r.Summary,
+		Description: r.Description,
+		Parameters:  append(params, r.Query...),
+		Responses:   map[string]*Response{},
+		Roles:       r.Roles,
+	}
+	if r.Tag != "" {
+		op.Tags = []string{r.Tag}
+	}
+	if r.Auth != "" {
+		op.Security = []map[string][]string{{r.Auth: {}}}
+	}
+	if r.Body != nil {
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]*MediaType{"application/json": {Schema: b.Schema(r.Body)}},
+		}
+	}
+
+	status := r.Status
+	if status == 0 {
+		status = 200
+	}
+	success := &Response{Description: http.StatusText(status)}
+	if r.Response != nil {
+		contentType := r.ContentType
+		if contentType == "" {
+			contentType = "application/json"
+		}
+		success.Content = map[string]*MediaType{contentType: {Schema: b.Schema(r.Response)}}
+	}
+	op.Responses[strconv.Itoa(status)] = success
+	for code, body := range r.Responses {
+		op.Responses[strconv.Itoa(code)] = &Response{
+			Description: http.StatusText(code),
+			Content:     map[string]*MediaType{"application/json": {Schema: b.Schema(body)}},
+		}
+	}
+
+	errs := map[int][]string{}
+	if b.Implied != nil {
+		for code, codes := range b.Implied(r) {
+			errs[code] = append(errs[code], codes...)
+		}
+	}
+	for code, codes := range r.Errors {
+		errs[code] = append(errs[code], codes...)
+	}
+	for code, codes := range errs {
+		res := &Response{Description: http.StatusText(code), ErrorCodes: dedupe(codes)}
+		res.Description += ": " + strings.Join(res.ErrorCodes, ", ")
+		if b.ErrorSchema != nil {
+			res.Content = map[string]*MediaType{"application/json": {Schema: b.ErrorSchema}}
+		}
+		if code == 429 {
+			res.Headers = map[string]*Header{"Retry-After": {
+				Description: "seconds to wait before retrying",
+				Schema:      &Schema{Type: "integer"},
+			}}
+		}
+		op.Responses[strconv.Itoa(code)] = res
+	}
+
+	item[method] = op
+	return nil
+}
+
+// Document
+// the built document
+func (b *Builder) Document() *Document {
+	return b.doc
+}
+
+// Has
+// reports whether method and gin path are documented
+func (d *Document) Has(method, ginPath string) bool {
+	path, _ := splitPath(ginPath)
+	return d.Paths[path][strings.ToLower(method)] != nil
+}
+
+// path
+// the OpenAPI form of a gin path and its path params
+func (b *Builder) path(ginPath string) (string, []*Parameter) {
+	path, names := splitPath(ginPath)
+
+	var params []*Parameter
+	for _, name := range names {
+		p := &Parameter{Name: name, Schema: &Schema{Type: "string"}}
+		if doc, ok := b.PathParams[name]; ok {
+			cp := *doc
+			p = &cp
+			p.Name = name
+		}
+		p.In = "path"
+		p.Required = true
+		params = append(params, p)
+	}
+	return path, params
+}
+
+// splitPath
+// turns /a/:id into /a/{id}, gin registers paths without a
+// leading slash with one
+func splitPath(ginPath string) (string, []string) {
+	var names []string
+	segments := strings.Split(strings.TrimPrefix(ginPath, "/"), "/")
+	for i, s := range segments {
+		if strings.HasPrefix(s, ":") || strings.HasPrefix(s, "*") {
+			names = append(names, s[1:])
+			segments[i] = "{" + s[1:] + "}"
+		}
+	}
+	return "/" + strings.Join(segments, "/"), names
+}
+
+// operationID
+// e.g. get_auth_travel-group_groupID_member
+func operationID(method, path string) string {
+	id := strings.ToLower(method)
+	for _, s := range strings.Split(path, "/") {
+		s = strings.Trim(s, "{}")
+		if s != "" {
+			id += "_" + s
+		}
+	}
+	return id
+}
+
+func dedupe(codes []string) []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, c := range codes {
+		if !seen[c] {
+			seen[c] = true
+			out = append(out, c)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
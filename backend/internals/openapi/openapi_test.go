@@ -0,0 +1,85 @@
+package openapi
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type base struct {
+	ID      uuid.UUID `json:"id"`
+	Created time.Time `json:"created_at"`
+}
+
+type item struct {
+	base
+	Name   string   `json:"name" binding:"required,max=20"`
+	Email  string   `json:"email,omitempty" binding:"omitempty,email"`
+	Count  *int     `json:"count"`
+	Tags   []string `json:"tags"`
+	Parent *item    `json:"parent"`
+	Secret string   `json:"-"`
+	Plain  bool
+}
+
+type box[T any] struct {
+	Items []T `json:"items"`
+}
+
+func TestSchema(t *testing.T) {
+	b := NewBuilder(Info{Title: "test", Version: "1"})
+	if ref := b.Schema([]item{}).Items.Ref; ref != "#/components/schemas/item" {
+		t.Fatalf("unexpected ref %q", ref)
+	}
+
+	s := b.Document().Components.Schemas["item"]
+	for name, want := range map[string]string{
+		"id": "uuid", "created_at": "date-time", "email": "email", "name": "",
+	} {
+		if p := s.Properties[name]; p == nil || p.Format != want {
+			t.Fatalf("%s: unexpected schema %+v", name, p)
+		}
+	}
+	if *s.Properties["name"].MaxLength != 20 || len(s.Required) != 1 || s.Required[0] != "name" {
+		t.Fatalf("unexpected constraints %+v", s)
+	}
+	if !s.Properties["count"].Nullable || s.Properties["tags"].Items.Type != "string" {
+		t.Fatalf("unexpected fields %+v", s.Properties)
+	}
+	if s.Properties["parent"].Ref != "#/components/schemas/item" || s.Properties["Plain"] == nil || s.Properties["Secret"] != nil {
+		t.Fatalf("unexpected fields %+v", s.Properties)
+	}
+
+	if ref := b.Schema(box[item]{}).Ref; ref != "#/components/schemas/box_item" {
+		t.Fatalf("unexpected generic ref %q", ref)
+	}
+}
+
+func TestAdd(t *testing.T) {
+	b := NewBuilder(Info{Title: "test", Version: "1"})
+	b.PathParams = map[string]*Parameter{"id": {Schema: &Schema{Type: "string", Format: "uuid"}}}
+
+	route := Route{Method: "DELETE", Path: "v1/items/:id/tags/:tag", Auth: BearerAuth, Status: 204, Errors: map[int][]string{404: {"B", "A", "B"}}}
+	if err := b.Add(route); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Add(route); err == nil {
+		t.Fatal("expected an error for a duplicate route")
+	}
+
+	doc := b.Document()
+	if !doc.Has("DELETE", "/v1/items/:id/tags/:tag") {
+		t.Fatal("route not documented")
+	}
+	op := doc.Paths["/v1/items/{id}/tags/{tag}"]["delete"]
+	if op.OperationID != "delete_v1_items_id_tags_tag" || op.Parameters[0].Schema.Format != "uuid" || op.Parameters[1].Schema.Format != "" {
+		t.Fatalf("unexpected operation %+v", op)
+	}
+	if res := op.Responses["404"]; len(res.ErrorCodes) != 2 || res.Description != "Not Found: A, B" {
+		t.Fatalf("unexpected error response %+v", res)
+	}
+	if res := op.Responses["204"]; res == nil || res.Content != nil {
+		t.Fatalf("unexpected success response %+v", res)
+	}
+}
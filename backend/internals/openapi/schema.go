@@ -0,0 +1,203 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Schema
+// a JSON schema as used by OpenAPI 3.0
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	AllOf                []*Schema          `json:"allOf,omitempty"`
+}
+
+// Types with a JSON form other than their Go structure
+var (
+	uuidType     = reflect.TypeOf(uuid.UUID{})
+	nullUUIDType = reflect.TypeOf(uuid.NullUUID{})
+	timeType     = reflect.TypeOf(time.Time{})
+	rawType      = reflect.TypeOf(json.RawMessage{})
+)
+
+// reflectSchema
+// the schema of v's type, named structs are stored in schemas
+func reflectSchema(schemas map[string]*Schema, v any) *Schema {
+	if s, ok := v.(*Schema); ok {
+		return s
+	}
+	return typeSchema(schemas, reflect.TypeOf(v))
+}
+
+func typeSchema(schemas map[string]*Schema, t reflect.Type) *Schema {
+	switch t {
+	case uuidType:
+		return &Schema{Type: "string", Format: "uuid"}
+	case nullUUIDType:
+		return &Schema{Type: "string", Format: "uuid", Nullable: true}
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case rawType:
+		return &Schema{Description: "any JSON value"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		s := typeSchema(schemas, t.Elem())
+		if s.Ref != "" {
+			return s
+		}
+		s.Nullable = true
+		return s
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: typeSchema(schemas, t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: typeSchema(schemas, t.Elem())}
+	case reflect.Interface:
+		return &Schema{}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return structSchema(schemas, t)
+		}
+		name := schemaName(t)
+		if _, ok := schemas[name]; !ok {
+			// placeholder first so recursive types terminate
+			schemas[name] = &Schema{}
+			*schemas[name] = *structSchema(schemas, t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	}
+	return &Schema{}
+}
+
+// schemaName
+// the component name of a named struct, type arguments of generic
+// types are appended without their package, Page[pkg.Guide] is
+// Page_Guide
+func schemaName(t reflect.Type) string {
+	name, args, generic := strings.Cut(t.Name(), "[")
+	if !generic {
+		return name
+	}
+	for _, arg := range strings.Split(strings.TrimSuffix(args, "]"), ",") {
+		arg = arg[strings.LastIndexAny(arg, "./")+1:]
+		name += "_" + strings.Trim(arg, "*[]")
+	}
+	return name
+}
+
+// structSchema
+// an object with the fields encoding/json writes, binding tags
+// add required fields and value constraints, doc and enum tags
+// describe fields validated by hand
+func structSchema(schemas map[string]*Schema, t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous && f.Tag.Get("json") == "" && f.Type.Kind() == reflect.Struct {
+			// embedded structs are flattened like encoding/json does
+			inner := structSchema(schemas, f.Type)
+			for k, v := range inner.Properties {
+				s.Properties[k] = v
+			}
+			s.Required = append(s.Required, inner.Required...)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" && opts == "" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		field := typeSchema(schemas, f.Type)
+		if enum := f.Tag.Get("enum"); enum != "" {
+			field.Enum = strings.Fields(enum)
+		}
+		if doc := f.Tag.Get("doc"); doc != "" {
+			field = withDescription(field, doc)
+		}
+		if applyBinding(field, f.Tag.Get("binding")) {
+			s.Required = append(s.Required, name)
+		}
+		s.Properties[name] = field
+	}
+	return s
+}
+
+// withDescription
+// a $ref can't carry siblings in OpenAPI 3.0, wrap it
+func withDescription(s *Schema, doc string) *Schema {
+	if s.Ref != "" {
+		return &Schema{Description: doc, AllOf: []*Schema{s}}
+	}
+	s.Description = doc
+	return s
+}
+
+// applyBinding
+// adds the validator rules of a binding tag to s, reports
+// whether the field is required
+func applyBinding(s *Schema, tag string) bool {
+	required := false
+	for _, rule := range strings.Split(tag, ",") {
+		name, arg, _ := strings.Cut(rule, "=")
+		switch name {
+		case "required":
+			required = true
+		case "email", "uuid":
+			s.Format = name
+		case "oneof":
+			s.Enum = strings.Fields(arg)
+		case "min", "max":
+			n, err := strconv.ParseFloat(arg, 64)
+			if err != nil {
+				continue
+			}
+			switch {
+			case s.Type == "string" && name == "max":
+				max := int(n)
+				s.MaxLength = &max
+			case s.Type == "integer" || s.Type == "number":
+				if name == "min" {
+					s.Minimum = &n
+				} else {
+					s.Maximum = &n
+				}
+			}
+		}
+	}
+	return required
+}
@@ -0,0 +1,33 @@
+package outbox
+
+import (
+	"context"
+
+	"github.com/ErebusAJ/YatraBandhu/internals/db"
+	"github.com/ErebusAJ/YatraBandhu/internals/mailer"
+)
+
+// Queue
+// anything that can insert into email_outbox, *db.Queries or a store
+type Queue interface {
+	EnqueueEmail(ctx context.Context, arg db.EnqueueEmailParams) error
+}
+
+// Enqueue
+// renders the named template and writes it to the email_outbox
+// pass queries bound to a transaction to commit the mail together
+// with the business change, the worker delivers it afterwards
+func Enqueue(ctx context.Context, q Queue, to, name string, data any) error {
+	msg, err := mailer.Render(name, to, data)
+	if err != nil {
+		return err
+	}
+
+	return q.EnqueueEmail(ctx, db.EnqueueEmailParams{
+		Recipient: msg.To,
+		Subject:   msg.Subject,
+		TextBody:  msg.Text,
+		HtmlBody:  msg.HTML,
+		Template:  msg.Template,
+	})
+}
@@ -0,0 +1,135 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+
+	"github.com/ErebusAJ/YatraBandhu/internals/db"
+	"github.com/ErebusAJ/YatraBandhu/internals/mailer"
+)
+
+// Worker
+// Delivers email_outbox rows with exponential backoff retries,
+// rows failing MaxAttempts times are marked dead, sent rows
+// have their bodies cleared
+type Worker struct {
+	DB     *db.Queries
+	Mailer mailer.Mailer
+
+	MaxAttempts  int
+	BaseDelay    time.Duration
+	MaxDelay     time.Duration
+	BatchSize    int
+	PollInterval time.Duration
+	// Lease keeps a claimed row from being picked up by another
+	// replica while it is being sent
+	Lease time.Duration
+}
+
+// NewWorker
+// returns a worker with default retry settings
+func NewWorker(q *db.Queries, m mailer.Mailer) *Worker {
+	return &Worker{
+		DB:           q,
+		Mailer:       m,
+		MaxAttempts:  8,
+		BaseDelay:    30 * time.Second,
+		MaxDelay:     time.Hour,
+		BatchSize:    20,
+		PollInterval: 5 * time.Second,
+		Lease:        2 * time.Minute,
+	}
+}
+
+// Run
+// polls and delivers pending mail until ctx is cancelled
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		for w.deliverBatch(ctx) == w.BatchSize {
+			// full batch, more may be waiting
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// deliverBatch
+// claims and sends one batch, returns number of rows claimed
+func (w *Worker) deliverBatch(ctx context.Context) int {
+	if ctx.Err() != nil {
+		return 0
+	}
+
+	rows, err := w.DB.ClaimPendingEmails(ctx, db.ClaimPendingEmailsParams{
+		NextAttemptAt: time.Now().Add(w.Lease),
+		Limit:         int32(w.BatchSize),
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "outbox: error claiming mail", "error", err)
+		return 0
+	}
+
+	for _, row := range rows {
+		// stop between messages on shutdown, unsent rows are
+		// picked up again once their lease expires
+		if ctx.Err() != nil {
+			break
+		}
+		w.deliver(ctx, row)
+	}
+
+	return len(rows)
+}
+
+func (w *Worker) deliver(ctx context.Context, row db.EmailOutbox) {
+	err := w.Mailer.Send(ctx, mailer.Message{
+		To:       row.Recipient,
+		Subject:  row.Subject,
+		Text:     row.TextBody,
+		HTML:     row.HtmlBody,
+		Template: row.Template,
+	})
+	if err == nil {
+		err = w.DB.MarkEmailSent(ctx, row.ID)
+		if err != nil {
+			slog.ErrorContext(ctx, "outbox: error marking mail sent", "email_id", row.ID, "error", err)
+		}
+		return
+	}
+
+	attempts := int(row.Attempts) + 1
+	status := "pending"
+	if attempts >= w.MaxAttempts {
+		status = "dead"
+		slog.WarnContext(ctx, "outbox: giving up on mail", "email_id", row.ID, "attempts", attempts, "error", err)
+	}
+
+	markErr := w.DB.MarkEmailFailed(ctx, db.MarkEmailFailedParams{
+		Status:        status,
+		LastError:     sql.NullString{String: err.Error(), Valid: true},
+		NextAttemptAt: time.Now().Add(w.backoff(attempts)),
+		ID:            row.ID,
+	})
+	if markErr != nil {
+		slog.ErrorContext(ctx, "outbox: error marking mail failed", "email_id", row.ID, "error", markErr)
+	}
+}
+
+// backoff
+// delay before the next attempt after attempts failures
+func (w *Worker) backoff(attempts int) time.Duration {
+	delay := w.BaseDelay << (attempts - 1)
+	if delay <= 0 || delay > w.MaxDelay {
+		return w.MaxDelay
+	}
+	return delay
+}
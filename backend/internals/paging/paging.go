@@ -0,0 +1,304 @@
+package paging
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Page size bounds
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+// Kind
+// the type of a filter or sort value
+type Kind int
+
+const (
+	Text Kind = iota
+	Number
+	Integer
+	Time
+	// Date is a YYYY-MM-DD string, compared as text
+	Date
+	UUID
+)
+
+// Field
+// a sort field or filter of a list
+type Field struct {
+	Name        string
+	Kind        Kind
+	Description string
+}
+
+// Spec
+// the sorts and filters a list endpoint accepts
+type Spec struct {
+	Sorts []Field
+	// Default sort, "-" prefixed for descending
+	Default string
+	Filters []Field
+}
+
+// Key
+// the position of a row in a sorted list, the value of the sort
+// field in the member matching its kind and the row id as tie breaker
+type Key struct {
+	Text string    `json:"t,omitempty"`
+	Num  string    `json:"n,omitempty"`
+	Time time.Time `json:"m,omitempty"`
+	ID   uuid.UUID `json:"i"`
+}
+
+// Compare
+// orders keys of the same sort field
+func (k Key) Compare(o Key) int {
+	if c := strings.Compare(k.Text, o.Text); c != 0 {
+		return c
+	}
+	a, _ := strconv.ParseFloat(k.Num, 64)
+	b, _ := strconv.ParseFloat(o.Num, 64)
+	if a != b {
+		if a < b {
+			return -1
+		}
+		return 1
+	}
+	if c := k.Time.Compare(o.Time); c != 0 {
+		return c
+	}
+	return bytes.Compare(k.ID[:], o.ID[:])
+}
+
+// Request
+// a parsed list request
+type Request struct {
+	Limit int32
+	// Sort field, Desc for "-" prefixed sorts
+	Sort string
+	Desc bool
+	// After is the key of the last row of the previous page, nil
+	// for the first page
+	After *Key
+	// Filters by name, only the ones given
+	Filters map[string]string
+}
+
+// SortParam
+// the sort as sent, "-" prefixed for descending, the form
+// the queries switch on
+func (r Request) SortParam() string {
+	if r.Desc {
+		return "-" + r.Sort
+	}
+	return r.Sort
+}
+
+// AfterKey
+// the after key, zero on the first page, Num is always a valid
+// number so queries can cast it whatever the sort
+func (r Request) AfterKey() Key {
+	k := Key{}
+	if r.After != nil {
+		k = *r.After
+	}
+	if k.Num == "" {
+		k.Num = "0"
+	}
+	return k
+}
+
+// Fetch
+// rows to query, one more than the limit to tell if a next page exists
+func (r Request) Fetch() int32 {
+	return r.Limit + 1
+}
+
+// Error
+// an invalid query param
+type Error struct {
+	Field   string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+var datePattern = regexp.MustCompile(`^\d{4}-(0[1-9]|1[0-2])-(0[1-9]|[12][0-9]|3[01])$`)
+
+// Parse
+// reads limit, sort, cursor and the filters of s from q
+func (s Spec) Parse(q url.Values) (Request, error) {
+	r := Request{Limit: DefaultLimit, Filters: map[string]string{}}
+
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return r, &Error{"limit", "must be a positive integer"}
+		}
+		r.Limit = int32(min(n, MaxLimit))
+	}
+
+	sort := q.Get("sort")
+	if sort == "" {
+		sort = s.Default
+	}
+	r.Sort, r.Desc = strings.TrimPrefix(sort, "-"), strings.HasPrefix(sort, "-")
+	if !slices.ContainsFunc(s.Sorts, func(f Field) bool { return f.Name == r.Sort }) {
+		return r, &Error{"sort", "must be one of " + strings.Join(s.SortNames(), ", ")}
+	}
+
+	for _, f := range s.Filters {
+		v := strings.TrimSpace(q.Get(f.Name))
+		if v == "" {
+			continue
+		}
+		if msg := f.check(v); msg != "" {
+			return r, &Error{f.Name, msg}
+		}
+		r.Filters[f.Name] = v
+	}
+
+	if v := q.Get("cursor"); v != "" {
+		c, err := decodeCursor(v)
+		if err == nil && c.After.Num != "" {
+			_, err = strconv.ParseFloat(c.After.Num, 64)
+		}
+		if err != nil {
+			return r, &Error{"cursor", "invalid cursor"}
+		}
+		// a cursor only makes sense for the query it came from
+		if c.Query != r.fingerprint() {
+			return r, &Error{"cursor", "cursor doesn't match the sort and filters"}
+		}
+		r.After = &c.After
+	}
+
+	return r, nil
+}
+
+// SortNames
+// the accepted sort params, ascending and descending
+func (s Spec) SortNames() []string {
+	var names []string
+	for _, f := range s.Sorts {
+		names = append(names, f.Name, "-"+f.Name)
+	}
+	return names
+}
+
+func (f Field) check(v string) string {
+	switch f.Kind {
+	case Number:
+		if _, err := strconv.ParseFloat(v, 64); err != nil {
+			return "must be a number"
+		}
+	case Integer:
+		if _, err := strconv.Atoi(v); err != nil {
+			return "must be an integer"
+		}
+	case Date:
+		if !datePattern.MatchString(v) {
+			return "must be YYYY-MM-DD"
+		}
+	case UUID:
+		if _, err := uuid.Parse(v); err != nil {
+			return "must be a uuid"
+		}
+	case Time:
+		if _, err := time.Parse(time.RFC3339, v); err != nil {
+			return "must be an RFC 3339 time"
+		}
+	}
+	return ""
+}
+
+// Page
+// one page of a list, Next is the link to the following page,
+// both it and NextCursor are empty on the last page
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	Limit      int32  `json:"limit"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	Next       string `json:"next,omitempty"`
+}
+
+// NewPage
+// trims rows fetched with Fetch to the limit, when there are more
+// the cursor points after the last row kept, link is the request url
+// to add it to
+func NewPage[T any](r Request, rows []T, key func(T) Key, link *url.URL) Page[T] {
+	p := Page[T]{Items: rows, Limit: r.Limit}
+	if p.Items == nil {
+		p.Items = []T{}
+	}
+	if len(rows) <= int(r.Limit) {
+		return p
+	}
+
+	p.Items = rows[:r.Limit]
+	p.NextCursor = encodeCursor(cursor{Query: r.fingerprint(), After: key(p.Items[len(p.Items)-1])})
+
+	if link != nil {
+		q := link.Query()
+		q.Set("cursor", p.NextCursor)
+		next := url.URL{Path: link.Path, RawQuery: q.Encode()}
+		p.Next = next.String()
+	}
+	return p
+}
+
+// cursor
+// the opaque cursor, base64 json
+type cursor struct {
+	Query string `json:"q"`
+	After Key    `json:"a"`
+}
+
+// fingerprint
+// identifies the sort and filters of r, the limit may change
+// between pages
+func (r Request) fingerprint() string {
+	names := make([]string, 0, len(r.Filters))
+	for name := range r.Filters {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n", r.SortParam())
+	for _, name := range names {
+		fmt.Fprintf(h, "%s=%s\n", name, r.Filters[name])
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+func encodeCursor(c cursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string) (cursor, error) {
+	var c cursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, err
+	}
+	err = json.Unmarshal(b, &c)
+	return c, err
+}
@@ -0,0 +1,88 @@
+package paging
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+var spec = Spec{
+	Sorts:   []Field{{Name: "name"}, {Name: "rating", Kind: Number}},
+	Default: "-rating",
+	Filters: []Field{{Name: "from", Kind: Date}, {Name: "min", Kind: Integer}},
+}
+
+func TestParse(t *testing.T) {
+	r, err := spec.Parse(url.Values{})
+	if err != nil || r.Limit != DefaultLimit || r.SortParam() != "-rating" || r.After != nil || r.AfterKey().Num != "0" {
+		t.Fatalf("unexpected defaults %+v, %v", r, err)
+	}
+
+	r, err = spec.Parse(url.Values{"limit": {"500"}, "sort": {"name"}, "from": {" 2025-01-31 "}})
+	if err != nil || r.Limit != MaxLimit || r.Desc || r.Filters["from"] != "2025-01-31" {
+		t.Fatalf("unexpected request %+v, %v", r, err)
+	}
+
+	for field, q := range map[string]url.Values{
+		"limit":  {"limit": {"-1"}},
+		"sort":   {"sort": {"-id"}},
+		"from":   {"from": {"2025-13-01"}},
+		"min":    {"min": {"1.5"}},
+		"cursor": {"cursor": {"e30"}},
+	} {
+		var perr *Error
+		if _, err := spec.Parse(q); !errors.As(err, &perr) || perr.Field != field {
+			t.Fatalf("%v: unexpected error %v", q, err)
+		}
+	}
+}
+
+func TestNewPage(t *testing.T) {
+	ids := []uuid.UUID{uuid.New(), uuid.New(), uuid.New()}
+	key := func(id uuid.UUID) Key { return Key{Text: "x", ID: id} }
+	link, _ := url.Parse("/guides?sort=name&limit=2")
+
+	r, _ := spec.Parse(link.Query())
+	p := NewPage(r, ids, key, link)
+	if len(p.Items) != 2 || p.NextCursor == "" {
+		t.Fatalf("unexpected page %+v", p)
+	}
+
+	next, _ := url.Parse(p.Next)
+	r, err := spec.Parse(next.Query())
+	if err != nil || r.After == nil || r.After.ID != ids[1] || r.SortParam() != "name" {
+		t.Fatalf("unexpected next request %+v, %v", r, err)
+	}
+	if p := NewPage(r, ids[2:], key, next); len(p.Items) != 1 || p.Next != "" || p.NextCursor != "" {
+		t.Fatalf("unexpected last page %+v", p)
+	}
+
+	// the cursor is tied to the sort and filters
+	q := next.Query()
+	q.Set("from", "2025-01-01")
+	if _, err := spec.Parse(q); err == nil {
+		t.Fatal("expected an error for a cursor of other filters")
+	}
+	if p := NewPage(r, []uuid.UUID(nil), key, nil); p.Items == nil {
+		t.Fatal("empty page should have items []")
+	}
+}
+
+func TestKeyCompare(t *testing.T) {
+	a, b := uuid.MustParse("00000000-0000-0000-0000-000000000001"), uuid.MustParse("00000000-0000-0000-0000-000000000002")
+	for _, c := range []struct {
+		x, y Key
+		want int
+	}{
+		{Key{Num: "9", ID: b}, Key{Num: "10", ID: a}, -1},
+		{Key{Num: "500", ID: a}, Key{Num: "500.00", ID: b}, -1},
+		{Key{Text: "b", ID: a}, Key{Text: "a", ID: b}, 1},
+		{Key{ID: a}, Key{ID: a}, 0},
+	} {
+		if got := c.x.Compare(c.y); got != c.want {
+			t.Fatalf("%+v vs %+v: got %d, want %d", c.x, c.y, got, c.want)
+		}
+	}
+}
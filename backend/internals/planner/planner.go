@@ -0,0 +1,75 @@
+package planner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/ErebusAJ/YatraBandhu/internals/itinerary"
+)
+
+// Statuses of a job
+const (
+	StatusQueued    = "queued"
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+	StatusCancelled = "cancelled"
+)
+
+// Stages of a job, the one in progress or last reached
+const (
+	StageQueued      = "queued"
+	StageCollecting  = "collecting_data"
+	StageDrafting    = "drafting"
+	StageStructuring = "structuring"
+	StageSaved       = "saved"
+)
+
+// Sources of a saved plan's versions
+const (
+	SourceGenerated   = "generated"
+	SourceRegenerated = "regenerated"
+	SourceEdited      = "edited"
+	SourceRestored    = "restored"
+)
+
+// ErrCancelled
+// the job was cancelled while it ran
+var ErrCancelled = errors.New("planner job cancelled")
+
+// Pipeline
+// The stages of a generation, the output of Collect and Draft is
+// stored with the job so a retry resumes at the stage that failed
+type Pipeline interface {
+	// Collect gathers the travel data of location, as json
+	Collect(ctx context.Context, location string, days int) (json.RawMessage, error)
+	// Draft writes an itinerary from the travel data
+	Draft(ctx context.Context, travelData json.RawMessage, interests string, days int) (string, error)
+	// Structure converts the draft to an itinerary of days days from start
+	Structure(ctx context.Context, draft string, days int, start time.Time) (*itinerary.Itinerary, error)
+}
+
+// Unavailable
+// a pipeline failing every stage with err, for a planner that
+// could not be configured
+func Unavailable(err error) Pipeline {
+	return unavailable{err}
+}
+
+type unavailable struct {
+	err error
+}
+
+func (u unavailable) Collect(ctx context.Context, location string, days int) (json.RawMessage, error) {
+	return nil, u.err
+}
+
+func (u unavailable) Draft(ctx context.Context, travelData json.RawMessage, interests string, days int) (string, error) {
+	return "", u.err
+}
+
+func (u unavailable) Structure(ctx context.Context, draft string, days int, start time.Time) (*itinerary.Itinerary, error) {
+	return nil, u.err
+}
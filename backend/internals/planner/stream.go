@@ -0,0 +1,77 @@
+package planner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ErebusAJ/YatraBandhu/internals/itinerary"
+	"github.com/ErebusAJ/YatraBandhu/internals/utils"
+)
+
+var _ Streamer = (*utils.Planner)(nil)
+
+// Streamer
+// A pipeline reporting progress within its stages, Run falls back
+// to the plain stages of pipelines without it
+type Streamer interface {
+	// CollectSteps is Collect calling onStep as each source is queried
+	CollectSteps(ctx context.Context, location string, days int, onStep func(step string)) (json.RawMessage, error)
+	// DraftStream is Draft passing the text to onText as it is written
+	DraftStream(ctx context.Context, travelData json.RawMessage, interests string, days int, onText func(text string)) (string, error)
+}
+
+// Event
+// Progress of a generation run by Run
+type Event struct {
+	// Stage is the stage in progress, an event with neither
+	// Step nor Text set starts it
+	Stage string
+	// Step is a step of the stage starting
+	Step string
+	// Text is the next piece of the draft
+	Text string
+}
+
+// Run
+// runs every stage of a generation in this process, reporting
+// progress to onEvent as it is made, the itinerary is not saved
+// and stage errors are wrapped like the worker's
+func Run(ctx context.Context, p Pipeline, location, interests string, days int, start time.Time, onEvent func(Event)) (*itinerary.Itinerary, error) {
+	s, streams := p.(Streamer)
+
+	onEvent(Event{Stage: StageCollecting})
+	var travelData json.RawMessage
+	var err error
+	if streams {
+		travelData, err = s.CollectSteps(ctx, location, days, func(step string) {
+			onEvent(Event{Stage: StageCollecting, Step: step})
+		})
+	} else {
+		travelData, err = p.Collect(ctx, location, days)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", StageCollecting, err)
+	}
+
+	onEvent(Event{Stage: StageDrafting})
+	var draft string
+	if streams {
+		draft, err = s.DraftStream(ctx, travelData, interests, days, func(text string) {
+			onEvent(Event{Stage: StageDrafting, Text: text})
+		})
+	} else if draft, err = p.Draft(ctx, travelData, interests, days); err == nil {
+		onEvent(Event{Stage: StageDrafting, Text: draft})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", StageDrafting, err)
+	}
+
+	onEvent(Event{Stage: StageStructuring})
+	it, err := p.Structure(ctx, draft, days, start)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", StageStructuring, err)
+	}
+	return it, nil
+}
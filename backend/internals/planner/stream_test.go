@@ -0,0 +1,58 @@
+package planner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// streamingPipeline
+// a fakePipeline reporting collection steps and the draft in words
+type streamingPipeline struct {
+	*fakePipeline
+}
+
+func (p streamingPipeline) CollectSteps(ctx context.Context, location string, days int, onStep func(step string)) (json.RawMessage, error) {
+	onStep("geocoding")
+	onStep("weather")
+	return p.Collect(ctx, location, days)
+}
+
+func (p streamingPipeline) DraftStream(ctx context.Context, travelData json.RawMessage, interests string, days int, onText func(text string)) (string, error) {
+	draft, err := p.Draft(ctx, travelData, interests, days)
+	for _, word := range strings.SplitAfter(draft, " ") {
+		onText(word)
+	}
+	return draft, err
+}
+
+func TestRun(t *testing.T) {
+	var events []string
+	record := func(e Event) { events = append(events, fmt.Sprintf("%s/%s/%s", e.Stage, e.Step, e.Text)) }
+
+	it, err := Run(ctx, streamingPipeline{&fakePipeline{}}, "Goa", "beaches", 2, time.Now(), record)
+	want := []string{
+		"collecting_data//", "collecting_data/geocoding/", "collecting_data/weather/",
+		"drafting//", "drafting//draft ", `drafting//of `, `drafting//{"location":"Goa"}`,
+		"structuring//",
+	}
+	if err != nil || it.Destination != `draft of {"location":"Goa"}` || strings.Join(events, "|") != strings.Join(want, "|") {
+		t.Fatalf("unexpected events %q, itinerary %+v, %v", events, it, err)
+	}
+
+	// plain pipelines send the draft in one piece
+	events = nil
+	if _, err := Run(ctx, &fakePipeline{}, "Goa", "beaches", 2, time.Now(), record); err != nil || len(events) != 4 || events[2] != `drafting//draft of {"location":"Goa"}` {
+		t.Fatalf("unexpected events %q, %v", events, err)
+	}
+
+	events = nil
+	p := &fakePipeline{draft: func(ctx context.Context) error { return errors.New("provider down") }}
+	if _, err := Run(ctx, p, "Goa", "beaches", 2, time.Now(), record); err == nil || err.Error() != "drafting: provider down" || len(events) != 2 {
+		t.Fatalf("unexpected error %v after events %q", err, events)
+	}
+}
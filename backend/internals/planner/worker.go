@@ -0,0 +1,367 @@
+package planner
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/ErebusAJ/YatraBandhu/internals/db"
+	"github.com/ErebusAJ/YatraBandhu/internals/itinerary"
+	"github.com/ErebusAJ/YatraBandhu/internals/metrics"
+	"github.com/ErebusAJ/YatraBandhu/internals/store"
+	"github.com/ErebusAJ/YatraBandhu/internals/utils"
+	"github.com/google/uuid"
+)
+
+// Worker
+// Runs queued planner_jobs through the pipeline, at most Concurrency
+// at once, a failing stage is retried with exponential backoff and
+// the job fails once it has been tried MaxAttempts times
+type Worker struct {
+	DB       store.Store
+	Pipeline Pipeline
+
+	Concurrency  int
+	MaxAttempts  int
+	BaseDelay    time.Duration
+	MaxDelay     time.Duration
+	PollInterval time.Duration
+	// Lease keeps a running job from being claimed by another replica,
+	// it is renewed while the job runs and a renewal finding the job
+	// cancelled stops it
+	Lease time.Duration
+
+	// OnFailed is called once a job has run out of attempts
+	OnFailed func(ctx context.Context, job db.PlannerJob)
+
+	wake    chan struct{}
+	mu      sync.Mutex
+	running map[uuid.UUID]context.CancelCauseFunc
+	wg      sync.WaitGroup
+}
+
+// NewWorker
+// returns a worker with default retry settings
+func NewWorker(st store.Store, p Pipeline, concurrency, maxAttempts int) *Worker {
+	return &Worker{
+		DB:           st,
+		Pipeline:     p,
+		Concurrency:  concurrency,
+		MaxAttempts:  maxAttempts,
+		BaseDelay:    10 * time.Second,
+		MaxDelay:     5 * time.Minute,
+		PollInterval: 5 * time.Second,
+		Lease:        time.Minute,
+		wake:         make(chan struct{}, 1),
+		running:      map[uuid.UUID]context.CancelCauseFunc{},
+	}
+}
+
+// Run
+// runs queued jobs until ctx is cancelled, jobs still running
+// then are released back to the queue
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.PollInterval)
+	defer ticker.Stop()
+	defer w.wg.Wait()
+
+	for {
+		w.claim(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-w.wake:
+		}
+	}
+}
+
+// Work
+// runs jobs until none is ready and waits for them, retries
+// without a delay included, for tests and one-off runs
+func (w *Worker) Work(ctx context.Context) {
+	for w.claim(ctx) > 0 {
+		w.wg.Wait()
+	}
+}
+
+// Notify
+// wakes Run to pick up a job that was just queued
+func (w *Worker) Notify() {
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Cancel
+// stops job id if it is running in this process, the caller
+// marks it cancelled, replicas notice on their next lease renewal
+func (w *Worker) Cancel(id uuid.UUID) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if cancel, ok := w.running[id]; ok {
+		cancel(ErrCancelled)
+	}
+}
+
+// claim
+// starts as many ready jobs as there are free slots, returns
+// the number started
+func (w *Worker) claim(ctx context.Context) int {
+	w.mu.Lock()
+	free := w.Concurrency - len(w.running)
+	w.mu.Unlock()
+	if free <= 0 || ctx.Err() != nil {
+		return 0
+	}
+
+	jobs, err := w.DB.ClaimPlannerJobs(ctx, db.ClaimPlannerJobsParams{
+		NextAttemptAt: time.Now().Add(w.Lease),
+		Limit:         int32(free),
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "planner: error claiming jobs", "error", err)
+		return 0
+	}
+
+	for _, job := range jobs {
+		jobCtx, cancel := context.WithCancelCause(ctx)
+		w.mu.Lock()
+		w.running[job.ID] = cancel
+		w.mu.Unlock()
+
+		w.wg.Add(1)
+		go func() {
+			defer w.wg.Done()
+			w.process(jobCtx, cancel, job)
+
+			// a slot is free
+			w.mu.Lock()
+			delete(w.running, job.ID)
+			w.mu.Unlock()
+			w.Notify()
+		}()
+	}
+	return len(jobs)
+}
+
+func (w *Worker) process(ctx context.Context, cancel context.CancelCauseFunc, job db.PlannerJob) {
+	go w.renew(ctx, cancel, job.ID)
+
+	started := time.Now()
+	err := w.run(ctx, job)
+	cause := context.Cause(ctx)
+	cancel(nil)
+
+	// bookkeeping outlives the job's context
+	ctx = context.WithoutCancel(ctx)
+	switch {
+	case err == nil:
+		metrics.ObserveItinerary(utils.ItineraryOutcome(nil), time.Since(started))
+		slog.InfoContext(ctx, "planner: job succeeded", "job_id", job.ID, "attempts", job.Attempts+1)
+	case errors.Is(err, ErrCancelled), errors.Is(cause, ErrCancelled):
+		slog.InfoContext(ctx, "planner: job cancelled", "job_id", job.ID)
+	case cause != nil:
+		// shutting down, another run picks the job up where it stopped
+		err = w.DB.ReleasePlannerJob(ctx, job.ID)
+		if err != nil {
+			slog.ErrorContext(ctx, "planner: error releasing job", "job_id", job.ID, "error", err)
+		}
+	default:
+		metrics.ObserveItinerary(utils.ItineraryOutcome(err), time.Since(started))
+		w.fail(ctx, job, err)
+	}
+}
+
+// run
+// runs the stages the job has no output of yet, then saves the plan
+func (w *Worker) run(ctx context.Context, job db.PlannerJob) error {
+	days := int(job.Days)
+	travelData, draft := job.TravelData, job.Draft
+
+	if !travelData.Valid {
+		err := w.advance(ctx, job.ID, StageCollecting, travelData, draft)
+		if err != nil {
+			return err
+		}
+		data, err := w.Pipeline.Collect(ctx, job.Location, days)
+		if err != nil {
+			return fmt.Errorf("%s: %w", StageCollecting, err)
+		}
+		travelData = sql.NullString{String: string(data), Valid: true}
+	}
+
+	if !draft.Valid {
+		err := w.advance(ctx, job.ID, StageDrafting, travelData, draft)
+		if err != nil {
+			return err
+		}
+		text, err := w.Pipeline.Draft(ctx, json.RawMessage(travelData.String), job.Interests, days)
+		if err != nil {
+			return fmt.Errorf("%s: %w", StageDrafting, err)
+		}
+		draft = sql.NullString{String: text, Valid: true}
+	}
+
+	err := w.advance(ctx, job.ID, StageStructuring, travelData, draft)
+	if err != nil {
+		return err
+	}
+	it, err := w.Pipeline.Structure(ctx, draft.String, days, job.StartDate)
+	if err != nil {
+		return fmt.Errorf("%s: %w", StageStructuring, err)
+	}
+
+	return w.save(ctx, job, it)
+}
+
+// advance
+// records the stage starting and the output of the ones before it
+func (w *Worker) advance(ctx context.Context, id uuid.UUID, stage string, travelData, draft sql.NullString) error {
+	n, err := w.DB.AdvancePlannerJob(ctx, db.AdvancePlannerJobParams{
+		Stage:      stage,
+		TravelData: travelData,
+		Draft:      draft,
+		ID:         id,
+	})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrCancelled
+	}
+	slog.DebugContext(ctx, "planner: job stage", "job_id", id, "stage", stage)
+	return nil
+}
+
+// save
+// stores the plan and finishes the job in one transaction, a job
+// cancelled meanwhile leaves no plan behind, a job regenerating a
+// saved plan adds a version to it
+func (w *Worker) save(ctx context.Context, job db.PlannerJob, it *itinerary.Itinerary) error {
+	return w.DB.InTx(ctx, func(st store.Store) error {
+		planID, err := SavePlan(ctx, st, job.UserID, job.PlanID, it)
+		if err != nil {
+			return err
+		}
+
+		n, err := st.FinishPlannerJob(ctx, db.FinishPlannerJobParams{
+			PlanID: uuid.NullUUID{UUID: planID, Valid: true},
+			ID:     job.ID,
+		})
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return ErrCancelled
+		}
+		return nil
+	})
+}
+
+// SavePlan
+// stores a generated itinerary as a new plan of userID, or as the
+// next version of planID when a saved plan was regenerated
+func SavePlan(ctx context.Context, st store.AIPlanStore, userID uuid.UUID, planID uuid.NullUUID, it *itinerary.Itinerary) (uuid.UUID, error) {
+	data, err := json.Marshal(it)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	if planID.Valid {
+		_, err := st.AddAIPlanVersion(ctx, db.AddAIPlanVersionParams{
+			RawData: data,
+			ID:      planID.UUID,
+			UserID:  userID,
+			Source:  SourceRegenerated,
+		})
+		return planID.UUID, err
+	}
+	return st.SavePlan(ctx, db.SavePlanParams{
+		UserID:  userID,
+		Title:   it.Destination,
+		RawData: data,
+	})
+}
+
+// fail
+// queues the job for another attempt, or fails it for good
+func (w *Worker) fail(ctx context.Context, job db.PlannerJob, err error) {
+	attempts := int(job.Attempts) + 1
+	lastError := sql.NullString{String: err.Error(), Valid: true}
+
+	if attempts < w.MaxAttempts {
+		slog.WarnContext(ctx, "planner: job failed, retrying", "job_id", job.ID, "attempts", attempts, "error", err)
+		_, err = w.DB.RetryPlannerJob(ctx, db.RetryPlannerJobParams{
+			LastError:     lastError,
+			NextAttemptAt: time.Now().Add(w.backoff(attempts)),
+			ID:            job.ID,
+		})
+		if err != nil {
+			slog.ErrorContext(ctx, "planner: error queueing job retry", "job_id", job.ID, "error", err)
+		}
+		return
+	}
+
+	slog.WarnContext(ctx, "planner: giving up on job", "job_id", job.ID, "attempts", attempts, "error", err)
+	n, err := w.DB.FailPlannerJob(ctx, db.FailPlannerJobParams{
+		LastError: lastError,
+		ID:        job.ID,
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "planner: error marking job failed", "job_id", job.ID, "error", err)
+		return
+	}
+	if n > 0 && w.OnFailed != nil {
+		w.OnFailed(ctx, job)
+	}
+}
+
+// renew
+// extends the lease of a running job until ctx is done, and
+// cancels it once it is no longer running
+func (w *Worker) renew(ctx context.Context, cancel context.CancelCauseFunc, id uuid.UUID) {
+	ticker := time.NewTicker(w.Lease / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		n, err := w.DB.ExtendPlannerJobLease(ctx, db.ExtendPlannerJobLeaseParams{
+			NextAttemptAt: time.Now().Add(w.Lease),
+			ID:            id,
+		})
+		if err != nil {
+			if ctx.Err() == nil {
+				slog.ErrorContext(ctx, "planner: error renewing job lease", "job_id", id, "error", err)
+			}
+			continue
+		}
+		if n == 0 {
+			cancel(ErrCancelled)
+			return
+		}
+	}
+}
+
+// backoff
+// delay before the next attempt after attempts failures
+func (w *Worker) backoff(attempts int) time.Duration {
+	delay := w.BaseDelay << (attempts - 1)
+	if delay < w.BaseDelay || delay > w.MaxDelay {
+		return w.MaxDelay
+	}
+	return delay
+}
@@ -0,0 +1,264 @@
+package planner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ErebusAJ/YatraBandhu/internals/db"
+	"github.com/ErebusAJ/YatraBandhu/internals/itinerary"
+	"github.com/ErebusAJ/YatraBandhu/internals/store"
+	"github.com/google/uuid"
+)
+
+var ctx = context.Background()
+
+// fakePipeline
+// counts the calls of each stage, a nil func succeeds
+type fakePipeline struct {
+	collect   func(ctx context.Context) error
+	draft     func(ctx context.Context) error
+	structure func(ctx context.Context) error
+
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func (p *fakePipeline) call(ctx context.Context, stage string, fn func(ctx context.Context) error) error {
+	p.mu.Lock()
+	if p.calls == nil {
+		p.calls = map[string]int{}
+	}
+	p.calls[stage]++
+	p.mu.Unlock()
+	if fn == nil {
+		return nil
+	}
+	return fn(ctx)
+}
+
+func (p *fakePipeline) Collect(ctx context.Context, location string, days int) (json.RawMessage, error) {
+	return json.RawMessage(`{"location":"` + location + `"}`), p.call(ctx, StageCollecting, p.collect)
+}
+
+func (p *fakePipeline) Draft(ctx context.Context, travelData json.RawMessage, interests string, days int) (string, error) {
+	return "draft of " + string(travelData), p.call(ctx, StageDrafting, p.draft)
+}
+
+func (p *fakePipeline) Structure(ctx context.Context, draft string, days int, start time.Time) (*itinerary.Itinerary, error) {
+	it := &itinerary.Itinerary{Destination: draft}
+	return it, p.call(ctx, StageStructuring, p.structure)
+}
+
+func (p *fakePipeline) count(stage string) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.calls[stage]
+}
+
+func newWorker(t *testing.T, p Pipeline) (*Worker, *store.Memory, uuid.UUID) {
+	t.Helper()
+	st := store.NewMemory()
+	userID, err := st.RegisterUser(ctx, db.RegisterUserParams{Name: "test", Age: 25, PhoneNumber: "9000000000", Email: "t@example.com", PasswordHash: "hash"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := NewWorker(st, p, 2, 3)
+	w.BaseDelay = 0
+	w.PollInterval = 10 * time.Millisecond
+	return w, st, userID
+}
+
+func enqueue(t *testing.T, st store.Store, userID uuid.UUID) db.PlannerJob {
+	t.Helper()
+	job, err := st.CreatePlannerJob(ctx, db.CreatePlannerJobParams{
+		UserID: userID, Location: "Goa", Interests: "beaches", Days: 2, StartDate: time.Now(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return job
+}
+
+func getJob(t *testing.T, st store.Store, job db.PlannerJob) db.PlannerJob {
+	t.Helper()
+	job, err := st.GetPlannerJob(ctx, db.GetPlannerJobParams{ID: job.ID, UserID: job.UserID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return job
+}
+
+// TestWorkerRetriesFailedStage
+// a retry resumes at the stage that failed
+func TestWorkerRetriesFailedStage(t *testing.T) {
+	p := &fakePipeline{}
+	p.draft = func(ctx context.Context) error {
+		if p.count(StageDrafting) == 1 {
+			return errors.New("provider down")
+		}
+		return nil
+	}
+	w, st, userID := newWorker(t, p)
+	job := enqueue(t, st, userID)
+
+	w.Work(ctx)
+
+	job = getJob(t, st, job)
+	if job.Status != StatusSucceeded || job.Stage != StageSaved || job.Attempts != 1 || !job.PlanID.Valid || !job.FinishedAt.Valid {
+		t.Fatalf("unexpected job %+v", job)
+	}
+	if p.count(StageCollecting) != 1 || p.count(StageDrafting) != 2 || p.count(StageStructuring) != 1 {
+		t.Fatalf("unexpected stage calls %v", p.calls)
+	}
+	plan, err := st.RetreivePlan(ctx, userID)
+	if err != nil || plan.ID != job.PlanID.UUID || !strings.Contains(string(plan.RawData), `draft of {\"location\":\"Goa\"}`) {
+		t.Fatalf("unexpected plan %+v, %v", plan, err)
+	}
+}
+
+// TestWorkerRegenerates
+// a job for a saved plan adds a version instead of a new plan
+func TestWorkerRegenerates(t *testing.T) {
+	w, st, userID := newWorker(t, &fakePipeline{})
+	first := enqueue(t, st, userID)
+	w.Work(ctx)
+	first = getJob(t, st, first)
+
+	job, err := st.CreatePlannerJob(ctx, db.CreatePlannerJobParams{
+		UserID: userID, Location: "Goa", Interests: "forts", Days: 2, StartDate: time.Now(), PlanID: first.PlanID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Work(ctx)
+
+	job = getJob(t, st, job)
+	plan, err := st.GetAIPlan(ctx, db.GetAIPlanParams{ID: first.PlanID.UUID, UserID: userID})
+	if job.Status != StatusSucceeded || job.PlanID != first.PlanID || err != nil || plan.Version != 2 || plan.Title == "" {
+		t.Fatalf("unexpected job %+v and plan %+v, %v", job, plan, err)
+	}
+	v, err := st.GetAIPlanVersion(ctx, db.GetAIPlanVersionParams{PlanID: plan.ID, Version: 2})
+	if err != nil || v.Source != SourceRegenerated {
+		t.Fatalf("unexpected version %+v, %v", v, err)
+	}
+	plans, _ := st.ListAIPlans(ctx, db.ListAIPlansParams{UserID: userID, Sort: "-created_at", PageLimit: 10})
+	if len(plans) != 1 {
+		t.Fatalf("expected one plan, got %+v", plans)
+	}
+}
+
+func TestWorkerGivesUp(t *testing.T) {
+	p := &fakePipeline{structure: func(ctx context.Context) error { return errors.New("no json") }}
+	w, st, userID := newWorker(t, p)
+	var failed []uuid.UUID
+	w.OnFailed = func(ctx context.Context, job db.PlannerJob) { failed = append(failed, job.ID) }
+	job := enqueue(t, st, userID)
+
+	w.Work(ctx)
+
+	job = getJob(t, st, job)
+	if job.Status != StatusFailed || job.Stage != StageStructuring || job.Attempts != 3 || job.LastError.String != "structuring: no json" {
+		t.Fatalf("unexpected job %+v", job)
+	}
+	if len(failed) != 1 || failed[0] != job.ID || p.count(StageDrafting) != 1 || p.count(StageStructuring) != 3 {
+		t.Fatalf("unexpected failure hooks %v and calls %v", failed, p.calls)
+	}
+	if _, err := st.RetreivePlan(ctx, userID); err == nil {
+		t.Fatal("failed job saved a plan")
+	}
+}
+
+func TestWorkerConcurrency(t *testing.T) {
+	var active, peak atomic.Int32
+	release := make(chan struct{})
+	p := &fakePipeline{collect: func(ctx context.Context) error {
+		n := active.Add(1)
+		defer active.Add(-1)
+		for {
+			old := peak.Load()
+			if n <= old || peak.CompareAndSwap(old, n) {
+				break
+			}
+		}
+		<-release
+		return nil
+	}}
+	w, st, userID := newWorker(t, p)
+	for range 3 {
+		enqueue(t, st, userID)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		w.Work(ctx)
+		close(done)
+	}()
+	for p.count(StageCollecting) < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	<-done
+
+	if peak.Load() != 2 || p.count(StageStructuring) != 3 {
+		t.Fatalf("ran %d jobs at once, %d finished", peak.Load(), p.count(StageStructuring))
+	}
+}
+
+func TestWorkerCancel(t *testing.T) {
+	started := make(chan struct{}, 1)
+	p := &fakePipeline{collect: func(ctx context.Context) error {
+		started <- struct{}{}
+		<-ctx.Done()
+		return ctx.Err()
+	}}
+	w, st, userID := newWorker(t, p)
+	w.Lease = 30 * time.Millisecond
+
+	runCtx, stop := context.WithCancel(ctx)
+	stopped := make(chan struct{})
+	go func() {
+		w.Run(runCtx)
+		close(stopped)
+	}()
+
+	// in this process
+	job := enqueue(t, st, userID)
+	w.Notify()
+	<-started
+	if n, _ := st.CancelPlannerJob(ctx, db.CancelPlannerJobParams{ID: job.ID, UserID: userID}); n != 1 {
+		t.Fatal("job not cancellable")
+	}
+	w.Cancel(job.ID)
+
+	// by another replica, noticed when the lease is renewed
+	other := enqueue(t, st, userID)
+	w.Notify()
+	<-started
+	st.CancelPlannerJob(ctx, db.CancelPlannerJobParams{ID: other.ID, UserID: userID})
+
+	// on shutdown a running job goes back to the queue
+	last := enqueue(t, st, userID)
+	w.Notify()
+	<-started
+	stop()
+	<-stopped
+
+	for _, j := range []db.PlannerJob{job, other} {
+		if j = getJob(t, st, j); j.Status != StatusCancelled || j.Attempts != 0 {
+			t.Fatalf("unexpected cancelled job %+v", j)
+		}
+	}
+	if last = getJob(t, st, last); last.Status != StatusQueued || last.Stage != StageCollecting || last.Attempts != 0 {
+		t.Fatalf("unexpected released job %+v", last)
+	}
+	if _, err := st.RetreivePlan(ctx, userID); err == nil {
+		t.Fatal("cancelled job saved a plan")
+	}
+}
@@ -0,0 +1,246 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ErebusAJ/YatraBandhu/internals/db"
+	"github.com/ErebusAJ/YatraBandhu/internals/paging"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+var _ Store = (*Memory)(nil)
+
+// Memory
+// In-memory Store for tests, it enforces the same unique, foreign key
+// and check constraints as the schema (returning *pq.Error with the
+// postgres constraint names) and cascades deletes the same way
+//
+// InTx serialises transactions and rolls back by restoring a snapshot,
+// calls made outside a transaction are not isolated from one in flight
+type Memory struct {
+	st   *memState
+	inTx bool
+}
+
+type memState struct {
+	mu   sync.Mutex
+	txMu sync.Mutex
+	t    *tables
+	last time.Time
+}
+
+type tables struct {
+	users              map[uuid.UUID]db.User
+	plans              map[uuid.UUID]db.TravelPlanDetail
+	passwordTokens     map[uuid.UUID]db.PasswordToken
+	refreshTokens      map[uuid.UUID]db.RefreshToken
+	verificationTokens map[uuid.UUID]db.VerificationToken
+	groups             map[uuid.UUID]db.TravelGroup
+	members            map[uuid.UUID]db.TravelGroupsMember
+	requests           map[uuid.UUID]db.TravelGroupsRequest
+	guides             map[uuid.UUID]db.Guide
+	guideRequests      map[uuid.UUID]db.GuideBookingRequest
+	aiPlans            map[uuid.UUID]db.AiPlan
+	aiPlanVersions     map[uuid.UUID]db.AiPlanVersion
+	plannerJobs        map[uuid.UUID]db.PlannerJob
+	reports            map[uuid.UUID]db.Report
+	outbox             map[uuid.UUID]db.EmailOutbox
+	auditEvents        map[uuid.UUID]db.AuditEvent
+}
+
+// NewMemory
+// returns an empty in-memory store
+func NewMemory() *Memory {
+	return &Memory{st: &memState{t: &tables{
+		users:              map[uuid.UUID]db.User{},
+		plans:              map[uuid.UUID]db.TravelPlanDetail{},
+		passwordTokens:     map[uuid.UUID]db.PasswordToken{},
+		refreshTokens:      map[uuid.UUID]db.RefreshToken{},
+		verificationTokens: map[uuid.UUID]db.VerificationToken{},
+		groups:             map[uuid.UUID]db.TravelGroup{},
+		members:            map[uuid.UUID]db.TravelGroupsMember{},
+		requests:           map[uuid.UUID]db.TravelGroupsRequest{},
+		guides:             map[uuid.UUID]db.Guide{},
+		guideRequests:      map[uuid.UUID]db.GuideBookingRequest{},
+		aiPlans:            map[uuid.UUID]db.AiPlan{},
+		aiPlanVersions:     map[uuid.UUID]db.AiPlanVersion{},
+		plannerJobs:        map[uuid.UUID]db.PlannerJob{},
+		reports:            map[uuid.UUID]db.Report{},
+		outbox:             map[uuid.UUID]db.EmailOutbox{},
+		auditEvents:        map[uuid.UUID]db.AuditEvent{},
+	}}}
+}
+
+func (t *tables) clone() *tables {
+	return &tables{
+		users:              maps.Clone(t.users),
+		plans:              maps.Clone(t.plans),
+		passwordTokens:     maps.Clone(t.passwordTokens),
+		refreshTokens:      maps.Clone(t.refreshTokens),
+		verificationTokens: maps.Clone(t.verificationTokens),
+		groups:             maps.Clone(t.groups),
+		members:            maps.Clone(t.members),
+		requests:           maps.Clone(t.requests),
+		guides:             maps.Clone(t.guides),
+		guideRequests:      maps.Clone(t.guideRequests),
+		aiPlans:            maps.Clone(t.aiPlans),
+		aiPlanVersions:     maps.Clone(t.aiPlanVersions),
+		plannerJobs:        maps.Clone(t.plannerJobs),
+		reports:            maps.Clone(t.reports),
+		outbox:             maps.Clone(t.outbox),
+		auditEvents:        maps.Clone(t.auditEvents),
+	}
+}
+
+// InTx
+// runs fn against a snapshot that is restored if fn fails
+func (m *Memory) InTx(ctx context.Context, fn func(Store) error) error {
+	if m.inTx {
+		return fn(m)
+	}
+
+	m.st.txMu.Lock()
+	defer m.st.txMu.Unlock()
+
+	m.st.mu.Lock()
+	snapshot := m.st.t.clone()
+	m.st.mu.Unlock()
+
+	err := fn(&Memory{st: m.st, inTx: true})
+	if err != nil {
+		m.st.mu.Lock()
+		m.st.t = snapshot
+		m.st.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// Ping
+// always succeeds
+func (m *Memory) Ping(ctx context.Context) error {
+	return nil
+}
+
+// lock
+// locks the state and returns its tables, callers must unlock
+func (m *Memory) lock() *tables {
+	m.st.mu.Lock()
+	return m.st.t
+}
+
+func (m *Memory) unlock() {
+	m.st.mu.Unlock()
+}
+
+// now
+// strictly increasing timestamps at postgres precision so rows
+// keep their insertion order when sorted by created_at
+func (m *Memory) now() time.Time {
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	if !now.After(m.st.last) {
+		now = m.st.last.Add(time.Microsecond)
+	}
+	m.st.last = now
+	return now
+}
+
+func uniqueViolation(constraint string) error {
+	return &pq.Error{
+		Severity:   "ERROR",
+		Code:       "23505",
+		Message:    fmt.Sprintf("duplicate key value violates unique constraint %q", constraint),
+		Constraint: constraint,
+	}
+}
+
+func foreignKeyViolation(table, constraint string) error {
+	return &pq.Error{
+		Severity:   "ERROR",
+		Code:       "23503",
+		Message:    fmt.Sprintf("insert or update on table %q violates foreign key constraint %q", table, constraint),
+		Table:      table,
+		Constraint: constraint,
+	}
+}
+
+func checkViolation(table, constraint string) error {
+	return &pq.Error{
+		Severity:   "ERROR",
+		Code:       "23514",
+		Message:    fmt.Sprintf("new row for relation %q violates check constraint %q", table, constraint),
+		Table:      table,
+		Constraint: constraint,
+	}
+}
+
+// rows
+// values of m matching keep, sorted by order
+func rows[V any](m map[uuid.UUID]V, keep func(V) bool, order func(a, b V) int) []V {
+	var items []V
+	for _, v := range m {
+		if keep(v) {
+			items = append(items, v)
+		}
+	}
+	slices.SortFunc(items, order)
+	return items
+}
+
+// page
+// applies LIMIT and OFFSET
+func page[V any](items []V, limit, offset int32) []V {
+	if int(offset) >= len(items) {
+		return nil
+	}
+	items = items[offset:]
+	if int(limit) < len(items) {
+		items = items[:limit]
+	}
+	return items
+}
+
+// keyset
+// applies the cursor, ORDER BY and LIMIT of the List queries, sort
+// is the field sorted on, "-" prefixed for descending, and key the
+// sort key of a row for a field
+func keyset[V any](items []V, sort string, hasCursor bool, after paging.Key, limit int32, key func(v V, field string) paging.Key) []V {
+	field, desc := strings.CutPrefix(sort, "-")
+	order := func(a, b paging.Key) int {
+		if desc {
+			return b.Compare(a)
+		}
+		return a.Compare(b)
+	}
+
+	var kept []V
+	for _, v := range items {
+		if !hasCursor || order(key(v, field), after) > 0 {
+			kept = append(kept, v)
+		}
+	}
+	slices.SortFunc(kept, func(a, b V) int { return order(key(a, field), key(b, field)) })
+	return page(kept, limit, 0)
+}
+
+// containsFold
+// ILIKE '%sub%'
+func containsFold(s, sub string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(sub))
+}
+
+func deleteWhere[V any](m map[uuid.UUID]V, match func(V) bool) {
+	maps.DeleteFunc(m, func(_ uuid.UUID, v V) bool { return match(v) })
+}
+
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: true}
+}
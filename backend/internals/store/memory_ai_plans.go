@@ -0,0 +1,210 @@
+package store
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"slices"
+	"strconv"
+
+	"github.com/ErebusAJ/YatraBandhu/internals/db"
+	"github.com/ErebusAJ/YatraBandhu/internals/paging"
+	"github.com/google/uuid"
+)
+
+// aiPlanSources
+// values allowed by ai_plan_versions_source_check
+var aiPlanSources = []string{"generated", "regenerated", "edited", "restored"}
+
+func (m *Memory) SavePlan(ctx context.Context, arg db.SavePlanParams) (uuid.UUID, error) {
+	t := m.lock()
+	defer m.unlock()
+
+	if _, ok := t.users[arg.UserID]; !ok {
+		return uuid.Nil, foreignKeyViolation("ai_plan", "ai_plan_user_id_fkey")
+	}
+
+	now := m.now()
+	id := uuid.New()
+	t.aiPlans[id] = db.AiPlan{
+		ID:        id,
+		UserID:    arg.UserID,
+		RawData:   arg.RawData,
+		CreatedAt: now,
+		UpdatedAt: now,
+		Title:     arg.Title,
+		Version:   1,
+	}
+	t.aiPlanVersions[uuid.New()] = db.AiPlanVersion{
+		PlanID:    id,
+		Version:   1,
+		RawData:   arg.RawData,
+		Source:    "generated",
+		CreatedAt: now,
+	}
+	return id, nil
+}
+
+func (m *Memory) RetreivePlan(ctx context.Context, userID uuid.UUID) (db.AiPlan, error) {
+	t := m.lock()
+	defer m.unlock()
+
+	plans := rows(t.aiPlans, func(p db.AiPlan) bool {
+		return p.UserID == userID
+	}, func(a, b db.AiPlan) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	if len(plans) == 0 {
+		return db.AiPlan{}, sql.ErrNoRows
+	}
+	return plans[0], nil
+}
+
+func (m *Memory) GetAIPlan(ctx context.Context, arg db.GetAIPlanParams) (db.AiPlan, error) {
+	t := m.lock()
+	defer m.unlock()
+
+	p, ok := t.aiPlans[arg.ID]
+	if !ok || p.UserID != arg.UserID {
+		return db.AiPlan{}, sql.ErrNoRows
+	}
+	return p, nil
+}
+
+func (m *Memory) ListAIPlans(ctx context.Context, arg db.ListAIPlansParams) ([]db.ListAIPlansRow, error) {
+	t := m.lock()
+	defer m.unlock()
+
+	plans := rows(t.aiPlans, func(p db.AiPlan) bool {
+		return p.UserID == arg.UserID &&
+			(arg.Title == "" || containsFold(p.Title, arg.Title))
+	}, func(a, b db.AiPlan) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+
+	var items []db.ListAIPlansRow
+	for _, p := range plans {
+		items = append(items, db.ListAIPlansRow{
+			ID:        p.ID,
+			Title:     p.Title,
+			Version:   p.Version,
+			CreatedAt: p.CreatedAt,
+			UpdatedAt: p.UpdatedAt,
+		})
+	}
+
+	after := paging.Key{Text: arg.AfterText, Time: arg.AfterTime, ID: arg.AfterID}
+	return keyset(items, arg.Sort, arg.HasCursor, after, arg.PageLimit, func(p db.ListAIPlansRow, field string) paging.Key {
+		switch field {
+		case "title":
+			return paging.Key{Text: p.Title, ID: p.ID}
+		case "updated_at":
+			return paging.Key{Time: p.UpdatedAt, ID: p.ID}
+		}
+		return paging.Key{Time: p.CreatedAt, ID: p.ID}
+	}), nil
+}
+
+func (m *Memory) RenameAIPlan(ctx context.Context, arg db.RenameAIPlanParams) (int64, error) {
+	t := m.lock()
+	defer m.unlock()
+
+	p, ok := t.aiPlans[arg.ID]
+	if !ok || p.UserID != arg.UserID {
+		return 0, nil
+	}
+	p.Title = arg.Title
+	p.UpdatedAt = m.now()
+	t.aiPlans[p.ID] = p
+	return 1, nil
+}
+
+func (m *Memory) DeleteAIPlan(ctx context.Context, arg db.DeleteAIPlanParams) (int64, error) {
+	t := m.lock()
+	defer m.unlock()
+
+	p, ok := t.aiPlans[arg.ID]
+	if !ok || p.UserID != arg.UserID {
+		return 0, nil
+	}
+	t.deleteAIPlan(p.ID)
+	return 1, nil
+}
+
+// deleteAIPlan
+// removes an AI plan with its versions, planner jobs that
+// saved it lose the reference ON DELETE SET NULL
+func (t *tables) deleteAIPlan(id uuid.UUID) {
+	delete(t.aiPlans, id)
+	deleteWhere(t.aiPlanVersions, func(v db.AiPlanVersion) bool { return v.PlanID == id })
+	for jobID, j := range t.plannerJobs {
+		if j.PlanID.Valid && j.PlanID.UUID == id {
+			j.PlanID = uuid.NullUUID{}
+			t.plannerJobs[jobID] = j
+		}
+	}
+}
+
+func (m *Memory) AddAIPlanVersion(ctx context.Context, arg db.AddAIPlanVersionParams) (int32, error) {
+	t := m.lock()
+	defer m.unlock()
+
+	p, ok := t.aiPlans[arg.ID]
+	if !ok || p.UserID != arg.UserID {
+		return 0, sql.ErrNoRows
+	}
+	if !slices.Contains(aiPlanSources, arg.Source) {
+		return 0, checkViolation("ai_plan_versions", "ai_plan_versions_source_check")
+	}
+
+	now := m.now()
+	p.RawData = arg.RawData
+	p.Version++
+	p.UpdatedAt = now
+	t.aiPlans[p.ID] = p
+	t.aiPlanVersions[uuid.New()] = db.AiPlanVersion{
+		PlanID:    p.ID,
+		Version:   p.Version,
+		RawData:   arg.RawData,
+		Source:    arg.Source,
+		CreatedAt: now,
+	}
+	return p.Version, nil
+}
+
+func (m *Memory) GetAIPlanVersion(ctx context.Context, arg db.GetAIPlanVersionParams) (db.AiPlanVersion, error) {
+	t := m.lock()
+	defer m.unlock()
+
+	for _, v := range t.aiPlanVersions {
+		if v.PlanID == arg.PlanID && v.Version == arg.Version {
+			return v, nil
+		}
+	}
+	return db.AiPlanVersion{}, sql.ErrNoRows
+}
+
+func (m *Memory) ListAIPlanVersions(ctx context.Context, arg db.ListAIPlanVersionsParams) ([]db.ListAIPlanVersionsRow, error) {
+	t := m.lock()
+	defer m.unlock()
+
+	versions := rows(t.aiPlanVersions, func(v db.AiPlanVersion) bool {
+		return v.PlanID == arg.PlanID
+	}, func(a, b db.AiPlanVersion) int {
+		return cmp.Compare(a.Version, b.Version)
+	})
+
+	var items []db.ListAIPlanVersionsRow
+	for _, v := range versions {
+		items = append(items, db.ListAIPlanVersionsRow{
+			Version:   v.Version,
+			Source:    v.Source,
+			CreatedAt: v.CreatedAt,
+		})
+	}
+
+	after := paging.Key{Num: strconv.Itoa(int(arg.AfterNum))}
+	return keyset(items, arg.Sort, arg.HasCursor, after, arg.PageLimit, func(v db.ListAIPlanVersionsRow, field string) paging.Key {
+		return paging.Key{Num: strconv.Itoa(int(v.Version))}
+	}), nil
+}
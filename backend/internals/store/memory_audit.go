@@ -0,0 +1,51 @@
+package store
+
+import (
+	"context"
+
+	"github.com/ErebusAJ/YatraBandhu/internals/db"
+	"github.com/ErebusAJ/YatraBandhu/internals/paging"
+	"github.com/google/uuid"
+)
+
+// audit events are never updated or deleted, like the
+// append-only triggers on the table
+
+func (m *Memory) InsertAuditEvent(ctx context.Context, arg db.InsertAuditEventParams) error {
+	t := m.lock()
+	defer m.unlock()
+
+	id := uuid.New()
+	t.auditEvents[id] = db.AuditEvent{
+		ID:         id,
+		Action:     arg.Action,
+		ActorID:    arg.ActorID,
+		SubjectID:  arg.SubjectID,
+		TargetType: arg.TargetType,
+		TargetID:   arg.TargetID,
+		Metadata:   arg.Metadata,
+		ClientIp:   arg.ClientIp,
+		RequestID:  arg.RequestID,
+		CreatedAt:  m.now(),
+	}
+	return nil
+}
+
+func (m *Memory) ListAuditEvents(ctx context.Context, arg db.ListAuditEventsParams) ([]db.AuditEvent, error) {
+	t := m.lock()
+	defer m.unlock()
+
+	events := rows(t.auditEvents, func(e db.AuditEvent) bool {
+		if arg.UserID.Valid && e.ActorID != arg.UserID && e.SubjectID != arg.UserID {
+			return false
+		}
+		return arg.Action == "" || e.Action == arg.Action
+	}, func(a, b db.AuditEvent) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+
+	after := paging.Key{Time: arg.AfterTime, ID: arg.AfterID}
+	return keyset(events, arg.Sort, arg.HasCursor, after, arg.PageLimit, func(e db.AuditEvent, field string) paging.Key {
+		return paging.Key{Time: e.CreatedAt, ID: e.ID}
+	}), nil
+}
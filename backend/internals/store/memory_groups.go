@@ -0,0 +1,438 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/ErebusAJ/YatraBandhu/internals/db"
+	"github.com/ErebusAJ/YatraBandhu/internals/paging"
+	"github.com/google/uuid"
+)
+
+func (m *Memory) AddTravelDetails(ctx context.Context, arg db.AddTravelDetailsParams) (uuid.UUID, error) {
+	t := m.lock()
+	defer m.unlock()
+
+	if _, ok := t.users[arg.CreatorID]; !ok {
+		return uuid.Nil, foreignKeyViolation("travel_plan_details", "travel_plan_details_creator_id_fkey")
+	}
+
+	now := nullTime(m.now())
+	p := db.TravelPlanDetail{
+		ID:        uuid.New(),
+		CreatorID: arg.CreatorID,
+		Place:     arg.Place,
+		StartDate: arg.StartDate,
+		EndDate:   arg.EndDate,
+		TripType:  arg.TripType,
+		Pets:      arg.Pets,
+		Interests: arg.Interests,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	t.plans[p.ID] = p
+	return p.ID, nil
+}
+
+func (m *Memory) GetUserPlansDetails(ctx context.Context, id uuid.UUID) ([]db.GetUserPlansDetailsRow, error) {
+	t := m.lock()
+	defer m.unlock()
+
+	plans := rows(t.plans, func(p db.TravelPlanDetail) bool {
+		return p.CreatorID == id
+	}, func(a, b db.TravelPlanDetail) int {
+		return a.CreatedAt.Time.Compare(b.CreatedAt.Time)
+	})
+
+	var items []db.GetUserPlansDetailsRow
+	for _, p := range plans {
+		items = append(items, db.GetUserPlansDetailsRow{
+			Place:     p.Place,
+			StartDate: p.StartDate,
+			EndDate:   p.EndDate,
+			TripType:  p.TripType,
+			Pets:      p.Pets,
+			Interests: p.Interests,
+		})
+	}
+	return items, nil
+}
+
+func (m *Memory) ListUserPlans(ctx context.Context, arg db.ListUserPlansParams) ([]db.ListUserPlansRow, error) {
+	t := m.lock()
+	defer m.unlock()
+
+	plans := rows(t.plans, func(p db.TravelPlanDetail) bool {
+		return p.CreatorID == arg.CreatorID &&
+			(arg.Place == "" || containsFold(p.Place, arg.Place)) &&
+			(arg.TripType == "" || p.TripType == arg.TripType) &&
+			(arg.FromDate == "" || p.StartDate >= arg.FromDate) &&
+			(arg.ToDate == "" || p.StartDate <= arg.ToDate)
+	}, func(a, b db.TravelPlanDetail) int {
+		return a.CreatedAt.Time.Compare(b.CreatedAt.Time)
+	})
+
+	var items []db.ListUserPlansRow
+	for _, p := range plans {
+		items = append(items, db.ListUserPlansRow{
+			ID:        p.ID,
+			Place:     p.Place,
+			StartDate: p.StartDate,
+			EndDate:   p.EndDate,
+			TripType:  p.TripType,
+			Pets:      p.Pets,
+			Interests: p.Interests,
+			CreatedAt: p.CreatedAt,
+		})
+	}
+
+	after := paging.Key{Text: arg.AfterText, Time: arg.AfterTime, ID: arg.AfterID}
+	return keyset(items, arg.Sort, arg.HasCursor, after, arg.PageLimit, func(p db.ListUserPlansRow, field string) paging.Key {
+		if field == "start_date" {
+			return paging.Key{Text: p.StartDate, ID: p.ID}
+		}
+		return paging.Key{Time: p.CreatedAt.Time, ID: p.ID}
+	}), nil
+}
+
+// deletePlan
+// removes a plan and the groups built on it
+func (t *tables) deletePlan(id uuid.UUID) {
+	delete(t.plans, id)
+	for groupID, g := range t.groups {
+		if g.PlanID == id {
+			t.deleteGroup(groupID)
+		}
+	}
+}
+
+func (m *Memory) CreateGroup(ctx context.Context, arg db.CreateGroupParams) error {
+	t := m.lock()
+	defer m.unlock()
+
+	if _, ok := t.groups[arg.ID]; ok {
+		return uniqueViolation("travel_groups_pkey")
+	}
+	if _, ok := t.users[arg.CreatorID]; !ok {
+		return foreignKeyViolation("travel_groups", "travel_groups_creator_id_fkey")
+	}
+	if _, ok := t.plans[arg.PlanID]; !ok {
+		return foreignKeyViolation("travel_groups", "travel_groups_plan_id_fkey")
+	}
+	for _, g := range t.groups {
+		if g.CreatorID == arg.CreatorID && g.PlanID == arg.PlanID {
+			return uniqueViolation("travel_groups_creator_id_plan_id_key")
+		}
+	}
+
+	now := nullTime(m.now())
+	t.groups[arg.ID] = db.TravelGroup{
+		ID:          arg.ID,
+		CreatorID:   arg.CreatorID,
+		Name:        arg.Name,
+		Description: arg.Description,
+		PlanID:      arg.PlanID,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	return nil
+}
+
+func (m *Memory) GetGroupByID(ctx context.Context, id uuid.UUID) (db.TravelGroup, error) {
+	t := m.lock()
+	defer m.unlock()
+
+	g, ok := t.groups[id]
+	if !ok {
+		return db.TravelGroup{}, sql.ErrNoRows
+	}
+	return g, nil
+}
+
+func (m *Memory) UpdateGroupByID(ctx context.Context, arg db.UpdateGroupByIDParams) error {
+	t := m.lock()
+	defer m.unlock()
+
+	g, ok := t.groups[arg.ID]
+	if !ok {
+		return nil
+	}
+	g.Name = arg.Name
+	g.Description = arg.Description
+	g.UpdatedAt = nullTime(m.now())
+	t.groups[arg.ID] = g
+	return nil
+}
+
+func (m *Memory) DeleteGroupByID(ctx context.Context, id uuid.UUID) error {
+	t := m.lock()
+	defer m.unlock()
+
+	t.deleteGroup(id)
+	return nil
+}
+
+// deleteGroup
+// removes a group with its members, join and guide requests
+func (t *tables) deleteGroup(id uuid.UUID) {
+	delete(t.groups, id)
+	deleteWhere(t.members, func(r db.TravelGroupsMember) bool { return r.GroupID == id })
+	deleteWhere(t.requests, func(r db.TravelGroupsRequest) bool { return r.GroupID == id })
+	deleteWhere(t.guideRequests, func(r db.GuideBookingRequest) bool { return r.GroupID == id })
+}
+
+func (m *Memory) AddUserToGroup(ctx context.Context, arg db.AddUserToGroupParams) error {
+	t := m.lock()
+	defer m.unlock()
+
+	if _, ok := t.groups[arg.GroupID]; !ok {
+		return foreignKeyViolation("travel_groups_members", "travel_groups_members_group_id_fkey")
+	}
+	if _, ok := t.users[arg.UserID]; !ok {
+		return foreignKeyViolation("travel_groups_members", "travel_groups_members_user_id_fkey")
+	}
+	for _, r := range t.members {
+		if r.GroupID == arg.GroupID && r.UserID == arg.UserID {
+			return uniqueViolation("travel_groups_members_group_id_user_id_key")
+		}
+	}
+
+	now := nullTime(m.now())
+	id := uuid.New()
+	t.members[id] = db.TravelGroupsMember{
+		ID:        id,
+		GroupID:   arg.GroupID,
+		UserID:    arg.UserID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	return nil
+}
+
+func (m *Memory) DeleteUserFromGroup(ctx context.Context, arg db.DeleteUserFromGroupParams) error {
+	t := m.lock()
+	defer m.unlock()
+
+	deleteWhere(t.members, func(r db.TravelGroupsMember) bool {
+		return r.UserID == arg.UserID && r.GroupID == arg.GroupID
+	})
+	return nil
+}
+
+func (m *Memory) GetGroupUsersDetails(ctx context.Context, groupID uuid.UUID) ([]db.GetGroupUsersDetailsRow, error) {
+	t := m.lock()
+	defer m.unlock()
+
+	members := rows(t.members, func(r db.TravelGroupsMember) bool {
+		return r.GroupID == groupID
+	}, func(a, b db.TravelGroupsMember) int {
+		return a.CreatedAt.Time.Compare(b.CreatedAt.Time)
+	})
+
+	var items []db.GetGroupUsersDetailsRow
+	for _, r := range members {
+		u := t.users[r.UserID]
+		items = append(items, db.GetGroupUsersDetailsRow{
+			ID:          u.ID,
+			Name:        u.Name,
+			Age:         u.Age,
+			PhoneNumber: u.PhoneNumber,
+			Email:       u.Email,
+		})
+	}
+	return items, nil
+}
+
+func (m *Memory) GetUserGroups(ctx context.Context, userID uuid.UUID) ([]db.GetUserGroupsRow, error) {
+	t := m.lock()
+	defer m.unlock()
+
+	members := rows(t.members, func(r db.TravelGroupsMember) bool {
+		return r.UserID == userID
+	}, func(a, b db.TravelGroupsMember) int {
+		return a.CreatedAt.Time.Compare(b.CreatedAt.Time)
+	})
+
+	var items []db.GetUserGroupsRow
+	for _, r := range members {
+		g := t.groups[r.GroupID]
+		items = append(items, db.GetUserGroupsRow{
+			ID:          g.ID,
+			CreatorID:   g.CreatorID,
+			Name:        g.Name,
+			Description: g.Description,
+			PlanID:      g.PlanID,
+		})
+	}
+	return items, nil
+}
+
+func (m *Memory) ListGroupMembers(ctx context.Context, arg db.ListGroupMembersParams) ([]db.ListGroupMembersRow, error) {
+	t := m.lock()
+	defer m.unlock()
+
+	members := rows(t.members, func(r db.TravelGroupsMember) bool {
+		return r.GroupID == arg.GroupID && (arg.Name == "" || containsFold(t.users[r.UserID].Name, arg.Name))
+	}, func(a, b db.TravelGroupsMember) int {
+		return a.CreatedAt.Time.Compare(b.CreatedAt.Time)
+	})
+
+	var items []db.ListGroupMembersRow
+	for _, r := range members {
+		u := t.users[r.UserID]
+		items = append(items, db.ListGroupMembersRow{
+			ID:          u.ID,
+			Name:        u.Name,
+			Age:         u.Age,
+			PhoneNumber: u.PhoneNumber,
+			Email:       u.Email,
+			JoinedAt:    r.CreatedAt,
+		})
+	}
+
+	after := paging.Key{Text: arg.AfterText, Time: arg.AfterTime, ID: arg.AfterID}
+	return keyset(items, arg.Sort, arg.HasCursor, after, arg.PageLimit, func(r db.ListGroupMembersRow, field string) paging.Key {
+		if field == "name" {
+			return paging.Key{Text: r.Name, ID: r.ID}
+		}
+		return paging.Key{Time: r.JoinedAt.Time, ID: r.ID}
+	}), nil
+}
+
+func (m *Memory) ListUserGroups(ctx context.Context, arg db.ListUserGroupsParams) ([]db.ListUserGroupsRow, error) {
+	t := m.lock()
+	defer m.unlock()
+
+	members := rows(t.members, func(r db.TravelGroupsMember) bool {
+		if r.UserID != arg.UserID {
+			return false
+		}
+		p, ok := t.plans[t.groups[r.GroupID].PlanID]
+		return ok &&
+			(arg.Place == "" || containsFold(p.Place, arg.Place)) &&
+			(arg.FromDate == "" || p.StartDate >= arg.FromDate) &&
+			(arg.ToDate == "" || p.StartDate <= arg.ToDate)
+	}, func(a, b db.TravelGroupsMember) int {
+		return a.CreatedAt.Time.Compare(b.CreatedAt.Time)
+	})
+
+	var items []db.ListUserGroupsRow
+	for _, r := range members {
+		g := t.groups[r.GroupID]
+		p := t.plans[g.PlanID]
+		items = append(items, db.ListUserGroupsRow{
+			ID:          g.ID,
+			CreatorID:   g.CreatorID,
+			Name:        g.Name,
+			Description: g.Description,
+			PlanID:      g.PlanID,
+			Place:       p.Place,
+			StartDate:   p.StartDate,
+			CreatedAt:   g.CreatedAt,
+		})
+	}
+
+	after := paging.Key{Text: arg.AfterText, Time: arg.AfterTime, ID: arg.AfterID}
+	return keyset(items, arg.Sort, arg.HasCursor, after, arg.PageLimit, func(g db.ListUserGroupsRow, field string) paging.Key {
+		switch field {
+		case "name":
+			return paging.Key{Text: g.Name, ID: g.ID}
+		case "start_date":
+			return paging.Key{Text: g.StartDate, ID: g.ID}
+		}
+		return paging.Key{Time: g.CreatedAt.Time, ID: g.ID}
+	}), nil
+}
+
+func (m *Memory) SendRequest(ctx context.Context, arg db.SendRequestParams) error {
+	t := m.lock()
+	defer m.unlock()
+
+	if _, ok := t.groups[arg.GroupID]; !ok {
+		return foreignKeyViolation("travel_groups_requests", "travel_groups_requests_group_id_fkey")
+	}
+	if _, ok := t.users[arg.UserID]; !ok {
+		return foreignKeyViolation("travel_groups_requests", "travel_groups_requests_user_id_fkey")
+	}
+	for _, r := range t.requests {
+		if r.GroupID == arg.GroupID && r.UserID == arg.UserID && r.Status == "pending" {
+			return uniqueViolation("travel_groups_requests_pending_idx")
+		}
+	}
+
+	now := nullTime(m.now())
+	id := uuid.New()
+	t.requests[id] = db.TravelGroupsRequest{
+		ID:        id,
+		GroupID:   arg.GroupID,
+		UserID:    arg.UserID,
+		Status:    "pending",
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	return nil
+}
+
+func (m *Memory) UpdateRequest(ctx context.Context, arg db.UpdateRequestParams) (int64, error) {
+	t := m.lock()
+	defer m.unlock()
+
+	switch arg.Status {
+	case "pending", "accepted", "rejected":
+	default:
+		return 0, checkViolation("travel_groups_requests", "travel_groups_requests_status_check")
+	}
+	var n int64
+	for id, r := range t.requests {
+		if r.GroupID == arg.GroupID && r.UserID == arg.UserID && r.Status == "pending" {
+			r.Status = arg.Status
+			r.UpdatedAt = nullTime(m.now())
+			t.requests[id] = r
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *Memory) RejectRequest(ctx context.Context, arg db.RejectRequestParams) error {
+	t := m.lock()
+	defer m.unlock()
+
+	deleteWhere(t.requests, func(r db.TravelGroupsRequest) bool {
+		return r.GroupID == arg.GroupID && r.UserID == arg.UserID
+	})
+	return nil
+}
+
+func (m *Memory) ListGroupRequests(ctx context.Context, arg db.ListGroupRequestsParams) ([]db.ListGroupRequestsRow, error) {
+	t := m.lock()
+	defer m.unlock()
+
+	requests := rows(t.requests, func(r db.TravelGroupsRequest) bool {
+		return r.Status == "pending" && t.groups[r.GroupID].CreatorID == arg.CreatorID &&
+			(!arg.GroupID.Valid || r.GroupID == arg.GroupID.UUID)
+	}, func(a, b db.TravelGroupsRequest) int {
+		return b.CreatedAt.Time.Compare(a.CreatedAt.Time)
+	})
+
+	var items []db.ListGroupRequestsRow
+	for _, r := range requests {
+		items = append(items, db.ListGroupRequestsRow{
+			RequestID:  r.ID,
+			GroupID:    r.GroupID,
+			Name:       t.groups[r.GroupID].Name,
+			SenderID:   r.UserID,
+			SenderName: t.users[r.UserID].Name,
+			Status:     r.Status,
+			CreatedAt:  r.CreatedAt,
+		})
+	}
+
+	after := paging.Key{Text: arg.AfterText, Time: arg.AfterTime, ID: arg.AfterID}
+	return keyset(items, arg.Sort, arg.HasCursor, after, arg.PageLimit, func(r db.ListGroupRequestsRow, field string) paging.Key {
+		if field == "sender_name" {
+			return paging.Key{Text: r.SenderName, ID: r.RequestID}
+		}
+		return paging.Key{Time: r.CreatedAt.Time, ID: r.RequestID}
+	}), nil
+}
@@ -0,0 +1,292 @@
+package store
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+
+	"github.com/ErebusAJ/YatraBandhu/internals/db"
+	"github.com/ErebusAJ/YatraBandhu/internals/paging"
+	"github.com/google/uuid"
+)
+
+func (m *Memory) AddGuide(ctx context.Context, arg db.AddGuideParams) (uuid.UUID, error) {
+	t := m.lock()
+	defer m.unlock()
+
+	g := db.Guide{
+		ID:         uuid.New(),
+		Name:       arg.Name,
+		Bio:        arg.Bio,
+		Location:   arg.Location,
+		Expertise:  arg.Expertise,
+		Rating:     arg.Rating,
+		HourlyRate: arg.HourlyRate,
+		Available:  true,
+	}
+	t.guides[g.ID] = g
+	return g.ID, nil
+}
+
+func (m *Memory) GetGuideByID(ctx context.Context, id uuid.UUID) (db.Guide, error) {
+	t := m.lock()
+	defer m.unlock()
+
+	g, ok := t.guides[id]
+	if !ok {
+		return db.Guide{}, sql.ErrNoRows
+	}
+	return g, nil
+}
+
+func (m *Memory) ListGuides(ctx context.Context, arg db.ListGuidesParams) ([]db.Guide, error) {
+	t := m.lock()
+	defer m.unlock()
+
+	guides := rows(t.guides, func(g db.Guide) bool {
+		if !g.Available || g.Rating < arg.MinRating {
+			return false
+		}
+		if arg.Location != "" && !strings.EqualFold(g.Location, arg.Location) {
+			return false
+		}
+		if arg.Expertise != "" && !containsFold(g.Expertise, arg.Expertise) {
+			return false
+		}
+		if arg.MaxRate.Valid {
+			rate, _ := strconv.ParseFloat(g.HourlyRate, 64)
+			max, _ := strconv.ParseFloat(arg.MaxRate.String, 64)
+			return rate <= max
+		}
+		return true
+	}, func(a, b db.Guide) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+
+	after := paging.Key{Text: arg.AfterText, Num: arg.AfterNum, ID: arg.AfterID}
+	return keyset(guides, arg.Sort, arg.HasCursor, after, arg.PageLimit, func(g db.Guide, field string) paging.Key {
+		switch field {
+		case "name":
+			return paging.Key{Text: g.Name, ID: g.ID}
+		case "rating":
+			return paging.Key{Num: strconv.Itoa(int(g.Rating)), ID: g.ID}
+		}
+		return paging.Key{Num: g.HourlyRate, ID: g.ID}
+	}), nil
+}
+
+func (m *Memory) UpdateGuideAvail(ctx context.Context, arg db.UpdateGuideAvailParams) (int64, error) {
+	t := m.lock()
+	defer m.unlock()
+
+	g, ok := t.guides[arg.ID]
+	if !ok {
+		return 0, nil
+	}
+	g.Available = arg.Available
+	t.guides[arg.ID] = g
+	return 1, nil
+}
+
+func (m *Memory) SetGuideVerified(ctx context.Context, arg db.SetGuideVerifiedParams) (int64, error) {
+	t := m.lock()
+	defer m.unlock()
+
+	g, ok := t.guides[arg.ID]
+	if !ok {
+		return 0, nil
+	}
+	g.Verified = arg.Verified
+	t.guides[arg.ID] = g
+	return 1, nil
+}
+
+func (m *Memory) SendGuideRequest(ctx context.Context, arg db.SendGuideRequestParams) error {
+	t := m.lock()
+	defer m.unlock()
+
+	if _, ok := t.groups[arg.GroupID]; !ok {
+		return foreignKeyViolation("guide_booking_requests", "guide_booking_requests_group_id_fkey")
+	}
+	if _, ok := t.users[arg.UserID]; !ok {
+		return foreignKeyViolation("guide_booking_requests", "guide_booking_requests_user_id_fkey")
+	}
+	if _, ok := t.guides[arg.GuideID]; !ok {
+		return foreignKeyViolation("guide_booking_requests", "guide_booking_requests_guide_id_fkey")
+	}
+
+	now := nullTime(m.now())
+	id := uuid.New()
+	t.guideRequests[id] = db.GuideBookingRequest{
+		ID:        id,
+		GroupID:   arg.GroupID,
+		UserID:    arg.UserID,
+		GuideID:   arg.GuideID,
+		Status:    "pending",
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	return nil
+}
+
+func (m *Memory) CreateReport(ctx context.Context, arg db.CreateReportParams) error {
+	t := m.lock()
+	defer m.unlock()
+
+	if _, ok := t.users[arg.ReporterID]; !ok {
+		return foreignKeyViolation("reports", "reports_reporter_id_fkey")
+	}
+	switch arg.TargetType {
+	case "user", "group", "guide":
+	default:
+		return checkViolation("reports", "reports_target_type_check")
+	}
+
+	now := m.now()
+	id := uuid.New()
+	t.reports[id] = db.Report{
+		ID:         id,
+		ReporterID: arg.ReporterID,
+		TargetType: arg.TargetType,
+		TargetID:   arg.TargetID,
+		Reason:     arg.Reason,
+		Status:     "pending",
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	return nil
+}
+
+func (m *Memory) GetReportsByStatus(ctx context.Context, arg db.GetReportsByStatusParams) ([]db.Report, error) {
+	t := m.lock()
+	defer m.unlock()
+
+	reports := rows(t.reports, func(r db.Report) bool {
+		return r.Status == arg.Status
+	}, func(a, b db.Report) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+
+	after := paging.Key{Time: arg.AfterTime, ID: arg.AfterID}
+	return keyset(reports, arg.Sort, arg.HasCursor, after, arg.PageLimit, func(r db.Report, field string) paging.Key {
+		return paging.Key{Time: r.CreatedAt, ID: r.ID}
+	}), nil
+}
+
+func (m *Memory) UpdateReportStatus(ctx context.Context, arg db.UpdateReportStatusParams) (int64, error) {
+	t := m.lock()
+	defer m.unlock()
+
+	switch arg.Status {
+	case "pending", "resolved", "dismissed":
+	default:
+		return 0, checkViolation("reports", "reports_status_check")
+	}
+	r, ok := t.reports[arg.ID]
+	if !ok {
+		return 0, nil
+	}
+	r.Status = arg.Status
+	r.UpdatedAt = m.now()
+	t.reports[arg.ID] = r
+	return 1, nil
+}
+
+func (m *Memory) EnqueueEmail(ctx context.Context, arg db.EnqueueEmailParams) error {
+	t := m.lock()
+	defer m.unlock()
+
+	now := m.now()
+	id := uuid.New()
+	t.outbox[id] = db.EmailOutbox{
+		ID:            id,
+		Recipient:     arg.Recipient,
+		Subject:       arg.Subject,
+		TextBody:      arg.TextBody,
+		HtmlBody:      arg.HtmlBody,
+		Template:      arg.Template,
+		Status:        "pending",
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	}
+	return nil
+}
+
+func (m *Memory) ListOutboxByStatus(ctx context.Context, arg db.ListOutboxByStatusParams) ([]db.ListOutboxByStatusRow, error) {
+	t := m.lock()
+	defer m.unlock()
+
+	emails := rows(t.outbox, func(e db.EmailOutbox) bool {
+		return e.Status == arg.Status
+	}, func(a, b db.EmailOutbox) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+
+	var items []db.ListOutboxByStatusRow
+	for _, e := range emails {
+		items = append(items, db.ListOutboxByStatusRow{
+			ID:            e.ID,
+			Recipient:     e.Recipient,
+			Subject:       e.Subject,
+			Status:        e.Status,
+			Attempts:      e.Attempts,
+			LastError:     e.LastError,
+			NextAttemptAt: e.NextAttemptAt,
+			CreatedAt:     e.CreatedAt,
+			SentAt:        e.SentAt,
+			Template:      e.Template,
+		})
+	}
+
+	after := paging.Key{Time: arg.AfterTime, ID: arg.AfterID}
+	return keyset(items, arg.Sort, arg.HasCursor, after, arg.PageLimit, func(e db.ListOutboxByStatusRow, field string) paging.Key {
+		return paging.Key{Time: e.CreatedAt, ID: e.ID}
+	}), nil
+}
+
+func (m *Memory) RequeueEmail(ctx context.Context, id uuid.UUID) (int64, error) {
+	t := m.lock()
+	defer m.unlock()
+
+	e, ok := t.outbox[id]
+	if !ok || e.Status != "dead" {
+		return 0, nil
+	}
+	e.Status = "pending"
+	e.Attempts = 0
+	e.LastError = sql.NullString{}
+	e.NextAttemptAt = m.now()
+	t.outbox[id] = e
+	return 1, nil
+}
+
+// PendingEmails
+// the pending outbox rows with their bodies, newest first, for
+// tests reading the links of queued mail
+func (m *Memory) PendingEmails() []db.EmailOutbox {
+	t := m.lock()
+	defer m.unlock()
+
+	return rows(t.outbox, func(e db.EmailOutbox) bool {
+		return e.Status == "pending"
+	}, func(a, b db.EmailOutbox) int {
+		return b.CreatedAt.Compare(a.CreatedAt)
+	})
+}
+
+// KillEmail
+// marks a queued email dead as the outbox worker does once it
+// runs out of attempts, for tests of requeueing
+func (m *Memory) KillEmail(id uuid.UUID, lastError string) {
+	t := m.lock()
+	defer m.unlock()
+
+	e, ok := t.outbox[id]
+	if ok {
+		e.Status = "dead"
+		e.LastError = sql.NullString{String: lastError, Valid: true}
+		t.outbox[id] = e
+	}
+}
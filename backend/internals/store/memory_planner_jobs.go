@@ -0,0 +1,181 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/ErebusAJ/YatraBandhu/internals/db"
+	"github.com/google/uuid"
+)
+
+func (m *Memory) CreatePlannerJob(ctx context.Context, arg db.CreatePlannerJobParams) (db.PlannerJob, error) {
+	t := m.lock()
+	defer m.unlock()
+
+	if _, ok := t.users[arg.UserID]; !ok {
+		return db.PlannerJob{}, foreignKeyViolation("planner_jobs", "planner_jobs_user_id_fkey")
+	}
+	if _, ok := t.aiPlans[arg.PlanID.UUID]; arg.PlanID.Valid && !ok {
+		return db.PlannerJob{}, foreignKeyViolation("planner_jobs", "planner_jobs_plan_id_fkey")
+	}
+	if arg.Days <= 0 {
+		return db.PlannerJob{}, checkViolation("planner_jobs", "planner_jobs_days_check")
+	}
+
+	// a DATE column reads back as midnight UTC
+	now := m.now()
+	y, mo, d := arg.StartDate.Date()
+	job := db.PlannerJob{
+		ID:            uuid.New(),
+		UserID:        arg.UserID,
+		Location:      arg.Location,
+		Interests:     arg.Interests,
+		Days:          arg.Days,
+		StartDate:     time.Date(y, mo, d, 0, 0, 0, 0, time.UTC),
+		Status:        "queued",
+		Stage:         "queued",
+		PlanID:        arg.PlanID,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	t.plannerJobs[job.ID] = job
+	return job, nil
+}
+
+func (m *Memory) GetPlannerJob(ctx context.Context, arg db.GetPlannerJobParams) (db.PlannerJob, error) {
+	t := m.lock()
+	defer m.unlock()
+
+	job, ok := t.plannerJobs[arg.ID]
+	if !ok || job.UserID != arg.UserID {
+		return db.PlannerJob{}, sql.ErrNoRows
+	}
+	return job, nil
+}
+
+func (m *Memory) CancelPlannerJob(ctx context.Context, arg db.CancelPlannerJobParams) (int64, error) {
+	t := m.lock()
+	defer m.unlock()
+
+	job, ok := t.plannerJobs[arg.ID]
+	if !ok || job.UserID != arg.UserID || !pendingJob(job) {
+		return 0, nil
+	}
+	now := m.now()
+	job.Status = "cancelled"
+	job.UpdatedAt = now
+	job.FinishedAt = nullTime(now)
+	t.plannerJobs[arg.ID] = job
+	return 1, nil
+}
+
+func (m *Memory) ClaimPlannerJobs(ctx context.Context, arg db.ClaimPlannerJobsParams) ([]db.PlannerJob, error) {
+	t := m.lock()
+	defer m.unlock()
+
+	now := m.now()
+	jobs := rows(t.plannerJobs, func(j db.PlannerJob) bool {
+		return pendingJob(j) && !j.NextAttemptAt.After(now)
+	}, func(a, b db.PlannerJob) int {
+		return a.NextAttemptAt.Compare(b.NextAttemptAt)
+	})
+	jobs = page(jobs, arg.Limit, 0)
+
+	for i := range jobs {
+		jobs[i].Status = "running"
+		jobs[i].NextAttemptAt = arg.NextAttemptAt
+		jobs[i].UpdatedAt = now
+		t.plannerJobs[jobs[i].ID] = jobs[i]
+	}
+	return jobs, nil
+}
+
+func (m *Memory) ExtendPlannerJobLease(ctx context.Context, arg db.ExtendPlannerJobLeaseParams) (int64, error) {
+	return m.updateRunningJob(arg.ID, func(j *db.PlannerJob) {
+		j.NextAttemptAt = arg.NextAttemptAt
+	})
+}
+
+func (m *Memory) AdvancePlannerJob(ctx context.Context, arg db.AdvancePlannerJobParams) (int64, error) {
+	return m.updateRunningJob(arg.ID, func(j *db.PlannerJob) {
+		j.Stage = arg.Stage
+		j.TravelData = arg.TravelData
+		j.Draft = arg.Draft
+		j.UpdatedAt = m.now()
+	})
+}
+
+func (m *Memory) FinishPlannerJob(ctx context.Context, arg db.FinishPlannerJobParams) (int64, error) {
+	t := m.lock()
+	if arg.PlanID.Valid {
+		if _, ok := t.aiPlans[arg.PlanID.UUID]; !ok {
+			m.unlock()
+			return 0, foreignKeyViolation("planner_jobs", "planner_jobs_plan_id_fkey")
+		}
+	}
+	m.unlock()
+
+	return m.updateRunningJob(arg.ID, func(j *db.PlannerJob) {
+		now := m.now()
+		j.Status = "succeeded"
+		j.Stage = "saved"
+		j.PlanID = arg.PlanID
+		j.LastError = sql.NullString{}
+		j.UpdatedAt = now
+		j.FinishedAt = nullTime(now)
+	})
+}
+
+func (m *Memory) RetryPlannerJob(ctx context.Context, arg db.RetryPlannerJobParams) (int64, error) {
+	return m.updateRunningJob(arg.ID, func(j *db.PlannerJob) {
+		j.Status = "queued"
+		j.Attempts++
+		j.LastError = arg.LastError
+		j.NextAttemptAt = arg.NextAttemptAt
+		j.UpdatedAt = m.now()
+	})
+}
+
+func (m *Memory) FailPlannerJob(ctx context.Context, arg db.FailPlannerJobParams) (int64, error) {
+	return m.updateRunningJob(arg.ID, func(j *db.PlannerJob) {
+		now := m.now()
+		j.Status = "failed"
+		j.Attempts++
+		j.LastError = arg.LastError
+		j.UpdatedAt = now
+		j.FinishedAt = nullTime(now)
+	})
+}
+
+func (m *Memory) ReleasePlannerJob(ctx context.Context, id uuid.UUID) error {
+	_, err := m.updateRunningJob(id, func(j *db.PlannerJob) {
+		now := m.now()
+		j.Status = "queued"
+		j.NextAttemptAt = now
+		j.UpdatedAt = now
+	})
+	return err
+}
+
+// updateRunningJob
+// applies update to a running job, WHERE status='running'
+func (m *Memory) updateRunningJob(id uuid.UUID, update func(j *db.PlannerJob)) (int64, error) {
+	t := m.lock()
+	defer m.unlock()
+
+	job, ok := t.plannerJobs[id]
+	if !ok || job.Status != "running" {
+		return 0, nil
+	}
+	update(&job)
+	t.plannerJobs[id] = job
+	return 1, nil
+}
+
+// pendingJob
+// status IN ('queued', 'running')
+func pendingJob(j db.PlannerJob) bool {
+	return j.Status == "queued" || j.Status == "running"
+}
@@ -0,0 +1,176 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ErebusAJ/YatraBandhu/internals/db"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+var ctx = context.Background()
+
+func register(t *testing.T, m *Memory, email, phone string) uuid.UUID {
+	t.Helper()
+	id, err := m.RegisterUser(ctx, db.RegisterUserParams{
+		Name:         "test",
+		Age:          25,
+		PhoneNumber:  phone,
+		Email:        email,
+		PasswordHash: "hash",
+	})
+	if err != nil {
+		t.Fatalf("register %s: %v", email, err)
+	}
+	return id
+}
+
+func newGroup(t *testing.T, m Store, creator uuid.UUID) uuid.UUID {
+	t.Helper()
+	planID, err := m.AddTravelDetails(ctx, db.AddTravelDetailsParams{
+		CreatorID: creator, Place: "Goa", StartDate: "2025-01-01", EndDate: "2025-01-03",
+	})
+	if err != nil {
+		t.Fatalf("add plan: %v", err)
+	}
+	id := uuid.New()
+	err = m.CreateGroup(ctx, db.CreateGroupParams{ID: id, CreatorID: creator, Name: "g", PlanID: planID})
+	if err != nil {
+		t.Fatalf("create group: %v", err)
+	}
+	return id
+}
+
+func wantConstraint(t *testing.T, err error, code pq.ErrorCode, constraint string) {
+	t.Helper()
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) || pqErr.Code != code || pqErr.Constraint != constraint {
+		t.Fatalf("got %v, want %s on %s", err, code, constraint)
+	}
+}
+
+func TestMemoryUniqueUsers(t *testing.T) {
+	m := NewMemory()
+	register(t, m, "a@x.test", "1111111111")
+
+	_, err := m.RegisterUser(ctx, db.RegisterUserParams{Name: "b", Age: 25, PhoneNumber: "2222222222", Email: "a@x.test"})
+	wantConstraint(t, err, "23505", "users_email_key")
+
+	_, err = m.RegisterUser(ctx, db.RegisterUserParams{Name: "b", Age: 25, PhoneNumber: "1111111111", Email: "b@x.test"})
+	wantConstraint(t, err, "23505", "users_phone_number_key")
+
+	_, err = m.RegisterUser(ctx, db.RegisterUserParams{Name: "c", Age: 12, PhoneNumber: "3333333333", Email: "c@x.test"})
+	wantConstraint(t, err, "23514", "users_age_check")
+}
+
+func TestMemoryForeignKeys(t *testing.T) {
+	m := NewMemory()
+	user := register(t, m, "a@x.test", "1111111111")
+	groupID := newGroup(t, m, user)
+
+	err := m.AddUserToGroup(ctx, db.AddUserToGroupParams{GroupID: groupID, UserID: uuid.New()})
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) || pqErr.Code != "23503" {
+		t.Fatalf("got %v, want a foreign key violation", err)
+	}
+
+	if err := m.AddUserToGroup(ctx, db.AddUserToGroupParams{GroupID: groupID, UserID: user}); err != nil {
+		t.Fatal(err)
+	}
+	err = m.AddUserToGroup(ctx, db.AddUserToGroupParams{GroupID: groupID, UserID: user})
+	wantConstraint(t, err, "23505", "travel_groups_members_group_id_user_id_key")
+}
+
+func TestMemoryDeleteUserCascades(t *testing.T) {
+	m := NewMemory()
+	owner := register(t, m, "a@x.test", "1111111111")
+	member := register(t, m, "b@x.test", "2222222222")
+	groupID := newGroup(t, m, owner)
+	if err := m.AddUserToGroup(ctx, db.AddUserToGroupParams{GroupID: groupID, UserID: member}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.DeleteUser(ctx, owner); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.GetGroupByID(ctx, groupID); err == nil {
+		t.Fatalf("group of deleted creator remains")
+	}
+	if groups, _ := m.GetUserGroups(ctx, member); len(groups) != 0 {
+		t.Fatalf("membership of deleted group remains %+v", groups)
+	}
+}
+
+func TestMemoryAIPlanVersions(t *testing.T) {
+	m := NewMemory()
+	user := register(t, m, "a@x.test", "1111111111")
+	planID, err := m.SavePlan(ctx, db.SavePlanParams{UserID: user, Title: "Goa", RawData: []byte(`{"v":1}`)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	job, err := m.CreatePlannerJob(ctx, db.CreatePlannerJobParams{UserID: user, Location: "Goa", Days: 2, PlanID: uuid.NullUUID{UUID: planID, Valid: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = m.AddAIPlanVersion(ctx, db.AddAIPlanVersionParams{RawData: []byte(`{"v":2}`), ID: planID, UserID: user, Source: "typed"})
+	wantConstraint(t, err, "23514", "ai_plan_versions_source_check")
+	if _, err := m.AddAIPlanVersion(ctx, db.AddAIPlanVersionParams{RawData: []byte(`{"v":2}`), ID: planID, UserID: uuid.New(), Source: "edited"}); err == nil {
+		t.Fatal("versioned another user's plan")
+	}
+	version, err := m.AddAIPlanVersion(ctx, db.AddAIPlanVersionParams{RawData: []byte(`{"v":2}`), ID: planID, UserID: user, Source: "edited"})
+	plan, _ := m.GetAIPlan(ctx, db.GetAIPlanParams{ID: planID, UserID: user})
+	if err != nil || version != 2 || plan.Version != 2 || string(plan.RawData) != `{"v":2}` {
+		t.Fatalf("unexpected version %d of plan %+v, %v", version, plan, err)
+	}
+
+	// versions go with the plan, jobs keep a null plan_id
+	if n, err := m.DeleteAIPlan(ctx, db.DeleteAIPlanParams{ID: planID, UserID: user}); err != nil || n != 1 {
+		t.Fatalf("delete: %d, %v", n, err)
+	}
+	if _, err := m.GetAIPlanVersion(ctx, db.GetAIPlanVersionParams{PlanID: planID, Version: 1}); err == nil {
+		t.Fatal("version of deleted plan remains")
+	}
+	if job, _ = m.GetPlannerJob(ctx, db.GetPlannerJobParams{ID: job.ID, UserID: user}); job.PlanID.Valid {
+		t.Fatalf("job still references deleted plan %+v", job)
+	}
+	_, err = m.CreatePlannerJob(ctx, db.CreatePlannerJobParams{UserID: user, Location: "Goa", Days: 2, PlanID: uuid.NullUUID{UUID: planID, Valid: true}})
+	wantConstraint(t, err, "23503", "planner_jobs_plan_id_fkey")
+}
+
+func TestMemoryInTxRollback(t *testing.T) {
+	m := NewMemory()
+	user := register(t, m, "a@x.test", "1111111111")
+	boom := errors.New("boom")
+
+	var groupID uuid.UUID
+	err := m.InTx(ctx, func(q Store) error {
+		groupID = newGroup(t, q, user)
+		return boom
+	})
+	if !errors.Is(err, boom) {
+		t.Fatalf("got %v, want %v", err, boom)
+	}
+	if _, err := m.GetGroupByID(ctx, groupID); err == nil {
+		t.Fatalf("rolled back group is visible")
+	}
+	if plans, _ := m.GetUserPlansDetails(ctx, user); len(plans) != 0 {
+		t.Fatalf("rolled back plan is visible %+v", plans)
+	}
+
+	err = m.InTx(ctx, func(q Store) error {
+		groupID = newGroup(t, q, user)
+		// nested calls join the outer transaction
+		return q.InTx(ctx, func(q Store) error {
+			return q.AddUserToGroup(ctx, db.AddUserToGroupParams{GroupID: groupID, UserID: user})
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if members, _ := m.GetGroupUsersDetails(ctx, groupID); len(members) != 1 {
+		t.Fatalf("committed membership missing %+v", members)
+	}
+}
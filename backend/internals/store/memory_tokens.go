@@ -0,0 +1,181 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/ErebusAJ/YatraBandhu/internals/db"
+	"github.com/google/uuid"
+)
+
+func (m *Memory) InsertToken(ctx context.Context, arg db.InsertTokenParams) error {
+	t := m.lock()
+	defer m.unlock()
+
+	if _, ok := t.users[arg.UserID]; !ok {
+		return foreignKeyViolation("password_tokens", "password_tokens_user_id_fkey")
+	}
+	for _, tok := range t.passwordTokens {
+		if tok.TokenHash == arg.TokenHash {
+			return uniqueViolation("password_tokens_token_hash_idx")
+		}
+	}
+
+	now := nullTime(m.now())
+	id := uuid.New()
+	t.passwordTokens[id] = db.PasswordToken{
+		ID:        id,
+		UserID:    arg.UserID,
+		TokenHash: arg.TokenHash,
+		CreatedAt: now,
+		ExpiresAt: arg.ExpiresAt,
+		UpdatedAt: now,
+	}
+	return nil
+}
+
+func (m *Memory) ConsumeUserToken(ctx context.Context, tokenHash string) (uuid.UUID, error) {
+	t := m.lock()
+	defer m.unlock()
+
+	for id, tok := range t.passwordTokens {
+		if tok.TokenHash == tokenHash && tok.ExpiresAt.After(m.now()) {
+			delete(t.passwordTokens, id)
+			return tok.UserID, nil
+		}
+	}
+	return uuid.Nil, sql.ErrNoRows
+}
+
+func (m *Memory) DeleteUserTokens(ctx context.Context, userID uuid.UUID) error {
+	t := m.lock()
+	defer m.unlock()
+
+	deleteWhere(t.passwordTokens, func(r db.PasswordToken) bool { return r.UserID == userID })
+	return nil
+}
+
+func (m *Memory) InsertRefreshToken(ctx context.Context, arg db.InsertRefreshTokenParams) error {
+	t := m.lock()
+	defer m.unlock()
+
+	if _, ok := t.users[arg.UserID]; !ok {
+		return foreignKeyViolation("refresh_tokens", "refresh_tokens_user_id_fkey")
+	}
+	for _, tok := range t.refreshTokens {
+		if tok.TokenHash == arg.TokenHash {
+			return uniqueViolation("refresh_tokens_token_hash_key")
+		}
+	}
+
+	id := uuid.New()
+	t.refreshTokens[id] = db.RefreshToken{
+		ID:        id,
+		UserID:    arg.UserID,
+		TokenHash: arg.TokenHash,
+		ExpiresAt: arg.ExpiresAt,
+		CreatedAt: m.now(),
+	}
+	return nil
+}
+
+func (m *Memory) GetRefreshToken(ctx context.Context, tokenHash string) (db.RefreshToken, error) {
+	t := m.lock()
+	defer m.unlock()
+
+	for _, tok := range t.refreshTokens {
+		if tok.TokenHash == tokenHash {
+			return tok, nil
+		}
+	}
+	return db.RefreshToken{}, sql.ErrNoRows
+}
+
+func (m *Memory) RevokeRefreshToken(ctx context.Context, id uuid.UUID) (int64, error) {
+	t := m.lock()
+	defer m.unlock()
+
+	tok, ok := t.refreshTokens[id]
+	if !ok || tok.RevokedAt.Valid {
+		return 0, nil
+	}
+	tok.RevokedAt = nullTime(m.now())
+	t.refreshTokens[id] = tok
+	return 1, nil
+}
+
+func (m *Memory) RevokeUserRefreshTokens(ctx context.Context, userID uuid.UUID) error {
+	t := m.lock()
+	defer m.unlock()
+
+	now := nullTime(m.now())
+	for id, tok := range t.refreshTokens {
+		if tok.UserID == userID && !tok.RevokedAt.Valid {
+			tok.RevokedAt = now
+			t.refreshTokens[id] = tok
+		}
+	}
+	return nil
+}
+
+func (m *Memory) InsertVerificationToken(ctx context.Context, arg db.InsertVerificationTokenParams) error {
+	t := m.lock()
+	defer m.unlock()
+
+	if _, ok := t.users[arg.UserID]; !ok {
+		return foreignKeyViolation("verification_tokens", "verification_tokens_user_id_fkey")
+	}
+
+	id := uuid.New()
+	t.verificationTokens[id] = db.VerificationToken{
+		ID:        id,
+		UserID:    arg.UserID,
+		Token:     arg.Token,
+		CreatedAt: m.now(),
+		ExpiresAt: arg.ExpiresAt,
+	}
+	return nil
+}
+
+func (m *Memory) GetVerificationToken(ctx context.Context, token string) (db.VerificationToken, error) {
+	t := m.lock()
+	defer m.unlock()
+
+	for _, tok := range t.verificationTokens {
+		if tok.Token == token {
+			return tok, nil
+		}
+	}
+	return db.VerificationToken{}, sql.ErrNoRows
+}
+
+func (m *Memory) DeleteUserVerificationTokens(ctx context.Context, userID uuid.UUID) error {
+	t := m.lock()
+	defer m.unlock()
+
+	deleteWhere(t.verificationTokens, func(r db.VerificationToken) bool { return r.UserID == userID })
+	return nil
+}
+
+// ExpireTokens
+// moves every password reset and verification token of userID into
+// the past, for tests of the expiry paths
+func (m *Memory) ExpireTokens(userID uuid.UUID) {
+	t := m.lock()
+	defer m.unlock()
+
+	past := m.now().Add(-time.Hour)
+	for id, tok := range t.passwordTokens {
+		if tok.UserID == userID {
+			tok.ExpiresAt = past
+			t.passwordTokens[id] = tok
+		}
+	}
+	for id, tok := range t.verificationTokens {
+		if tok.UserID == userID {
+			tok.ExpiresAt = past
+			t.verificationTokens[id] = tok
+		}
+	}
+}
@@ -0,0 +1,272 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/ErebusAJ/YatraBandhu/internals/db"
+	"github.com/ErebusAJ/YatraBandhu/internals/paging"
+	"github.com/google/uuid"
+)
+
+func (m *Memory) RegisterUser(ctx context.Context, arg db.RegisterUserParams) (uuid.UUID, error) {
+	t := m.lock()
+	defer m.unlock()
+
+	if arg.Age < 18 || arg.Age >= 100 {
+		return uuid.Nil, checkViolation("users", "users_age_check")
+	}
+	for _, u := range t.users {
+		if u.Email == arg.Email {
+			return uuid.Nil, uniqueViolation("users_email_key")
+		}
+		if u.PhoneNumber == arg.PhoneNumber {
+			return uuid.Nil, uniqueViolation("users_phone_number_key")
+		}
+	}
+
+	now := nullTime(m.now())
+	u := db.User{
+		ID:             uuid.New(),
+		Name:           arg.Name,
+		Age:            arg.Age,
+		PhoneNumber:    arg.PhoneNumber,
+		Email:          arg.Email,
+		PasswordHash:   arg.PasswordHash,
+		TokenVersion:   sql.NullInt32{Int32: 0, Valid: true},
+		AccessLevel:    sql.NullString{String: "user", Valid: true},
+		VerifiedStatus: sql.NullBool{Bool: false, Valid: true},
+		CreatedAt:      now,
+		UpdatedAt:      now,
+		LastLoggedIn:   now,
+	}
+	t.users[u.ID] = u
+	return u.ID, nil
+}
+
+func (m *Memory) GetUserByID(ctx context.Context, id uuid.UUID) (db.User, error) {
+	t := m.lock()
+	defer m.unlock()
+
+	u, ok := t.users[id]
+	if !ok {
+		return db.User{}, sql.ErrNoRows
+	}
+	return u, nil
+}
+
+func (m *Memory) GetUserByEmail(ctx context.Context, email string) (db.GetUserByEmailRow, error) {
+	t := m.lock()
+	defer m.unlock()
+
+	for _, u := range t.users {
+		if u.Email == email {
+			return db.GetUserByEmailRow{
+				ID:             u.ID,
+				Email:          u.Email,
+				PasswordHash:   u.PasswordHash,
+				AccessLevel:    u.AccessLevel,
+				TokenVersion:   u.TokenVersion,
+				VerifiedStatus: u.VerifiedStatus,
+				SuspendedAt:    u.SuspendedAt,
+			}, nil
+		}
+	}
+	return db.GetUserByEmailRow{}, sql.ErrNoRows
+}
+
+func (m *Memory) GetUserAuthState(ctx context.Context, id uuid.UUID) (db.GetUserAuthStateRow, error) {
+	t := m.lock()
+	defer m.unlock()
+
+	u, ok := t.users[id]
+	if !ok {
+		return db.GetUserAuthStateRow{}, sql.ErrNoRows
+	}
+	return db.GetUserAuthStateRow{TokenVersion: u.TokenVersion, SuspendedAt: u.SuspendedAt}, nil
+}
+
+func (m *Memory) GetUserVerifiedStatus(ctx context.Context, id uuid.UUID) (sql.NullBool, error) {
+	t := m.lock()
+	defer m.unlock()
+
+	u, ok := t.users[id]
+	if !ok {
+		return sql.NullBool{}, sql.ErrNoRows
+	}
+	return u.VerifiedStatus, nil
+}
+
+func (m *Memory) SearchUsers(ctx context.Context, arg db.SearchUsersParams) ([]db.SearchUsersRow, error) {
+	t := m.lock()
+	defer m.unlock()
+
+	users := rows(t.users, func(u db.User) bool {
+		return arg.Search == "" || containsFold(u.Name, arg.Search) || containsFold(u.Email, arg.Search)
+	}, func(a, b db.User) int {
+		return a.CreatedAt.Time.Compare(b.CreatedAt.Time)
+	})
+
+	var items []db.SearchUsersRow
+	for _, u := range users {
+		items = append(items, db.SearchUsersRow{
+			ID:             u.ID,
+			Name:           u.Name,
+			Email:          u.Email,
+			PhoneNumber:    u.PhoneNumber,
+			AccessLevel:    u.AccessLevel,
+			VerifiedStatus: u.VerifiedStatus,
+			SuspendedAt:    u.SuspendedAt,
+			CreatedAt:      u.CreatedAt,
+			LastLoggedIn:   u.LastLoggedIn,
+		})
+	}
+
+	after := paging.Key{Text: arg.AfterText, Time: arg.AfterTime, ID: arg.AfterID}
+	return keyset(items, arg.Sort, arg.HasCursor, after, arg.PageLimit, func(u db.SearchUsersRow, field string) paging.Key {
+		if field == "name" {
+			return paging.Key{Text: u.Name, ID: u.ID}
+		}
+		return paging.Key{Time: u.CreatedAt.Time, ID: u.ID}
+	}), nil
+}
+
+// updateUser
+// applies fn to the user with id, a missing row is not an error
+// just like an UPDATE matching nothing
+func (m *Memory) updateUser(id uuid.UUID, fn func(t *tables, u *db.User) error) error {
+	t := m.lock()
+	defer m.unlock()
+
+	u, ok := t.users[id]
+	if !ok {
+		return nil
+	}
+	err := fn(t, &u)
+	if err != nil {
+		return err
+	}
+	t.users[id] = u
+	return nil
+}
+
+func bumpTokenVersion(u *db.User) {
+	u.TokenVersion = sql.NullInt32{Int32: u.TokenVersion.Int32 + 1, Valid: true}
+}
+
+func (m *Memory) UpdateUser(ctx context.Context, arg db.UpdateUserParams) error {
+	return m.updateUser(arg.ID, func(t *tables, u *db.User) error {
+		for _, other := range t.users {
+			if other.ID != u.ID && other.PhoneNumber == arg.PhoneNumber {
+				return uniqueViolation("users_phone_number_key")
+			}
+		}
+		u.Name = arg.Name
+		u.PasswordHash = arg.PasswordHash
+		u.PhoneNumber = arg.PhoneNumber
+		u.UpdatedAt = nullTime(m.now())
+		return nil
+	})
+}
+
+func (m *Memory) UpdateUserPassword(ctx context.Context, arg db.UpdateUserPasswordParams) error {
+	return m.updateUser(arg.ID, func(t *tables, u *db.User) error {
+		u.PasswordHash = arg.PasswordHash
+		bumpTokenVersion(u)
+		u.UpdatedAt = nullTime(m.now())
+		return nil
+	})
+}
+
+func (m *Memory) UpdateUserAccessLevel(ctx context.Context, arg db.UpdateUserAccessLevelParams) error {
+	return m.updateUser(arg.ID, func(t *tables, u *db.User) error {
+		switch arg.AccessLevel.String {
+		case "user", "guide", "admin":
+		default:
+			if arg.AccessLevel.Valid {
+				return checkViolation("users", "users_access_level_check")
+			}
+		}
+		u.AccessLevel = arg.AccessLevel
+		bumpTokenVersion(u)
+		u.UpdatedAt = nullTime(m.now())
+		return nil
+	})
+}
+
+func (m *Memory) UpdateLastLoggedIn(ctx context.Context, id uuid.UUID) error {
+	return m.updateUser(id, func(t *tables, u *db.User) error {
+		u.LastLoggedIn = nullTime(m.now())
+		return nil
+	})
+}
+
+func (m *Memory) IncrementTokenVersion(ctx context.Context, id uuid.UUID) error {
+	return m.updateUser(id, func(t *tables, u *db.User) error {
+		bumpTokenVersion(u)
+		return nil
+	})
+}
+
+func (m *Memory) SetUserVerified(ctx context.Context, id uuid.UUID) error {
+	return m.updateUser(id, func(t *tables, u *db.User) error {
+		u.VerifiedStatus = sql.NullBool{Bool: true, Valid: true}
+		u.UpdatedAt = nullTime(m.now())
+		return nil
+	})
+}
+
+func (m *Memory) SuspendUser(ctx context.Context, id uuid.UUID) error {
+	return m.updateUser(id, func(t *tables, u *db.User) error {
+		now := nullTime(m.now())
+		u.SuspendedAt = now
+		bumpTokenVersion(u)
+		u.UpdatedAt = now
+		return nil
+	})
+}
+
+func (m *Memory) UnsuspendUser(ctx context.Context, id uuid.UUID) error {
+	return m.updateUser(id, func(t *tables, u *db.User) error {
+		u.SuspendedAt = sql.NullTime{}
+		u.UpdatedAt = nullTime(m.now())
+		return nil
+	})
+}
+
+func (m *Memory) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	t := m.lock()
+	defer m.unlock()
+
+	t.deleteUser(id)
+	return nil
+}
+
+// deleteUser
+// removes a user and everything referencing it ON DELETE CASCADE
+func (t *tables) deleteUser(id uuid.UUID) {
+	delete(t.users, id)
+	for planID, p := range t.plans {
+		if p.CreatorID == id {
+			t.deletePlan(planID)
+		}
+	}
+	for groupID, g := range t.groups {
+		if g.CreatorID == id {
+			t.deleteGroup(groupID)
+		}
+	}
+	deleteWhere(t.members, func(r db.TravelGroupsMember) bool { return r.UserID == id })
+	deleteWhere(t.requests, func(r db.TravelGroupsRequest) bool { return r.UserID == id })
+	deleteWhere(t.guideRequests, func(r db.GuideBookingRequest) bool { return r.UserID == id })
+	for planID, p := range t.aiPlans {
+		if p.UserID == id {
+			t.deleteAIPlan(planID)
+		}
+	}
+	deleteWhere(t.plannerJobs, func(r db.PlannerJob) bool { return r.UserID == id })
+	deleteWhere(t.passwordTokens, func(r db.PasswordToken) bool { return r.UserID == id })
+	deleteWhere(t.refreshTokens, func(r db.RefreshToken) bool { return r.UserID == id })
+	deleteWhere(t.verificationTokens, func(r db.VerificationToken) bool { return r.UserID == id })
+	deleteWhere(t.reports, func(r db.Report) bool { return r.ReporterID == id })
+}
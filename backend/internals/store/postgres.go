@@ -0,0 +1,58 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/ErebusAJ/YatraBandhu/internals/db"
+)
+
+var _ Store = (*Postgres)(nil)
+
+// Postgres
+// Store backed by the sqlc generated queries
+type Postgres struct {
+	*db.Queries
+	conn *sql.DB
+	tx   *sql.Tx
+}
+
+// NewPostgres
+// returns a store using the shared pool conn
+// statements are tagged with the request id of their context
+func NewPostgres(conn *sql.DB) *Postgres {
+	return &Postgres{Queries: db.New(tracedDB{conn}), conn: conn}
+}
+
+// InTx
+// runs fn inside a database transaction
+func (p *Postgres) InTx(ctx context.Context, fn func(Store) error) error {
+	if p.tx != nil {
+		return fn(p)
+	}
+
+	tx, err := p.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = fn(&Postgres{Queries: db.New(tracedDB{tx}), conn: p.conn, tx: tx})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Ping
+// pings the pool
+func (p *Postgres) Ping(ctx context.Context) error {
+	return p.conn.PingContext(ctx)
+}
+
+// Stats
+// returns the pool statistics
+func (p *Postgres) Stats() sql.DBStats {
+	return p.conn.Stats()
+}
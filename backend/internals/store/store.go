@@ -0,0 +1,169 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/ErebusAJ/YatraBandhu/internals/db"
+	"github.com/google/uuid"
+)
+
+// UserStore
+// Accounts, their auth state and moderation
+type UserStore interface {
+	RegisterUser(ctx context.Context, arg db.RegisterUserParams) (uuid.UUID, error)
+	GetUserByID(ctx context.Context, id uuid.UUID) (db.User, error)
+	GetUserByEmail(ctx context.Context, email string) (db.GetUserByEmailRow, error)
+	GetUserAuthState(ctx context.Context, id uuid.UUID) (db.GetUserAuthStateRow, error)
+	GetUserVerifiedStatus(ctx context.Context, id uuid.UUID) (sql.NullBool, error)
+	SearchUsers(ctx context.Context, arg db.SearchUsersParams) ([]db.SearchUsersRow, error)
+	UpdateUser(ctx context.Context, arg db.UpdateUserParams) error
+	UpdateUserPassword(ctx context.Context, arg db.UpdateUserPasswordParams) error
+	UpdateUserAccessLevel(ctx context.Context, arg db.UpdateUserAccessLevelParams) error
+	UpdateLastLoggedIn(ctx context.Context, id uuid.UUID) error
+	IncrementTokenVersion(ctx context.Context, id uuid.UUID) error
+	SetUserVerified(ctx context.Context, id uuid.UUID) error
+	SuspendUser(ctx context.Context, id uuid.UUID) error
+	UnsuspendUser(ctx context.Context, id uuid.UUID) error
+	DeleteUser(ctx context.Context, id uuid.UUID) error
+}
+
+// TokenStore
+// Password reset, refresh and email verification tokens
+type TokenStore interface {
+	InsertToken(ctx context.Context, arg db.InsertTokenParams) error
+	// ConsumeUserToken deletes an unexpired reset token, returning its user
+	ConsumeUserToken(ctx context.Context, tokenHash string) (uuid.UUID, error)
+	DeleteUserTokens(ctx context.Context, userID uuid.UUID) error
+
+	InsertRefreshToken(ctx context.Context, arg db.InsertRefreshTokenParams) error
+	GetRefreshToken(ctx context.Context, tokenHash string) (db.RefreshToken, error)
+	RevokeRefreshToken(ctx context.Context, id uuid.UUID) (int64, error)
+	RevokeUserRefreshTokens(ctx context.Context, userID uuid.UUID) error
+
+	InsertVerificationToken(ctx context.Context, arg db.InsertVerificationTokenParams) error
+	GetVerificationToken(ctx context.Context, token string) (db.VerificationToken, error)
+	DeleteUserVerificationTokens(ctx context.Context, userID uuid.UUID) error
+}
+
+// PlanStore
+// Travel plan details created by users
+type PlanStore interface {
+	AddTravelDetails(ctx context.Context, arg db.AddTravelDetailsParams) (uuid.UUID, error)
+	GetUserPlansDetails(ctx context.Context, id uuid.UUID) ([]db.GetUserPlansDetailsRow, error)
+	ListUserPlans(ctx context.Context, arg db.ListUserPlansParams) ([]db.ListUserPlansRow, error)
+}
+
+// GroupStore
+// Travel groups and their members
+type GroupStore interface {
+	CreateGroup(ctx context.Context, arg db.CreateGroupParams) error
+	GetGroupByID(ctx context.Context, id uuid.UUID) (db.TravelGroup, error)
+	UpdateGroupByID(ctx context.Context, arg db.UpdateGroupByIDParams) error
+	DeleteGroupByID(ctx context.Context, id uuid.UUID) error
+	AddUserToGroup(ctx context.Context, arg db.AddUserToGroupParams) error
+	DeleteUserFromGroup(ctx context.Context, arg db.DeleteUserFromGroupParams) error
+	GetGroupUsersDetails(ctx context.Context, groupID uuid.UUID) ([]db.GetGroupUsersDetailsRow, error)
+	GetUserGroups(ctx context.Context, userID uuid.UUID) ([]db.GetUserGroupsRow, error)
+	ListGroupMembers(ctx context.Context, arg db.ListGroupMembersParams) ([]db.ListGroupMembersRow, error)
+	ListUserGroups(ctx context.Context, arg db.ListUserGroupsParams) ([]db.ListUserGroupsRow, error)
+}
+
+// RequestStore
+// Requests to join a travel group
+type RequestStore interface {
+	SendRequest(ctx context.Context, arg db.SendRequestParams) error
+	UpdateRequest(ctx context.Context, arg db.UpdateRequestParams) (int64, error)
+	RejectRequest(ctx context.Context, arg db.RejectRequestParams) error
+	ListGroupRequests(ctx context.Context, arg db.ListGroupRequestsParams) ([]db.ListGroupRequestsRow, error)
+}
+
+// GuideStore
+// Guide profiles and booking requests
+type GuideStore interface {
+	AddGuide(ctx context.Context, arg db.AddGuideParams) (uuid.UUID, error)
+	GetGuideByID(ctx context.Context, id uuid.UUID) (db.Guide, error)
+	ListGuides(ctx context.Context, arg db.ListGuidesParams) ([]db.Guide, error)
+	UpdateGuideAvail(ctx context.Context, arg db.UpdateGuideAvailParams) (int64, error)
+	SetGuideVerified(ctx context.Context, arg db.SetGuideVerifiedParams) (int64, error)
+	SendGuideRequest(ctx context.Context, arg db.SendGuideRequestParams) error
+}
+
+// AIPlanStore
+// Itineraries generated by the AI planner and their versions
+type AIPlanStore interface {
+	SavePlan(ctx context.Context, arg db.SavePlanParams) (uuid.UUID, error)
+	RetreivePlan(ctx context.Context, userID uuid.UUID) (db.AiPlan, error)
+	GetAIPlan(ctx context.Context, arg db.GetAIPlanParams) (db.AiPlan, error)
+	ListAIPlans(ctx context.Context, arg db.ListAIPlansParams) ([]db.ListAIPlansRow, error)
+	RenameAIPlan(ctx context.Context, arg db.RenameAIPlanParams) (int64, error)
+	DeleteAIPlan(ctx context.Context, arg db.DeleteAIPlanParams) (int64, error)
+
+	AddAIPlanVersion(ctx context.Context, arg db.AddAIPlanVersionParams) (int32, error)
+	GetAIPlanVersion(ctx context.Context, arg db.GetAIPlanVersionParams) (db.AiPlanVersion, error)
+	ListAIPlanVersions(ctx context.Context, arg db.ListAIPlanVersionsParams) ([]db.ListAIPlanVersionsRow, error)
+}
+
+// PlannerJobStore
+// AI planner generations queued for the planner worker
+type PlannerJobStore interface {
+	CreatePlannerJob(ctx context.Context, arg db.CreatePlannerJobParams) (db.PlannerJob, error)
+	GetPlannerJob(ctx context.Context, arg db.GetPlannerJobParams) (db.PlannerJob, error)
+	CancelPlannerJob(ctx context.Context, arg db.CancelPlannerJobParams) (int64, error)
+
+	ClaimPlannerJobs(ctx context.Context, arg db.ClaimPlannerJobsParams) ([]db.PlannerJob, error)
+	ExtendPlannerJobLease(ctx context.Context, arg db.ExtendPlannerJobLeaseParams) (int64, error)
+	AdvancePlannerJob(ctx context.Context, arg db.AdvancePlannerJobParams) (int64, error)
+	FinishPlannerJob(ctx context.Context, arg db.FinishPlannerJobParams) (int64, error)
+	RetryPlannerJob(ctx context.Context, arg db.RetryPlannerJobParams) (int64, error)
+	FailPlannerJob(ctx context.Context, arg db.FailPlannerJobParams) (int64, error)
+	ReleasePlannerJob(ctx context.Context, id uuid.UUID) error
+}
+
+// ReportStore
+// Moderation reports
+type ReportStore interface {
+	CreateReport(ctx context.Context, arg db.CreateReportParams) error
+	GetReportsByStatus(ctx context.Context, arg db.GetReportsByStatusParams) ([]db.Report, error)
+	UpdateReportStatus(ctx context.Context, arg db.UpdateReportStatusParams) (int64, error)
+}
+
+// OutboxStore
+// Queued outgoing mail
+type OutboxStore interface {
+	EnqueueEmail(ctx context.Context, arg db.EnqueueEmailParams) error
+	ListOutboxByStatus(ctx context.Context, arg db.ListOutboxByStatusParams) ([]db.ListOutboxByStatusRow, error)
+	RequeueEmail(ctx context.Context, id uuid.UUID) (int64, error)
+}
+
+// AuditStore
+// Append-only security audit trail
+type AuditStore interface {
+	InsertAuditEvent(ctx context.Context, arg db.InsertAuditEventParams) error
+	ListAuditEvents(ctx context.Context, arg db.ListAuditEventsParams) ([]db.AuditEvent, error)
+}
+
+// Store
+// Everything the handlers read and write
+// implemented by Postgres and by the in-memory Memory used in tests
+type Store interface {
+	UserStore
+	TokenStore
+	PlanStore
+	GroupStore
+	RequestStore
+	GuideStore
+	AIPlanStore
+	PlannerJobStore
+	ReportStore
+	OutboxStore
+	AuditStore
+
+	// InTx runs fn with a store bound to a single transaction,
+	// committed if fn returns nil and rolled back otherwise
+	// calls on a store already in a transaction reuse it
+	InTx(ctx context.Context, fn func(Store) error) error
+
+	// Ping checks the store is reachable
+	Ping(ctx context.Context) error
+}
@@ -0,0 +1,43 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/ErebusAJ/YatraBandhu/internals/db"
+	"github.com/ErebusAJ/YatraBandhu/internals/logging"
+)
+
+// tracedDB
+// prefixes every statement with a comment naming the request id
+// of its context, so slow or failing statements in pg_stat_activity
+// and the postgres logs can be matched to the request that ran them
+type tracedDB struct {
+	db.DBTX
+}
+
+func (t tracedDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return t.DBTX.ExecContext(ctx, tagQuery(ctx, query), args...)
+}
+
+func (t tracedDB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return t.DBTX.PrepareContext(ctx, tagQuery(ctx, query))
+}
+
+func (t tracedDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return t.DBTX.QueryContext(ctx, tagQuery(ctx, query), args...)
+}
+
+func (t tracedDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return t.DBTX.QueryRowContext(ctx, tagQuery(ctx, query), args...)
+}
+
+// tagQuery
+// ids that could break out of the comment are left out
+func tagQuery(ctx context.Context, query string) string {
+	id := logging.RequestID(ctx)
+	if !logging.ValidRequestID(id) {
+		return query
+	}
+	return "/* request_id=" + id + " */ " + query
+}
@@ -0,0 +1,27 @@
+package store
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/ErebusAJ/YatraBandhu/internals/logging"
+)
+
+func TestTagQuery(t *testing.T) {
+	query := "-- name: GetUserByID :one\nSELECT 1"
+
+	if got := tagQuery(context.Background(), query); got != query {
+		t.Fatalf("untagged context changed the query: %q", got)
+	}
+
+	ctx := logging.WithRequestID(context.Background(), "req-7")
+	if got := tagQuery(ctx, query); got != "/* request_id=req-7 */ "+query {
+		t.Fatalf("unexpected tagged query %q", got)
+	}
+
+	ctx = logging.WithRequestID(context.Background(), "x */ DROP TABLE users; /*")
+	if got := tagQuery(ctx, query); strings.Contains(got, "DROP") {
+		t.Fatalf("unsafe request id in query %q", got)
+	}
+}
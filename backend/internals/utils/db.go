@@ -2,23 +2,15 @@ package utils
 
 import (
 	"database/sql"
-	"log"
-	"os"
 
-	"github.com/joho/godotenv"
+	"github.com/ErebusAJ/YatraBandhu/config"
 	_ "github.com/lib/pq"
 )
 
 // ConnectDB
-// connects with the DB specified by URL in .env
-func ConnectDB() (*sql.DB, error) {
-	// Load env
-	godotenv.Load()
-
-	dbURL := os.Getenv("DB_URL")
-	if dbURL == ""{
-		log.Fatalf("error connecting to DB couldn't retrieve url")
-	}
+// connects with the DB specified by URL
+// the returned pool is shared by the whole process
+func ConnectDB(dbURL string, pool config.DBConfig) (*sql.DB, error) {
 
 	// Open connection
 	db, err := sql.Open("postgres", dbURL)
@@ -26,6 +18,12 @@ func ConnectDB() (*sql.DB, error) {
 		return nil, err
 	}
 
+	// Pool limits
+	db.SetMaxOpenConns(pool.MaxOpenConns)
+	db.SetMaxIdleConns(pool.MaxIdleConns)
+	db.SetConnMaxLifetime(pool.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(pool.ConnMaxIdleTime)
+
 	// Ping DB to verify connection
 	err = db.Ping() ; if err != nil {
 		return nil, err
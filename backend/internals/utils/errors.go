@@ -0,0 +1,308 @@
+package utils
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"reflect"
+	"strings"
+
+	"github.com/ErebusAJ/YatraBandhu/internals/logging"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/lib/pq"
+)
+
+// Stable error codes returned to clients
+// clients should branch on these, messages may change
+const(
+	CodeValidationFailed	=	"VALIDATION_FAILED"
+	CodeMalformedURL		=	"MALFORMED_URL"
+	CodeUnauthorized		=	"UNAUTHORIZED"
+	CodeForbidden			=	"FORBIDDEN"
+	CodeNotFound			=	"NOT_FOUND"
+	CodeConflict			=	"CONFLICT"
+	CodeTooManyRequests		=	"TOO_MANY_REQUESTS"
+	CodeInternal			=	"INTERNAL_ERROR"
+
+	CodeEmailNotVerified	=	"EMAIL_NOT_VERIFIED"
+	CodeAccountSuspended	=	"ACCOUNT_SUSPENDED"
+	CodeInvalidCredentials	=	"INVALID_CREDENTIALS"
+	CodeInvalidToken		=	"INVALID_TOKEN"
+
+	CodeUserNotFound		=	"USER_NOT_FOUND"
+	CodeUserEmailTaken		=	"USER_EMAIL_TAKEN"
+	CodeUserPhoneTaken		=	"USER_PHONE_TAKEN"
+	CodePlanNotFound		=	"PLAN_NOT_FOUND"
+	CodeGroupNotFound		=	"GROUP_NOT_FOUND"
+	CodeGroupExists			=	"GROUP_EXISTS"
+	CodeNotGroupCreator		=	"NOT_GROUP_CREATOR"
+	CodeMemberExists		=	"GROUP_MEMBER_EXISTS"
+	CodeRequestNotFound		=	"REQUEST_NOT_FOUND"
+	CodeRequestPending		=	"REQUEST_ALREADY_PENDING"
+	CodeGuideNotFound		=	"GUIDE_NOT_FOUND"
+	CodeReportNotFound		=	"REPORT_NOT_FOUND"
+	CodeEmailNotFound		=	"EMAIL_NOT_FOUND"
+	CodePlannerFailed		=	"PLANNER_FAILED"
+	CodeQuotaExceeded		=	"QUOTA_EXCEEDED"
+	CodePlannerJobNotFound	=	"PLANNER_JOB_NOT_FOUND"
+	CodePlannerJobFinished	=	"PLANNER_JOB_FINISHED"
+	CodeAIPlanNotFound		=	"AI_PLAN_NOT_FOUND"
+	CodeAIPlanVersionNotFound	=	"AI_PLAN_VERSION_NOT_FOUND"
+)
+
+// APIError
+// error body sent to clients
+// message keeps the old plain string so existing clients still work
+type APIError struct{
+	Status		int				`json:"-"`
+	Code		string			`json:"code"`
+	Message		string			`json:"message"`
+	Details		[]FieldError	`json:"details,omitempty"`
+	RequestID	string			`json:"request_id,omitempty"`
+}
+
+// FieldError
+// a single invalid request field
+type FieldError struct{
+	Field		string	`json:"field"`
+	Message		string	`json:"message"`
+}
+
+func (e *APIError) Error() string{
+	return e.Code + ": " + e.Message
+}
+
+// Typed errors shared by the handlers
+var(
+	ErrInvalidCredentials	=	&APIError{Status: 401, Code: CodeInvalidCredentials, Message: UnauthorizedError}
+	ErrUserNotFound		=	&APIError{Status: 404, Code: CodeUserNotFound, Message: "user not found"}
+	ErrUserEmailTaken	=	&APIError{Status: 409, Code: CodeUserEmailTaken, Message: "email already registered"}
+	ErrUserPhoneTaken	=	&APIError{Status: 409, Code: CodeUserPhoneTaken, Message: "phone number already registered"}
+	ErrPlanNotFound		=	&APIError{Status: 404, Code: CodePlanNotFound, Message: "travel plan not found"}
+	ErrGroupNotFound	=	&APIError{Status: 404, Code: CodeGroupNotFound, Message: "group not found"}
+	ErrGroupExists		=	&APIError{Status: 409, Code: CodeGroupExists, Message: "a group already exists for this plan"}
+	ErrNotGroupCreator	=	&APIError{Status: 403, Code: CodeNotGroupCreator, Message: ForbiddenError}
+	ErrMemberExists		=	&APIError{Status: 409, Code: CodeMemberExists, Message: "user is already a group member"}
+	ErrRequestNotFound	=	&APIError{Status: 404, Code: CodeRequestNotFound, Message: "no pending join request"}
+	ErrRequestPending	=	&APIError{Status: 409, Code: CodeRequestPending, Message: "a join request is already pending"}
+	ErrGuideNotFound	=	&APIError{Status: 404, Code: CodeGuideNotFound, Message: "guide not found"}
+	ErrReportNotFound	=	&APIError{Status: 404, Code: CodeReportNotFound, Message: "report not found"}
+	ErrEmailNotFound	=	&APIError{Status: 404, Code: CodeEmailNotFound, Message: "no dead email with that id"}
+	ErrPlannerFailed	=	&APIError{Status: 502, Code: CodePlannerFailed, Message: "unable to generate a plan, try again later"}
+	ErrRateLimited		=	&APIError{Status: 429, Code: CodeTooManyRequests, Message: "rate limit exceeded, try again later"}
+	ErrQuotaExceeded	=	&APIError{Status: 429, Code: CodeQuotaExceeded, Message: "AI planner quota used up, see /auth/usage"}
+	ErrPlannerJobNotFound	=	&APIError{Status: 404, Code: CodePlannerJobNotFound, Message: "planner job not found"}
+	ErrPlannerJobFinished	=	&APIError{Status: 409, Code: CodePlannerJobFinished, Message: "planner job already finished"}
+	ErrAIPlanNotFound	=	&APIError{Status: 404, Code: CodeAIPlanNotFound, Message: "AI plan not found"}
+	ErrAIPlanVersionNotFound	=	&APIError{Status: 404, Code: CodeAIPlanVersionNotFound, Message: "AI plan version not found"}
+)
+
+// Unique constraints and the error each one means
+var uniqueErrors = map[string]*APIError{
+	"users_email_key":								ErrUserEmailTaken,
+	"users_phone_number_key":						ErrUserPhoneTaken,
+	"travel_groups_creator_id_plan_id_key":			ErrGroupExists,
+	"travel_groups_members_group_id_user_id_key":	ErrMemberExists,
+	"travel_groups_requests_pending_idx":			ErrRequestPending,
+}
+
+// Referenced columns and the error a missing row means
+// postgres names foreign keys <table>_<column>_fkey
+var foreignKeyErrors = map[string]*APIError{
+	"user_id":		ErrUserNotFound,
+	"creator_id":	ErrUserNotFound,
+	"reporter_id":	ErrUserNotFound,
+	"group_id":		ErrGroupNotFound,
+	"plan_id":		ErrPlanNotFound,
+	"guide_id":		ErrGuideNotFound,
+}
+
+// Codes for the client messages passed to ErrorJSON
+var messageCodes = map[string]string{
+	JSONError:				CodeValidationFailed,
+	EndpointError:			CodeMalformedURL,
+	UnverifiedError:		CodeEmailNotVerified,
+	SuspendedError:			CodeAccountSuspended,
+	TooManyAttemptsError:	CodeTooManyRequests,
+	InvalidTokenError:		CodeInvalidToken,
+}
+
+// Fallback codes by status
+var statusCodes = map[int]string{
+	400:	CodeValidationFailed,
+	401:	CodeUnauthorized,
+	403:	CodeForbidden,
+	404:	CodeNotFound,
+	409:	CodeConflict,
+	429:	CodeTooManyRequests,
+}
+
+// Report validation errors with the json field names
+func init(){
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok{
+		return
+	}
+
+	v.RegisterTagNameFunc(func(f reflect.StructField) string{
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-"{
+			return ""
+		}
+		if name == ""{
+			return f.Name
+		}
+		return name
+	})
+}
+
+
+// NewAPIError
+// builds an error for status, the code is picked from the
+// client message or the status
+func NewAPIError(status int, client string) *APIError{
+	code, ok := messageCodes[client]
+	if !ok{
+		code, ok = statusCodes[status]
+	}
+	if !ok{
+		code = CodeInternal
+	}
+
+	return &APIError{Status: status, Code: code, Message: client}
+}
+
+
+// SendError
+// writes apiErr with the request id and logs the server side error,
+// at error level for 5xx responses
+func SendError(c *gin.Context, apiErr *APIError, server string, err error){
+	res := *apiErr
+	res.RequestID = c.GetString(logging.RequestIDKey)
+
+	c.IndentedJSON(res.Status, res)
+	logError(c, res, server, err)
+}
+
+
+// SendErrorEvent
+// SendError for an event stream that already answered 200,
+// apiErr goes out as an error event
+func SendErrorEvent(c *gin.Context, apiErr *APIError, server string, err error){
+	res := *apiErr
+	res.RequestID = c.GetString(logging.RequestIDKey)
+
+	c.SSEvent("error", res)
+	c.Writer.Flush()
+	logError(c, res, server, err)
+}
+
+
+// logError
+// logs an error sent to the client, at error level for 5xx
+func logError(c *gin.Context, res APIError, server string, err error){
+	level := slog.LevelInfo
+	if res.Status >= 500{
+		level = slog.LevelError
+	}
+	attrs := []any{"status", res.Status, "code", res.Code}
+	if err != nil{
+		attrs = append(attrs, "error", err)
+	}
+	slog.Log(c, level, server, attrs...)
+}
+
+
+// DBErrorJSON
+// maps a store error to the matching client error
+// notFound is sent for sql.ErrNoRows, typed errors returned
+// from a transaction are sent as they are
+func DBErrorJSON(c *gin.Context, err error, notFound *APIError){
+	SendError(c, FromDBError(err, notFound), DatabaseError, err)
+}
+
+
+// FromDBError
+// the client error for a store error, 500 when nothing matches
+func FromDBError(err error, notFound *APIError) *APIError{
+	var apiErr *APIError
+	if errors.As(err, &apiErr){
+		return apiErr
+	}
+
+	if errors.Is(err, sql.ErrNoRows){
+		if notFound != nil{
+			return notFound
+		}
+		return NewAPIError(404, NotFoundError)
+	}
+
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr){
+		return NewAPIError(500, InternalError)
+	}
+
+	switch pqErr.Code.Class(){
+	case "23":
+		switch pqErr.Code{
+		case "23505":
+			if apiErr, ok := uniqueErrors[pqErr.Constraint]; ok{
+				return apiErr
+			}
+			return NewAPIError(409, ConflictError)
+
+		case "23503":
+			for column, apiErr := range foreignKeyErrors{
+				if strings.HasSuffix(pqErr.Constraint, "_" + column + "_fkey"){
+					return apiErr
+				}
+			}
+			return NewAPIError(409, ConflictError)
+
+		case "23514":
+			// <table>_<column>_check
+			field := strings.TrimSuffix(strings.TrimPrefix(pqErr.Constraint, pqErr.Table + "_"), "_check")
+			return &APIError{
+				Status: 400,
+				Code: CodeValidationFailed,
+				Message: JSONError,
+				Details: []FieldError{{Field: field, Message: "invalid value"}},
+			}
+		}
+
+	// invalid text representation, datetime format, out of range values
+	case "22":
+		return NewAPIError(400, JSONError)
+	}
+
+	return NewAPIError(500, InternalError)
+}
+
+
+// fieldErrors
+// field level details for a request binding error
+func fieldErrors(err error) []FieldError{
+	var details []FieldError
+
+	var verrs validator.ValidationErrors
+	if errors.As(err, &verrs){
+		for _, fe := range verrs{
+			msg := "failed " + fe.Tag() + " check"
+			if fe.Tag() == "required"{
+				msg = "is required"
+			}
+			details = append(details, FieldError{Field: fe.Field(), Message: msg})
+		}
+		return details
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr){
+		return []FieldError{{Field: typeErr.Field, Message: "must be a " + typeErr.Type.String()}}
+	}
+
+	return nil
+}
@@ -9,6 +9,7 @@ const(
 	InvalidAcces		= 	"error invalid access level"
 	InvalidAuth			= 	"error invalid authorization header"
 	ParsingError		= 	"error parsing the specified field"
+	RevokedTokenError	=	"error token version revoked"
 )
 
 // Client Errors
@@ -19,4 +20,17 @@ const(
 	NotFoundError		=	"not found"
 	UnauthorizedError	=	"unauthorized" 
 	EndpointError		= 	"malformed url"
-)
\ No newline at end of file
+	UnverifiedError		=	"email not verified"
+	ForbiddenError		=	"forbidden"
+	SuspendedError		=	"account suspended"
+	TooManyAttemptsError	=	"too many attempts, try again later"
+	ConflictError		=	"already exists"
+	InvalidTokenError	=	"invalid/expired token"
+)
+
+// User roles stored in users.access_level
+const(
+	RoleUser			=	"user"
+	RoleGuide			=	"guide"
+	RoleAdmin			=	"admin"
+)
@@ -1,26 +1,33 @@
 package utils
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
-	"os"
 	"strconv"
 	"strings"
 	"time"
 
-	"github.com/joho/godotenv"
+	"github.com/ErebusAJ/YatraBandhu/config"
+	"github.com/ErebusAJ/YatraBandhu/internals/httpclient"
+	"github.com/ErebusAJ/YatraBandhu/internals/itinerary"
+	"github.com/ErebusAJ/YatraBandhu/internals/llm"
+	"github.com/ErebusAJ/YatraBandhu/internals/metrics"
 )
 
 const (
-	CFModel     = "@cf/mistral/mistral-7b-instruct-v0.1"
 	MaxTokens   = 1800
 	DefaultDays = 3
 )
 
+// ErrItineraryParse
+// the structuring model's answer was not a usable itinerary
+var ErrItineraryParse = errors.New("error unmarshaling itinerary JSON")
+
 type WeatherData struct {
 	CurrentTemp float64
 	CurrentWind float64
@@ -46,12 +53,12 @@ type TravelData struct {
 	RiskDetails    RiskDetails
 }
 
-func getCoordinates(location, token string) ([]float64, error) {
+func getCoordinates(ctx context.Context, location, token string) ([]float64, error) {
 	escapedLocation := url.PathEscape(location)
 	url := fmt.Sprintf("https://api.mapbox.com/geocoding/v5/mapbox.places/%s.json", escapedLocation)
 
-	client := &http.Client{Timeout: 10 * time.Second}
-	req, err := http.NewRequest("GET", url, nil)
+	client := httpclient.New("mapbox", 10 * time.Second)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -88,8 +95,8 @@ func getCoordinates(location, token string) ([]float64, error) {
 	return []float64{data.Features[0].Center[0], data.Features[0].Center[1]}, nil
 }
 
-func getMapboxPOIs(location, category string, limit int, token string) ([]string, error) {
-	coords, err := getCoordinates(location, token)
+func getMapboxPOIs(ctx context.Context, location, category string, limit int, token string) ([]string, error) {
+	coords, err := getCoordinates(ctx, location, token)
 	if err != nil {
 		return nil, err
 	}
@@ -97,8 +104,8 @@ func getMapboxPOIs(location, category string, limit int, token string) ([]string
 	escapedCategory := url.PathEscape(category)
 	url := fmt.Sprintf("https://api.mapbox.com/geocoding/v5/mapbox.places/%s.json", escapedCategory)
 
-	client := &http.Client{Timeout: 10 * time.Second}
-	req, err := http.NewRequest("GET", url, nil)
+	client := httpclient.New("mapbox", 10 * time.Second)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -138,14 +145,14 @@ func getMapboxPOIs(location, category string, limit int, token string) ([]string
 	return pois, nil
 }
 
-func getTomTomPOIs(coords []float64, category string, limit int, apiKey string) ([]string, error) {
+func getTomTomPOIs(ctx context.Context, coords []float64, category string, limit int, apiKey string) ([]string, error) {
 	if len(coords) < 2 {
 		return nil, fmt.Errorf("invalid coordinates")
 	}
 
 	url := "https://api.tomtom.com/search/2/categorySearch/.json"
-	client := &http.Client{Timeout: 10 * time.Second}
-	req, err := http.NewRequest("GET", url, nil)
+	client := httpclient.New("tomtom", 10 * time.Second)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -189,10 +196,10 @@ func getTomTomPOIs(coords []float64, category string, limit int, apiKey string)
 	return pois, nil
 }
 
-func getWeatherData(coords []float64) (*WeatherData, error) {
+func getWeatherData(ctx context.Context, coords []float64) (*WeatherData, error) {
 	url := "https://api.open-meteo.com/v1/forecast"
-	client := &http.Client{Timeout: 10 * time.Second}
-	req, err := http.NewRequest("GET", url, nil)
+	client := httpclient.New("open-meteo", 10 * time.Second)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -309,28 +316,46 @@ func getTransportationInfo(coords []float64, token string) string {
 	return "Typical travel time: 15-45 mins"
 }
 
-func collectTravelData(location string, numDays int, mapboxToken, tomtomKey string) (*TravelData, error) {
-	coords, err := getCoordinates(location, mapboxToken)
+// Steps of collectTravelData, reported as each source is queried
+const (
+	StepGeocoding   = "geocoding"
+	StepWeather     = "weather"
+	StepHotels      = "hotels"
+	StepAttractions = "attractions"
+)
+
+func collectTravelData(ctx context.Context, location string, numDays int, mapboxToken, tomtomKey string, onStep func(step string)) (*TravelData, error) {
+	step := func(name string) {
+		if onStep != nil {
+			onStep(name)
+		}
+	}
+
+	step(StepGeocoding)
+	coords, err := getCoordinates(ctx, location, mapboxToken)
 	if err != nil {
-		return nil, fmt.Errorf("could not retrieve coordinates: %v", err)
+		return nil, fmt.Errorf("could not retrieve coordinates: %w", err)
 	}
 
-	weatherData, err := getWeatherData(coords)
+	step(StepWeather)
+	weatherData, err := getWeatherData(ctx, coords)
 	if err != nil {
-		return nil, fmt.Errorf("could not get weather data: %v", err)
+		return nil, fmt.Errorf("could not get weather data: %w", err)
 	}
 
 	riskPercentage := calculateRiskFactor(weatherData)
 
 	// Collect POIs from both services
+	step(StepHotels)
 	hotels := unique(append(
-		getPOISafe(getMapboxPOIs(location, "hotel", 10, mapboxToken)),
-		getPOISafe(getTomTomPOIs(coords, "hotel", 10, tomtomKey))...,
+		getPOISafe(getMapboxPOIs(ctx, location, "hotel", 10, mapboxToken)),
+		getPOISafe(getTomTomPOIs(ctx, coords, "hotel", 10, tomtomKey))...,
 	))[:10]
 
+	step(StepAttractions)
 	attractions := unique(append(
-		getPOISafe(getMapboxPOIs(location, "attraction", numDays*4, mapboxToken)),
-		getPOISafe(getTomTomPOIs(coords, "attraction", numDays*4, tomtomKey))...,
+		getPOISafe(getMapboxPOIs(ctx, location, "attraction", numDays*4, mapboxToken)),
+		getPOISafe(getTomTomPOIs(ctx, coords, "attraction", numDays*4, tomtomKey))...,
 	))[:numDays*4]
 
 	return &TravelData{
@@ -376,7 +401,7 @@ func formatPrompt(ragData *TravelData, userQuery string, numDays int) string {
     attractions := strings.Join(ragData.Attractions[:min(6, len(ragData.Attractions))], ", ")
     riskFactors := strings.Join(ragData.RiskDetails.Factors, ", ")
 
-    return fmt.Sprintf(`<s>[INST] Create a detailed %d-day travel itinerary with these components:
+    return fmt.Sprintf(`Create a detailed %d-day travel itinerary with these components:
 
 Destination Context: %s
 
@@ -398,7 +423,7 @@ Final Section:
 - Safety Summary: %s overall risk
   * Primary factors: %s
   * Weather-adjusted recommendations
-  * Emergency preparedness tips[/INST]</s>`,
+  * Emergency preparedness tips`,
         numDays,
         ragData.LocationInfo,
         hotels,
@@ -418,65 +443,13 @@ func min(a, b int) int {
     return b
 }
 
-func generateItinerary(prompt, accountID, apiKey string) (string, error) {
-    url := fmt.Sprintf("https://api.cloudflare.com/client/v4/accounts/%s/ai/run/%s", accountID, CFModel)
-
-    payload := map[string]interface{}{
-        "prompt":      prompt,
-        "max_tokens":  MaxTokens,
-        "temperature": 0.7,
-        "top_p":       0.9,
-        "stream":      false,
-    }
-
-    jsonPayload, err := json.Marshal(payload)
-    if err != nil {
-        return "", fmt.Errorf("error marshaling payload: %w", err)
-    }
-
-    req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonPayload))
-    if err != nil {
-        return "", fmt.Errorf("error creating request: %w", err)
-    }
-
-    req.Header.Set("Authorization", "Bearer "+apiKey)
-    req.Header.Set("Content-Type", "application/json")
-
-    client := &http.Client{Timeout: 300 * time.Second}
-    resp, err := client.Do(req)
-    if err != nil {
-        return "", fmt.Errorf("API request failed: %w", err)
-    }
-    defer resp.Body.Close()
-
-    if resp.StatusCode != http.StatusOK {
-        body, _ := io.ReadAll(resp.Body)
-        return "", fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
-    }
-
-    var result struct {
-        Result struct {
-            Response string `json:"response"`
-        } `json:"result"`
-        Success bool   `json:"success"`
-        Errors  []any  `json:"errors"`
-    }
-
-    if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-        return "", fmt.Errorf("error decoding response: %w", err)
-    }
-
-    if !result.Success || len(result.Errors) > 0 {
-        return "", fmt.Errorf("API errors: %v", result.Errors)
-    }
-
-    return result.Result.Response, nil
-}
-
-func convertToJSON(textItinerary string, numDays int, apiKey string) (map[string]interface{}, error) {
-	today := time.Now()
-	startDate := today.Format("2006-01-02")
-	endDate := today.AddDate(0, 0, numDays-1).Format("2006-01-02")
+// convertToJSON
+// has the structuring models turn the drafted text into an itinerary
+// of numDays days from start, answers that don't conform are sent back
+// with the problems found, up to p.Repairs times
+func (p *Planner) convertToJSON(ctx context.Context, textItinerary string, numDays int, start time.Time) (*itinerary.Itinerary, error) {
+	startDate := start.Format(itinerary.DateFormat)
+	endDate := start.AddDate(0, 0, numDays-1).Format(itinerary.DateFormat)
 
 	prompt := fmt.Sprintf(`Convert this %d-day travel itinerary into structured JSON:
 
@@ -551,116 +524,160 @@ func convertToJSON(textItinerary string, numDays int, apiKey string) (map[string
     6. Use provided dates: %s to %s
     7. Never shorten the duration
     8. Include null values for missing optional fields
-    9. Add realistic weather data if missing`,
+    9. Add realistic weather data if missing
+    10. Answer with the JSON object only, no other fields`,
 		numDays, textItinerary, numDays, startDate, endDate,
 		startDate, numDays, startDate, endDate)
 
-	url := "https://generativelanguage.googleapis.com/v1beta/models/gemini-2.0-flash:generateContent"
-	client := &http.Client{Timeout: 30 * time.Second}
+	req := llm.Request{Prompt: prompt, Temperature: 0.2}
+	for attempt := 0; ; attempt++ {
+		res, model, err := p.StructureModels.Structured(ctx, req, itinerary.Schema)
+		if err != nil {
+			return nil, err
+		}
 
-	payload := map[string]interface{}{
-		"contents": []interface{}{
-			map[string]interface{}{
-				"parts": []interface{}{
-					map[string]string{"text": prompt},
-				},
-			},
-		},
-	}
+		it, err := itinerary.Parse([]byte(res.Text), numDays, start)
+		if err == nil {
+			slog.DebugContext(ctx, "planner: itinerary structured", "model", model.String(), "repairs", attempt)
+			return it, nil
+		}
+		if attempt >= p.Repairs {
+			return nil, fmt.Errorf("%w after %d attempts: %v\nResponse text: %s", ErrItineraryParse, attempt+1, err, res.Text)
+		}
 
-	jsonPayload, err := json.Marshal(payload)
-	if err != nil {
-		return nil, fmt.Errorf("error creating request payload: %v", err)
+		slog.WarnContext(ctx, "planner: itinerary does not conform, asking for a repair", "model", model.String(), "attempt", attempt+1, "error", err)
+		req.Prompt = repairPrompt(prompt, res.Text, err)
 	}
+}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonPayload))
-	if err != nil {
-		return nil, fmt.Errorf("error creating request: %v", err)
+// repairPrompt
+// the structuring prompt followed by the rejected answer and why
+func repairPrompt(prompt, answer string, err error) string {
+	problems := []string{err.Error()}
+	var verr *itinerary.ValidationError
+	if errors.As(err, &verr) {
+		problems = verr.Problems
 	}
 
-	q := req.URL.Query()
-	q.Add("key", apiKey)
-	req.URL.RawQuery = q.Encode()
-	req.Header.Set("Content-Type", "application/json")
+	return fmt.Sprintf(`%s
 
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("API request failed: %v", err)
-	}
-	defer resp.Body.Close()
+    Your previous answer was:
+    %s
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API returned status %d: %s", resp.StatusCode, body)
-	}
+    It was rejected because:
+    - %s
 
-	var result struct {
-		Candidates []struct {
-			Content struct {
-				Parts []struct {
-					Text string `json:"text"`
-				} `json:"parts"`
-			} `json:"content"`
-		} `json:"candidates"`
-	}
+    Answer again with the corrected JSON object only.`, prompt, answer, strings.Join(problems, "\n    - "))
+}
 
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("error decoding response: %v", err)
-	}
+// Planner
+// Generates itineraries from travel data, a draft model writes
+// the plan and a structuring model converts it to json
+type Planner struct {
+	DraftModels     llm.Chain
+	StructureModels llm.Chain
+	// Repairs is how often a non conforming itinerary is sent back
+	Repairs     int
+	MapboxToken string
+	TomTomKey   string
+}
 
-	if len(result.Candidates) == 0 || len(result.Candidates[0].Content.Parts) == 0 {
-		return nil, fmt.Errorf("no valid response from Gemini")
+// NewPlanner
+// builds the planner of cfg, models are tried in their configured order
+func NewPlanner(cfg config.PlannerConfig) (*Planner, error) {
+	providers := llm.NewProviders(llm.Config{
+		CloudflareAccountID: cfg.CloudflareAccountID,
+		CloudflareAPIKey:    cfg.CloudflareAPIKey,
+		GeminiAPIKey:        cfg.GeminiAPIKey,
+		OpenAIBaseURL:       cfg.OpenAIBaseURL,
+		OpenAIAPIKey:        cfg.OpenAIAPIKey,
+		OllamaURL:           cfg.OllamaURL,
+	})
+
+	draft, err := providers.Chain(cfg.DraftModels)
+	if err != nil {
+		return nil, fmt.Errorf("draft models: %w", err)
 	}
-
-	jsonText := result.Candidates[0].Content.Parts[0].Text
-
-	// Clean up JSON wrapping
-	if strings.Contains(jsonText, "```json") {
-		jsonText = strings.SplitN(jsonText, "```json", 2)[1]
-		jsonText = strings.SplitN(jsonText, "```", 2)[0]
+	structure, err := providers.Chain(cfg.StructureModels)
+	if err != nil {
+		return nil, fmt.Errorf("structure models: %w", err)
 	}
 
-	var jsonData map[string]interface{}
-	if err := json.Unmarshal([]byte(jsonText), &jsonData); err != nil {
-		return nil, fmt.Errorf("error unmarshaling JSON: %v\nResponse text: %s", err, jsonText)
-	}
+	return &Planner{DraftModels: draft, StructureModels: structure, Repairs: cfg.RepairAttempts, MapboxToken: cfg.MapboxToken, TomTomKey: cfg.TomTomAPIKey}, nil
+}
 
-	return jsonData, nil
+// ItineraryOutcome
+// classifies the error of a generation for the metrics
+func ItineraryOutcome(err error) string {
+	switch {
+	case err == nil:
+		return metrics.OutcomeSuccess
+	case errors.Is(err, ErrItineraryParse), errors.Is(err, llm.ErrNoOutput):
+		return metrics.OutcomeParseFailure
+	case httpclient.IsTimeout(err):
+		return metrics.OutcomeTimeout
+	default:
+		return metrics.OutcomeProviderError
+	}
 }
 
-func GenerateTravelItinerary(location, userQuery string, numDays int) (map[string]interface{}, error) {
-	godotenv.Load()
+// The stages of a generation, run by the planner worker which keeps
+// the output of each so a retry resumes at the stage that failed, or
+// streamed to the client with progress reported as it is made
+// provider calls are made with ctx
 
-	cloudflareAPIKey := os.Getenv("CLOUDFLARE_API_KEY")
-	geminiAPIKey := os.Getenv("GEMINI_API_KEY")
-	mapboxToken := os.Getenv("MAPBOX_TOKEN")
-	tomtomAPIKey := os.Getenv("TOMTOM_API_KEY")
-	cloudflareAccountID := os.Getenv("CLOUDFLARE_ACC_ID")
+// Collect
+// gathers the travel data of location the draft is based on, as json
+func (p *Planner) Collect(ctx context.Context, location string, numDays int) (json.RawMessage, error) {
+	return p.CollectSteps(ctx, location, numDays, nil)
+}
 
-	fmt.Println("Collecting data...")
-	travelData, err := collectTravelData(location, numDays, mapboxToken, tomtomAPIKey)
+// CollectSteps
+// is Collect calling onStep as each source is queried
+func (p *Planner) CollectSteps(ctx context.Context, location string, numDays int, onStep func(step string)) (json.RawMessage, error) {
+	slog.InfoContext(ctx, "planner: collecting data", "location", location, "days", numDays)
+	travelData, err := collectTravelData(ctx, location, numDays, p.MapboxToken, p.TomTomKey, onStep)
 	if err != nil {
-		fmt.Printf("Error: %v\n", err)
 		return nil, err
 	}
+	return json.Marshal(travelData)
+}
 
-	prompt := formatPrompt(travelData, userQuery, numDays)
+// Draft
+// has the draft models write an itinerary from the collected data
+func (p *Planner) Draft(ctx context.Context, data json.RawMessage, userQuery string, numDays int) (string, error) {
+	return p.DraftStream(ctx, data, userQuery, numDays, nil)
+}
 
-	fmt.Println("Generating itinerary...")
-	itinerary, err := generateItinerary(prompt, cloudflareAccountID, cloudflareAPIKey)
-	if err != nil {
-		fmt.Printf("Error: %v\n", err)
-		return nil, err
+// DraftStream
+// is Draft passing the text to onText as it is written, models
+// of providers that can't stream send it in one piece
+func (p *Planner) DraftStream(ctx context.Context, data json.RawMessage, userQuery string, numDays int, onText func(text string)) (string, error) {
+	var travelData TravelData
+	if err := json.Unmarshal(data, &travelData); err != nil {
+		return "", fmt.Errorf("decoding travel data: %w", err)
+	}
+	req := llm.Request{Prompt: formatPrompt(&travelData, userQuery, numDays), MaxTokens: MaxTokens, Temperature: 0.7}
+
+	slog.InfoContext(ctx, "planner: generating itinerary", "streamed", onText != nil)
+	var draft llm.Completion
+	var model llm.Model
+	var err error
+	if onText == nil {
+		draft, model, err = p.DraftModels.Complete(ctx, req)
+	} else {
+		draft, model, err = p.DraftModels.Stream(ctx, req, onText)
 	}
-
-	fmt.Println(itinerary)
-
-	fmt.Println("Converting to JSON...")
-	jsonItinerary, err := convertToJSON(itinerary, numDays, geminiAPIKey)
 	if err != nil {
-		fmt.Printf("Error: %v\n", err)
-		return nil, err
+		return "", err
 	}
+	slog.DebugContext(ctx, "planner: itinerary drafted", "model", model.String(), "chars", len(draft.Text))
+	return draft.Text, nil
+}
 
-	return jsonItinerary, err
+// Structure
+// converts the draft to an itinerary of numDays days from start
+func (p *Planner) Structure(ctx context.Context, draft string, numDays int, start time.Time) (*itinerary.Itinerary, error) {
+	slog.InfoContext(ctx, "planner: converting to json")
+	return p.convertToJSON(ctx, draft, numDays, start)
 }
@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ErebusAJ/YatraBandhu/internals/llm"
+	"github.com/ErebusAJ/YatraBandhu/internals/metrics"
+)
+
+func TestConvertToJSONRepairs(t *testing.T) {
+	start := time.Date(2025, 5, 1, 9, 0, 0, 0, time.UTC)
+	day := func(n int) string {
+		return fmt.Sprintf(`{"day_number": %d, "date": "2025-05-0%d", "activities": [{"time_slot": "Morning", "name": "Fort"}]}`, n, n)
+	}
+	answer := func(days ...string) string {
+		return `{"destination": "Jaipur, India",
+			"travel_duration": {"total_days": 2, "start_date": "2025-05-01", "end_date": "2025-05-02"},
+			"daily_itinerary": [` + strings.Join(days, ",") + `],
+			"key_highlights": {}, "safety_considerations": {}}`
+	}
+
+	var prompts []string
+	var schemas []json.RawMessage
+	fake := &llm.Fake{JSON: func(model string, req llm.Request, schema json.RawMessage) (string, error) {
+		prompts = append(prompts, req.Prompt)
+		schemas = append(schemas, schema)
+		if len(prompts) == 1 {
+			// a day short, wrapped in a fence
+			return "```json\n" + answer(day(1)) + "\n```", nil
+		}
+		return answer(day(1), day(2)), nil
+	}}
+	chain, _ := llm.Providers{llm.ProviderFake: fake}.Chain([]string{"fake:structure"})
+	p := &Planner{StructureModels: chain, Repairs: 1}
+
+	it, err := p.convertToJSON(context.Background(), "Day 1: Fort. Day 2: Fort.", 2, start)
+	if err != nil || len(it.DailyItinerary) != 2 || len(prompts) != 2 {
+		t.Fatalf("expected a repaired itinerary, got %+v, %v after %d calls", it, err, len(prompts))
+	}
+	if !strings.Contains(prompts[1], "daily_itinerary must have exactly 2 days, got 1") || !strings.HasPrefix(prompts[1], prompts[0]) {
+		t.Fatalf("repair prompt lacks the problems:\n%s", prompts[1])
+	}
+	if schemas[0] == nil {
+		t.Fatal("schema not sent to the model")
+	}
+
+	// out of repairs
+	prompts = nil
+	p.Repairs = 0
+	fake.JSON = func(model string, req llm.Request, schema json.RawMessage) (string, error) {
+		prompts = append(prompts, req.Prompt)
+		return `{"destination": "Jaipur"}`, nil
+	}
+	_, err = p.convertToJSON(context.Background(), "Day 1: Fort.", 2, start)
+	if !errors.Is(err, ErrItineraryParse) || len(prompts) != 1 || ItineraryOutcome(err) != metrics.OutcomeParseFailure {
+		t.Fatalf("expected a parse failure after one call, got %v after %d calls", err, len(prompts))
+	}
+}
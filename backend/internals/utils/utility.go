@@ -1,14 +1,15 @@
 package utils
 
 import (
-	"log"
-	"os"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/google/uuid"
-	"github.com/joho/godotenv"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -20,10 +21,14 @@ func MessageObj(msg string) map[string]any{
 
 // ErrorJSON
 // Used to send error response if a handler malfunctions
-// and logs error to server
+// and logs error to server, binding errors get field details
 func ErrorJSON(c *gin.Context, code int, server string, client string, err error){
-	c.IndentedJSON(code, MessageObj(client))
-	log.Printf("%v : %v", server, err)
+	apiErr := NewAPIError(code, client)
+	if code == 400{
+		apiErr.Details = fieldErrors(err)
+	}
+
+	SendError(c, apiErr, server, err)
 }
 
 
@@ -40,22 +45,28 @@ func HashPassword(password string) (string, error){
 }
 
 
+// Token lifetimes
+const(
+	AccessTokenTTL		=	15 * time.Minute
+	RefreshTokenTTL		=	30 * 24 * time.Hour
+)
+
+
 // GenerateJWT
-// generates a JWT (JSON Web Token) for logging in a user
-func GenerateJWT(userID uuid.UUID, userRole string) (string, error){
+// generates a short lived JWT (JSON Web Token) for logging in a user
+// tokenVersion is checked by the middleware so tokens can be revoked server side
+func GenerateJWT(signedKey string, userID uuid.UUID, userRole string, tokenVersion int32) (string, error){
 	claims := jwt.MapClaims{
 		"user_id" : userID,
 		"user_role" : userRole,
-		"exp" : time.Now().Add(24 * time.Hour).Unix(),
+		"token_version" : tokenVersion,
+		"exp" : time.Now().Add(AccessTokenTTL).Unix(),
 		"issue_at" : time.Now().Unix(),
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
-	godotenv.Load()
-	key := os.Getenv("SIGNED_KEY")
-
-	signedToken, err := token.SignedString([]byte(key))
+	signedToken, err := token.SignedString([]byte(signedKey))
 	if err != nil{
 		return "", err
 	}
@@ -63,3 +74,28 @@ func GenerateJWT(userID uuid.UUID, userRole string) (string, error){
 	return signedToken, nil
 }
 
+
+
+
+// GenerateOpaqueToken
+// generates a random opaque token (refresh, verification links)
+// returns the raw token for the client and its hash to store in db
+func GenerateOpaqueToken() (string, string, error){
+	buf := make([]byte, 32)
+	_, err := rand.Read(buf)
+	if err != nil{
+		return "", "", err
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(buf)
+	return token, HashToken(token), nil
+}
+
+
+// HashToken
+// returns hex encoded sha256 of a token string
+// opaque tokens are only ever stored hashed
+func HashToken(token string) string{
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
\ No newline at end of file
@@ -0,0 +1,9 @@
+-- +goose Up
+UPDATE users SET access_level='user' WHERE access_level IS NULL;
+
+ALTER TABLE users
+ADD CONSTRAINT users_access_level_check CHECK (access_level IN ('user', 'guide', 'admin'));
+
+-- +goose Down
+ALTER TABLE users
+DROP CONSTRAINT users_access_level_check;
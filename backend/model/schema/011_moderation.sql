@@ -0,0 +1,26 @@
+-- +goose Up
+ALTER TABLE users
+ADD COLUMN suspended_at TIMESTAMP;
+
+ALTER TABLE guides
+ADD COLUMN verified BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE reports(
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    reporter_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    target_type TEXT NOT NULL CHECK (target_type IN ('user', 'group', 'guide')),
+    target_id UUID NOT NULL,
+    reason VARCHAR(500) NOT NULL,
+    status TEXT NOT NULL CHECK (status IN ('pending', 'resolved', 'dismissed')) DEFAULT 'pending',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- +goose Down
+DROP TABLE reports;
+
+ALTER TABLE guides
+DROP COLUMN verified;
+
+ALTER TABLE users
+DROP COLUMN suspended_at;
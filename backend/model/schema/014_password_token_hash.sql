@@ -0,0 +1,16 @@
+-- +goose Up
+-- raw tokens can't be converted, outstanding links expire in minutes anyway
+DELETE FROM password_tokens;
+
+ALTER TABLE password_tokens
+RENAME COLUMN token TO token_hash;
+
+CREATE UNIQUE INDEX password_tokens_token_hash_idx ON password_tokens(token_hash);
+
+-- +goose Down
+DROP INDEX password_tokens_token_hash_idx;
+
+DELETE FROM password_tokens;
+
+ALTER TABLE password_tokens
+RENAME COLUMN token_hash TO token;
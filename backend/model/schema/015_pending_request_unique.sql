@@ -0,0 +1,14 @@
+-- +goose Up
+-- keep the oldest of any duplicated pending requests
+DELETE FROM travel_groups_requests r
+USING travel_groups_requests d
+WHERE r.status='pending' AND d.status='pending'
+AND r.group_id=d.group_id AND r.user_id=d.user_id
+AND (COALESCE(r.created_at, '-infinity'), r.id) > (COALESCE(d.created_at, '-infinity'), d.id);
+
+CREATE UNIQUE INDEX travel_groups_requests_pending_idx
+ON travel_groups_requests(group_id, user_id)
+WHERE status='pending';
+
+-- +goose Down
+DROP INDEX travel_groups_requests_pending_idx;
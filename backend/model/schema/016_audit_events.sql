@@ -0,0 +1,39 @@
+-- +goose Up
+-- ids are not foreign keys so events outlive the users, groups
+-- and guides they mention
+CREATE TABLE audit_events(
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    action TEXT NOT NULL,
+    actor_id UUID,
+    subject_id UUID,
+    target_type TEXT NOT NULL DEFAULT '',
+    target_id UUID,
+    metadata JSONB NOT NULL DEFAULT '{}',
+    client_ip TEXT NOT NULL DEFAULT '',
+    request_id TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX audit_events_actor_idx ON audit_events(actor_id, created_at DESC);
+CREATE INDEX audit_events_subject_idx ON audit_events(subject_id, created_at DESC);
+CREATE INDEX audit_events_action_idx ON audit_events(action, created_at DESC);
+
+-- +goose StatementBegin
+CREATE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER audit_events_no_update
+BEFORE UPDATE OR DELETE ON audit_events
+FOR EACH ROW EXECUTE FUNCTION audit_events_append_only();
+
+CREATE TRIGGER audit_events_no_truncate
+BEFORE TRUNCATE ON audit_events
+FOR EACH STATEMENT EXECUTE FUNCTION audit_events_append_only();
+
+-- +goose Down
+DROP TABLE audit_events;
+DROP FUNCTION audit_events_append_only();
@@ -0,0 +1,19 @@
+-- +goose Up
+CREATE TABLE rate_buckets(
+    bucket_key VARCHAR(255) PRIMARY KEY,
+    tokens DOUBLE PRECISION NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- one row per key and calendar period (UTC)
+CREATE TABLE quota_usage(
+    quota_key VARCHAR(255) NOT NULL,
+    period VARCHAR(10) NOT NULL,
+    period_start TIMESTAMP NOT NULL,
+    used INT NOT NULL DEFAULT 0 CHECK (used >= 0),
+    PRIMARY KEY(quota_key, period, period_start)
+);
+
+-- +goose Down
+DROP TABLE quota_usage;
+DROP TABLE rate_buckets;
@@ -0,0 +1,28 @@
+-- +goose Up
+-- AI planner generations, run in the background by the planner worker
+CREATE TABLE planner_jobs(
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    location TEXT NOT NULL,
+    interests TEXT NOT NULL,
+    days INT NOT NULL CHECK (days > 0),
+    start_date DATE NOT NULL,
+    status TEXT NOT NULL CHECK (status IN ('queued', 'running', 'succeeded', 'failed', 'cancelled')) DEFAULT 'queued',
+    stage TEXT NOT NULL CHECK (stage IN ('queued', 'collecting_data', 'drafting', 'structuring', 'saved')) DEFAULT 'queued',
+    -- output of the finished stages, a retry resumes after them
+    travel_data TEXT,
+    draft TEXT,
+    plan_id UUID REFERENCES ai_plan(id) ON DELETE SET NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    -- when a queued job may run, or when the lease of a running one expires
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    finished_at TIMESTAMP
+);
+
+CREATE INDEX planner_jobs_pending_idx ON planner_jobs(next_attempt_at) WHERE status IN ('queued', 'running');
+
+-- +goose Down
+DROP TABLE planner_jobs;
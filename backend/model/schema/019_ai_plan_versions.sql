@@ -0,0 +1,25 @@
+-- +goose Up
+-- Saved AI plans get a title and a history, raw_data stays the
+-- current itinerary and each change to it is kept as a version
+ALTER TABLE ai_plan ADD COLUMN title TEXT NOT NULL DEFAULT '';
+ALTER TABLE ai_plan ADD COLUMN version INT NOT NULL DEFAULT 1;
+
+UPDATE ai_plan SET title = COALESCE(raw_data->>'destination', '');
+
+CREATE TABLE ai_plan_versions(
+    plan_id UUID NOT NULL REFERENCES ai_plan(id) ON DELETE CASCADE,
+    version INT NOT NULL CHECK (version > 0),
+    raw_data JSONB NOT NULL,
+    source TEXT NOT NULL CHECK (source IN ('generated', 'regenerated', 'edited', 'restored')),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (plan_id, version)
+);
+
+-- existing plans start their history at version 1
+INSERT INTO ai_plan_versions(plan_id, version, raw_data, source, created_at)
+SELECT id, 1, raw_data, 'generated', created_at FROM ai_plan;
+
+-- +goose Down
+DROP TABLE ai_plan_versions;
+ALTER TABLE ai_plan DROP COLUMN version;
+ALTER TABLE ai_plan DROP COLUMN title;
@@ -0,0 +1,7 @@
+-- +goose Up
+-- The template a mail was rendered from, so it can be named in
+-- logs and the admin list without its body
+ALTER TABLE email_outbox ADD COLUMN template TEXT NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE email_outbox DROP COLUMN template;
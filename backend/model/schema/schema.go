@@ -0,0 +1,11 @@
+// Package schema embeds the goose migrations so the binary
+// can apply them without the source tree
+package schema
+
+import "embed"
+
+// Migrations
+// every NNN_name.sql file of this directory
+//
+//go:embed *.sql
+var Migrations embed.FS
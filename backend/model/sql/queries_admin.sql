@@ -0,0 +1,58 @@
+-- name: SearchUsers :many
+SELECT id, name, email, phone_number, access_level, verified_status, suspended_at, created_at, last_logged_in
+FROM users
+WHERE (sqlc.arg(search)::text = ''
+    OR name ILIKE '%' || sqlc.arg(search)::text || '%'
+    OR email ILIKE '%' || sqlc.arg(search)::text || '%')
+AND (NOT sqlc.arg(has_cursor)::boolean OR CASE sqlc.arg(sort)::text
+    WHEN 'name' THEN (name, id) > (sqlc.arg(after_text)::text, sqlc.arg(after_id)::uuid)
+    WHEN '-name' THEN (name, id) < (sqlc.arg(after_text)::text, sqlc.arg(after_id)::uuid)
+    WHEN 'created_at' THEN (created_at, id) > (sqlc.arg(after_time)::timestamp, sqlc.arg(after_id)::uuid)
+    WHEN '-created_at' THEN (created_at, id) < (sqlc.arg(after_time)::timestamp, sqlc.arg(after_id)::uuid)
+END)
+ORDER BY
+    CASE WHEN sqlc.arg(sort)::text = 'name' THEN name END,
+    CASE WHEN sqlc.arg(sort)::text = '-name' THEN name END DESC,
+    CASE WHEN sqlc.arg(sort)::text = 'created_at' THEN created_at END,
+    CASE WHEN sqlc.arg(sort)::text = '-created_at' THEN created_at END DESC,
+    CASE WHEN sqlc.arg(sort)::text LIKE '-%' THEN id END DESC,
+    id
+LIMIT sqlc.arg(page_limit);
+
+-- name: SuspendUser :exec
+UPDATE users
+SET suspended_at=CURRENT_TIMESTAMP, token_version=COALESCE(token_version, 0) + 1, updated_at=CURRENT_TIMESTAMP
+WHERE id=$1;
+
+-- name: UnsuspendUser :exec
+UPDATE users
+SET suspended_at=NULL, updated_at=CURRENT_TIMESTAMP
+WHERE id=$1;
+
+-- name: SetGuideVerified :execrows
+UPDATE guides
+SET verified=$1
+WHERE id=$2;
+
+-- name: CreateReport :exec
+INSERT INTO reports(reporter_id, target_type, target_id, reason)
+VALUES($1, $2, $3, $4);
+
+-- name: GetReportsByStatus :many
+SELECT * FROM reports
+WHERE status = sqlc.arg(status)
+AND (NOT sqlc.arg(has_cursor)::boolean OR CASE sqlc.arg(sort)::text
+    WHEN 'created_at' THEN (created_at, id) > (sqlc.arg(after_time)::timestamp, sqlc.arg(after_id)::uuid)
+    WHEN '-created_at' THEN (created_at, id) < (sqlc.arg(after_time)::timestamp, sqlc.arg(after_id)::uuid)
+END)
+ORDER BY
+    CASE WHEN sqlc.arg(sort)::text = 'created_at' THEN created_at END,
+    CASE WHEN sqlc.arg(sort)::text = '-created_at' THEN created_at END DESC,
+    CASE WHEN sqlc.arg(sort)::text LIKE '-%' THEN id END DESC,
+    id
+LIMIT sqlc.arg(page_limit);
+
+-- name: UpdateReportStatus :execrows
+UPDATE reports
+SET status=$1, updated_at=CURRENT_TIMESTAMP
+WHERE id=$2;
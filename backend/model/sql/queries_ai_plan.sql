@@ -1,7 +1,92 @@
--- name: SavePlan :exec
-INSERT INTO ai_plan(user_id, raw_data)
-VALUES($1, $2);
+-- name: SavePlan :one
+WITH plan AS (
+    INSERT INTO ai_plan(user_id, title, raw_data)
+    VALUES($1, $2, $3)
+    RETURNING id, raw_data
+)
+INSERT INTO ai_plan_versions(plan_id, version, raw_data, source)
+SELECT id, 1, raw_data, 'generated' FROM plan
+RETURNING plan_id;
 
 -- name: RetreivePlan :one
 SELECT * FROM ai_plan
-WHERE user_id=$1;
\ No newline at end of file
+WHERE user_id=$1;
+
+-- name: GetUserAIPlans :many
+SELECT * FROM ai_plan
+WHERE user_id=$1
+ORDER BY created_at DESC;
+
+-- name: GetAIPlan :one
+SELECT * FROM ai_plan
+WHERE id=$1 AND user_id=$2;
+
+-- name: ListAIPlans :many
+SELECT id, title, version, created_at, updated_at FROM ai_plan
+WHERE user_id = sqlc.arg(user_id)
+AND (sqlc.arg(title)::text = '' OR title ILIKE '%' || sqlc.arg(title)::text || '%')
+AND (NOT sqlc.arg(has_cursor)::boolean OR CASE sqlc.arg(sort)::text
+    WHEN 'title' THEN (title, id) > (sqlc.arg(after_text)::text, sqlc.arg(after_id)::uuid)
+    WHEN '-title' THEN (title, id) < (sqlc.arg(after_text)::text, sqlc.arg(after_id)::uuid)
+    WHEN 'created_at' THEN (created_at, id) > (sqlc.arg(after_time)::timestamp, sqlc.arg(after_id)::uuid)
+    WHEN '-created_at' THEN (created_at, id) < (sqlc.arg(after_time)::timestamp, sqlc.arg(after_id)::uuid)
+    WHEN 'updated_at' THEN (updated_at, id) > (sqlc.arg(after_time)::timestamp, sqlc.arg(after_id)::uuid)
+    WHEN '-updated_at' THEN (updated_at, id) < (sqlc.arg(after_time)::timestamp, sqlc.arg(after_id)::uuid)
+END)
+ORDER BY
+    CASE WHEN sqlc.arg(sort)::text = 'title' THEN title END,
+    CASE WHEN sqlc.arg(sort)::text = '-title' THEN title END DESC,
+    CASE WHEN sqlc.arg(sort)::text = 'created_at' THEN created_at END,
+    CASE WHEN sqlc.arg(sort)::text = '-created_at' THEN created_at END DESC,
+    CASE WHEN sqlc.arg(sort)::text = 'updated_at' THEN updated_at END,
+    CASE WHEN sqlc.arg(sort)::text = '-updated_at' THEN updated_at END DESC,
+    CASE WHEN sqlc.arg(sort)::text LIKE '-%' THEN id END DESC,
+    id
+LIMIT sqlc.arg(page_limit);
+
+-- name: RenameAIPlan :execrows
+UPDATE ai_plan
+SET title=$1, updated_at=CURRENT_TIMESTAMP
+WHERE id=$2 AND user_id=$3;
+
+-- name: AddAIPlanVersion :one
+WITH plan AS (
+    UPDATE ai_plan
+    SET raw_data=sqlc.arg(raw_data), version=version+1, updated_at=CURRENT_TIMESTAMP
+    WHERE id=sqlc.arg(id) AND user_id=sqlc.arg(user_id)
+    RETURNING id, version, raw_data
+)
+INSERT INTO ai_plan_versions(plan_id, version, raw_data, source)
+SELECT id, version, raw_data, sqlc.arg(source)::text FROM plan
+RETURNING version;
+
+-- name: DeleteAIPlan :execrows
+DELETE FROM ai_plan
+WHERE id=$1 AND user_id=$2;
+
+-- name: GetAIPlanVersion :one
+SELECT * FROM ai_plan_versions
+WHERE plan_id=$1 AND version=$2;
+
+-- name: ListAIPlanVersions :many
+SELECT version, source, created_at FROM ai_plan_versions
+WHERE plan_id = sqlc.arg(plan_id)
+AND (NOT sqlc.arg(has_cursor)::boolean OR CASE sqlc.arg(sort)::text
+    WHEN 'version' THEN version > sqlc.arg(after_num)::int
+    WHEN '-version' THEN version < sqlc.arg(after_num)::int
+END)
+ORDER BY
+    CASE WHEN sqlc.arg(sort)::text = 'version' THEN version END,
+    CASE WHEN sqlc.arg(sort)::text = '-version' THEN version END DESC
+LIMIT sqlc.arg(page_limit);
+
+-- name: GetUserAIPlanVersions :many
+SELECT v.* FROM ai_plan_versions v
+JOIN ai_plan p ON p.id = v.plan_id
+WHERE p.user_id=$1
+ORDER BY v.plan_id, v.version;
+
+-- name: DeleteOrphanedPlans :execrows
+DELETE FROM ai_plan
+WHERE updated_at < $1
+    AND NOT EXISTS (SELECT 1 FROM ai_plan_versions WHERE ai_plan_versions.plan_id = ai_plan.id);
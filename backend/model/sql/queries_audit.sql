@@ -0,0 +1,18 @@
+-- name: InsertAuditEvent :exec
+INSERT INTO audit_events(action, actor_id, subject_id, target_type, target_id, metadata, client_ip, request_id)
+VALUES($1, $2, $3, $4, $5, $6, $7, $8);
+
+-- name: ListAuditEvents :many
+SELECT * FROM audit_events
+WHERE (sqlc.narg(user_id)::uuid IS NULL OR actor_id=sqlc.narg(user_id) OR subject_id=sqlc.narg(user_id))
+AND (sqlc.arg(action)::text = '' OR action=sqlc.arg(action)::text)
+AND (NOT sqlc.arg(has_cursor)::boolean OR CASE sqlc.arg(sort)::text
+    WHEN 'created_at' THEN (created_at, id) > (sqlc.arg(after_time)::timestamp, sqlc.arg(after_id)::uuid)
+    WHEN '-created_at' THEN (created_at, id) < (sqlc.arg(after_time)::timestamp, sqlc.arg(after_id)::uuid)
+END)
+ORDER BY
+    CASE WHEN sqlc.arg(sort)::text = 'created_at' THEN created_at END,
+    CASE WHEN sqlc.arg(sort)::text = '-created_at' THEN created_at END DESC,
+    CASE WHEN sqlc.arg(sort)::text LIKE '-%' THEN id END DESC,
+    id
+LIMIT sqlc.arg(page_limit);
@@ -0,0 +1,48 @@
+-- name: EnqueueEmail :exec
+INSERT INTO email_outbox(recipient, subject, text_body, html_body, template)
+VALUES($1, $2, $3, $4, $5);
+
+-- name: ClaimPendingEmails :many
+UPDATE email_outbox
+SET next_attempt_at=$1
+WHERE id IN (
+    SELECT id FROM email_outbox
+    WHERE status='pending' AND next_attempt_at <= CURRENT_TIMESTAMP
+    ORDER BY next_attempt_at
+    LIMIT $2
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: MarkEmailSent :exec
+UPDATE email_outbox
+SET status='sent', attempts=attempts + 1, sent_at=CURRENT_TIMESTAMP, last_error=NULL, text_body='', html_body=''
+WHERE id=$1;
+
+-- name: MarkEmailFailed :exec
+UPDATE email_outbox
+SET status=$1, attempts=attempts + 1, last_error=$2, next_attempt_at=$3
+WHERE id=$4;
+
+-- name: ListOutboxByStatus :many
+SELECT id, recipient, subject, status, attempts, last_error, next_attempt_at, created_at, sent_at, template FROM email_outbox
+WHERE status = sqlc.arg(status)
+AND (NOT sqlc.arg(has_cursor)::boolean OR CASE sqlc.arg(sort)::text
+    WHEN 'created_at' THEN (created_at, id) > (sqlc.arg(after_time)::timestamp, sqlc.arg(after_id)::uuid)
+    WHEN '-created_at' THEN (created_at, id) < (sqlc.arg(after_time)::timestamp, sqlc.arg(after_id)::uuid)
+END)
+ORDER BY
+    CASE WHEN sqlc.arg(sort)::text = 'created_at' THEN created_at END,
+    CASE WHEN sqlc.arg(sort)::text = '-created_at' THEN created_at END DESC,
+    CASE WHEN sqlc.arg(sort)::text LIKE '-%' THEN id END DESC,
+    id
+LIMIT sqlc.arg(page_limit);
+
+-- name: RequeueEmail :execrows
+UPDATE email_outbox
+SET status='pending', attempts=0, last_error=NULL, next_attempt_at=CURRENT_TIMESTAMP
+WHERE id=$1 AND status='dead';
+
+-- name: DeleteFinishedEmails :execrows
+DELETE FROM email_outbox
+WHERE status IN ('sent', 'dead') AND created_at < $1;
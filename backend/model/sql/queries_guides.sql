@@ -1,8 +1,9 @@
--- name: AddGuide :exec
+-- name: AddGuide :one
 INSERT INTO guides(name, bio, location, expertise, rating, hourly_rate)
-VALUES($1, $2, $3, $4, $5, $6);
+VALUES($1, $2, $3, $4, $5, $6)
+RETURNING id;
 
--- name: UpdateGuideAvail :exec
+-- name: UpdateGuideAvail :execrows
 UPDATE guides 
 SET available=$1
 WHERE id=$2;
@@ -11,9 +12,31 @@ WHERE id=$2;
 INSERT INTO guide_bookings(guide_id, group_id, status)
 VALUES($1, $2, $3);
 
--- name: GetGuideDetails :many
+-- name: ListGuides :many
 SELECT * FROM guides
-WHERE location=$1 AND available=TRUE;
+WHERE available = TRUE
+AND (sqlc.arg(location)::text = '' OR lower(location) = lower(sqlc.arg(location)::text))
+AND (sqlc.arg(expertise)::text = '' OR expertise ILIKE '%' || sqlc.arg(expertise)::text || '%')
+AND rating >= sqlc.arg(min_rating)::int
+AND (sqlc.narg(max_rate)::numeric IS NULL OR hourly_rate <= sqlc.narg(max_rate)::numeric)
+AND (NOT sqlc.arg(has_cursor)::boolean OR CASE sqlc.arg(sort)::text
+    WHEN 'name' THEN (name, id) > (sqlc.arg(after_text)::text, sqlc.arg(after_id)::uuid)
+    WHEN '-name' THEN (name, id) < (sqlc.arg(after_text)::text, sqlc.arg(after_id)::uuid)
+    WHEN 'rating' THEN (rating, id) > (sqlc.arg(after_num)::numeric, sqlc.arg(after_id)::uuid)
+    WHEN '-rating' THEN (rating, id) < (sqlc.arg(after_num)::numeric, sqlc.arg(after_id)::uuid)
+    WHEN 'rate' THEN (hourly_rate, id) > (sqlc.arg(after_num)::numeric, sqlc.arg(after_id)::uuid)
+    WHEN '-rate' THEN (hourly_rate, id) < (sqlc.arg(after_num)::numeric, sqlc.arg(after_id)::uuid)
+END)
+ORDER BY
+    CASE WHEN sqlc.arg(sort)::text = 'name' THEN name END,
+    CASE WHEN sqlc.arg(sort)::text = '-name' THEN name END DESC,
+    CASE WHEN sqlc.arg(sort)::text = 'rating' THEN rating END,
+    CASE WHEN sqlc.arg(sort)::text = '-rating' THEN rating END DESC,
+    CASE WHEN sqlc.arg(sort)::text = 'rate' THEN hourly_rate END,
+    CASE WHEN sqlc.arg(sort)::text = '-rate' THEN hourly_rate END DESC,
+    CASE WHEN sqlc.arg(sort)::text LIKE '-%' THEN id END DESC,
+    id
+LIMIT sqlc.arg(page_limit);
 
 -- name: SendGuideRequest :exec
 INSERT INTO guide_booking_requests(group_id, user_id, guide_id)
@@ -27,3 +50,11 @@ WHERE group_id=$1 AND guide_id=$2;
 -- name: RejectGuideRequest :exec
 DELETE FROM guide_booking_requests
 WHERE guide_id=$1 AND group_id=$2;
+
+-- name: GetGuideByID :one
+SELECT * FROM guides
+WHERE id=$1;
+
+-- name: DeleteStaleGuideRequests :execrows
+DELETE FROM guide_booking_requests
+WHERE status='pending' AND created_at < $1;
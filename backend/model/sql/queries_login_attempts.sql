@@ -0,0 +1,26 @@
+-- name: EnsureLoginAttempt :exec
+INSERT INTO login_attempts(attempt_key)
+VALUES($1)
+ON CONFLICT (attempt_key) DO NOTHING;
+
+-- name: GetLoginAttempt :one
+SELECT * FROM login_attempts
+WHERE attempt_key=$1;
+
+-- name: GetLoginAttemptForUpdate :one
+SELECT * FROM login_attempts
+WHERE attempt_key=$1
+FOR UPDATE;
+
+-- name: UpdateLoginAttempt :exec
+UPDATE login_attempts
+SET failures=$1, last_failure=$2, blocked_until=$3
+WHERE attempt_key=$4;
+
+-- name: DeleteLoginAttempt :exec
+DELETE FROM login_attempts
+WHERE attempt_key=$1;
+
+-- name: DeleteStaleLoginAttempts :exec
+DELETE FROM login_attempts
+WHERE last_failure < $1 AND blocked_until < CURRENT_TIMESTAMP;
@@ -1,16 +1,21 @@
 -- name: InsertToken :exec
-INSERT INTO password_tokens(user_id, token, expires_at)
+INSERT INTO password_tokens(user_id, token_hash, expires_at)
 VALUES($1, $2, $3);
 
--- name: GetUserToken :one
-SELECT * FROM password_tokens
-WHERE token=$1;
+-- name: ConsumeUserToken :one
+DELETE FROM password_tokens
+WHERE token_hash=$1 AND expires_at > CURRENT_TIMESTAMP
+RETURNING user_id;
+
+-- name: DeleteUserTokens :exec
+DELETE FROM password_tokens
+WHERE user_id=$1;
 
--- name: DeleteToken :exec
+-- name: DeleteExpiredTokens :execrows
 DELETE FROM password_tokens
-WHERE token=$1;
+WHERE expires_at < CURRENT_TIMESTAMP;
 
 -- name: UpdateUserPassword :exec
 UPDATE users
-SET password_hash=$1
-WHERE id=$2;
\ No newline at end of file
+SET password_hash=$1, token_version=COALESCE(token_version, 0) + 1, updated_at=CURRENT_TIMESTAMP
+WHERE id=$2;
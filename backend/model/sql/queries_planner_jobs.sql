@@ -0,0 +1,59 @@
+-- name: CreatePlannerJob :one
+INSERT INTO planner_jobs(user_id, location, interests, days, start_date, plan_id)
+VALUES($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: GetPlannerJob :one
+SELECT * FROM planner_jobs
+WHERE id=$1 AND user_id=$2;
+
+-- name: ClaimPlannerJobs :many
+UPDATE planner_jobs
+SET status='running', next_attempt_at=$1, updated_at=CURRENT_TIMESTAMP
+WHERE id IN (
+    SELECT id FROM planner_jobs
+    WHERE status IN ('queued', 'running') AND next_attempt_at <= CURRENT_TIMESTAMP
+    ORDER BY next_attempt_at
+    LIMIT $2
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: ExtendPlannerJobLease :execrows
+UPDATE planner_jobs
+SET next_attempt_at=$1
+WHERE id=$2 AND status='running';
+
+-- name: AdvancePlannerJob :execrows
+UPDATE planner_jobs
+SET stage=$1, travel_data=$2, draft=$3, updated_at=CURRENT_TIMESTAMP
+WHERE id=$4 AND status='running';
+
+-- name: FinishPlannerJob :execrows
+UPDATE planner_jobs
+SET status='succeeded', stage='saved', plan_id=$1, last_error=NULL, updated_at=CURRENT_TIMESTAMP, finished_at=CURRENT_TIMESTAMP
+WHERE id=$2 AND status='running';
+
+-- name: RetryPlannerJob :execrows
+UPDATE planner_jobs
+SET status='queued', attempts=attempts + 1, last_error=$1, next_attempt_at=$2, updated_at=CURRENT_TIMESTAMP
+WHERE id=$3 AND status='running';
+
+-- name: FailPlannerJob :execrows
+UPDATE planner_jobs
+SET status='failed', attempts=attempts + 1, last_error=$1, updated_at=CURRENT_TIMESTAMP, finished_at=CURRENT_TIMESTAMP
+WHERE id=$2 AND status='running';
+
+-- name: ReleasePlannerJob :exec
+UPDATE planner_jobs
+SET status='queued', next_attempt_at=CURRENT_TIMESTAMP, updated_at=CURRENT_TIMESTAMP
+WHERE id=$1 AND status='running';
+
+-- name: CancelPlannerJob :execrows
+UPDATE planner_jobs
+SET status='cancelled', updated_at=CURRENT_TIMESTAMP, finished_at=CURRENT_TIMESTAMP
+WHERE id=$1 AND user_id=$2 AND status IN ('queued', 'running');
+
+-- name: DeleteFinishedPlannerJobs :execrows
+DELETE FROM planner_jobs
+WHERE finished_at < $1;
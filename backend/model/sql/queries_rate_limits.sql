@@ -0,0 +1,46 @@
+-- name: EnsureRateBucket :exec
+INSERT INTO rate_buckets(bucket_key, tokens, updated_at)
+VALUES($1, $2, $3)
+ON CONFLICT (bucket_key) DO NOTHING;
+
+-- name: GetRateBucketForUpdate :one
+SELECT * FROM rate_buckets
+WHERE bucket_key=$1
+FOR UPDATE;
+
+-- name: UpdateRateBucket :exec
+UPDATE rate_buckets
+SET tokens=$1, updated_at=$2
+WHERE bucket_key=$3;
+
+-- name: DeleteStaleRateBuckets :execrows
+DELETE FROM rate_buckets
+WHERE updated_at < $1;
+
+-- name: EnsureQuotaUsage :exec
+INSERT INTO quota_usage(quota_key, period, period_start)
+VALUES($1, $2, $3)
+ON CONFLICT (quota_key, period, period_start) DO NOTHING;
+
+-- name: GetQuotaUsed :one
+SELECT used FROM quota_usage
+WHERE quota_key=$1 AND period=$2 AND period_start=$3;
+
+-- name: GetQuotaUsedForUpdate :one
+SELECT used FROM quota_usage
+WHERE quota_key=$1 AND period=$2 AND period_start=$3
+FOR UPDATE;
+
+-- name: SetQuotaUsed :exec
+UPDATE quota_usage
+SET used=$1
+WHERE quota_key=$2 AND period=$3 AND period_start=$4;
+
+-- name: RefundQuotaUsage :exec
+UPDATE quota_usage
+SET used=used - 1
+WHERE quota_key=$1 AND period=$2 AND period_start=$3 AND used > 0;
+
+-- name: DeleteExpiredQuotaUsage :execrows
+DELETE FROM quota_usage
+WHERE period_start < $1;
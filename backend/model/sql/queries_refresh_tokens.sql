@@ -0,0 +1,17 @@
+-- name: InsertRefreshToken :exec
+INSERT INTO refresh_tokens(user_id, token_hash, expires_at)
+VALUES($1, $2, $3);
+
+-- name: GetRefreshToken :one
+SELECT * FROM refresh_tokens
+WHERE token_hash=$1;
+
+-- name: RevokeRefreshToken :execrows
+UPDATE refresh_tokens
+SET revoked_at=CURRENT_TIMESTAMP
+WHERE id=$1 AND revoked_at IS NULL;
+
+-- name: RevokeUserRefreshTokens :exec
+UPDATE refresh_tokens
+SET revoked_at=CURRENT_TIMESTAMP
+WHERE user_id=$1 AND revoked_at IS NULL;
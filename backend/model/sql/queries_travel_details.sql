@@ -1,9 +1,33 @@
--- name: AddTravelDetails :exec
+-- name: AddTravelDetails :one
 INSERT INTO travel_plan_details(creator_id, place, start_date, end_date, trip_type, pets, interests)
-VALUES($1, $2, $3, $4, $5, $6, $7);
+VALUES($1, $2, $3, $4, $5, $6, $7)
+RETURNING id;
 
 -- name: GetUserPlansDetails :many
 SELECT t.place, t.start_date, t.end_date, t.trip_type, t.pets, t.interests
 FROM travel_plan_details as t
 INNER JOIN users ON users.id = t.creator_id
-WHERE users.id = $1;
\ No newline at end of file
+WHERE users.id = $1;
+
+-- name: ListUserPlans :many
+SELECT t.id, t.place, t.start_date, t.end_date, t.trip_type, t.pets, t.interests, t.created_at
+FROM travel_plan_details as t
+WHERE t.creator_id = sqlc.arg(creator_id)
+AND (sqlc.arg(place)::text = '' OR t.place ILIKE '%' || sqlc.arg(place)::text || '%')
+AND (sqlc.arg(trip_type)::text = '' OR t.trip_type = sqlc.arg(trip_type)::text)
+AND (sqlc.arg(from_date)::text = '' OR t.start_date >= sqlc.arg(from_date)::text)
+AND (sqlc.arg(to_date)::text = '' OR t.start_date <= sqlc.arg(to_date)::text)
+AND (NOT sqlc.arg(has_cursor)::boolean OR CASE sqlc.arg(sort)::text
+    WHEN 'start_date' THEN (t.start_date, t.id) > (sqlc.arg(after_text)::text, sqlc.arg(after_id)::uuid)
+    WHEN '-start_date' THEN (t.start_date, t.id) < (sqlc.arg(after_text)::text, sqlc.arg(after_id)::uuid)
+    WHEN 'created_at' THEN (t.created_at, t.id) > (sqlc.arg(after_time)::timestamp, sqlc.arg(after_id)::uuid)
+    WHEN '-created_at' THEN (t.created_at, t.id) < (sqlc.arg(after_time)::timestamp, sqlc.arg(after_id)::uuid)
+END)
+ORDER BY
+    CASE WHEN sqlc.arg(sort)::text = 'start_date' THEN t.start_date END,
+    CASE WHEN sqlc.arg(sort)::text = '-start_date' THEN t.start_date END DESC,
+    CASE WHEN sqlc.arg(sort)::text = 'created_at' THEN t.created_at END,
+    CASE WHEN sqlc.arg(sort)::text = '-created_at' THEN t.created_at END DESC,
+    CASE WHEN sqlc.arg(sort)::text LIKE '-%' THEN t.id END DESC,
+    t.id
+LIMIT sqlc.arg(page_limit);
@@ -36,3 +36,52 @@ SELECT g.id, g.creator_id, g.name, g.description, g.plan_id  FROM travel_groups
 INNER JOIN travel_groups_members t ON t.group_id = g.id
 WHERE t.user_id=$1;
 
+
+-- name: ListUserGroups :many
+SELECT g.id, g.creator_id, g.name, g.description, g.plan_id, p.place, p.start_date, g.created_at
+FROM travel_groups g
+INNER JOIN travel_groups_members t ON t.group_id = g.id
+INNER JOIN travel_plan_details p ON p.id = g.plan_id
+WHERE t.user_id = sqlc.arg(user_id)
+AND (sqlc.arg(place)::text = '' OR p.place ILIKE '%' || sqlc.arg(place)::text || '%')
+AND (sqlc.arg(from_date)::text = '' OR p.start_date >= sqlc.arg(from_date)::text)
+AND (sqlc.arg(to_date)::text = '' OR p.start_date <= sqlc.arg(to_date)::text)
+AND (NOT sqlc.arg(has_cursor)::boolean OR CASE sqlc.arg(sort)::text
+    WHEN 'name' THEN (g.name, g.id) > (sqlc.arg(after_text)::text, sqlc.arg(after_id)::uuid)
+    WHEN '-name' THEN (g.name, g.id) < (sqlc.arg(after_text)::text, sqlc.arg(after_id)::uuid)
+    WHEN 'start_date' THEN (p.start_date, g.id) > (sqlc.arg(after_text)::text, sqlc.arg(after_id)::uuid)
+    WHEN '-start_date' THEN (p.start_date, g.id) < (sqlc.arg(after_text)::text, sqlc.arg(after_id)::uuid)
+    WHEN 'created_at' THEN (g.created_at, g.id) > (sqlc.arg(after_time)::timestamp, sqlc.arg(after_id)::uuid)
+    WHEN '-created_at' THEN (g.created_at, g.id) < (sqlc.arg(after_time)::timestamp, sqlc.arg(after_id)::uuid)
+END)
+ORDER BY
+    CASE WHEN sqlc.arg(sort)::text = 'name' THEN g.name END,
+    CASE WHEN sqlc.arg(sort)::text = '-name' THEN g.name END DESC,
+    CASE WHEN sqlc.arg(sort)::text = 'start_date' THEN p.start_date END,
+    CASE WHEN sqlc.arg(sort)::text = '-start_date' THEN p.start_date END DESC,
+    CASE WHEN sqlc.arg(sort)::text = 'created_at' THEN g.created_at END,
+    CASE WHEN sqlc.arg(sort)::text = '-created_at' THEN g.created_at END DESC,
+    CASE WHEN sqlc.arg(sort)::text LIKE '-%' THEN g.id END DESC,
+    g.id
+LIMIT sqlc.arg(page_limit);
+
+-- name: ListGroupMembers :many
+SELECT u.id, u.name, u.age, u.phone_number, u.email, m.created_at AS joined_at
+FROM users u
+INNER JOIN travel_groups_members m ON m.user_id = u.id
+WHERE m.group_id = sqlc.arg(group_id)
+AND (sqlc.arg(name)::text = '' OR u.name ILIKE '%' || sqlc.arg(name)::text || '%')
+AND (NOT sqlc.arg(has_cursor)::boolean OR CASE sqlc.arg(sort)::text
+    WHEN 'name' THEN (u.name, u.id) > (sqlc.arg(after_text)::text, sqlc.arg(after_id)::uuid)
+    WHEN '-name' THEN (u.name, u.id) < (sqlc.arg(after_text)::text, sqlc.arg(after_id)::uuid)
+    WHEN 'joined_at' THEN (m.created_at, u.id) > (sqlc.arg(after_time)::timestamp, sqlc.arg(after_id)::uuid)
+    WHEN '-joined_at' THEN (m.created_at, u.id) < (sqlc.arg(after_time)::timestamp, sqlc.arg(after_id)::uuid)
+END)
+ORDER BY
+    CASE WHEN sqlc.arg(sort)::text = 'name' THEN u.name END,
+    CASE WHEN sqlc.arg(sort)::text = '-name' THEN u.name END DESC,
+    CASE WHEN sqlc.arg(sort)::text = 'joined_at' THEN m.created_at END,
+    CASE WHEN sqlc.arg(sort)::text = '-joined_at' THEN m.created_at END DESC,
+    CASE WHEN sqlc.arg(sort)::text LIKE '-%' THEN u.id END DESC,
+    u.id
+LIMIT sqlc.arg(page_limit);
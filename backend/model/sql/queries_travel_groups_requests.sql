@@ -2,23 +2,45 @@
 INSERT INTO travel_groups_requests(group_id, user_id)
 VALUES($1, $2);
 
--- name: UpdateRequest :exec
+-- name: UpdateRequest :execrows
 UPDATE travel_groups_requests
-SET status=$1
-WHERE group_id=$2 AND user_id=$3;
+SET status=$1, updated_at=CURRENT_TIMESTAMP
+WHERE group_id=$2 AND user_id=$3 AND status='pending';
 
 -- name: RejectRequest :exec
 DELETE FROM travel_groups_requests
 WHERE group_id=$1 AND user_id=$2;
 
--- name: GetUserGroupRequests :many
+-- name: ListGroupRequests :many
 SELECT r.id AS request_id, r.group_id, g.name, u.id AS sender_id, u.name AS sender_name, r.status, r.created_at
 FROM travel_groups_requests as r
 JOIN travel_groups g ON r.group_id = g.id
 JOIN users u ON r.user_id = u.id
-WHERE g.creator_id =$1
+WHERE g.creator_id = sqlc.arg(creator_id)
 AND r.status = 'pending'
-ORDER BY r.created_at DESC;
-
+AND (sqlc.narg(group_id)::uuid IS NULL OR r.group_id = sqlc.narg(group_id)::uuid)
+AND (NOT sqlc.arg(has_cursor)::boolean OR CASE sqlc.arg(sort)::text
+    WHEN 'created_at' THEN (r.created_at, r.id) > (sqlc.arg(after_time)::timestamp, sqlc.arg(after_id)::uuid)
+    WHEN '-created_at' THEN (r.created_at, r.id) < (sqlc.arg(after_time)::timestamp, sqlc.arg(after_id)::uuid)
+    WHEN 'sender_name' THEN (u.name, r.id) > (sqlc.arg(after_text)::text, sqlc.arg(after_id)::uuid)
+    WHEN '-sender_name' THEN (u.name, r.id) < (sqlc.arg(after_text)::text, sqlc.arg(after_id)::uuid)
+END)
+ORDER BY
+    CASE WHEN sqlc.arg(sort)::text = 'created_at' THEN r.created_at END,
+    CASE WHEN sqlc.arg(sort)::text = '-created_at' THEN r.created_at END DESC,
+    CASE WHEN sqlc.arg(sort)::text = 'sender_name' THEN u.name END,
+    CASE WHEN sqlc.arg(sort)::text = '-sender_name' THEN u.name END DESC,
+    CASE WHEN sqlc.arg(sort)::text LIKE '-%' THEN r.id END DESC,
+    r.id
+LIMIT sqlc.arg(page_limit);
 
+-- name: GetRequestsSentByUser :many
+SELECT r.id, r.group_id, g.name, r.status, r.created_at
+FROM travel_groups_requests as r
+JOIN travel_groups g ON r.group_id = g.id
+WHERE r.user_id=$1
+ORDER BY r.created_at DESC;
 
+-- name: DeleteStalePendingRequests :execrows
+DELETE FROM travel_groups_requests
+WHERE status='pending' AND created_at < $1;
@@ -1,9 +1,10 @@
 -- name: GetUsers :many
 SELECT * FROM users;
 
--- name: RegisterUser :exec
+-- name: RegisterUser :one
 INSERT INTO users(name, age, phone_number, email, password_hash)
-VALUES ($1, $2, $3, $4, $5);
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id;
 
 -- name: GetUserByID :one
 SELECT * FROM users
@@ -20,5 +21,29 @@ WHERE id=$1;
 
 
 -- name: GetUserByEmail :one
-SELECT id, email, password_hash, access_level FROM users
+SELECT id, email, password_hash, access_level, token_version, verified_status, suspended_at FROM users
 WHERE email=$1;
+
+-- name: GetUserVerifiedStatus :one
+SELECT verified_status FROM users
+WHERE id=$1;
+
+-- name: GetUserAuthState :one
+SELECT token_version, suspended_at FROM users
+WHERE id=$1;
+
+-- name: IncrementTokenVersion :exec
+UPDATE users
+SET token_version=COALESCE(token_version, 0) + 1
+WHERE id=$1;
+
+
+-- name: UpdateUserAccessLevel :exec
+UPDATE users
+SET access_level=$1, token_version=COALESCE(token_version, 0) + 1, updated_at=CURRENT_TIMESTAMP
+WHERE id=$2;
+
+-- name: UpdateLastLoggedIn :exec
+UPDATE users
+SET last_logged_in=CURRENT_TIMESTAMP
+WHERE id=$1;
\ No newline at end of file
@@ -0,0 +1,16 @@
+-- name: InsertVerificationToken :exec
+INSERT INTO verification_tokens(user_id, token, expires_at)
+VALUES($1, $2, $3);
+
+-- name: GetVerificationToken :one
+SELECT * FROM verification_tokens
+WHERE token=$1;
+
+-- name: DeleteUserVerificationTokens :exec
+DELETE FROM verification_tokens
+WHERE user_id=$1;
+
+-- name: SetUserVerified :exec
+UPDATE users
+SET verified_status=TRUE, updated_at=CURRENT_TIMESTAMP
+WHERE id=$1;